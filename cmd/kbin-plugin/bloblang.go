@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// methodLogger discards interpreter logs, as Bloblang methods have no logger
+var methodLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// methodSchemas caches the schema files read by methods that name no
+// processor. Unlike a processor's cache, it is never reloaded.
+var methodSchemas = newSchemaLRU(defaultSchemaCacheSize)
+
+// methodInterpreters and methodSerializers hold what methods that name no
+// processor compile from the schemas of methodSchemas, by root type
+var (
+	methodInterpreters = newCompiledCache[*kst.KaitaiInterpreter](2 * defaultSchemaCacheSize)
+	methodSerializers  = newCompiledCache[*kst.KaitaiSerializer](2 * defaultSchemaCacheSize)
+)
+
+// methodLimits bounds the parses of methods that name no processor, as
+// mappings may apply them to untrusted bytes
+var methodLimits = kst.Limits{
+	MaxDepth:       64,
+	MaxRepeatItems: 1 << 20,
+	MaxAllocation:  64 << 20,
+	MaxNodes:       1 << 22,
+	CELCostLimit:   1_000_000,
+}
+
+func init() {
+	err := bloblang.RegisterMethodV2("kaitai_parse",
+		bloblang.NewPluginSpec().
+			Category("Parsing").
+			Description("Parses binary data with a Kaitai Struct (.ksy) schema into a structured object, like the `kaitai` processor in parser mode. Schemas are loaded and cached by the `kaitai` processor named by `processor`, from its `schema_resource` if set, and parsed with its resource limits. Without `processor`, schemas are read from files and cached, and parsing is bounded by default limits: a nesting depth of 64, 1048576 items per repeated field, 64 MiB per sized read, 4194304 fields and a CEL cost of 1000000 per expression.").
+			Param(bloblang.NewStringParam("schema").Description("Path to the KSY schema.")).
+			Param(bloblang.NewStringParam("root").Description("Root type to parse. Defaults to the schema's `meta.id`.").Default("")).
+			Param(bloblang.NewStringParam("processor").Description("Label of a `kaitai` processor whose schema source, cache, hot reload and resource limits to use.").Default("")).
+			ExampleNotTested("Decode an embedded binary field.",
+				`root.reading = this.payload.decode("base64").kaitai_parse(schema: "./schemas/reading.ksy")`),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			target, err := newMethodTarget(args)
+			if err != nil {
+				return nil, err
+			}
+			return bloblang.BytesMethod(func(data []byte) (any, error) {
+				interpreter, err := target.interpreter()
+				if err != nil {
+					return nil, err
+				}
+				parsed, err := interpreter.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse binary data (size: %d bytes): %w", len(data), err)
+				}
+				return kst.ParsedDataToMap(parsed), nil
+			}), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+
+	err = bloblang.RegisterMethodV2("kaitai_serialize",
+		bloblang.NewPluginSpec().
+			Category("Parsing").
+			Description("Serializes a structured object to binary data with a Kaitai Struct (.ksy) schema, like the `kaitai` processor in serializer mode. Schemas are loaded and cached as by `kaitai_parse`.").
+			Param(bloblang.NewStringParam("schema").Description("Path to the KSY schema.")).
+			Param(bloblang.NewStringParam("root").Description("Root type to serialize. Defaults to the schema's `meta.id`.").Default("")).
+			Param(bloblang.NewStringParam("processor").Description("Label of a `kaitai` processor whose schema source, cache, hot reload, `skip_validation` and input shapes to use.").Default("")).
+			Param(bloblang.NewBoolParam("auto_compute").Description("Infer fields used as the `size` or `repeat-expr` of other fields from the data.").Default(false)).
+			ExampleNotTested("Encode an object into an embedded binary field.",
+				`root.payload = this.reading.kaitai_serialize(schema: "./schemas/reading.ksy").encode("base64")`),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			target, err := newMethodTarget(args)
+			if err != nil {
+				return nil, err
+			}
+			autoCompute, err := args.GetBool("auto_compute")
+			if err != nil {
+				return nil, err
+			}
+			return bloblang.ObjectMethod(func(obj map[string]any) (any, error) {
+				serializer, err := target.serializer(autoCompute)
+				if err != nil {
+					return nil, err
+				}
+				data, err := serializer.Serialize(context.Background(), obj)
+				if err != nil {
+					return nil, fmt.Errorf("failed to serialize data: %w", err)
+				}
+				return data, nil
+			}), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// methodTarget is the schema and root type named by the `schema`, `root`
+// and `processor` arguments of a Bloblang method
+type methodTarget struct {
+	path     string
+	rootType string
+	label    string
+}
+
+// newMethodTarget reads the arguments naming the schema of a Bloblang method
+func newMethodTarget(args *bloblang.ParsedParams) (*methodTarget, error) {
+	path, err := args.GetString("schema")
+	if err != nil {
+		return nil, err
+	}
+	rootType, err := args.GetString("root")
+	if err != nil {
+		return nil, err
+	}
+	label, err := args.GetString("processor")
+	if err != nil {
+		return nil, err
+	}
+	target := &methodTarget{path: path, rootType: rootType, label: label}
+	if label == "" {
+		// Catch a missing schema file when the mapping is parsed; a named
+		// processor may not have been created yet
+		if _, err := target.fileSchema(); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// processor returns the processor named by the target
+func (m *methodTarget) processor() (*KaitaiProcessor, error) {
+	processor, ok := labelledProcessors.Load(m.label)
+	if !ok {
+		return nil, fmt.Errorf("no kaitai processor labelled '%s'", m.label)
+	}
+	return processor.(*KaitaiProcessor), nil
+}
+
+// fileSchema returns the schema file of a target naming no processor
+func (m *methodTarget) fileSchema() (*kst.KaitaiSchema, error) {
+	if schema, ok := methodSchemas.Load(m.path); ok {
+		return schema, nil
+	}
+	schema, err := schemaSource{}.read(m.path)
+	if err != nil {
+		return nil, err
+	}
+	schema, _ = methodSchemas.LoadOrStore(m.path, schema)
+	return schema, nil
+}
+
+// interpreter returns an interpreter for the target, forked from one compiled
+// once per schema version. The schema is loaded on every call, as a processor
+// may reload it; the caches make that cheap.
+func (m *methodTarget) interpreter() (*kst.KaitaiInterpreter, error) {
+	if m.label != "" {
+		kp, err := m.processor()
+		if err != nil {
+			return nil, err
+		}
+		schema, err := kp.loadDataSchema(m.path)
+		if err != nil {
+			return nil, err
+		}
+		return kp.interpreter(schema, m.rootType, "method_interpreter")
+	}
+	schema, err := m.fileSchema()
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := methodInterpreters.LoadOrCompile(schema, m.rootType, func() (*kst.KaitaiInterpreter, error) {
+		currentSchema := *schema // Copy so the cached schema keeps its own root type
+		if m.rootType != "" {
+			currentSchema.RootType = m.rootType
+		}
+		interpreter, err := kst.NewKaitaiInterpreter(&currentSchema, methodLogger, kst.WithLimits(methodLimits))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create interpreter: %w", err)
+		}
+		return interpreter, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Fork(), nil
+}
+
+// serializer returns a serializer for the target, forked from one compiled
+// once per schema version. Through a processor, it validates and reads input
+// shapes as the processor does.
+func (m *methodTarget) serializer(autoCompute bool) (*kst.KaitaiSerializer, error) {
+	if m.label != "" {
+		kp, err := m.processor()
+		if err != nil {
+			return nil, err
+		}
+		schema, err := kp.loadDataSchema(m.path)
+		if err != nil {
+			return nil, err
+		}
+		component := "method_serializer"
+		if autoCompute {
+			component = "method_auto_compute_serializer"
+		}
+		return kp.serializer(schema, m.rootType, component, kst.WithAutoCompute(autoCompute), kst.WithSkipValidation(kp.config.SkipValidation), kst.WithInputFormat(kp.config.outputOptions()...))
+	}
+	schema, err := m.fileSchema()
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := methodSerializers.LoadOrCompile(schema, fmt.Sprint(m.rootType, "/", autoCompute), func() (*kst.KaitaiSerializer, error) {
+		currentSchema := *schema // Copy so the cached schema keeps its own root type
+		if m.rootType != "" {
+			currentSchema.RootType = m.rootType
+		}
+		serializer, err := kst.NewKaitaiSerializer(&currentSchema, methodLogger, kst.WithAutoCompute(autoCompute))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create serializer: %w", err)
+		}
+		return serializer, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Fork(), nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// carryOverStream holds the unparsed tail of a stream and when it was stored.
+// Its lock is held from taking the tail until the next one is stored, so the
+// messages of a stream are cut into frames one at a time.
+type carryOverStream struct {
+	mu       sync.Mutex
+	data     []byte
+	storedAt time.Time
+	users    int // Holders of mu and those waiting for it; guarded by carryOverBuffers.mu
+}
+
+// carryOverBuffers keeps the bytes of a partial trailing frame per stream key,
+// so a frame that straddles messages is parsed once the rest of it arrives.
+// Every stream buffers at most maxSize bytes, and tails older than idleTimeout
+// are dropped, by the next message of the stream or by a periodic sweep.
+type carryOverBuffers struct {
+	mu          sync.Mutex
+	streams     map[string]*carryOverStream
+	maxSize     int
+	idleTimeout time.Duration // 0 keeps tails until the stream sends more data
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newCarryOverBuffers creates carry-over buffers holding up to maxSize bytes
+// per stream. With an idle timeout, idle tails are swept in the background
+// and onSweep is called with the number of bytes dropped.
+func newCarryOverBuffers(maxSize int, idleTimeout time.Duration, onSweep func(dropped int)) *carryOverBuffers {
+	c := &carryOverBuffers{
+		streams:     make(map[string]*carryOverStream),
+		maxSize:     maxSize,
+		idleTimeout: idleTimeout,
+		done:        make(chan struct{}),
+	}
+	if idleTimeout > 0 {
+		c.wg.Add(1)
+		go c.runSweeper(onSweep)
+	}
+	return c
+}
+
+// runSweeper sweeps idle tails until close is called
+func (c *carryOverBuffers) runSweeper(onSweep func(dropped int)) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(max(c.idleTimeout/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if dropped := c.sweep(); dropped > 0 && onSweep != nil {
+				onSweep(dropped)
+			}
+		}
+	}
+}
+
+// lock locks the stream of key, waiting while another message of it is
+// processed. The stream must be passed to unlock once done with.
+func (c *carryOverBuffers) lock(key string) *carryOverStream {
+	c.mu.Lock()
+	s, ok := c.streams[key]
+	if !ok {
+		s = &carryOverStream{}
+		c.streams[key] = s
+	}
+	s.users++
+	c.mu.Unlock()
+
+	s.mu.Lock()
+	return s
+}
+
+// unlock unlocks a stream locked by lock, forgetting it if it holds no tail
+func (c *carryOverBuffers) unlock(key string, s *carryOverStream) {
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.users--
+	if s.users == 0 && len(s.data) == 0 && c.streams[key] == s {
+		delete(c.streams, key)
+	}
+}
+
+// take removes and returns the tail of a locked stream. A tail stored longer
+// than the idle timeout ago is dropped instead, and its size returned.
+func (c *carryOverBuffers) take(s *carryOverStream) (data []byte, dropped int) {
+	data, s.data = s.data, nil
+	if c.idleTimeout > 0 && len(data) > 0 && SystemTime.Now().Sub(s.storedAt) >= c.idleTimeout {
+		return nil, len(data)
+	}
+	return data, 0
+}
+
+// store buffers data as the tail of a locked stream. A tail larger than the
+// maximum buffer size can never complete a frame, so it isn't kept and store
+// returns false.
+func (c *carryOverBuffers) store(s *carryOverStream, data []byte) bool {
+	if len(data) > c.maxSize {
+		return false
+	}
+	// Copy so the tail doesn't pin the whole message buffer
+	s.data = append([]byte(nil), data...)
+	s.storedAt = SystemTime.Now()
+	return true
+}
+
+// sweep drops the tails of streams not being processed that were stored
+// longer than the idle timeout ago, and returns how many bytes were dropped
+func (c *carryOverBuffers) sweep() int {
+	if c.idleTimeout <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := SystemTime.Now()
+	dropped := 0
+	for key, s := range c.streams {
+		// Streams in use are left to their message
+		if s.users == 0 && now.Sub(s.storedAt) >= c.idleTimeout {
+			dropped += len(s.data)
+			delete(c.streams, key)
+		}
+	}
+	return dropped
+}
+
+// close stops the sweeper, drops every buffered tail and returns how many
+// bytes were dropped
+func (c *carryOverBuffers) close() int {
+	close(c.done)
+	c.wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dropped := 0
+	for key, s := range c.streams {
+		s.mu.Lock()
+		dropped += len(s.data)
+		s.data = nil
+		s.mu.Unlock()
+		delete(c.streams, key)
+	}
+	return dropped
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/redpanda-data/benthos/v4/public/service"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// How the kaitai_file input handles a record that fails to parse
+const (
+	fileOnErrorFail = "fail" // Fail reads at the record, retrying it
+	fileOnErrorSkip = "skip" // Skip bytes until a record parses
+)
+
+// fileReadBufferSize is the read buffer size of kaitai_file inputs
+const fileReadBufferSize = 64 * 1024
+
+func init() {
+	err := service.RegisterInput(
+		"kaitai_file",
+		kaitaiFileInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			input, err := newKaitaiFileInputFromConfig(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(input), nil
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// kaitaiFileInputConfig returns a config spec for a kaitai_file input.
+func kaitaiFileInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Summary("Reads a binary file as a sequence of records defined by a Kaitai Struct schema, emitting one message per record.").
+		Description("The file is read through a seekable stream one record at a time, so files of any size can be processed without loading them into memory. Each message holds the parsed record and carries its byte offset in the `kaitai_file_offset` metadata. Reading ends at the end of the file. A truncated or corrupt record is handled as set by `on_error`.").
+		Field(service.NewStringField("path").
+			Description("Path of the binary file to read.").
+			Example("./recordings/flight_042.bin")).
+		Field(service.NewStringField("schema_path").
+			Description("Path to the Kaitai Struct (.ksy) schema defining the records.").
+			Example("./schemas/recorder.ksy")).
+		Field(service.NewStringField("record_type").
+			Description("Type of the schema that each record is parsed as. Defaults to the schema's `meta.id`.").
+			Default("")).
+		Field(service.NewStringField("header_type").
+			Description("Optional type of the schema parsed once at the start of the file, before the first record.").
+			Default("")).
+		Field(service.NewStringField("header_key").
+			Description("When set, the parsed header is added under this key to the structured output of each record.").
+			Example("header").
+			Default("")).
+		Field(service.NewStringEnumField("on_error", fileOnErrorFail, fileOnErrorSkip).
+			Description("What to do with a record that fails to parse. `fail` fails the read with the parse error and retries the record up to 'max_retries' times, so that, for instance, a file still being written can complete the record; after that, the error is logged and reading ends. `skip` skips the bytes from the record's offset up to the next offset where a record parses, logging how many were skipped; at the end of the file, reading ends.").
+			Default(fileOnErrorFail)).
+		Field(service.NewIntField("max_retries").
+			Description("With `on_error: fail`, how many times to read a record that failed to parse again before giving up. 0 gives up at the first failure.").
+			Default(3)).
+		Field(service.NewIntField("start_offset").
+			Description("Byte offset of the first record to read, e.g. to resume from a known position. Defaults to the first record after the header.").
+			Default(0).Advanced()).
+		Field(service.NewStringField("checkpoint_cache").
+			Description("Optional cache resource storing the offset after the last record whose message, and every message before it, was acknowledged. Reading resumes from that offset on restart, taking precedence over 'start_offset'.").
+			Default("")).
+		Field(service.NewStringField("checkpoint_key").
+			Description("Key of the checkpoint in 'checkpoint_cache'. Defaults to 'path'.").
+			Default("").Advanced()).
+		Fields(limitFields()...)
+}
+
+// kaitaiFileInput reads the records of a binary file as messages
+type kaitaiFileInput struct {
+	path            string
+	schema          *kst.KaitaiSchema
+	recordType      string
+	headerType      string
+	headerKey       string
+	onError         string
+	maxRetries      int
+	startOffset     int64
+	checkpointCache string
+	checkpointKey   string
+	limits          kst.Limits
+	resources       *service.Resources
+	logger          *service.Logger
+
+	file     *os.File
+	records  *recordReader
+	header   map[string]any
+	progress *offsetCheckpoint
+
+	failedOffset int64 // Offset of the record that last failed to parse
+	failures     int   // Consecutive failed reads of the record at failedOffset
+}
+
+// newKaitaiFileInputFromConfig creates a new kaitaiFileInput from a parsed config.
+func newKaitaiFileInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*kaitaiFileInput, error) {
+	path, err := conf.FieldString("path")
+	if err != nil {
+		return nil, err
+	}
+	schemaPath, err := conf.FieldString("schema_path")
+	if err != nil {
+		return nil, err
+	}
+	recordType, err := conf.FieldString("record_type")
+	if err != nil {
+		return nil, err
+	}
+	headerType, err := conf.FieldString("header_type")
+	if err != nil {
+		return nil, err
+	}
+	headerKey, err := conf.FieldString("header_key")
+	if err != nil {
+		return nil, err
+	}
+	onError, err := conf.FieldString("on_error")
+	if err != nil {
+		return nil, err
+	}
+	maxRetries, err := conf.FieldInt("max_retries")
+	if err != nil {
+		return nil, err
+	}
+	startOffset, err := conf.FieldInt("start_offset")
+	if err != nil {
+		return nil, err
+	}
+	checkpointCache, err := conf.FieldString("checkpoint_cache")
+	if err != nil {
+		return nil, err
+	}
+	checkpointKey, err := conf.FieldString("checkpoint_key")
+	if err != nil {
+		return nil, err
+	}
+	limits, err := limitsFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRetries < 0 {
+		return nil, fmt.Errorf("max_retries must not be negative")
+	}
+	if startOffset < 0 {
+		return nil, fmt.Errorf("start_offset must not be negative")
+	}
+	if headerKey != "" && headerType == "" {
+		return nil, fmt.Errorf("header_key requires header_type")
+	}
+	if checkpointCache != "" && !mgr.HasCache(checkpointCache) {
+		return nil, fmt.Errorf("checkpoint_cache '%s' not found", checkpointCache)
+	}
+	if checkpointKey == "" {
+		checkpointKey = path
+	}
+
+	schema, err := schemaSource{logger: mgr.Logger()}.read(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	if recordType == "" {
+		recordType = schema.Meta.ID
+	}
+
+	return &kaitaiFileInput{
+		path:            path,
+		schema:          schema,
+		recordType:      recordType,
+		headerType:      headerType,
+		headerKey:       headerKey,
+		onError:         onError,
+		maxRetries:      maxRetries,
+		startOffset:     int64(startOffset),
+		checkpointCache: checkpointCache,
+		checkpointKey:   checkpointKey,
+		limits:          limits,
+		resources:       mgr,
+		logger:          mgr.Logger(),
+	}, nil
+}
+
+// Connect opens the file, parses its header and seeks to the first record to read.
+func (f *kaitaiFileInput) Connect(ctx context.Context) error {
+	if f.file != nil {
+		return nil
+	}
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open '%s': %w", f.path, err)
+	}
+	records := newRecordReader(newBufferedFile(file), f.schema, f.limits, slog.New(newBenthosLogHandler(f.logger)).With("component", "file_interpreter"))
+
+	var header map[string]any
+	if f.headerType != "" {
+		if header, _, err = records.read(ctx, f.headerType); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to parse header of '%s': %w", f.path, err)
+		}
+	}
+
+	offset := records.offset()
+	if f.startOffset > 0 {
+		offset = f.startOffset
+	}
+	if checkpoint, ok, err := f.loadCheckpoint(ctx); err != nil {
+		file.Close()
+		return err
+	} else if ok {
+		offset = checkpoint
+	}
+	if err := records.seek(offset); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek to offset %d of '%s': %w", offset, f.path, err)
+	}
+
+	f.file, f.records, f.header = file, records, header
+	f.progress = newOffsetCheckpoint(offset)
+	f.logger.With("path", f.path, "offset", offset).Infof("Reading %s records", f.recordType)
+	return nil
+}
+
+// Read parses the next record.
+func (f *kaitaiFileInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	if f.records == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+	offset := f.records.offset()
+	if f.records.eof() {
+		return nil, nil, service.ErrEndOfInput
+	}
+	record, end, err := f.records.read(ctx, f.recordType)
+	if err != nil && f.onError == fileOnErrorSkip {
+		record, offset, end, err = f.skipCorrupt(ctx, offset, err)
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrEndOfInput) {
+			return nil, nil, err
+		}
+		// Read the record again on retry
+		if seekErr := f.records.seek(offset); seekErr != nil {
+			return nil, nil, fmt.Errorf("failed to seek back to offset %d of '%s': %w", offset, f.path, seekErr)
+		}
+		if isEOFError(err) {
+			err = fmt.Errorf("truncated %s record at offset %d of '%s': %w", f.recordType, offset, f.path, err)
+		} else {
+			err = fmt.Errorf("failed to parse %s record at offset %d of '%s': %w", f.recordType, offset, f.path, err)
+		}
+		if offset != f.failedOffset {
+			f.failedOffset, f.failures = offset, 0
+		}
+		if f.failures++; f.failures > f.maxRetries {
+			f.logger.With("path", f.path, "offset", offset, "attempts", f.failures).Errorf("Giving up reading: %v", err)
+			return nil, nil, service.ErrEndOfInput
+		}
+		return nil, nil, err
+	}
+	f.failures = 0
+
+	msg := service.NewMessage(nil)
+	if f.headerKey != "" {
+		record = withFrameHeader(record, f.headerKey, copyHeader(f.header))
+	}
+	msg.SetStructured(record)
+	msg.MetaSetMut("kaitai_file_path", f.path)
+	msg.MetaSetMut("kaitai_file_offset", offset)
+	msg.MetaSetMut("kaitai_record_size", end-offset)
+	msg.MetaSet("kaitai_root_type", f.recordType)
+
+	f.progress.add(offset, end)
+	return msg, func(ctx context.Context, err error) error {
+		if err != nil {
+			return nil // Retried by AutoRetryNacks
+		}
+		if committed, ok := f.progress.ack(offset); ok {
+			return f.storeCheckpoint(ctx, committed)
+		}
+		return nil
+	}, nil
+}
+
+// skipCorrupt skips bytes after the corrupt record at offset, a byte at a
+// time, until a record parses. It returns the record with its offset and end,
+// or ErrEndOfInput if no record parses before the end of the file.
+func (f *kaitaiFileInput) skipCorrupt(ctx context.Context, offset int64, cause error) (map[string]any, int64, int64, error) {
+	for start := offset + 1; ; start++ {
+		if err := ctx.Err(); err != nil {
+			return nil, offset, 0, err
+		}
+		if err := f.records.seek(start); err != nil {
+			return nil, offset, 0, err
+		}
+		if f.records.eof() {
+			f.logger.With("path", f.path, "offset", offset, "skipped_bytes", start-offset).Warnf("Skipped a corrupt %s record up to the end of the file: %v", f.recordType, cause)
+			return nil, offset, 0, service.ErrEndOfInput
+		}
+		if record, end, err := f.records.read(ctx, f.recordType); err == nil {
+			f.logger.With("path", f.path, "offset", offset, "skipped_bytes", start-offset).Warnf("Skipped a corrupt %s record: %v", f.recordType, cause)
+			return record, start, end, nil
+		}
+	}
+}
+
+// Close closes the file.
+func (f *kaitaiFileInput) Close(ctx context.Context) error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file, f.records = nil, nil
+	return err
+}
+
+// loadCheckpoint returns the offset stored in checkpoint_cache, if any
+func (f *kaitaiFileInput) loadCheckpoint(ctx context.Context) (int64, bool, error) {
+	if f.checkpointCache == "" {
+		return 0, false, nil
+	}
+	var data []byte
+	var getErr error
+	err := f.resources.AccessCache(ctx, f.checkpointCache, func(c service.Cache) {
+		data, getErr = c.Get(ctx, f.checkpointKey)
+	})
+	if err == nil && errors.Is(getErr, service.ErrKeyNotFound) {
+		return 0, false, nil
+	}
+	if err == nil {
+		err = getErr
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read checkpoint '%s': %w", f.checkpointKey, err)
+	}
+	offset, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, false, fmt.Errorf("invalid checkpoint '%s': %q", f.checkpointKey, data)
+	}
+	return offset, true, nil
+}
+
+// storeCheckpoint stores offset in checkpoint_cache, if set
+func (f *kaitaiFileInput) storeCheckpoint(ctx context.Context, offset int64) error {
+	if f.checkpointCache == "" {
+		return nil
+	}
+	var setErr error
+	err := f.resources.AccessCache(ctx, f.checkpointCache, func(c service.Cache) {
+		setErr = c.Set(ctx, f.checkpointKey, []byte(strconv.FormatInt(offset, 10)), nil)
+	})
+	if err == nil {
+		err = setErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store checkpoint '%s': %w", f.checkpointKey, err)
+	}
+	return nil
+}
+
+// recordReader parses consecutive records from a seekable stream, reading
+// only the bytes of the record being parsed
+type recordReader struct {
+	stream       *kaitai.Stream
+	schema       *kst.KaitaiSchema
+	limits       kst.Limits
+	logger       *slog.Logger
+	interpreters map[string]*kst.KaitaiInterpreter // By root type, reused for every record
+}
+
+// newRecordReader reads records described by schema from r, within limits
+func newRecordReader(r io.ReadSeeker, schema *kst.KaitaiSchema, limits kst.Limits, logger *slog.Logger) *recordReader {
+	return &recordReader{
+		stream:       kaitai.NewStream(r),
+		schema:       schema,
+		limits:       limits,
+		logger:       logger,
+		interpreters: make(map[string]*kst.KaitaiInterpreter),
+	}
+}
+
+// interpreter returns the interpreter parsing values of typeName
+func (r *recordReader) interpreter(typeName string) (*kst.KaitaiInterpreter, error) {
+	if interpreter, ok := r.interpreters[typeName]; ok {
+		return interpreter, nil
+	}
+	currentSchema := *r.schema // Copy so the schema keeps its own root type
+	currentSchema.RootType = typeName
+	interpreter, err := kst.NewKaitaiInterpreter(&currentSchema, r.logger, kst.WithLimits(r.limits))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create interpreter: %w", err)
+	}
+	r.interpreters[typeName] = interpreter
+	return interpreter, nil
+}
+
+// read parses a value of typeName at the current offset and returns it with
+// the offset right after it
+func (r *recordReader) read(ctx context.Context, typeName string) (map[string]any, int64, error) {
+	start := r.offset()
+	interpreter, err := r.interpreter(typeName)
+	if err != nil {
+		return nil, 0, err
+	}
+	parsed, err := interpreter.Parse(ctx, r.stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	end := r.offset()
+	if end <= start {
+		return nil, 0, fmt.Errorf("%s at offset %d is empty, so records can't advance", typeName, start)
+	}
+	record, _ := kst.ParsedDataToMap(parsed).(map[string]any)
+	return record, end, nil
+}
+
+// offset returns the offset of the next record
+func (r *recordReader) offset() int64 {
+	pos, _ := r.stream.Pos()
+	return pos
+}
+
+// seek moves to the record at offset
+func (r *recordReader) seek(offset int64) error {
+	_, err := r.stream.Seek(offset, io.SeekStart)
+	return err
+}
+
+// eof reports whether there are no more records
+func (r *recordReader) eof() bool {
+	eof, err := r.stream.EOF()
+	return err != nil || eof
+}
+
+// bufferedFile buffers reads of a file for the many small reads of parsing,
+// while staying seekable as kaitai streams require. Seeks only move the
+// offset of the next read, so the seeks kaitai streams make to find their
+// size or check for EOF keep the buffer. Reads are filled completely unless
+// the file ends, as kaitai streams read each value with a single Read.
+type bufferedFile struct {
+	file      io.ReadSeeker
+	reader    *bufio.Reader
+	pos       int64 // Offset of the next byte to read
+	readerPos int64 // Offset of the next byte returned by reader
+}
+
+// newBufferedFile buffers reads of file from its current offset, which must be 0
+func newBufferedFile(file io.ReadSeeker) *bufferedFile {
+	return &bufferedFile{file: file, reader: bufio.NewReaderSize(file, fileReadBufferSize)}
+}
+
+// Read reads len(p) bytes from the current offset, or fewer if the file ends
+func (b *bufferedFile) Read(p []byte) (int, error) {
+	if err := b.sync(); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(b.reader, p)
+	b.pos += int64(n)
+	b.readerPos = b.pos
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next read
+func (b *bufferedFile) Seek(offset int64, whence int) (int64, error) {
+	target := offset
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		target = b.pos + offset
+	case io.SeekEnd:
+		size, err := b.size()
+		if err != nil {
+			return b.pos, err
+		}
+		target = size + offset
+	default:
+		return b.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if target < 0 {
+		return b.pos, fmt.Errorf("negative offset %d", target)
+	}
+	b.pos = target
+	return b.pos, nil
+}
+
+// size returns the current size of the file, which may still be growing,
+// leaving the file offset where reader expects it
+func (b *bufferedFile) size() (int64, error) {
+	cur, err := b.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := b.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := b.file.Seek(cur, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
+
+// sync moves reader to the offset of the next read, within the buffered
+// bytes where it can, and otherwise by seeking the file and dropping the buffer
+func (b *bufferedFile) sync() error {
+	switch {
+	case b.pos == b.readerPos:
+		return nil
+	case b.pos == b.readerPos-1 && b.reader.UnreadByte() == nil:
+		// Stream.EOF reads a byte and seeks back over it
+		b.readerPos = b.pos
+		return nil
+	case b.pos > b.readerPos && b.pos-b.readerPos <= int64(b.reader.Buffered()):
+		n, err := b.reader.Discard(int(b.pos - b.readerPos))
+		b.readerPos += int64(n)
+		return err
+	}
+	if _, err := b.file.Seek(b.pos, io.SeekStart); err != nil {
+		return err
+	}
+	b.reader.Reset(b.file)
+	b.readerPos = b.pos
+	return nil
+}
+
+// offsetCheckpoint tracks the records in flight, in file order, to find the
+// offset before which every record has been acknowledged
+type offsetCheckpoint struct {
+	mu        sync.Mutex
+	committed int64
+	pending   []pendingRecord
+}
+
+// pendingRecord is a record in flight
+type pendingRecord struct {
+	start, end int64
+	acked      bool
+}
+
+// newOffsetCheckpoint starts tracking records from offset
+func newOffsetCheckpoint(offset int64) *offsetCheckpoint {
+	return &offsetCheckpoint{committed: offset}
+}
+
+// add tracks a record read from start up to end
+func (c *offsetCheckpoint) add(start, end int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, pendingRecord{start: start, end: end})
+}
+
+// ack marks the record at start as acknowledged. It returns the new
+// committed offset if the record completes a run of acknowledged records
+// from the previous one.
+func (c *offsetCheckpoint) ack(start int64) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.pending {
+		if c.pending[i].start == start {
+			c.pending[i].acked = true
+			break
+		}
+	}
+	advanced := false
+	for len(c.pending) > 0 && c.pending[0].acked {
+		c.committed = c.pending[0].end
+		c.pending = c.pending[1:]
+		advanced = true
+	}
+	return c.committed, advanced
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// allScalarFrameFields is the framing_metadata_fields key that copies every
+// top-level scalar field of the frame, with its value used as a key prefix
+const allScalarFrameFields = "*"
+
+// frameHeader returns the parsed frame without its payload field
+func frameHeader(frame map[string]any, dataFieldID string) map[string]any {
+	header := maps.Clone(frame)
+	delete(header, dataFieldID)
+	return header
+}
+
+// copyHeader returns a deep copy of a parsed header, for messages that are
+// each given the same header and may modify it
+func copyHeader(header map[string]any) map[string]any {
+	if header == nil {
+		return nil
+	}
+	return copyParsedValue(header).(map[string]any)
+}
+
+// copyParsedValue deep copies the maps, arrays and bytes of a parsed value
+func copyParsedValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, item := range v {
+			copied[key] = copyParsedValue(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = copyParsedValue(item)
+		}
+		return copied
+	case []byte:
+		return bytes.Clone(v)
+	}
+	return value
+}
+
+// withFrameHeader adds header to an output object under key, if set
+func withFrameHeader(output map[string]any, key string, header map[string]any) map[string]any {
+	if header != nil {
+		output[key] = header
+	}
+	return output
+}
+
+// addFrameHeader adds header under key, if set, to parsed output that is an
+// object, either a map or ordered by preserve_order
+func addFrameHeader(output any, key string, header map[string]any) {
+	switch obj := output.(type) {
+	case map[string]any:
+		withFrameHeader(obj, key, header)
+	case *kst.OrderedMap:
+		if header != nil {
+			obj.Set(key, header)
+		}
+	}
+}
+
+// frameMetadata returns the metadata to set on the payload messages of frame.
+// fields maps a frame field path such as `header.seq` to a metadata key;
+// fields missing from the frame (e.g. because of an `if`) are left out.
+func frameMetadata(frame map[string]any, fields map[string]string) map[string]any {
+	if len(fields) == 0 {
+		return nil
+	}
+	meta := make(map[string]any, len(fields))
+	if prefix, ok := fields[allScalarFrameFields]; ok {
+		for name, value := range frame {
+			if v, ok := scalarMetaValue(value); ok {
+				meta[prefix+name] = v
+			}
+		}
+	}
+	for path, key := range fields {
+		if path == allScalarFrameFields {
+			continue
+		}
+		value, found := lookupFramePath(frame, path)
+		if !found {
+			continue
+		}
+		if v, ok := scalarMetaValue(value); ok {
+			meta[key] = v
+		} else if b, ok := value.([]byte); ok {
+			meta[key] = hex.EncodeToString(b)
+		}
+	}
+	return meta
+}
+
+// lookupFramePath resolves a dot-separated field path in a parsed frame
+func lookupFramePath(frame map[string]any, path string) (any, bool) {
+	var current any = frame
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// scalarMetaValue returns the metadata value of a scalar frame field. Enums
+// are given by their label, or by their value when it has no label.
+func scalarMetaValue(value any) (any, bool) {
+	switch v := value.(type) {
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, true
+	case map[string]any:
+		enumValue, isEnum := v["value"]
+		if _, hasValid := v["valid"]; !isEnum || !hasValid {
+			return nil, false
+		}
+		if name, ok := v["name"].(string); ok && name != "" {
+			return name, true
+		}
+		return enumValue, true
+	default:
+		return nil, false
+	}
+}
+
+// Kaitai integer and bit field types, whose metadata values are parsed as numbers
+var (
+	intTypePattern = regexp.MustCompile(`^[us][1248](le|be)?$`)
+	bitTypePattern = regexp.MustCompile(`^b[0-9]+(le|be)?$`)
+)
+
+// frameFromMetadata is the inverse of frameMetadata: it fills frame with the
+// values of msg's metadata keys mapped by fields, converting strings to the
+// type of the framing field they are written to. Fields already set in frame
+// are kept.
+func frameFromMetadata(frame map[string]any, msg *service.Message, schema *kst.KaitaiSchema, rootType string, fields map[string]string) error {
+	set := func(path, key string) error {
+		if _, exists := lookupFramePath(frame, path); exists {
+			return nil
+		}
+		value, found := msg.MetaGetMut(key)
+		if !found {
+			return nil
+		}
+		if str, ok := value.(string); ok {
+			if item, ok := framingField(schema, rootType, path); ok {
+				converted, err := metaToFrameValue(schema, rootType, item, str)
+				if err != nil {
+					return fmt.Errorf("metadata '%s' for frame field '%s': %w", key, path, err)
+				}
+				value = converted
+			}
+		}
+		setFramePath(frame, path, value)
+		return nil
+	}
+
+	if prefix, ok := fields[allScalarFrameFields]; ok {
+		for _, item := range framingRootSeq(schema, rootType) {
+			if err := set(item.ID, prefix+item.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for path, key := range fields {
+		if path == allScalarFrameFields {
+			continue
+		}
+		if err := set(path, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFramePath sets a dot-separated field path in a frame, creating nested objects
+func setFramePath(frame map[string]any, path string, value any) {
+	parts := strings.Split(path, ".")
+	current := frame
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+// framingField finds the sequence item a dot-separated path names in the framing root type
+func framingField(schema *kst.KaitaiSchema, rootType string, path string) (kst.SequenceItem, bool) {
+	seq := framingRootSeq(schema, rootType)
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		var item *kst.SequenceItem
+		for j := range seq {
+			if seq[j].ID == part {
+				item = &seq[j]
+				break
+			}
+		}
+		if item == nil {
+			return kst.SequenceItem{}, false
+		}
+		if i == len(parts)-1 {
+			return *item, true
+		}
+		typeName, _ := item.Type.(string)
+		userType, ok := schema.Types[typeName]
+		if !ok {
+			return kst.SequenceItem{}, false
+		}
+		seq = userType.Seq
+	}
+	return kst.SequenceItem{}, false
+}
+
+// metaToFrameValue converts a metadata string to the value a framing field expects
+func metaToFrameValue(schema *kst.KaitaiSchema, rootType string, item kst.SequenceItem, value string) (any, error) {
+	typeName, _ := item.Type.(string)
+	switch {
+	case item.Enum != "":
+		// Enums are given by their label, as frameMetadata writes them, or by their value
+		if n, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return n, nil
+		}
+		return framingEnumValue(schema, rootType, item.Enum, value)
+	case typeName == "b1":
+		return strconv.ParseBool(value)
+	case intTypePattern.MatchString(typeName) || bitTypePattern.MatchString(typeName):
+		if strings.HasPrefix(typeName, "u") || strings.HasPrefix(typeName, "b") {
+			return strconv.ParseUint(value, 0, 64)
+		}
+		return strconv.ParseInt(value, 0, 64)
+	case strings.HasPrefix(typeName, "f4") || strings.HasPrefix(typeName, "f8"):
+		return strconv.ParseFloat(value, 64)
+	case typeName == "" && item.Size != nil:
+		// Byte fields travel hex-encoded, as frameMetadata writes them
+		return hex.DecodeString(value)
+	default:
+		return value, nil
+	}
+}
+
+// framingEnumValue returns the value of label in the framing schema's enum
+// name, declared either in the root type or at the top level
+func framingEnumValue(schema *kst.KaitaiSchema, rootType string, name string, label string) (any, error) {
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+	enumDef, ok := schema.Types[rootType].Enums[name]
+	if !ok {
+		enumDef = schema.Enums[name]
+	}
+	for value, l := range enumDef {
+		if l == label {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("no value named '%s' in enum '%s'", label, name)
+}
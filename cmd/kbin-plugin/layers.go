@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/redpanda-data/benthos/v4/public/service"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// maxLayerDepth bounds how many layers one message is decoded through, so
+// dispatch rules that loop (e.g. IP-in-IP tunnels) always terminate
+const maxLayerDepth = 32
+
+// LayerConfig describes one layer of a layered protocol decoding chain, such
+// as Ethernet → IPv4 → UDP → application protocol
+type LayerConfig struct {
+	Name          string            `json:"name,omitempty" yaml:"name,omitempty"` // Output key, defaults to the schema's meta.id
+	SchemaPath    string            `json:"schema_path" yaml:"schema_path"`
+	RootType      string            `json:"root_type,omitempty" yaml:"root_type,omitempty"`
+	PayloadField  string            `json:"payload_field,omitempty" yaml:"payload_field,omitempty"`   // Field holding the next layer's bytes, empty for the last layer
+	DispatchField string            `json:"dispatch_field,omitempty" yaml:"dispatch_field,omitempty"` // Field whose value selects the next layer
+	Dispatch      map[string]string `json:"dispatch,omitempty" yaml:"dispatch,omitempty"`             // Field value -> next layer name or schema path
+}
+
+// layerConfigFields returns the config spec fields of one entry of `layers`
+func layerConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField("name").
+			Description("Key of this layer's fields in the output. Defaults to the meta.id of the layer's schema.").
+			Default(""),
+		service.NewStringField("schema_path").
+			Description("Path to the KSY schema of this layer."),
+		service.NewStringField("root_type").
+			Description("Root type in the layer's schema. Defaults to its meta.id.").
+			Default(""),
+		service.NewStringField("payload_field").
+			Description("Field holding the bytes of the next layer. Leave empty for the last layer.").
+			Default(""),
+		service.NewStringField("dispatch_field").
+			Description("Field whose value selects the next layer from 'dispatch', as a dot-separated path. Without it, decoding continues with the next layer in the list.").
+			Default(""),
+		service.NewStringMapField("dispatch").
+			Description("Maps values of 'dispatch_field' to the next layer, given by its name or schema_path. Values may be numbers (e.g. `0x0800`), enum labels or strings.").
+			Example(map[string]any{"0x0800": "ipv4.ksy", "0x86dd": "ipv6.ksy"}).
+			Optional(),
+	}
+}
+
+// layerConfigsFromParsed reads the entries of the `layers` field
+func layerConfigsFromParsed(confs []*service.ParsedConfig) ([]LayerConfig, error) {
+	layers := make([]LayerConfig, 0, len(confs))
+	for i, conf := range confs {
+		var l LayerConfig
+		var err error
+		if l.Name, err = conf.FieldString("name"); err != nil {
+			return nil, err
+		}
+		if l.SchemaPath, err = conf.FieldString("schema_path"); err != nil {
+			return nil, err
+		}
+		if l.RootType, err = conf.FieldString("root_type"); err != nil {
+			return nil, err
+		}
+		if l.PayloadField, err = conf.FieldString("payload_field"); err != nil {
+			return nil, err
+		}
+		if l.DispatchField, err = conf.FieldString("dispatch_field"); err != nil {
+			return nil, err
+		}
+		if conf.Contains("dispatch") {
+			if l.Dispatch, err = conf.FieldStringMap("dispatch"); err != nil {
+				return nil, err
+			}
+		}
+		if l.SchemaPath == "" {
+			return nil, fmt.Errorf("layers[%d]: schema_path is required", i)
+		}
+		layers = append(layers, l)
+	}
+	return layers, nil
+}
+
+// protocolLayer is a layer with its dispatch rules resolved to layer indexes
+type protocolLayer struct {
+	LayerConfig
+	numeric map[int64]int  // Numeric dispatch values, matched against integers and enum values
+	labels  map[string]int // Other dispatch values, matched against strings and enum labels
+}
+
+// layerChain decodes a message through a list of protocol layers
+type layerChain struct {
+	layers []protocolLayer
+}
+
+// newLayerChain resolves layer names and dispatch targets. schemaID returns
+// the meta.id of a schema, used as the name of layers that don't set one.
+func newLayerChain(configs []LayerConfig, schemaID func(path string) (string, error)) (*layerChain, error) {
+	c := &layerChain{layers: make([]protocolLayer, len(configs))}
+	byTarget := make(map[string]int, 2*len(configs))
+	for i, conf := range configs {
+		if conf.Name == "" {
+			id, err := schemaID(conf.SchemaPath)
+			if err != nil {
+				return nil, fmt.Errorf("layer %d: %w", i, err)
+			}
+			conf.Name = id
+		}
+		if (conf.DispatchField == "") != (len(conf.Dispatch) == 0) {
+			return nil, fmt.Errorf("layer '%s': dispatch_field and dispatch must be set together", conf.Name)
+		}
+		if conf.DispatchField != "" && conf.PayloadField == "" {
+			return nil, fmt.Errorf("layer '%s': dispatch requires payload_field", conf.Name)
+		}
+		c.layers[i].LayerConfig = conf
+		// Names take precedence over schema paths, and earlier layers over later ones
+		if _, exists := byTarget[conf.Name]; !exists {
+			byTarget[conf.Name] = i
+		}
+	}
+	for i, conf := range configs {
+		if _, exists := byTarget[conf.SchemaPath]; !exists {
+			byTarget[conf.SchemaPath] = i
+		}
+	}
+
+	for i := range c.layers {
+		layer := &c.layers[i]
+		for value, target := range layer.Dispatch {
+			next, ok := byTarget[target]
+			if !ok {
+				return nil, fmt.Errorf("layer '%s': dispatch target '%s' is not the name or schema_path of a layer", layer.Name, target)
+			}
+			if n, err := strconv.ParseInt(value, 0, 64); err == nil {
+				if layer.numeric == nil {
+					layer.numeric = make(map[int64]int)
+				}
+				layer.numeric[n] = next
+				continue
+			}
+			if layer.labels == nil {
+				layer.labels = make(map[string]int)
+			}
+			layer.labels[value] = next
+		}
+	}
+	return c, nil
+}
+
+// next returns the index of the layer decoding the payload of layer i, given
+// its parsed fields. It returns -1 if i is the last layer, and false if no
+// dispatch rule matches.
+func (c *layerChain) next(i int, parsed map[string]any) (int, bool) {
+	layer := &c.layers[i]
+	if layer.PayloadField == "" {
+		return -1, true
+	}
+	if layer.DispatchField == "" {
+		if i+1 < len(c.layers) {
+			return i + 1, true
+		}
+		return -1, true
+	}
+	value, found := lookupFramePath(parsed, layer.DispatchField)
+	if !found {
+		return 0, false
+	}
+	return layer.match(value)
+}
+
+// match finds the dispatch rule for a parsed field value
+func (l *protocolLayer) match(value any) (int, bool) {
+	if enum, ok := value.(map[string]any); ok {
+		if name, ok := enum["name"].(string); ok && name != "" {
+			if next, ok := l.labels[name]; ok {
+				return next, true
+			}
+		}
+		value = enum["value"]
+	}
+	switch v := value.(type) {
+	case int64:
+		next, ok := l.numeric[v]
+		return next, ok
+	case uint64:
+		next, ok := l.numeric[int64(v)]
+		return next, ok
+	case int:
+		next, ok := l.numeric[int64(v)]
+		return next, ok
+	case string:
+		next, ok := l.labels[v]
+		return next, ok
+	case bool:
+		next, ok := l.labels[strconv.FormatBool(v)]
+		return next, ok
+	case []byte:
+		next, ok := l.labels[hex.EncodeToString(v)]
+		return next, ok
+	default:
+		return 0, false
+	}
+}
+
+// parseLayeredBinary decodes a message through the configured layers. Each
+// layer's fields are added to the output under the layer's name; the payload
+// field of a layer is dropped once the next layer has decoded it. Decoding
+// stops, keeping the undecoded payload, when no dispatch rule matches.
+func (k *KaitaiProcessor) parseLayeredBinary(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	binData, err := msg.AsBytes()
+	if err != nil {
+		k.logger.Errorf("Failed to get binary data from message for layered parsing: %v", err)
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(fmt.Errorf("failed to get binary data from message: %w", err))
+		return service.MessageBatch{msg}, nil
+	}
+	if len(binData) == 0 {
+		k.logger.Warnf("Empty binary data provided for layered parsing")
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(fmt.Errorf("empty binary data provided"))
+		return service.MessageBatch{msg}, nil
+	}
+	startTime := SystemTime.Now()
+
+	output := make(map[string]any)
+	var decoded []string
+	var layerErr error
+	var failure *kst.ParseError // Where the failing layer failed, with partial_results
+	var previous any            // Output fields of the previously decoded layer
+	var previousLayer *protocolLayer
+	data := binData
+	for index := 0; index >= 0; {
+		if len(decoded) == maxLayerDepth {
+			layerErr = fmt.Errorf("stopped decoding after %d layers", maxLayerDepth)
+			break
+		}
+		layer := &k.layers.layers[index]
+
+		// Tunnels may repeat a layer, so later occurrences get a numbered key
+		key := layer.Name
+		for n := 2; output[key] != nil; n++ {
+			key = fmt.Sprintf("%s_%d", layer.Name, n)
+		}
+
+		parsed, shaped, err := k.parseLayer(ctx, layer, data)
+		if err != nil {
+			k.logger.With("data_size", len(data), "layer", layer.Name).Errorf("Failed to parse layer: %v", err)
+			k.mPayloadParsingErrors.Incr(1)
+			k.mErrorsTotal.Incr(1)
+			layerErr = fmt.Errorf("failed to parse layer '%s' (size: %d bytes): %w", layer.Name, len(data), err)
+			if failure = k.partialFailure(err); failure != nil {
+				// Locate the failing field within the output
+				failure.Path = strings.TrimSuffix(key+"."+failure.Path, ".")
+				output[key] = k.partialResult(failure)
+				decoded = append(decoded, key)
+				break
+			}
+			if previous == nil {
+				msg.SetError(layerErr)
+				return service.MessageBatch{msg}, nil
+			}
+			break
+		}
+		// The payload is output decoded, by this layer
+		switch fields := previous.(type) {
+		case map[string]any:
+			delete(fields, previousLayer.PayloadField)
+		case *kst.OrderedMap:
+			fields.Delete(previousLayer.PayloadField)
+		}
+		output[key] = shaped
+		decoded = append(decoded, key)
+
+		next, matched := k.layers.next(index, parsed)
+		if !matched {
+			k.logger.With("layer", layer.Name, "dispatch_field", layer.DispatchField).Debugf("No dispatch rule matches, leaving payload undecoded")
+			k.mLayerDispatchMisses.Incr(1)
+			break
+		}
+		if next < 0 {
+			break
+		}
+		payload, ok := parsed[layer.PayloadField].([]byte)
+		if !ok {
+			k.mErrorsTotal.Incr(1)
+			layerErr = fmt.Errorf("payload field '%s' of layer '%s' is not a byte field", layer.PayloadField, layer.Name)
+			break
+		}
+		previous, previousLayer, data, index = shaped, layer, payload, next
+	}
+	k.mBytesProcessed.Incr(int64(len(binData)))
+	k.mFrameProcDuration.Timing(SystemTime.Since(startTime).Nanoseconds())
+
+	newMsg := service.NewMessage(nil)
+	if k.config.PreserveOrder {
+		// Layers in the order they were decoded
+		ordered := kst.NewOrderedMap()
+		for _, key := range decoded {
+			ordered.Set(key, output[key])
+		}
+		setParsedOutput(newMsg, ordered)
+	} else {
+		newMsg.SetStructured(output)
+	}
+	msg.MetaWalk(func(key, value string) error {
+		newMsg.MetaSet(key, value)
+		return nil
+	})
+	newMsg.MetaSet("kaitai_layers", strings.Join(decoded, ","))
+	if layerErr != nil {
+		// Keep the outer layers, which decoded fine
+		if failure != nil {
+			setFailureLocation(newMsg, failure)
+		}
+		newMsg.SetError(layerErr)
+		return service.MessageBatch{newMsg}, nil
+	}
+	k.logger.With("data_size", len(binData), "layers", decoded).Debugf("Successfully parsed layered binary data")
+	k.mParsedTotal.Incr(1)
+	return service.MessageBatch{newMsg}, nil
+}
+
+// parseLayer parses data with the schema of layer, returning its fields for
+// dispatch, and in the configured output shapes and order, which may be the same map
+func (k *KaitaiProcessor) parseLayer(ctx context.Context, layer *protocolLayer, data []byte) (map[string]any, any, error) {
+	schema, err := k.loadDataSchema(layer.SchemaPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	interpreter, err := k.interpreter(schema, layer.RootType, "layer_interpreter", "layer", layer.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create interpreter: %w", err)
+	}
+	parsed, err := interpreter.Parse(ctx, kaitai.NewStream(bytes.NewReader(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	result, ok := kst.ParsedDataToMap(parsed).(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("layer '%s' did not parse to an object", layer.Name)
+	}
+	// Dispatch reads payloads as bytes and dispatch values as numbers, so it
+	// works on the default shapes
+	output := k.config.outputOptions()
+	if len(output) == 0 {
+		return result, result, nil
+	}
+	return result, kst.ParsedDataToMap(parsed, output...), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/redpanda-data/benthos/v4/public/service"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// limitFields returns the config fields bounding the resources parsing one
+// message or record may use, shared by the components that parse untrusted input
+func limitFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewIntField("max_depth").
+			Description("Maximum nesting depth of user types while parsing. Recursive types are allowed when set. 0 means unlimited.").
+			Default(0).Advanced(),
+		service.NewIntField("max_repeat_items").
+			Description("Maximum number of items in a single repeated field. 0 means unlimited.").
+			Default(0).Advanced(),
+		service.NewIntField("max_allocation_size").
+			Description("Maximum size in bytes of a single sized read (e.g. a `size` driven by a length field). 0 means unlimited.").
+			Default(0).Advanced(),
+		service.NewIntField("max_output_nodes").
+			Description("Maximum total number of fields produced by parsing one message. 0 means unlimited.").
+			Default(0).Advanced(),
+		service.NewIntField("cel_cost_limit").
+			Description("Maximum CEL cost of evaluating a single schema expression. 0 means unlimited.").
+			Default(0).Advanced(),
+	}
+}
+
+// limitsFromConfig reads the fields added by limitFields
+func limitsFromConfig(conf *service.ParsedConfig) (kst.Limits, error) {
+	maxDepth, err := conf.FieldInt("max_depth")
+	if err != nil {
+		return kst.Limits{}, err
+	}
+	maxRepeatItems, err := conf.FieldInt("max_repeat_items")
+	if err != nil {
+		return kst.Limits{}, err
+	}
+	maxAllocationSize, err := conf.FieldInt("max_allocation_size")
+	if err != nil {
+		return kst.Limits{}, err
+	}
+	maxOutputNodes, err := conf.FieldInt("max_output_nodes")
+	if err != nil {
+		return kst.Limits{}, err
+	}
+	celCostLimit, err := conf.FieldInt("cel_cost_limit")
+	if err != nil {
+		return kst.Limits{}, err
+	}
+	if maxDepth < 0 || maxRepeatItems < 0 || maxAllocationSize < 0 || maxOutputNodes < 0 || celCostLimit < 0 {
+		return kst.Limits{}, fmt.Errorf("resource limits (max_depth, max_repeat_items, max_allocation_size, max_output_nodes, cel_cost_limit) must not be negative")
+	}
+	return kst.Limits{
+		MaxDepth:       maxDepth,
+		MaxRepeatItems: maxRepeatItems,
+		MaxAllocation:  maxAllocationSize,
+		MaxNodes:       maxOutputNodes,
+		CELCostLimit:   uint64(celCostLimit),
+	}, nil
+}
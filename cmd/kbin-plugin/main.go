@@ -3,11 +3,17 @@ package main
 import (
 	"bytes"
 	"context"
-	"errors" // Import for errors.Is
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io" // Import for io.EOF and io.ErrUnexpectedEOF
+	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,7 +21,6 @@ import (
 	"github.com/redpanda-data/benthos/v4/public/service"
 	kcel "github.com/twinfer/kbin-plugin/pkg/kaitaicel" // Import the kaitaicel package
 	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	"gopkg.in/yaml.v3"
 )
 
 // benthosLogHandler wraps a Benthos logger to implement slog.Handler
@@ -164,8 +169,18 @@ func putBuffer(buf []byte) {
 
 type KaitaiProcessor struct {
 	config             KaitaiConfig
-	schemaCache        sync.Map // Cache for main data schemas
-	framingSchemaCache sync.Map // Cache for framing schemas
+	schemaPath         *service.InterpolatedString            // schema_path, resolved per message
+	rootType           *service.InterpolatedString            // root_type, resolved per message
+	schemaBaseDir      string                                 // Directory schema paths resolved per message must be in, empty if schema_path is static
+	schemaCache        *schemaLRU                             // Cache for main data schemas
+	framingSchemaCache *schemaLRU                             // Cache for framing schemas
+	interpreters       *compiledCache[*kst.KaitaiInterpreter] // Compiled interpreters forked for each message
+	serializers        *compiledCache[*kst.KaitaiSerializer]  // Compiled serializers forked for each message
+	carryOver          *carryOverBuffers                      // Partial trailing frames per stream, nil unless max_buffer_size is set
+	layers             *layerChain                            // Protocol layers to decode through, nil unless layers is set
+	watcher            *schemaWatcher                         // Reloads changed schemas, nil unless hot_reload is set
+	source             schemaSource                           // Reads schemas from files or schema_resource
+	resources          *service.Resources                     // Gives access to schema_resource
 	logger             *service.Logger
 
 	// Metrics
@@ -181,7 +196,13 @@ type KaitaiProcessor struct {
 	mPayloadParsingErrors       *service.MetricCounter
 	mPayloadSerializationErrors *service.MetricCounter
 	mBytesProcessed             *service.MetricCounter
+	mBufferDroppedBytes         *service.MetricCounter // Carried-over bytes dropped for exceeding max_buffer_size, going idle or on close
+	mLayerDispatchMisses        *service.MetricCounter // Layer payloads left undecoded because no dispatch rule matched
+	mPartialResults             *service.MetricCounter // Failed parses emitted with the fields decoded before the failure
+	mSchemaReloads              *service.MetricCounter // Changed schemas swapped into the caches
+	mSchemaReloadErrors         *service.MetricCounter // Changed schemas rejected, keeping the previous version
 	mFrameProcDuration          *service.MetricTimer
+	mBatchProcDuration          *service.MetricTimer
 }
 
 // KaitaiConfig contains configuration parameters for the Kaitai processor
@@ -190,19 +211,109 @@ type KaitaiConfig struct {
 	IsParser   bool   `json:"is_parser" yaml:"is_parser"`     // True for parsing binary to JSON, false for serializing JSON to binary
 	RootType   string `json:"root_type" yaml:"root_type"`
 
+	// Schema sources other than files (optional)
+	Schema         string `json:"schema,omitempty" yaml:"schema,omitempty"`                   // Inline KSY schema, used instead of SchemaPath
+	SchemaResource string `json:"schema_resource,omitempty" yaml:"schema_resource,omitempty"` // Cache resource to read schemas from instead of files
+
+	// Schema paths resolved per message and the schemas kept loaded
+	SchemaBaseDir   string `json:"schema_base_dir,omitempty" yaml:"schema_base_dir,omitempty"`     // Directory interpolated schema paths must be in
+	SchemaCacheSize int    `json:"schema_cache_size,omitempty" yaml:"schema_cache_size,omitempty"` // Most schemas cached per cache
+
+	// Layered decoding (optional), used instead of SchemaPath
+	Layers []LayerConfig `json:"layers,omitempty" yaml:"layers,omitempty"`
+
 	// Framing configuration (optional)
 	FramingSchemaPath  string `json:"framing_schema_path,omitempty" yaml:"framing_schema_path,omitempty"`
 	FramingRootType    string `json:"framing_root_type,omitempty" yaml:"framing_root_type,omitempty"`
 	FramingDataFieldID string `json:"framing_data_field_id,omitempty" yaml:"framing_data_field_id,omitempty"`
-	// MaxBufferSize int `json:"max_buffer_size,omitempty" yaml:"max_buffer_size,omitempty"` // Defer for now based on simplified framing
+	Resync             string `json:"resync,omitempty" yaml:"resync,omitempty"` // How to find the next frame after a bad one
+
+	// Frame fields passed on to payload messages
+	FramingMetadataFields map[string]string `json:"framing_metadata_fields,omitempty" yaml:"framing_metadata_fields,omitempty"` // Frame field path -> metadata key
+	FramingHeaderKey      string            `json:"framing_header_key,omitempty" yaml:"framing_header_key,omitempty"`           // Output key to nest the frame header under
+
+	// Carry-over of partial trailing frames to the next message of the same stream
+	MaxBufferSize     int           `json:"max_buffer_size,omitempty" yaml:"max_buffer_size,omitempty"` // 0 disables carry-over
+	BufferKey         string        `json:"buffer_key,omitempty" yaml:"buffer_key,omitempty"`           // Metadata key identifying the stream
+	BufferIdleTimeout time.Duration `json:"buffer_idle_timeout,omitempty" yaml:"buffer_idle_timeout,omitempty"`
+
+	// Reloading of changed schema files
+	HotReload         bool          `json:"hot_reload,omitempty" yaml:"hot_reload,omitempty"`
+	HotReloadDebounce time.Duration `json:"hot_reload_debounce,omitempty" yaml:"hot_reload_debounce,omitempty"`
+
+	// Number of messages of a batch processed in parallel (0 means one per CPU)
+	MaxInFlight int `json:"max_in_flight,omitempty" yaml:"max_in_flight,omitempty"`
+
+	// PartialResults emits the fields decoded before a parse failure along with the error
+	PartialResults bool `json:"partial_results,omitempty" yaml:"partial_results,omitempty"`
+
+	// PreserveOrder emits parsed output as JSON with fields in declaration order
+	PreserveOrder bool `json:"preserve_order,omitempty" yaml:"preserve_order,omitempty"`
+
+	// Shapes of values in parsed output, also read back in serializer mode
+	OutputEnums    string `json:"output_enums,omitempty" yaml:"output_enums,omitempty"`         // object, name or int
+	OutputBytes    string `json:"output_bytes,omitempty" yaml:"output_bytes,omitempty"`         // raw, hex, base64 or array
+	OutputBigInts  string `json:"output_big_ints,omitempty" yaml:"output_big_ints,omitempty"`   // number or string
+	OutputBitFlags string `json:"output_bit_flags,omitempty" yaml:"output_bit_flags,omitempty"` // bool or int
+
+	// AutoCompute infers length/count fields during serialization
+	AutoCompute bool `json:"auto_compute,omitempty" yaml:"auto_compute,omitempty"`
+	// SkipValidation disables `valid` and `contents` checks during serialization
+	SkipValidation bool `json:"skip_validation,omitempty" yaml:"skip_validation,omitempty"`
+
+	// Resource limits for untrusted input (0 means unlimited)
+	MaxDepth          int `json:"max_depth,omitempty" yaml:"max_depth,omitempty"`
+	MaxRepeatItems    int `json:"max_repeat_items,omitempty" yaml:"max_repeat_items,omitempty"`
+	MaxAllocationSize int `json:"max_allocation_size,omitempty" yaml:"max_allocation_size,omitempty"`
+	MaxOutputNodes    int `json:"max_output_nodes,omitempty" yaml:"max_output_nodes,omitempty"`
+	CELCostLimit      int `json:"cel_cost_limit,omitempty" yaml:"cel_cost_limit,omitempty"`
+}
+
+// limits returns the interpreter resource limits described by the config
+func (c KaitaiConfig) limits() kst.Limits {
+	return kst.Limits{
+		MaxDepth:       c.MaxDepth,
+		MaxRepeatItems: c.MaxRepeatItems,
+		MaxAllocation:  c.MaxAllocationSize,
+		MaxNodes:       c.MaxOutputNodes,
+		CELCostLimit:   uint64(c.CELCostLimit),
+	}
+}
+
+// outputOptions returns the output shapes and order selected by the config,
+// leaving out the defaults, so an empty result means unordered default shapes
+func (c KaitaiConfig) outputOptions() []kst.OutputOption {
+	var opts []kst.OutputOption
+	if c.OutputEnums != "" && c.OutputEnums != string(kst.EnumObject) {
+		opts = append(opts, kst.EnumsAs(kst.EnumFormat(c.OutputEnums)))
+	}
+	if c.OutputBytes != "" && c.OutputBytes != string(kst.BytesRaw) {
+		opts = append(opts, kst.BytesAs(kst.BytesFormat(c.OutputBytes)))
+	}
+	if c.OutputBigInts != "" && c.OutputBigInts != string(kst.BigIntNumber) {
+		opts = append(opts, kst.BigIntsAs(kst.BigIntFormat(c.OutputBigInts)))
+	}
+	if c.OutputBitFlags != "" && c.OutputBitFlags != string(kst.BitFlagBool) {
+		opts = append(opts, kst.BitFlagsAs(kst.BitFlagFormat(c.OutputBitFlags)))
+	}
+	if c.PreserveOrder {
+		opts = append(opts, kst.PreserveOrder(true))
+		if c.OutputBytes == "" || c.OutputBytes == string(kst.BytesRaw) {
+			// Ordered output is JSON, which would turn raw bytes into base64
+			// strings that the serializer reads back as text; arrays of byte
+			// values are read back as the same bytes
+			opts = append(opts, kst.BytesAs(kst.BytesArray))
+		}
+	}
+	return opts
 }
 
 func init() {
 	// Register the processor with Benthos
-	err := service.RegisterProcessor(
+	err := service.RegisterBatchProcessor(
 		"kaitai",
 		kaitaiProcessorConfig(),
-		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
 			return newKaitaiProcessorFromConfig(conf, mgr)
 		},
 	)
@@ -216,27 +327,107 @@ func kaitaiProcessorConfig() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Summary("Parses or serializes binary data using Kaitai Struct definitions without code generation.").
 		Description("This processor uses Kaitai Struct to parse binary data into JSON or serialize JSON back to binary according to KSY schema definitions.").
-		Field(service.NewStringField("schema_path").
-			Description("Path to the Kaitai Struct (.ksy) schema file.").
-			Example("./schemas/my_format.ksy")).
+		Field(service.NewInterpolatedStringField("schema_path").
+			Description("Path to the Kaitai Struct (.ksy) schema file. Required unless 'layers' is set. Interpolated per message, so one processor can handle several formats; each distinct schema is loaded once and cached.").
+			Example("./schemas/my_format.ksy").
+			Example(`./schemas/${! meta("device_type") }.ksy`).
+			Default("")).
+		Field(service.NewStringField("schema").
+			Description("Inline Kaitai Struct (.ksy) schema, used instead of 'schema_path' so the schema can ship with the pipeline config. Relative imports are resolved against the working directory.").
+			Example("meta:\n  id: reading\nseq:\n  - id: value\n    type: u2le\n").
+			Default("")).
+		Field(service.NewStringField("schema_resource").
+			Description("Name of a cache resource to read schemas from instead of files. 'schema_path', 'framing_schema_path' and the layers' schema paths are then keys in this cache, and imports are resolved relative to the importing key and read from the same cache.").
+			Example("schemas").
+			Default("")).
+		Field(service.NewStringField("schema_base_dir").
+			Description("Directory that an interpolated 'schema_path' must resolve to a file within, so message metadata can't point it at other files. Defaults to the working directory. Doesn't apply to a static 'schema_path' or to 'schema_resource'.").
+			Example("./schemas").
+			Default("").Advanced()).
+		Field(service.NewIntField("schema_cache_size").
+			Description("Most schemas kept loaded at once. When an interpolated 'schema_path' resolves to more schemas, the least recently used one is dropped, to be loaded again when next needed.").
+			Default(defaultSchemaCacheSize).Advanced()).
 		Field(service.NewBoolField("is_parser").
 			Description("Whether this processor parses binary to JSON (true) or serializes JSON to binary (false).").
 			Default(true)).
-		Field(service.NewStringField("root_type").
-			Description("The root type name from the KSY file to use when parsing or serializing. Leave empty to use the default root type.").
+		Field(service.NewInterpolatedStringField("root_type").
+			Description("The root type name from the KSY file to use when parsing or serializing. Leave empty to use the default root type. Interpolated per message.").
+			Example(`${! meta("message_type") }`).
 			Default("")).
+		Field(service.NewBoolField("auto_compute").
+			Description("Serializer mode only: infer fields used as the `size` or `repeat-expr` of other fields (directly or via simple arithmetic such as `len - 4`) from the data, so input messages don't need to carry wire lengths.").
+			Default(false)).
+		Field(service.NewBoolField("skip_validation").
+			Description("Serializer mode only: write data without enforcing `valid` constraints and `contents`, e.g. to produce deliberately malformed test vectors.").
+			Advanced().
+			Default(false)).
 		Field(service.NewStringField("framing_schema_path").
-			Description("Optional: Path to a KSY schema file used to define message/frame boundaries. If used, the processor will first parse frames using this schema, then parse the extracted payload using the main 'schema_path'.").
+			Description("Optional: Path to a KSY schema file used to define message/frame boundaries. If used, the processor will first parse frames using this schema, then parse the extracted payload using the main 'schema_path'. In serializer mode, the payload serialized with 'schema_path' is wrapped in a frame serialized with this schema instead.").
 			Default("").Optional()).
 		Field(service.NewStringField("framing_root_type").
 			Description("Optional: The root type in the 'framing_schema_path' to parse for frame extraction. Defaults to the meta.id of the framing schema if left empty.").
 			Default("").Optional()).
 		Field(service.NewStringField("framing_data_field_id").
-			Description("Required if 'framing_schema_path' is set. The ID of the field within the parsed framing structure that contains the actual data payload to be processed by the main schema.").
+			Description("Required if 'framing_schema_path' is set. The ID of the field within the framing structure that contains the actual data payload to be processed by the main schema.").
 			Default("").Optional()).
-		// Field(service.NewIntField("max_buffer_size").
-		//     Description("Optional: Maximum size of the internal buffer for holding partial frame data, in bytes. Only used if framing is active.").
-		//     Default(1024*1024*10).Optional()), // Example: 10MB, deferring for now
+		Field(service.NewStringEnumField("resync", resyncByte, resyncSyncMarker, resyncHeader, resyncAbort).
+			Description("Framing in parser mode only: how to find the next frame after one fails to parse. `byte` retries at every following byte. `sync_marker` skips to the next occurrence of the `contents` the framing root type starts with. `header` skips to the next offset where the framing root type's leading fields, up to its last field with `contents` or `valid`, parse. `abort` stops processing the rest of the message. Each run of skipped bytes is reported as one error with its byte range.").
+			Default(resyncByte)).
+		Field(service.NewStringMapField("framing_metadata_fields").
+			Description("Framing only: copy frame fields into the metadata of each payload message, mapping a frame field (a dot-separated path for nested fields) to a metadata key. Numbers, strings and booleans keep their type, enums use their label and byte fields are hex-encoded. The key `*` copies every top-level scalar field of the frame, using its value as a metadata key prefix. In serializer mode, frame fields not given under 'framing_header_key' are read from these metadata keys.").
+			Example(map[string]any{"seq_no": "frame_seq", "header.device_id": "device_id"}).
+			Example(map[string]any{"*": "frame_"}).
+			Optional()).
+		Field(service.NewStringField("framing_header_key").
+			Description("Framing only: when set, the parsed frame, without its payload field, is added under this key to the structured output of each payload message. In serializer mode, frame fields are read from this key of the input, which is not serialized with the payload.").
+			Example("frame").
+			Default("")).
+		Field(service.NewIntField("max_buffer_size").
+			Description("Framing in parser mode only: maximum number of bytes of a partial frame at the end of a message to carry over and prepend to the next message of the same stream, for frames that straddle messages (e.g. TCP or serial feeds). A longer partial frame is dropped. 0 disables carry-over, so a partial trailing frame is an error.").
+			Default(0)).
+		Field(service.NewStringField("buffer_key").
+			Description("Framing in parser mode only: metadata key identifying the stream a message belongs to, such as a connection ID. Partial frames are only carried over between messages with the same value. Leave empty to treat all messages as one stream.").
+			Example("connection_id").
+			Default("")).
+		Field(service.NewDurationField("buffer_idle_timeout").
+			Description("Framing in parser mode only: drop a carried-over partial frame if its stream sends nothing else for this long. 0s keeps it until the stream's next message.").
+			Default("1m").Advanced()).
+		Field(service.NewObjectListField("layers", layerConfigFields()...).
+			Description("Parser mode only: decode each message through a chain of protocol layers (e.g. Ethernet → IPv4 → UDP → application protocol) instead of a single 'schema_path'. Decoding starts with the first layer; each layer's payload field is decoded by the layer its dispatch rule selects. The output holds every decoded layer's fields under the layer's name, and the `kaitai_layers` metadata lists them in order. When no dispatch rule matches, decoding stops and the undecoded payload is kept.").
+			Example([]any{
+				map[string]any{"schema_path": "./schemas/ethernet_frame.ksy", "payload_field": "body", "dispatch_field": "ether_type", "dispatch": map[string]any{"0x0800": "./schemas/ipv4_packet.ksy"}},
+				map[string]any{"schema_path": "./schemas/ipv4_packet.ksy", "payload_field": "body", "dispatch_field": "protocol", "dispatch": map[string]any{"17": "./schemas/udp_datagram.ksy"}},
+				map[string]any{"schema_path": "./schemas/udp_datagram.ksy"},
+			}).
+			Optional()).
+		Field(service.NewBoolField("partial_results").
+			Description("Parser mode only: when data fails to parse, emit the fields decoded before the failure instead of the raw message. The message is still flagged with the error, and the `kaitai_error_path` and `kaitai_error_offset` metadata give the path of the failing field (e.g. `header.entries[2].kind`) and its byte offset in the parsed data (the message, frame payload or layer payload).").
+			Default(false)).
+		Field(service.NewBoolField("preserve_order").
+			Description("Parser mode only: emit parsed output as JSON bytes with fields in the order the schema declares them, `seq` fields then instances, which keeps diffs and logs in wire order. With `output_bytes: raw`, byte fields are emitted as arrays of byte values, as JSON has no bytes type. When `false`, output is set as a structured object, whose fields JSON encoding sorts by name.").
+			Default(true)).
+		Field(service.NewStringEnumField("output_enums", string(kst.EnumObject), string(kst.EnumName), string(kst.EnumInt)).
+			Description("How enum fields appear in parsed output: `object` gives `{\"name\": \"cat\", \"value\": 4, \"valid\": true}`, `name` gives the entry name (or the value if it has none), `int` gives the value. In serializer mode, objects, names and values are all accepted.").
+			Default(string(kst.EnumObject)).Advanced()).
+		Field(service.NewStringEnumField("output_bytes", string(kst.BytesRaw), string(kst.BytesHex), string(kst.BytesBase64), string(kst.BytesArray)).
+			Description("How byte fields appear in parsed output: `raw` keeps bytes (base64 once encoded as JSON), `hex` and `base64` give strings, `array` gives an array of integers. In serializer mode, strings for byte fields are decoded with the same encoding, and integer arrays are always accepted.").
+			Default(string(kst.BytesRaw)).Advanced()).
+		Field(service.NewStringEnumField("output_big_ints", string(kst.BigIntNumber), string(kst.BigIntString)).
+			Description("How integers beyond ±(2^53-1), which JSON consumers such as JavaScript round, appear in parsed output: `number` or `string` (a decimal string). In serializer mode, decimal strings are always accepted for integer fields.").
+			Default(string(kst.BigIntNumber)).Advanced()).
+		Field(service.NewStringEnumField("output_bit_flags", string(kst.BitFlagBool), string(kst.BitFlagInt)).
+			Description("How 1-bit fields appear in parsed output: `bool` or `int` (0 or 1). In serializer mode, both are accepted.").
+			Default(string(kst.BitFlagBool)).Advanced()).
+		Field(service.NewIntField("max_in_flight").
+			Description("Maximum number of messages of a batch processed in parallel, sharing the cached schemas. Output messages keep the order of the batch. 0 uses one worker per CPU. Messages are processed one at a time when 'max_buffer_size' is set, as partial frames are carried over in order.").
+			Default(0)).
+		Field(service.NewBoolField("hot_reload").
+			Description("Watch schema files, including the files they import, and reload a schema when it changes, without restarting the pipeline. A new version replaces the cached one only if it loads and doesn't break expressions that compiled before; otherwise the error is logged and the previous version stays in use. Messages already being processed finish with the version they started with.").
+			Default(false)).
+		Field(service.NewDurationField("hot_reload_debounce").
+			Description("How long to wait after a schema file changes before reloading it, so a file written in several steps is reloaded once.").
+			Default(defaultHotReloadDebounce.String()).Advanced()).
+		Fields(limitFields()...).
 		Version("0.1.0")
 }
 
@@ -246,6 +437,26 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 	if err != nil {
 		return nil, err
 	}
+	schemaPathInterp, err := conf.FieldInterpolatedString("schema_path")
+	if err != nil {
+		return nil, err
+	}
+	inlineSchema, err := conf.FieldString("schema")
+	if err != nil {
+		return nil, err
+	}
+	schemaResource, err := conf.FieldString("schema_resource")
+	if err != nil {
+		return nil, err
+	}
+	schemaBaseDir, err := conf.FieldString("schema_base_dir")
+	if err != nil {
+		return nil, err
+	}
+	schemaCacheSize, err := conf.FieldInt("schema_cache_size")
+	if err != nil {
+		return nil, err
+	}
 
 	isParser, err := conf.FieldBool("is_parser")
 	if err != nil {
@@ -256,6 +467,58 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 	if err != nil {
 		return nil, err
 	}
+	rootTypeInterp, err := conf.FieldInterpolatedString("root_type")
+	if err != nil {
+		return nil, err
+	}
+
+	autoCompute, err := conf.FieldBool("auto_compute")
+	if err != nil {
+		return nil, err
+	}
+
+	skipValidation, err := conf.FieldBool("skip_validation")
+	if err != nil {
+		return nil, err
+	}
+
+	partialResults, err := conf.FieldBool("partial_results")
+	if err != nil {
+		return nil, err
+	}
+
+	preserveOrder, err := conf.FieldBool("preserve_order")
+	if err != nil {
+		return nil, err
+	}
+
+	outputEnums, err := conf.FieldString("output_enums")
+	if err != nil {
+		return nil, err
+	}
+	outputBytes, err := conf.FieldString("output_bytes")
+	if err != nil {
+		return nil, err
+	}
+	outputBigInts, err := conf.FieldString("output_big_ints")
+	if err != nil {
+		return nil, err
+	}
+	outputBitFlags, err := conf.FieldString("output_bit_flags")
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []LayerConfig
+	if conf.Contains("layers") {
+		layerConfs, err := conf.FieldObjectList("layers")
+		if err != nil {
+			return nil, err
+		}
+		if layers, err = layerConfigsFromParsed(layerConfs); err != nil {
+			return nil, err
+		}
+	}
 
 	framingSchemaPath, err := conf.FieldString("framing_schema_path")
 	if err != nil {
@@ -269,39 +532,172 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 	if err != nil {
 		return nil, err
 	}
+	resyncStrategy, err := conf.FieldString("resync")
+	if err != nil {
+		return nil, err
+	}
+	var framingMetadataFields map[string]string
+	if conf.Contains("framing_metadata_fields") {
+		if framingMetadataFields, err = conf.FieldStringMap("framing_metadata_fields"); err != nil {
+			return nil, err
+		}
+	}
+	framingHeaderKey, err := conf.FieldString("framing_header_key")
+	if err != nil {
+		return nil, err
+	}
+	maxBufferSize, err := conf.FieldInt("max_buffer_size")
+	if err != nil {
+		return nil, err
+	}
+	bufferKey, err := conf.FieldString("buffer_key")
+	if err != nil {
+		return nil, err
+	}
+	bufferIdleTimeout, err := conf.FieldDuration("buffer_idle_timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	maxInFlight, err := conf.FieldInt("max_in_flight")
+	if err != nil {
+		return nil, err
+	}
+
+	hotReload, err := conf.FieldBool("hot_reload")
+	if err != nil {
+		return nil, err
+	}
+	hotReloadDebounce, err := conf.FieldDuration("hot_reload_debounce")
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := limitsFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
 
 	// Validation for configuration
-	if schemaPath == "" {
+	if schemaPath == "" && inlineSchema == "" && len(layers) == 0 {
 		return nil, fmt.Errorf("schema_path is required")
 	}
+
+	// Validation for schema sources
+	if inlineSchema != "" && (schemaPath != "" || schemaResource != "") {
+		return nil, fmt.Errorf("schema cannot be combined with schema_path or schema_resource")
+	}
+	if schemaResource != "" {
+		if !mgr.HasCache(schemaResource) {
+			return nil, fmt.Errorf("schema_resource cache '%s' not found", schemaResource)
+		}
+		if hotReload {
+			return nil, fmt.Errorf("hot_reload watches schema files and cannot be combined with schema_resource")
+		}
+	}
+
+	// Validation for layers
+	if len(layers) > 0 {
+		if schemaPath != "" || inlineSchema != "" || framingSchemaPath != "" {
+			return nil, fmt.Errorf("layers cannot be combined with schema_path, schema or framing_schema_path")
+		}
+		if !isParser {
+			return nil, fmt.Errorf("layers are only supported in parser mode (is_parser: true)")
+		}
+	}
 	
 	// Validation for framing
 	if framingSchemaPath != "" && framingDataFieldID == "" {
 		return nil, fmt.Errorf("framing_data_field_id is required when framing_schema_path is set")
 	}
 	
-	// Validate that framing is only used with parser mode
-	if framingSchemaPath != "" && !isParser {
-		return nil, fmt.Errorf("framing is only supported in parser mode (is_parser: true)")
+	// Validation for carry-over
+	if maxBufferSize < 0 {
+		return nil, fmt.Errorf("max_buffer_size must not be negative")
+	}
+	if maxBufferSize > 0 && framingSchemaPath == "" {
+		return nil, fmt.Errorf("max_buffer_size requires framing_schema_path")
+	}
+
+	if schemaCacheSize < 1 {
+		return nil, fmt.Errorf("schema_cache_size must be at least 1")
+	}
+	if maxInFlight < 0 {
+		return nil, fmt.Errorf("max_in_flight must not be negative")
+	}
+
+	// Validation for output shapes
+	for _, shape := range []struct {
+		field, value string
+		allowed      []string
+	}{
+		{"output_enums", outputEnums, []string{string(kst.EnumObject), string(kst.EnumName), string(kst.EnumInt)}},
+		{"output_bytes", outputBytes, []string{string(kst.BytesRaw), string(kst.BytesHex), string(kst.BytesBase64), string(kst.BytesArray)}},
+		{"output_big_ints", outputBigInts, []string{string(kst.BigIntNumber), string(kst.BigIntString)}},
+		{"output_bit_flags", outputBitFlags, []string{string(kst.BitFlagBool), string(kst.BitFlagInt)}},
+	} {
+		if !slices.Contains(shape.allowed, shape.value) {
+			return nil, fmt.Errorf("%s must be one of %s, got '%s'", shape.field, strings.Join(shape.allowed, ", "), shape.value)
+		}
 	}
 
 	config := KaitaiConfig{
-		SchemaPath:         schemaPath,
-		IsParser:           isParser,
-		RootType:           rootType,
-		FramingSchemaPath:  framingSchemaPath,
-		FramingRootType:    framingRootType,
-		FramingDataFieldID: framingDataFieldID,
+		SchemaPath:            schemaPath,
+		Schema:                inlineSchema,
+		SchemaResource:        schemaResource,
+		SchemaBaseDir:         schemaBaseDir,
+		SchemaCacheSize:       schemaCacheSize,
+		IsParser:              isParser,
+		RootType:              rootType,
+		AutoCompute:           autoCompute,
+		SkipValidation:        skipValidation,
+		Layers:                layers,
+		FramingSchemaPath:     framingSchemaPath,
+		FramingRootType:       framingRootType,
+		FramingDataFieldID:    framingDataFieldID,
+		Resync:                resyncStrategy,
+		FramingMetadataFields: framingMetadataFields,
+		FramingHeaderKey:      framingHeaderKey,
+		MaxBufferSize:         maxBufferSize,
+		BufferKey:             bufferKey,
+		BufferIdleTimeout:     bufferIdleTimeout,
+		MaxInFlight:           maxInFlight,
+		PartialResults:        partialResults,
+		PreserveOrder:         preserveOrder,
+		OutputEnums:           outputEnums,
+		OutputBytes:           outputBytes,
+		OutputBigInts:         outputBigInts,
+		OutputBitFlags:        outputBitFlags,
+		HotReload:             hotReload,
+		HotReloadDebounce:     hotReloadDebounce,
+		MaxDepth:              limits.MaxDepth,
+		MaxRepeatItems:        limits.MaxRepeatItems,
+		MaxAllocationSize:     limits.MaxAllocation,
+		MaxOutputNodes:        limits.MaxNodes,
+		CELCostLimit:          int(limits.CELCostLimit),
 	}
 
-	// Check if schema file exists
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("schema file not found at path: %s", schemaPath)
-	} else if err != nil {
-		return nil, fmt.Errorf("error accessing schema file: %w", err)
+	// Check if schema files exist. Interpolated paths can only be checked per
+	// message, and schemas in a cache resource when they're loaded.
+	var schemaPaths []string
+	if static, ok := schemaPathInterp.Static(); ok && len(layers) == 0 && inlineSchema == "" {
+		schemaPaths = append(schemaPaths, static)
+	}
+	for _, layer := range layers {
+		schemaPaths = append(schemaPaths, layer.SchemaPath)
+	}
+	if schemaResource != "" {
+		schemaPaths = nil
+	}
+	for _, path := range schemaPaths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, fmt.Errorf("schema file not found at path: %s", path)
+		} else if err != nil {
+			return nil, fmt.Errorf("error accessing schema file: %w", err)
+		}
 	}
 	
-	if framingSchemaPath != "" {
+	if framingSchemaPath != "" && schemaResource == "" {
 		if _, err := os.Stat(framingSchemaPath); os.IsNotExist(err) {
 			return nil, fmt.Errorf("framing schema file not found at path: %s", framingSchemaPath)
 		} else if err != nil {
@@ -309,6 +705,28 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 		}
 	}
 
+	kp, err := newKaitaiProcessor(config, schemaPathInterp, rootTypeInterp, mgr)
+	if err != nil {
+		return nil, err
+	}
+	if label := mgr.Label(); label != "" {
+		// Bloblang methods naming the processor share its schemas
+		labelledProcessors.Store(label, kp)
+	}
+	return kp, nil
+}
+
+// Defaults of hot_reload_debounce and schema_cache_size
+const (
+	defaultHotReloadDebounce = 500 * time.Millisecond
+	defaultSchemaCacheSize   = 100
+)
+
+// labelledProcessors holds the kaitai processors that have a label, by label
+var labelledProcessors sync.Map
+
+// newKaitaiProcessor creates a processor from a checked configuration
+func newKaitaiProcessor(config KaitaiConfig, schemaPathInterp, rootTypeInterp *service.InterpolatedString, mgr *service.Resources) (*KaitaiProcessor, error) {
 	logger := mgr.Logger()
 	metrics := mgr.Metrics()
 
@@ -316,17 +734,31 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 	if config.IsParser {
 		processorMode = "parser"
 	}
-	framingActive := config.FramingSchemaPath != "" && config.IsParser
+	framingActive := config.FramingSchemaPath != ""
 
+	schemaDesc := config.SchemaPath
+	if config.Schema != "" {
+		schemaDesc = inlineSchemaPath
+	} else if config.SchemaResource != "" {
+		schemaDesc = fmt.Sprintf("%s (from cache %s)", config.SchemaPath, config.SchemaResource)
+	}
 	logger.Infof("Kaitai processor configured. Mode: %s, Schema: %s, RootType: %s, Framing active: %t",
-		processorMode, config.SchemaPath, config.RootType, framingActive)
+		processorMode, schemaDesc, config.RootType, framingActive)
 	if framingActive {
-		logger.Infof("Framing config: Schema: %s, RootType: %s, DataFieldID: %s",
-			config.FramingSchemaPath, config.FramingRootType, config.FramingDataFieldID)
+		logger.Infof("Framing config: Schema: %s, RootType: %s, DataFieldID: %s, Resync: %s",
+			config.FramingSchemaPath, config.FramingRootType, config.FramingDataFieldID, config.Resync)
+		if config.MaxBufferSize > 0 {
+			logger.Infof("Framing carry-over: MaxBufferSize: %d, BufferKey: %q, IdleTimeout: %s",
+				config.MaxBufferSize, config.BufferKey, config.BufferIdleTimeout)
+		}
 	}
 
 	kp := &KaitaiProcessor{
 		config:                      config,
+		schemaPath:                  schemaPathInterp,
+		rootType:                    rootTypeInterp,
+		source:                      schemaSource{resource: config.SchemaResource, resources: mgr, logger: logger},
+		resources:                   mgr,
 		logger:                      logger,
 		mParsedTotal:                metrics.NewCounter("kaitai_parsed_total"),
 		mSerializedTotal:            metrics.NewCounter("kaitai_serialized_total"),
@@ -340,8 +772,83 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 		mPayloadParsingErrors:       metrics.NewCounter("kaitai_payload_parsing_errors_total"),
 		mPayloadSerializationErrors: metrics.NewCounter("kaitai_payload_serialization_errors_total"),
 		mBytesProcessed:             metrics.NewCounter("kaitai_bytes_processed_total"),
+		mBufferDroppedBytes:         metrics.NewCounter("kaitai_buffer_dropped_bytes_total"),
+		mLayerDispatchMisses:        metrics.NewCounter("kaitai_layer_dispatch_misses_total"),
+		mPartialResults:             metrics.NewCounter("kaitai_partial_results_total"),
+		mSchemaReloads:              metrics.NewCounter("kaitai_schema_reloads_total"),
+		mSchemaReloadErrors:         metrics.NewCounter("kaitai_schema_reload_errors_total"),
 		mFrameProcDuration:          metrics.NewTimer("kaitai_frame_processing_duration_seconds"),
-		// schemaCache and framingSchemaCache are zero-value sync.Map and ready to use
+		mBatchProcDuration:          metrics.NewTimer("kaitai_batch_processing_duration_seconds"),
+	}
+	cacheSize := config.SchemaCacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultSchemaCacheSize
+	}
+	kp.schemaCache = newSchemaLRU(cacheSize)
+	kp.framingSchemaCache = newSchemaLRU(cacheSize)
+	// A schema may be compiled for several roles, such as frames and payloads
+	kp.interpreters = newCompiledCache[*kst.KaitaiInterpreter](2 * cacheSize)
+	kp.serializers = newCompiledCache[*kst.KaitaiSerializer](2 * cacheSize)
+	if schemaPathInterp != nil && !isStatic(schemaPathInterp) && config.SchemaResource == "" && config.Schema == "" {
+		// Metadata decides the path, so it's kept to the base directory
+		baseDir := config.SchemaBaseDir
+		if baseDir == "" {
+			baseDir = "."
+		}
+		absBaseDir, err := filepath.Abs(baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve schema_base_dir: %w", err)
+		}
+		kp.schemaBaseDir = absBaseDir
+	}
+	if config.Schema != "" {
+		// The inline schema never changes, so it's cached up front
+		schema, err := kp.source.parse(inlineSchemaPath, []byte(config.Schema))
+		if err != nil {
+			return nil, err
+		}
+		kp.schemaCache.LoadOrStore(inlineSchemaPath, schema)
+	}
+	var err error
+	if config.HotReload {
+		// Start watching first, so every schema loaded from here on is watched
+		if kp.watcher, err = newSchemaWatcher(config.HotReloadDebounce, kp.reloadSchema, logger); err != nil {
+			return nil, fmt.Errorf("failed to start schema watcher: %w", err)
+		}
+		logger.Infof("Schema hot reload enabled, debounce: %s", config.HotReloadDebounce)
+	}
+	if len(config.Layers) > 0 {
+		kp.layers, err = newLayerChain(config.Layers, func(path string) (string, error) {
+			schema, err := kp.loadDataSchema(path)
+			if err != nil {
+				return "", err
+			}
+			return schema.Meta.ID, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("Layered decoding: %d layers", len(config.Layers))
+	}
+	if framingActive && config.IsParser && config.MaxBufferSize > 0 {
+		kp.carryOver = newCarryOverBuffers(config.MaxBufferSize, config.BufferIdleTimeout, func(dropped int) {
+			logger.With("dropped_bytes", dropped).Warnf("Dropped carried-over partial frames of idle streams after buffer_idle_timeout")
+			kp.mBufferDroppedBytes.Incr(int64(dropped))
+		})
+	}
+	if framingActive && config.IsParser && config.Resync != resyncByte {
+		// Check the framing schema supports the strategy before any message arrives
+		framingSchema, err := kp.loadFramingSchema(config.FramingSchemaPath)
+		if err != nil {
+			return nil, err
+		}
+		rootType := config.FramingRootType
+		if rootType == "" {
+			rootType = framingSchema.Meta.ID
+		}
+		if _, err := newFrameResync(config.Resync, framingSchema, rootType); err != nil {
+			return nil, err
+		}
 	}
 	return kp, nil
 }
@@ -350,6 +857,9 @@ func newKaitaiProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Resou
 func (k *KaitaiProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
 	if k.config.IsParser {
 		k.logger.Debugf("Entering PARSER mode")
+		if k.layers != nil {
+			return k.parseLayeredBinary(ctx, msg)
+		}
 		if k.config.FramingSchemaPath != "" {
 			k.logger.Debugf("Framing is ENABLED")
 			return k.parseFramedBinary(ctx, msg)
@@ -361,6 +871,77 @@ func (k *KaitaiProcessor) Process(ctx context.Context, msg *service.Message) (se
 	return k.serializeToBinary(ctx, msg)
 }
 
+// ProcessBatch applies Kaitai parsing or serialization to the messages of a
+// batch, up to max_in_flight at a time. The output keeps the order of the
+// batch, and an error processing a message is attached to it. Messages left
+// unprocessed when ctx is cancelled carry the context's error.
+func (k *KaitaiProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	startTime := SystemTime.Now()
+	results := make([]service.MessageBatch, len(batch))
+	processed := make([]bool, len(batch))
+	process := func(i int) {
+		msg := batch[i]
+		out, err := k.Process(ctx, msg)
+		if err != nil {
+			msg.SetError(err)
+			out = service.MessageBatch{msg}
+		}
+		results[i] = out
+		processed[i] = true
+	}
+
+	workers := k.config.MaxInFlight
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if k.carryOver != nil {
+		workers = 1 // A partial frame must be carried over to the message after it
+	}
+	workers = min(workers, len(batch))
+	if workers <= 1 {
+		for i := range batch {
+			if ctx.Err() != nil {
+				break
+			}
+			process(i)
+		}
+	} else {
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					process(i)
+				}
+			}()
+		}
+		for i := range batch {
+			if ctx.Err() != nil {
+				break
+			}
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+	output := make(service.MessageBatch, 0, len(batch))
+	for i, out := range results {
+		if !processed[i] {
+			// Left unprocessed as the context was cancelled
+			batch[i].SetError(ctx.Err())
+			out = service.MessageBatch{batch[i]}
+		}
+		output = append(output, out...)
+	}
+	k.mBatchProcDuration.Timing(SystemTime.Since(startTime).Nanoseconds())
+	if len(output) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{output}, nil
+}
+
 // parseFramedBinary handles parsing of messages that require KSY-defined framing.
 // It extracts multiple frames from a single input message, parses each frame's payload,
 // and returns a batch of messages, one for each successfully parsed payload.
@@ -375,6 +956,27 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 		return service.MessageBatch{msg}, nil
 	}
 
+	// Prepend the partial frame left over from this stream's previous message,
+	// keeping the stream locked until this message's partial frame is stored
+	var streamKey string
+	var stream *carryOverStream
+	if k.carryOver != nil {
+		if k.config.BufferKey != "" {
+			streamKey, _ = msg.MetaGet(k.config.BufferKey)
+		}
+		stream = k.carryOver.lock(streamKey)
+		defer k.carryOver.unlock(streamKey, stream)
+		carried, dropped := k.carryOver.take(stream)
+		if dropped > 0 {
+			k.logger.With("dropped_bytes", dropped).Warnf("Dropped carried-over partial frames after buffer_idle_timeout")
+			k.mBufferDroppedBytes.Incr(int64(dropped))
+		}
+		if len(carried) > 0 {
+			k.logger.With("stream_key", streamKey, "carried_bytes", len(carried)).Debugf("Prepending carried-over partial frame")
+			inputData = append(carried, inputData...)
+		}
+	}
+
 	if len(inputData) == 0 {
 		k.logger.Warnf("Empty binary data provided for framed parsing")
 		return service.MessageBatch{}, nil
@@ -394,11 +996,18 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 	}
 	k.logger.With("path", k.config.FramingSchemaPath, "root_type", effectiveFramingRootType).Debugf("Using framing schema")
 
-	dataSchema, err := k.loadDataSchema(k.config.SchemaPath)
+	schemaPath, rootType, err := k.resolveDataSchema(msg)
+	if err != nil {
+		k.logger.Errorf("Failed to resolve data schema: %v", err)
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(err)
+		return service.MessageBatch{msg}, nil
+	}
+	dataSchema, err := k.loadDataSchema(schemaPath)
 	if err != nil {
 		// Error logged by loadDataSchema
 		k.mErrorsTotal.Incr(1)
-		msg.SetError(fmt.Errorf("failed to load data schema '%s': %w", k.config.SchemaPath, err))
+		msg.SetError(fmt.Errorf("failed to load data schema '%s': %w", schemaPath, err))
 		return service.MessageBatch{msg}, nil
 	}
 
@@ -414,9 +1023,40 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 	outputMessages := service.MessageBatch{}
 	var mainFramingError error // To store the first critical framing error
 
-	// Create slog.Logger instances using Benthos logger
+	// Create slog.Logger instance using Benthos logger
 	frameInterpreterSlog := slog.New(newBenthosLogHandler(k.logger)).With("component", "frame_interpreter")
-	dataInterpreterSlog := slog.New(newBenthosLogHandler(k.logger)).With("component", "data_interpreter")
+
+	// One interpreter parses every frame of the message
+	frameInterpreter, err := k.interpreter(framingSchema, k.config.FramingRootType, "frame_interpreter")
+	if err != nil {
+		k.logger.Errorf("Failed to create frame interpreter: %v", err)
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(fmt.Errorf("failed to create frame interpreter: %w", err))
+		return service.MessageBatch{msg}, nil
+	}
+	resync, err := newFrameResync(k.config.Resync, framingSchema, effectiveFramingRootType)
+	if err != nil {
+		k.logger.Errorf("Failed to set up frame resynchronization: %v", err)
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(err)
+		return service.MessageBatch{msg}, nil
+	}
+
+	// Bytes skipped while resynchronizing after a bad frame are reported once per region
+	corruptStart := int64(-1)
+	var corruptErr error
+	reportCorrupt := func(end int64) {
+		if corruptStart < 0 {
+			return
+		}
+		k.logger.With("start", corruptStart, "end", end, "skipped_bytes", end-corruptStart).Errorf("Skipped corrupt frame data: %v", corruptErr)
+		k.mFrameParsingErrors.Incr(1)
+		k.mErrorsTotal.Incr(1)
+		if mainFramingError == nil {
+			mainFramingError = fmt.Errorf("failed to parse frames in bytes %d-%d: %w", corruptStart, end, corruptErr)
+		}
+		corruptStart = -1
+	}
 
 	for {
 		currentPos, errLoopPos := inputStream.Pos()
@@ -435,72 +1075,78 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 		bufferRemaining := streamSize - frameStartPos
 		k.logger.With("stream_pos", frameStartPos, "buffer_remaining", bufferRemaining).Debugf("Attempting to parse frame")
 
-		// Ensure RootType is set for the schema if specified in config
-		// Create a copy of the schema to avoid modifying the cached version
-		currentFramingSchema := *framingSchema
-		if k.config.FramingRootType != "" {
-			currentFramingSchema.RootType = k.config.FramingRootType
-		}
-
-		frameInterpreter, err := kst.NewKaitaiInterpreter(&currentFramingSchema, frameInterpreterSlog)
-		if err != nil {
-			k.logger.Errorf("Failed to create frame interpreter: %v", err)
-			mainFramingError = fmt.Errorf("failed to create frame interpreter: %w", err)
-			k.mErrorsTotal.Incr(1)
-			break
-		}
-
 		frameParseStartTime := SystemTime.Now() // Start timing for this frame attempt
 		frameContainerPd, frameErr := frameInterpreter.Parse(ctx, inputStream)
 
-		// Helper to check for various EOF conditions
-		isEofError := func(e error) bool {
-			if e == nil {
-				return false
-			}
-			_, isKaitaiCustomEof := e.(kaitai.EndOfStreamError)
-			return isKaitaiCustomEof || errors.Is(e, io.EOF) || errors.Is(e, io.ErrUnexpectedEOF)
-		}
-		if frameErr != nil {
-			if isEofError(frameErr) {
-				k.logger.With("stream_pos", frameStartPos).Warnf("Partial frame data at end of message: %v", frameErr)
-				mainFramingError = frameErr // This isn't necessarily a frame parsing error for metrics if it's just EOF
-				break
-			}
-			k.logger.With("stream_pos", frameStartPos).Errorf("Error parsing frame container: %v", frameErr)
-			k.mFrameParsingErrors.Incr(1)
-			k.mErrorsTotal.Incr(1) // Also count as general error
-			// Store the first critical framing error
-			if mainFramingError == nil {
-				mainFramingError = fmt.Errorf("failed to parse frame at pos %d: %w", frameStartPos, frameErr)
-			}
-
-			posAfterError, errSeekPos := inputStream.Pos()
-			if errSeekPos != nil {
-				k.logger.Errorf("Failed to get stream position after frame parse error (before seek): %v", errSeekPos)
-				if mainFramingError == nil {
-					mainFramingError = fmt.Errorf("stream error (pos after frame_err): %w", errSeekPos)
-				}
+		if frameErr != nil && isEOFError(frameErr) && k.carryOver == nil && frameStartPos+1 < streamSize {
+			// No later message can complete the frame, so a length running past the
+			// end may be corrupt; the tail is only dropped if no frame follows
+			nextPos, errResync := resync.next(ctx, inputData, frameStartPos, frameInterpreterSlog, k.config.limits())
+			if errResync != nil {
+				reportCorrupt(frameStartPos)
+				k.logger.Errorf("Failed to resynchronize after frame parse error: %v", errResync)
 				k.mErrorsTotal.Incr(1)
 				break
 			}
-
-			if posAfterError < streamSize {
-				_, errSeek := inputStream.Seek(posAfterError, 1)
-				if errSeek != nil {
+			if nextPos >= 0 && nextPos < streamSize {
+				k.logger.With("stream_pos", frameStartPos, "resync", resync.strategy).Debugf("Frame runs past the end of the message: %v", frameErr)
+				if corruptStart < 0 {
+					corruptStart, corruptErr = frameStartPos, frameErr
+				}
+				if _, errSeek := inputStream.Seek(nextPos, io.SeekStart); errSeek != nil {
+					reportCorrupt(nextPos)
 					k.logger.Errorf("Failed to seek stream after frame parse error: %v", errSeek)
-					if mainFramingError == nil {
-						mainFramingError = fmt.Errorf("stream error (seek after frame_err): %w", errSeek)
-					}
 					k.mErrorsTotal.Incr(1)
 					break
 				}
-				newPosLog, _ := inputStream.Pos() // For logging, best effort
-				k.logger.With("new_pos", newPosLog).Debugf("Advanced stream by 1 byte due to frame parse error")
+				k.logger.With("new_pos", nextPos).Debugf("Resynchronized after frame parse error")
 				continue
 			}
-			break // Break if at EOF after error
 		}
+		if frameErr != nil && isEOFError(frameErr) {
+			reportCorrupt(frameStartPos)
+			if k.carryOver != nil {
+				tail := inputData[frameStartPos:]
+				if k.carryOver.store(stream, tail) {
+					k.logger.With("stream_pos", frameStartPos, "stream_key", streamKey, "carried_bytes", len(tail)).Debugf("Carrying partial frame over to the next message")
+					break
+				}
+				k.logger.With("stream_pos", frameStartPos, "stream_key", streamKey, "dropped_bytes", len(tail)).Warnf("Partial frame exceeds max_buffer_size, dropping it")
+				k.mBufferDroppedBytes.Incr(int64(len(tail)))
+			}
+			k.logger.With("stream_pos", frameStartPos).Warnf("Partial frame data at end of message: %v", frameErr)
+			if mainFramingError == nil {
+				mainFramingError = frameErr // This isn't necessarily a frame parsing error for metrics if it's just EOF
+			}
+			break
+		}
+		if frameErr != nil {
+			k.logger.With("stream_pos", frameStartPos, "resync", resync.strategy).Debugf("Error parsing frame container: %v", frameErr)
+			if corruptStart < 0 {
+				corruptStart, corruptErr = frameStartPos, frameErr
+			}
+			nextPos, errResync := resync.next(ctx, inputData, frameStartPos, frameInterpreterSlog, k.config.limits())
+			if errResync != nil {
+				reportCorrupt(frameStartPos + 1)
+				k.logger.Errorf("Failed to resynchronize after frame parse error: %v", errResync)
+				k.mErrorsTotal.Incr(1)
+				break
+			}
+			if nextPos < 0 {
+				// Abort: the rest of the message is reported as one corrupt region
+				reportCorrupt(streamSize)
+				break
+			}
+			if _, errSeek := inputStream.Seek(nextPos, io.SeekStart); errSeek != nil {
+				reportCorrupt(nextPos)
+				k.logger.Errorf("Failed to seek stream after frame parse error: %v", errSeek)
+				k.mErrorsTotal.Incr(1)
+				break
+			}
+			k.logger.With("new_pos", nextPos).Debugf("Resynchronized after frame parse error")
+			continue
+		}
+		reportCorrupt(frameStartPos)
 
 		// Successfully parsed a frame container
 		posAfterFrameParse, errPosSuccess := inputStream.Pos()
@@ -587,21 +1233,23 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 		}
 		k.logger.With("payload_len", len(payloadBytes)).Debugf("Payload extracted for data parsing")
 
+		// Frame fields passed on to every message made from this frame
+		frameMeta := frameMetadata(frameMap, k.config.FramingMetadataFields)
+		var header map[string]any
+		if k.config.FramingHeaderKey != "" {
+			header = frameHeader(frameMap, k.config.FramingDataFieldID)
+		}
+
 		// Always create a message, even for empty payloads
 		if len(payloadBytes) > 0 {
 			payloadStream := kaitai.NewStream(bytes.NewReader(payloadBytes))
-			// Ensure RootType is set for the data schema if specified in config
-			currentDataSchema := *dataSchema // Create a copy
-			effectiveDataRootType := k.config.RootType
-			if effectiveDataRootType == "" && currentDataSchema.Meta.ID != "" {
-				effectiveDataRootType = currentDataSchema.Meta.ID
-			}
-			if k.config.RootType != "" { // Use configured if present
-				currentDataSchema.RootType = k.config.RootType
+			effectiveDataRootType := rootType
+			if effectiveDataRootType == "" && dataSchema.Meta.ID != "" {
+				effectiveDataRootType = dataSchema.Meta.ID
 			}
-			k.logger.With("path", k.config.SchemaPath, "root_type", effectiveDataRootType).Debugf("Using data schema for payload")
+			k.logger.With("path", schemaPath, "root_type", effectiveDataRootType).Debugf("Using data schema for payload")
 
-			dataInterpreter, err := kst.NewKaitaiInterpreter(&currentDataSchema, dataInterpreterSlog)
+			dataInterpreter, err := k.interpreter(dataSchema, rootType, "data_interpreter")
 			if err != nil { // Handle error from NewKaitaiInterpreter
 				k.logger.Errorf("Failed to create data interpreter for payload: %v", err)
 				k.mPayloadParsingErrors.Incr(1)
@@ -623,15 +1271,22 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 				k.mPayloadParsingErrors.Incr(1)
 				k.mErrorsTotal.Incr(1)
 				newMsg.SetError(fmt.Errorf("failed to parse payload: %w", payloadErr))
+				if failure := k.partialFailure(payloadErr); failure != nil {
+					setFailureLocation(newMsg, failure)
+					resultMap := k.partialResult(failure)
+					addFrameHeader(resultMap, k.config.FramingHeaderKey, header)
+					setParsedOutput(newMsg, resultMap)
+				}
 			}
 
 			if parsedPayloadPd != nil {
 				k.mBytesProcessed.Incr(int64(len(payloadBytes)))                                 // Count successfully processed payload bytes
 				k.mFrameProcDuration.Timing(SystemTime.Since(frameParseStartTime).Nanoseconds()) // Timing for the entire frame processing (framing + payload)
-				resultMap := kst.ParsedDataToMap(parsedPayloadPd)
-				newMsg.SetStructured(resultMap)
+				resultMap := kst.ParsedDataToMap(parsedPayloadPd, k.config.outputOptions()...)
+				addFrameHeader(resultMap, k.config.FramingHeaderKey, header)
+				setParsedOutput(newMsg, resultMap)
 			} else if payloadErr == nil {
-				newMsg.SetStructured(map[string]any{})
+				newMsg.SetStructured(withFrameHeader(map[string]any{}, k.config.FramingHeaderKey, header))
 			}
 
 			msg.MetaWalk(func(key, value string) error {
@@ -639,7 +1294,7 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 				return nil
 			})
 			// Set plugin-specific metadata
-			newMsg.MetaSet("kaitai_schema_path", k.config.SchemaPath)
+			newMsg.MetaSet("kaitai_schema_path", schemaPath)
 			if effectiveDataRootType == "" && dataSchema != nil { // Recalculate effectiveDataRootType if it was empty and schema is available
 				effectiveDataRootType = dataSchema.Meta.ID
 			}
@@ -649,6 +1304,9 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 				effectiveFramingRootType = framingSchema.Meta.ID
 			}
 			newMsg.MetaSet("kaitai_frame_root_type", effectiveFramingRootType)
+			for key, value := range frameMeta {
+				newMsg.MetaSetMut(key, value)
+			}
 
 			outputMessages = append(outputMessages, newMsg)
 			k.mParsedTotal.Incr(1)
@@ -658,7 +1316,7 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 
 			// Create a message with empty structure for zero-length payloads
 			newMsg := service.NewMessage(nil)
-			newMsg.SetStructured(map[string]any{})
+			newMsg.SetStructured(withFrameHeader(map[string]any{}, k.config.FramingHeaderKey, header))
 
 			// Copy metadata from original message
 			msg.MetaWalk(func(key, value string) error {
@@ -667,8 +1325,8 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 			})
 
 			// Set plugin-specific metadata
-			newMsg.MetaSet("kaitai_schema_path", k.config.SchemaPath)
-			effectiveDataRootType := k.config.RootType
+			newMsg.MetaSet("kaitai_schema_path", schemaPath)
+			effectiveDataRootType := rootType
 			if effectiveDataRootType == "" && dataSchema != nil {
 				effectiveDataRootType = dataSchema.Meta.ID
 			}
@@ -679,6 +1337,9 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 				effectiveFramingRootType = framingSchema.Meta.ID
 			}
 			newMsg.MetaSet("kaitai_frame_root_type", effectiveFramingRootType)
+			for key, value := range frameMeta {
+				newMsg.MetaSetMut(key, value)
+			}
 
 			outputMessages = append(outputMessages, newMsg)
 			k.mParsedTotal.Incr(1)
@@ -718,6 +1379,10 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 		}
 	}
 
+	if streamPos, errPos := inputStream.Pos(); errPos == nil {
+		reportCorrupt(streamPos)
+	}
+
 	if len(outputMessages) == 0 && mainFramingError != nil {
 		// If no messages were successfully processed and a framing error occurred,
 		// propagate the error on the original message.
@@ -736,16 +1401,7 @@ func (k *KaitaiProcessor) parseFramedBinary(ctx context.Context, msg *service.Me
 	}
 	k.logger.With("frames_extracted", len(outputMessages), "bytes_remaining_in_msg_buffer", bytesRemainingAfterLoop).Debugf("Finished attempting to extract frames from message")
 
-	// Re-define or ensure isEofError is accessible here if it was a very local closure.
-	// For simplicity, assuming it's defined in a scope accessible here or re-declared if necessary.
-	isEofErrorCheckForFinal := func(e error) bool { // Duplicating for clarity if scope was an issue.
-		if e == nil {
-			return false
-		}
-		_, isKaitaiCustomEof := e.(kaitai.EndOfStreamError)
-		return isKaitaiCustomEof || errors.Is(e, io.EOF) || errors.Is(e, io.ErrUnexpectedEOF)
-	}
-	if mainFramingError != nil && isEofErrorCheckForFinal(mainFramingError) && len(outputMessages) > 0 {
+	if mainFramingError != nil && isEOFError(mainFramingError) && len(outputMessages) > 0 {
 		k.logger.Infof("Finished processing message with some frames parsed and a partial frame at the end (EOF).")
 		return outputMessages, nil
 	}
@@ -775,21 +1431,25 @@ func (k *KaitaiProcessor) parseBinary(ctx context.Context, msg *service.Message)
 	startTime := SystemTime.Now()
 
 	// Load schema
-	schema, err := k.loadDataSchema(k.config.SchemaPath)
+	schemaPath, rootType, err := k.resolveDataSchema(msg)
+	if err != nil {
+		k.logger.Errorf("Failed to resolve data schema: %v", err)
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(err)
+		return service.MessageBatch{msg}, nil
+	}
+	schema, err := k.loadDataSchema(schemaPath)
 	if err != nil {
 		// Error logged by loadDataSchema
 		k.mErrorsTotal.Incr(1) // General error as it's schema loading
 		msg.SetError(fmt.Errorf("failed to load schema: %w", err))
 		return service.MessageBatch{msg}, nil
 	}
-
 	// Create Kaitai stream for parsing
 	stream := kaitai.NewStream(bytes.NewReader(binData))
 
 	// Create interpreter and parse data
-	// Create slog.Logger instance using Benthos logger
-	interpreterSlog := slog.New(newBenthosLogHandler(k.logger)).With("component", "data_interpreter")
-	interpreter, err := kst.NewKaitaiInterpreter(schema, interpreterSlog)
+	interpreter, err := k.interpreter(schema, rootType, "data_interpreter")
 	if err != nil {
 		k.logger.Errorf("Failed to create Kaitai interpreter for single parse: %v", err)
 		k.mErrorsTotal.Incr(1)
@@ -797,26 +1457,33 @@ func (k *KaitaiProcessor) parseBinary(ctx context.Context, msg *service.Message)
 		return service.MessageBatch{msg}, nil
 	}
 
+	var result any
 	parsedDataPd, parseErr := interpreter.Parse(ctx, stream) // Use ctx from function signature
+	failure := k.partialFailure(parseErr)
 	if parseErr != nil {
-		k.logger.With("data_size", len(binData)).Errorf("Failed to parse non-framed binary data: %v", err)
+		k.logger.With("data_size", len(binData)).Errorf("Failed to parse non-framed binary data: %v", parseErr)
 		k.mPayloadParsingErrors.Incr(1) // Specific error for payload parsing
 		k.mErrorsTotal.Incr(1)
-		msg.SetError(fmt.Errorf("failed to parse binary data (size: %d bytes): %w", len(binData), err))
-		return service.MessageBatch{msg}, nil
-	}
-	k.mBytesProcessed.Incr(int64(len(binData)))
-	k.mFrameProcDuration.Timing(SystemTime.Since(startTime).Nanoseconds()) // Treat as one "frame"
+		parseErr = fmt.Errorf("failed to parse binary data (size: %d bytes): %w", len(binData), parseErr)
+		if failure == nil {
+			msg.SetError(parseErr)
+			return service.MessageBatch{msg}, nil
+		}
+		result = k.partialResult(failure)
+	} else {
+		k.mBytesProcessed.Incr(int64(len(binData)))
+		k.mFrameProcDuration.Timing(SystemTime.Since(startTime).Nanoseconds()) // Treat as one "frame"
 
-	// Convert parsed data to a map/JSON structure
-	result := kst.ParsedDataToMap(parsedDataPd)
+		// Convert parsed data to a map/JSON structure
+		result = kst.ParsedDataToMap(parsedDataPd, k.config.outputOptions()...)
 
-	k.logger.With("data_size", len(binData)).Debugf("Successfully parsed non-framed binary data")
-	k.mParsedTotal.Incr(1)
+		k.logger.With("data_size", len(binData)).Debugf("Successfully parsed non-framed binary data")
+		k.mParsedTotal.Incr(1)
+	}
 
 	// Create new message with parsed data
 	newMsg := service.NewMessage(nil)
-	newMsg.SetStructured(result)
+	setParsedOutput(newMsg, result)
 
 	// Copy metadata from original message
 	msg.MetaWalk(func(key, value string) error {
@@ -825,16 +1492,59 @@ func (k *KaitaiProcessor) parseBinary(ctx context.Context, msg *service.Message)
 	})
 
 	// Set plugin-specific metadata
-	newMsg.MetaSet("kaitai_schema_path", k.config.SchemaPath)
-	effectiveDataRootType := k.config.RootType
+	newMsg.MetaSet("kaitai_schema_path", schemaPath)
+	effectiveDataRootType := rootType
 	if effectiveDataRootType == "" && schema != nil { // schema is the loaded dataSchema in this function
 		effectiveDataRootType = schema.Meta.ID
 	}
 	newMsg.MetaSet("kaitai_root_type", effectiveDataRootType)
+	if failure != nil {
+		setFailureLocation(newMsg, failure)
+		newMsg.SetError(parseErr)
+	}
 
 	return service.MessageBatch{newMsg}, nil
 }
 
+// setParsedOutput sets parsed output as the contents of msg. Output ordered by
+// preserve_order is set as JSON bytes, as structured contents don't keep key
+// order; if it can't be encoded, as with NaN floats, it is set unordered.
+func setParsedOutput(msg *service.Message, output any) {
+	if ordered, ok := output.(*kst.OrderedMap); ok {
+		if encoded, err := json.Marshal(ordered); err == nil {
+			msg.SetBytes(encoded)
+			return
+		}
+		output = ordered.ToMap()
+	}
+	msg.SetStructured(output)
+}
+
+// partialFailure returns where a parse failed, with the data decoded before
+// the failure, if partial_results is set; nil otherwise
+func (k *KaitaiProcessor) partialFailure(parseErr error) *kst.ParseError {
+	var failure *kst.ParseError
+	if !k.config.PartialResults || !errors.As(parseErr, &failure) {
+		return nil
+	}
+	k.mPartialResults.Incr(1)
+	return failure
+}
+
+// partialResult converts the data decoded before a parse failure to an object
+func (k *KaitaiProcessor) partialResult(failure *kst.ParseError) any {
+	if failure.Partial == nil {
+		return map[string]any{}
+	}
+	return kst.ParsedDataToMap(failure.Partial, k.config.outputOptions()...)
+}
+
+// setFailureLocation records in the metadata of msg where a parse failed
+func setFailureLocation(msg *service.Message, failure *kst.ParseError) {
+	msg.MetaSetMut("kaitai_error_path", failure.Path)
+	msg.MetaSetMut("kaitai_error_offset", failure.Offset)
+}
+
 // serializeToBinary serializes a JSON structure to binary using Kaitai Struct.
 func (k *KaitaiProcessor) serializeToBinary(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
 	k.logger.Debugf("Serializing structured data to binary with Kaitai Struct")
@@ -859,18 +1569,22 @@ func (k *KaitaiProcessor) serializeToBinary(ctx context.Context, msg *service.Me
 	k.logger.With("data_type", fmt.Sprintf("%T", structData), "num_keys", len(dataMap)).Debugf("Input data for serialization")
 
 	// Load schema
-	schema, err := k.loadDataSchema(k.config.SchemaPath)
+	schemaPath, rootType, err := k.resolveDataSchema(msg)
+	if err != nil {
+		k.logger.Errorf("Failed to resolve data schema: %v", err)
+		k.mErrorsTotal.Incr(1)
+		msg.SetError(err)
+		return service.MessageBatch{msg}, nil
+	}
+	schema, err := k.loadDataSchema(schemaPath)
 	if err != nil {
 		// Error logged by loadDataSchema
 		k.mErrorsTotal.Incr(1) // General error as it's schema loading
 		msg.SetError(fmt.Errorf("failed to load schema: %w", err))
 		return service.MessageBatch{msg}, nil
 	}
-
 	// Create serializer and serialize data
-	// Create slog.Logger instance using Benthos logger
-	serializerSlog := slog.New(newBenthosLogHandler(k.logger)).With("component", "data_serializer")
-	serializer, err := kst.NewKaitaiSerializer(schema, serializerSlog)
+	serializer, err := k.serializer(schema, rootType, "data_serializer", kst.WithAutoCompute(k.config.AutoCompute), kst.WithSkipValidation(k.config.SkipValidation), kst.WithInputFormat(k.config.outputOptions()...))
 	if err != nil {
 		k.logger.Errorf("Failed to create Kaitai serializer: %v", err)
 		k.mErrorsTotal.Incr(1)
@@ -883,6 +1597,20 @@ func (k *KaitaiProcessor) serializeToBinary(ctx context.Context, msg *service.Me
 	if !ok {
 		return service.MessageBatch{msg}, fmt.Errorf("structured data is not a map[string]any, got %T", structData)
 	}
+
+	// With framing, envelope fields may travel next to the payload under framing_header_key
+	var frame map[string]any
+	if k.config.FramingSchemaPath != "" {
+		frame = map[string]any{}
+		if k.config.FramingHeaderKey != "" {
+			if header, ok := dataMap[k.config.FramingHeaderKey].(map[string]any); ok {
+				frame = maps.Clone(header)
+			}
+			dataMap = maps.Clone(dataMap)
+			delete(dataMap, k.config.FramingHeaderKey)
+		}
+	}
+
 	binData, err := serializer.Serialize(ctx, dataMap) // Use ctx from function signature
 	if err != nil {
 		k.logger.Errorf("Failed to serialize data: %v", err)
@@ -892,6 +1620,18 @@ func (k *KaitaiProcessor) serializeToBinary(ctx context.Context, msg *service.Me
 		return service.MessageBatch{msg}, nil
 	}
 
+	if frame != nil {
+		payloadLen := len(binData)
+		if binData, err = k.serializeFrame(ctx, msg, frame, binData); err != nil {
+			k.logger.Errorf("Failed to serialize frame: %v", err)
+			k.mPayloadSerializationErrors.Incr(1)
+			k.mErrorsTotal.Incr(1)
+			msg.SetError(fmt.Errorf("failed to serialize frame: %w", err))
+			return service.MessageBatch{msg}, nil
+		}
+		k.logger.With("payload_size_bytes", payloadLen, "frame_size_bytes", len(binData)).Debugf("Wrapped payload in frame")
+	}
+
 	k.logger.With("output_size_bytes", len(binData)).Debugf("Successfully serialized data")
 	k.mSerializedTotal.Incr(1)
 
@@ -906,18 +1646,105 @@ func (k *KaitaiProcessor) serializeToBinary(ctx context.Context, msg *service.Me
 	return service.MessageBatch{newMsg}, nil
 }
 
+// serializeFrame wraps a serialized payload in the envelope described by the
+// framing schema. The payload goes into framing_data_field_id, other envelope
+// fields come from frame or from metadata mapped by framing_metadata_fields,
+// and fields such as lengths are computed from the data.
+func (k *KaitaiProcessor) serializeFrame(ctx context.Context, msg *service.Message, frame map[string]any, payload []byte) ([]byte, error) {
+	framingSchema, err := k.loadFramingSchema(k.config.FramingSchemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load framing schema '%s': %w", k.config.FramingSchemaPath, err)
+	}
+	rootType := framingSchema.Meta.ID
+	if k.config.FramingRootType != "" {
+		rootType = k.config.FramingRootType
+	}
+
+	frame[k.config.FramingDataFieldID] = payload
+	if err := frameFromMetadata(frame, msg, framingSchema, rootType, k.config.FramingMetadataFields); err != nil {
+		return nil, err
+	}
+
+	serializer, err := k.serializer(framingSchema, k.config.FramingRootType, "frame_serializer", kst.WithAutoCompute(true), kst.WithSkipValidation(k.config.SkipValidation))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create frame serializer: %w", err)
+	}
+	return serializer.Serialize(ctx, frame)
+}
+
+// inlineSchemaPath stands for the inline schema in the schema cache and metadata
+const inlineSchemaPath = "<inline>"
+
+// resolveDataSchema resolves schema_path and root_type for msg
+func (k *KaitaiProcessor) resolveDataSchema(msg *service.Message) (schemaPath, rootType string, err error) {
+	if k.config.Schema != "" {
+		schemaPath = inlineSchemaPath
+	} else if schemaPath, err = k.schemaPath.TryString(msg); err != nil {
+		return "", "", fmt.Errorf("failed to resolve schema_path: %w", err)
+	}
+	if schemaPath == "" {
+		return "", "", fmt.Errorf("schema_path resolved to an empty path")
+	}
+	if k.schemaBaseDir != "" {
+		if err := checkWithinDir(k.schemaBaseDir, schemaPath); err != nil {
+			return "", "", fmt.Errorf("schema_path resolved to '%s': %w", schemaPath, err)
+		}
+	}
+	if rootType, err = k.rootType.TryString(msg); err != nil {
+		return "", "", fmt.Errorf("failed to resolve root_type: %w", err)
+	}
+	return schemaPath, rootType, nil
+}
+
+// interpreter returns an interpreter for schema, with rootType if set,
+// forked from one compiled once per schema version so that messages only
+// pay for their own parse state
+func (k *KaitaiProcessor) interpreter(schema *kst.KaitaiSchema, rootType, component string, logAttrs ...any) (*kst.KaitaiInterpreter, error) {
+	role := fmt.Sprint(component, "/", rootType, logAttrs)
+	compiled, err := k.interpreters.LoadOrCompile(schema, role, func() (*kst.KaitaiInterpreter, error) {
+		currentSchema := *schema // Copy so the cached schema keeps its own root type
+		if rootType != "" {
+			currentSchema.RootType = rootType
+		}
+		logger := slog.New(newBenthosLogHandler(k.logger)).With("component", component).With(logAttrs...)
+		return kst.NewKaitaiInterpreter(&currentSchema, logger, kst.WithLimits(k.config.limits()))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Fork(), nil
+}
+
+// serializer returns a serializer for schema, with rootType if set, forked
+// from one compiled once per schema version. The options of a component
+// must not change between calls.
+func (k *KaitaiProcessor) serializer(schema *kst.KaitaiSchema, rootType, component string, opts ...kst.SerializerOption) (*kst.KaitaiSerializer, error) {
+	compiled, err := k.serializers.LoadOrCompile(schema, component+"/"+rootType, func() (*kst.KaitaiSerializer, error) {
+		currentSchema := *schema // Copy so the cached schema keeps its own root type
+		if rootType != "" {
+			currentSchema.RootType = rootType
+		}
+		logger := slog.New(newBenthosLogHandler(k.logger)).With("component", component)
+		return kst.NewKaitaiSerializer(&currentSchema, logger, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Fork(), nil
+}
+
 // loadDataSchema loads the main data schema using the internal helper.
 func (k *KaitaiProcessor) loadDataSchema(path string) (*kst.KaitaiSchema, error) {
-	return k.loadSchemaInternal(path, &k.schemaCache, *k.mSchemaCacheHits, *k.mSchemaCacheMisses, "data")
+	return k.loadSchemaInternal(path, k.schemaCache, *k.mSchemaCacheHits, *k.mSchemaCacheMisses, "data")
 }
 
 // loadFramingSchema loads the framing schema using the internal helper.
 func (k *KaitaiProcessor) loadFramingSchema(path string) (*kst.KaitaiSchema, error) {
-	return k.loadSchemaInternal(path, &k.framingSchemaCache, *k.mFramingSchemaCacheHits, *k.mFramingSchemaCacheMisses, "framing")
+	return k.loadSchemaInternal(path, k.framingSchemaCache, *k.mFramingSchemaCacheHits, *k.mFramingSchemaCacheMisses, "framing")
 }
 
 // loadSchemaInternal loads and parses a KSY schema file using the specified cache and metrics.
-func (k *KaitaiProcessor) loadSchemaInternal(path string, cache *sync.Map, mHits, mMisses service.MetricCounter, schemaType string) (*kst.KaitaiSchema, error) {
+func (k *KaitaiProcessor) loadSchemaInternal(path string, cache *schemaLRU, mHits, mMisses service.MetricCounter, schemaType string) (*kst.KaitaiSchema, error) {
 	if path == "" {
 		return nil, fmt.Errorf("%s schema path is empty", schemaType)
 	}
@@ -925,38 +1752,164 @@ func (k *KaitaiProcessor) loadSchemaInternal(path string, cache *sync.Map, mHits
 	if cachedSchema, ok := cache.Load(path); ok {
 		k.logger.With("path", path).Tracef("%s schema cache hit", schemaType)
 		mHits.Incr(1)
-		return cachedSchema.(*kst.KaitaiSchema), nil
+		return cachedSchema, nil
 	}
 
 	k.logger.With("path", path).Debugf("Loading %s schema from file", schemaType)
 	mMisses.Incr(1)
 
-	// Read schema file
-	data, err := os.ReadFile(path)
+	schema, err := k.source.read(path)
 	if err != nil {
-		k.logger.With("path", path).Errorf("Failed to read schema file: %v", err)
+		return nil, err
+	}
+
+	// Store in cache, keeping the schema of a concurrent load of the same path
+	cached, loaded := cache.LoadOrStore(path, schema)
+	k.logger.With("path", path).Debugf("Loaded and cached schema successfully")
+	if !loaded && k.watcher != nil {
+		k.watcher.watch(path, schemaImportFiles(path, schema)...)
+	}
+
+	return cached, nil
+}
+
+// isStatic reports whether s has no interpolations
+func isStatic(s *service.InterpolatedString) bool {
+	_, static := s.Static()
+	return static
+}
+
+// checkWithinDir checks that path names a file within dir, an absolute path
+func checkWithinDir(dir, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("outside schema_base_dir '%s'", dir)
+	}
+	return nil
+}
+
+// schemaSource reads KSY schemas, merging their imports, from files or from
+// the entries of a cache resource. Users of schemas other than the processor
+// read files, with a zero schemaSource or one holding their logger.
+type schemaSource struct {
+	resource  string             // Cache resource holding schemas, or "" for files
+	resources *service.Resources // Gives access to resource
+	logger    *service.Logger
+}
+
+// read reads and parses the KSY schema at path
+func (s schemaSource) read(path string) (*kst.KaitaiSchema, error) {
+	data, err := s.readFile(path)
+	if err != nil {
+		s.logger.With("path", path).Errorf("Failed to read schema file: %v", err)
 		return nil, fmt.Errorf("failed to read schema file '%s': %w", path, err)
 	}
+	return s.parse(path, data)
+}
 
-	// Parse YAML
-	schema := &kst.KaitaiSchema{}
-	if err := yaml.Unmarshal(data, schema); err != nil {
-		k.logger.With("path", path).Errorf("Failed to parse schema YAML: %v", err)
+// parse parses the KSY schema found at path and merges its imports, which
+// are read from the same source
+func (s schemaSource) parse(path string, data []byte) (*kst.KaitaiSchema, error) {
+	schema, err := kst.NewKaitaiSchemaFromYAML(data)
+	if err != nil {
+		s.logger.With("path", path).Errorf("Failed to parse schema YAML: %v", err)
 		return nil, fmt.Errorf("failed to parse schema YAML from '%s': %w", path, err)
 	}
 
-	// Store in cache
-	cache.Store(path, schema)
-	k.logger.With("path", path).Debugf("Loaded and cached schema successfully")
-
+	importer := filepath.ToSlash(path)
+	if path == inlineSchemaPath {
+		importer = "." // Relative to the working directory
+	}
+	err = schema.ResolveImports(importer, func(importPath string) ([]byte, error) {
+		return s.readFile(filepath.FromSlash(importPath))
+	})
+	if err != nil {
+		s.logger.With("path", path).Errorf("Failed to resolve schema imports: %v", err)
+		return nil, fmt.Errorf("failed to resolve imports of '%s': %w", path, err)
+	}
 	return schema, nil
 }
 
+// readFile reads the KSY file at path, or the entry with that key in the
+// cache resource if set
+func (s schemaSource) readFile(path string) ([]byte, error) {
+	if s.resource == "" {
+		return os.ReadFile(path)
+	}
+	var data []byte
+	var getErr error
+	ctx := context.Background()
+	err := s.resources.AccessCache(ctx, s.resource, func(c service.Cache) {
+		data, getErr = c.Get(ctx, path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("accessing cache '%s': %w", s.resource, err)
+	}
+	if getErr != nil {
+		return nil, fmt.Errorf("reading key '%s' of cache '%s': %w", path, s.resource, getErr)
+	}
+	return data, nil
+}
+
+// reloadSchema reloads a cached schema after its file or one of its imports
+// changed. The new version replaces the cached one only if it loads and
+// doesn't break expressions that compiled before. Messages already being
+// processed keep the schema they loaded, so they finish on the old version.
+func (k *KaitaiProcessor) reloadSchema(path string) {
+	caches := []*schemaLRU{k.schemaCache, k.framingSchemaCache}
+	var previous *kst.KaitaiSchema
+	for _, cache := range caches {
+		if cached, ok := cache.Load(path); ok {
+			previous = cached
+		}
+	}
+	if previous == nil {
+		return // Dropped from the caches since it was watched
+	}
+
+	schema, err := k.source.read(path)
+	if err == nil {
+		// Some expressions are beyond the interpreter; only reject what used to compile
+		if compileErr := schema.CompileExpressions(); compileErr != nil && previous.CompileExpressions() == nil {
+			err = compileErr
+		}
+	}
+	if err != nil {
+		k.logger.With("path", path).Errorf("Schema changed but failed to reload, keeping the previous version: %v", err)
+		k.mSchemaReloadErrors.Incr(1)
+		return
+	}
+
+	for _, cache := range caches {
+		cache.Replace(path, schema)
+	}
+	// The new version may import other files
+	k.watcher.watch(path, schemaImportFiles(path, schema)...)
+	k.logger.With("path", path).Infof("Reloaded changed schema")
+	k.mSchemaReloads.Incr(1)
+}
+
 // Close the processor resources
 func (k *KaitaiProcessor) Close(ctx context.Context) error {
 	k.logger.Debugf("Closing Kaitai processor and clearing schema cache")
-	k.schemaCache = sync.Map{}        // Clear the main data schema cache
-	k.framingSchemaCache = sync.Map{} // Clear the framing schema cache
+	if k.watcher != nil {
+		k.watcher.close()
+	}
+	labelledProcessors.CompareAndDelete(k.resources.Label(), k)
+	k.schemaCache.Clear()
+	k.framingSchemaCache.Clear()
+	k.interpreters.Clear()
+	k.serializers.Clear()
+	if k.carryOver != nil {
+		if dropped := k.carryOver.close(); dropped > 0 {
+			k.logger.With("dropped_bytes", dropped).Warnf("Dropped carried-over partial frames on close")
+			k.mBufferDroppedBytes.Incr(int64(dropped))
+		}
+	}
 	return nil
 }
 
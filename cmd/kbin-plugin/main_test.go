@@ -1,18 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"testing/iotest"
 	"time"
 
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
 	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
 )
 
 // --- Test Helpers ---
@@ -666,6 +676,2154 @@ framing_data_field_id: data_payload
 	})
 }
 
+// --- Test Suite for Serializer Auto-Compute ---
+
+func TestKaitaiProcessor_AutoCompute(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, `
+meta:
+  id: tlv
+seq:
+  - id: tag
+    type: u1
+  - id: len
+    type: u1
+  - id: value
+    size: len
+`)
+
+	newProcessor := func(t *testing.T, autoCompute bool) *KaitaiProcessor {
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf("schema_path: %s\nis_parser: false\nauto_compute: %t", dataPath, autoCompute), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+
+	t.Run("Enabled", func(t *testing.T) {
+		inputMsg := service.NewMessage(nil)
+		inputMsg.SetStructured(map[string]any{"tag": 7, "value": []byte("abcd")})
+		batch, err := newProcessor(t, true).Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.NoError(t, batch[0].GetError())
+
+		resBytes, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x07, 0x04, 'a', 'b', 'c', 'd'}, resBytes)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		inputMsg := service.NewMessage(nil)
+		inputMsg.SetStructured(map[string]any{"tag": 7, "value": []byte("abcd")})
+		batch, err := newProcessor(t, false).Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		assert.Error(t, batch[0].GetError(), "len is required when auto_compute is off")
+	})
+}
+
+func TestKaitaiProcessor_SkipValidation(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, `
+meta:
+  id: versioned
+seq:
+  - id: magic
+    contents: [0xCA, 0xFE]
+  - id: version
+    type: u1
+    valid:
+      max: 2
+`)
+
+	newProcessor := func(t *testing.T, skip bool) *KaitaiProcessor {
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf("schema_path: %s\nis_parser: false\nskip_validation: %t", dataPath, skip), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+
+	t.Run("Enforced_By_Default", func(t *testing.T) {
+		inputMsg := service.NewMessage(nil)
+		inputMsg.SetStructured(map[string]any{"version": 9})
+		batch, err := newProcessor(t, false).Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msgErr := batch[0].GetError()
+		require.Error(t, msgErr)
+		assert.ErrorIs(t, msgErr, kst.ErrValidationFailed)
+		assert.Contains(t, msgErr.Error(), "'version'")
+	})
+
+	t.Run("Skipped", func(t *testing.T) {
+		inputMsg := service.NewMessage(nil)
+		inputMsg.SetStructured(map[string]any{"version": 9})
+		batch, err := newProcessor(t, true).Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.NoError(t, batch[0].GetError())
+
+		resBytes, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xCA, 0xFE, 0x09}, resBytes)
+	})
+}
+
+// --- Test Suite for Resource Limits ---
+
+func TestKaitaiProcessor_Limits(t *testing.T) {
+	ctx := context.Background()
+	lengthPrefixedSchema := `
+meta:
+  id: length_prefixed
+  endian: le
+seq:
+  - id: len
+    type: u4
+  - id: body
+    size: len
+`
+
+	t.Run("MaxAllocationSize_Exceeded", func(t *testing.T) {
+		dataPath := writeTempSchema(t, lengthPrefixedSchema)
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf("schema_path: %s\nis_parser: true\nmax_allocation_size: 16", dataPath), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+
+		inputMsg := service.NewMessage([]byte{0xFF, 0xFF, 0xFF, 0x7F, 0x01})
+		batch, err := processor.Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msgErr := batch[0].GetError()
+		require.Error(t, msgErr)
+		assert.ErrorIs(t, msgErr, kst.ErrLimitExceeded)
+		assert.Contains(t, msgErr.Error(), "max_allocation_size")
+	})
+
+	t.Run("Unlimited_By_Default", func(t *testing.T) {
+		dataPath := writeTempSchema(t, lengthPrefixedSchema)
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf("schema_path: %s\nis_parser: true", dataPath), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		assert.Equal(t, kst.Limits{}, processor.config.limits())
+
+		inputMsg := service.NewMessage([]byte{0x02, 0x00, 0x00, 0x00, 0x01, 0x02})
+		batch, err := processor.Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		assert.NoError(t, batch[0].GetError())
+	})
+
+	t.Run("Framed_MaxRepeatItems_Exceeded", func(t *testing.T) {
+		dataPath := writeTempSchema(t, `
+meta:
+  id: repeated
+seq:
+  - id: items
+    type: u1
+    repeat: eos
+`)
+		framingPath := writeTempSchema(t, dummyFramingSchemaContent)
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+is_parser: true
+framing_schema_path: %s
+framing_data_field_id: data_payload
+max_repeat_items: 2
+`, dataPath, framingPath), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+
+		inputMsg := service.NewMessage([]byte{0x03, 0x01, 0x02, 0x03})
+		batch, err := processor.Process(ctx, inputMsg)
+		require.NoError(t, err)
+		require.NotEmpty(t, batch)
+		assert.ErrorIs(t, batch[0].GetError(), kst.ErrLimitExceeded)
+	})
+
+	t.Run("Negative_Limit_Rejected", func(t *testing.T) {
+		dataPath := writeTempSchema(t, dummyDataSchemaContent)
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf("schema_path: %s\nmax_depth: -1", dataPath), nil)
+		require.NoError(t, err)
+		_, err = newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must not be negative")
+	})
+}
+
+// --- Test Suite for Framing Carry-Over ---
+
+func TestKaitaiProcessor_CarryOver(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, dummyDataSchemaContent)
+	framingPath := writeTempSchema(t, dummyFramingSchemaContent)
+
+	newProcessor := func(t *testing.T, extra string) *KaitaiProcessor {
+		t.Helper()
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+is_parser: true
+framing_schema_path: %s
+framing_data_field_id: data_payload
+%s`, dataPath, framingPath, extra), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		t.Cleanup(func() { processor.Close(ctx) })
+		return processor
+	}
+	values := func(t *testing.T, batch service.MessageBatch) []int64 {
+		t.Helper()
+		var got []int64
+		for _, msg := range batch {
+			require.NoError(t, msg.GetError())
+			structured, err := msg.AsStructured()
+			require.NoError(t, err)
+			// Ordered output, the default, is JSON
+			value, err := structured.(map[string]any)["value"].(json.Number).Int64()
+			require.NoError(t, err)
+			got = append(got, value)
+		}
+		return got
+	}
+
+	t.Run("FrameStraddlesMessages", func(t *testing.T) {
+		processor := newProcessor(t, "max_buffer_size: 16")
+
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x01, 0xAA, 0x02}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0xAA}, values(t, batch))
+		raw, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, `{"value":170}`, string(raw))
+
+		// The second message only completes the frame's payload
+		batch, err = processor.Process(ctx, service.NewMessage([]byte{0xBB}))
+		require.NoError(t, err)
+		assert.Empty(t, batch, "a partial frame is buffered, not an error")
+
+		batch, err = processor.Process(ctx, service.NewMessage([]byte{0xCC, 0x01, 0xDD}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0xBB, 0xDD}, values(t, batch))
+	})
+
+	t.Run("StreamsKeyedByMetadata", func(t *testing.T) {
+		processor := newProcessor(t, "max_buffer_size: 16\nbuffer_key: connection_id")
+		message := func(conn string, data ...byte) *service.Message {
+			msg := service.NewMessage(data)
+			msg.MetaSet("connection_id", conn)
+			return msg
+		}
+
+		batch, err := processor.Process(ctx, message("a", 0x01))
+		require.NoError(t, err)
+		assert.Empty(t, batch)
+		batch, err = processor.Process(ctx, message("b", 0x01, 0xB1, 0x01))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0xB1}, values(t, batch))
+
+		batch, err = processor.Process(ctx, message("a", 0xA1))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0xA1}, values(t, batch))
+		batch, err = processor.Process(ctx, message("b", 0xB2))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0xB2}, values(t, batch))
+	})
+
+	t.Run("PartialFrameLargerThanBuffer", func(t *testing.T) {
+		processor := newProcessor(t, "max_buffer_size: 2")
+
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x05, 0x01, 0x02}))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.Error(t, batch[0].GetError())
+		assert.Contains(t, batch[0].GetError().Error(), "EOF")
+
+		// Nothing was carried over, so the next message starts a new frame
+		batch, err = processor.Process(ctx, service.NewMessage([]byte{0x01, 0x2A}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0x2A}, values(t, batch))
+	})
+
+	t.Run("IdlePartialFrameDropped", func(t *testing.T) {
+		mockTime := &mockTimeSource{}
+		originalSystemTime := SystemTime
+		SystemTime = mockTime
+		// Restored after the processor's sweeper is stopped
+		t.Cleanup(func() { SystemTime = originalSystemTime })
+		processor := newProcessor(t, "max_buffer_size: 16\nbuffer_idle_timeout: 5s")
+
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x02, 0xAA}))
+		require.NoError(t, err)
+		assert.Empty(t, batch)
+
+		mockTime.Advance(10 * time.Second)
+		batch, err = processor.Process(ctx, service.NewMessage([]byte{0x01, 0x2A}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0x2A}, values(t, batch))
+	})
+
+	t.Run("IdleStreamsSwept", func(t *testing.T) {
+		mockTime := &mockTimeSource{}
+		originalSystemTime := SystemTime
+		SystemTime = mockTime
+		t.Cleanup(func() { SystemTime = originalSystemTime })
+		processor := newProcessor(t, "max_buffer_size: 16\nbuffer_key: connection_id\nbuffer_idle_timeout: 1h")
+
+		for _, conn := range []string{"a", "b"} {
+			msg := service.NewMessage([]byte{0x02, 0xAA})
+			msg.MetaSet("connection_id", conn)
+			batch, err := processor.Process(ctx, msg)
+			require.NoError(t, err)
+			assert.Empty(t, batch)
+		}
+		assert.Zero(t, processor.carryOver.sweep(), "tails aren't idle yet")
+
+		// Streams that never send again are freed without another message
+		mockTime.Advance(2 * time.Hour)
+		assert.Equal(t, 4, processor.carryOver.sweep())
+		processor.carryOver.mu.Lock()
+		assert.Empty(t, processor.carryOver.streams)
+		processor.carryOver.mu.Unlock()
+	})
+
+	t.Run("ConcurrentMessagesOfStream", func(t *testing.T) {
+		processor := newProcessor(t, "max_buffer_size: 16")
+
+		// Every byte is 0x01, so in whatever order the messages are processed
+		// the stream is a run of one-byte frames of value 1, and no byte may
+		// be lost between taking and storing a stream's partial frame
+		const messages = 200
+		batches := make([]service.MessageBatch, messages)
+		var wg sync.WaitGroup
+		for i := range batches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				batch, err := processor.Process(ctx, service.NewMessage([]byte{0x01}))
+				assert.NoError(t, err)
+				batches[i] = batch
+			}()
+		}
+		wg.Wait()
+
+		var frames []int64
+		for _, batch := range batches {
+			frames = append(frames, values(t, batch)...)
+		}
+		assert.Len(t, frames, messages/2)
+		for _, value := range frames {
+			assert.Equal(t, int64(1), value)
+		}
+	})
+
+	t.Run("Disabled_By_Default", func(t *testing.T) {
+		processor := newProcessor(t, "")
+		assert.Nil(t, processor.carryOver)
+
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x02, 0xAA}))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		assert.Error(t, batch[0].GetError())
+	})
+
+	t.Run("Requires_Framing", func(t *testing.T) {
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf("schema_path: %s\npreserve_order: false\nmax_buffer_size: 16", dataPath), nil)
+		require.NoError(t, err)
+		_, err = newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max_buffer_size requires framing_schema_path")
+	})
+}
+
+// --- Test Suite for Framing Resynchronization ---
+
+func TestKaitaiProcessor_Resync(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, dummyDataSchemaContent)
+	framingPath := writeTempSchema(t, `
+meta:
+  id: synced_frame
+seq:
+  - id: magic
+    contents: [0xAA, 0x55]
+  - id: len
+    type: u1
+    valid:
+      max: 4
+  - id: data_payload
+    size: len
+`)
+
+	newProcessor := func(t *testing.T, extra string) *KaitaiProcessor {
+		t.Helper()
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+preserve_order: false
+is_parser: true
+framing_schema_path: %s
+framing_data_field_id: data_payload
+%s`, dataPath, framingPath, extra), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	values := func(t *testing.T, batch service.MessageBatch) []int64 {
+		t.Helper()
+		var got []int64
+		for _, msg := range batch {
+			require.NoError(t, msg.GetError())
+			structured, err := msg.AsStructured()
+			require.NoError(t, err)
+			got = append(got, structured.(map[string]any)["value"].(int64))
+		}
+		return got
+	}
+	frame := func(value byte) []byte { return []byte{0xAA, 0x55, 0x01, value} }
+	withGarbage := append(append(frame(0x01), 0x00, 0xAA, 0x11, 0xAA, 0x55, 0x09), frame(0x02)...)
+
+	for _, strategy := range []string{resyncByte, resyncSyncMarker, resyncHeader} {
+		t.Run("SkipsGarbage_"+strategy, func(t *testing.T) {
+			processor := newProcessor(t, "resync: "+strategy)
+			batch, err := processor.Process(ctx, service.NewMessage(withGarbage))
+			require.NoError(t, err)
+			assert.Equal(t, []int64{0x01, 0x02}, values(t, batch))
+		})
+	}
+
+	t.Run("CorruptRegionReportedOnce", func(t *testing.T) {
+		processor := newProcessor(t, "resync: byte")
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.Error(t, batch[0].GetError())
+		assert.Contains(t, batch[0].GetError().Error(), "failed to parse frames in bytes 0-5")
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		processor := newProcessor(t, "resync: abort")
+		batch, err := processor.Process(ctx, service.NewMessage(append([]byte{0x00}, frame(0x01)...)))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.Error(t, batch[0].GetError())
+		assert.Contains(t, batch[0].GetError().Error(), "failed to parse frames in bytes 0-5")
+	})
+
+	t.Run("LengthPastEnd", func(t *testing.T) {
+		unboundedFramingPath := writeTempSchema(t, `
+meta:
+  id: synced_frame
+seq:
+  - id: magic
+    contents: [0xAA, 0x55]
+  - id: len
+    type: u1
+  - id: data_payload
+    size: len
+`)
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+preserve_order: false
+is_parser: true
+framing_schema_path: %s
+framing_data_field_id: data_payload
+resync: sync_marker
+`, dataPath, unboundedFramingPath), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+
+		// Without max_buffer_size, frames after a corrupt length are still read
+		batch, err := processor.Process(ctx, service.NewMessage(append(append(frame(0x01), 0xAA, 0x55, 0x09), frame(0x02)...)))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0x01, 0x02}, values(t, batch))
+
+		// A truncated last frame is dropped
+		batch, err = processor.Process(ctx, service.NewMessage(append(frame(0x01), 0xAA, 0x55, 0x09, 0x00)))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0x01}, values(t, batch))
+	})
+
+	t.Run("SyncMarkerStraddlesMessages", func(t *testing.T) {
+		processor := newProcessor(t, "resync: sync_marker\nmax_buffer_size: 16")
+		batch, err := processor.Process(ctx, service.NewMessage(append(frame(0x01), 0x00, 0x00, 0xAA)))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0x01}, values(t, batch))
+
+		batch, err = processor.Process(ctx, service.NewMessage([]byte{0x55, 0x01, 0x02}))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{0x02}, values(t, batch))
+	})
+
+	t.Run("SyncMarker_Requires_Contents", func(t *testing.T) {
+		plainFramingPath := writeTempSchema(t, dummyFramingSchemaContent)
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+preserve_order: false
+framing_schema_path: %s
+framing_data_field_id: data_payload
+resync: sync_marker
+`, dataPath, plainFramingPath), nil)
+		require.NoError(t, err)
+		_, err = newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to start with a `contents` field")
+	})
+}
+
+// --- Test Suite for Frame Metadata ---
+
+func TestKaitaiProcessor_FramingMetadata(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, dummyDataSchemaContent)
+	framingPath := writeTempSchema(t, `
+meta:
+  id: header_frame
+  endian: be
+seq:
+  - id: seq_no
+    type: u2
+  - id: device
+    type: device_info
+  - id: kind
+    type: u1
+    enum: frame_kind
+  - id: len
+    type: u1
+  - id: data_payload
+    size: len
+  - id: crc
+    size: 2
+types:
+  device_info:
+    seq:
+      - id: id
+        type: u1
+enums:
+  frame_kind:
+    1: telemetry
+`)
+	input := []byte{0x00, 0x07, 0x05, 0x01, 0x01, 0x2A, 0xBE, 0xEF}
+
+	process := func(t *testing.T, extra string) *service.Message {
+		t.Helper()
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+preserve_order: false
+is_parser: true
+framing_schema_path: %s
+framing_data_field_id: data_payload
+%s`, dataPath, framingPath, extra), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		batch, err := processor.Process(ctx, service.NewMessage(input))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.NoError(t, batch[0].GetError())
+		return batch[0]
+	}
+
+	t.Run("SelectedFields", func(t *testing.T) {
+		msg := process(t, `
+framing_metadata_fields:
+  seq_no: frame_seq
+  device.id: device_id
+  kind: frame_kind
+  crc: frame_crc
+  missing: not_set
+`)
+		seq, found := msg.MetaGetMut("frame_seq")
+		require.True(t, found)
+		assert.Equal(t, int64(7), seq)
+		seqStr, _ := msg.MetaGet("frame_seq")
+		assert.Equal(t, "7", seqStr)
+		deviceID, _ := msg.MetaGetMut("device_id")
+		assert.Equal(t, int64(5), deviceID)
+		kind, _ := msg.MetaGet("frame_kind")
+		assert.Equal(t, "telemetry", kind)
+		crc, _ := msg.MetaGet("frame_crc")
+		assert.Equal(t, "beef", crc)
+		_, found = msg.MetaGet("not_set")
+		assert.False(t, found)
+	})
+
+	t.Run("AllScalars", func(t *testing.T) {
+		msg := process(t, "framing_metadata_fields:\n  \"*\": frame_")
+		var keys []string
+		require.NoError(t, msg.MetaWalkMut(func(key string, _ any) error {
+			if strings.HasPrefix(key, "frame_") {
+				keys = append(keys, key)
+			}
+			return nil
+		}))
+		assert.ElementsMatch(t, []string{"frame_seq_no", "frame_kind", "frame_len"}, keys)
+		seq, _ := msg.MetaGetMut("frame_seq_no")
+		assert.Equal(t, int64(7), seq)
+	})
+
+	t.Run("HeaderNestedInOutput", func(t *testing.T) {
+		msg := process(t, "framing_header_key: frame")
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		result := structured.(map[string]any)
+		assert.Equal(t, int64(0x2A), result["value"])
+		frame, ok := result["frame"].(map[string]any)
+		require.True(t, ok, "frame header missing from %v", result)
+		assert.Equal(t, int64(7), frame["seq_no"])
+		assert.Equal(t, map[string]any{"id": int64(5)}, frame["device"])
+		assert.Equal(t, []byte{0xBE, 0xEF}, frame["crc"])
+		assert.NotContains(t, frame, "data_payload")
+	})
+
+	t.Run("Disabled_By_Default", func(t *testing.T) {
+		msg := process(t, "")
+		_, found := msg.MetaGet("frame_seq_no")
+		assert.False(t, found)
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"value": int64(0x2A)}, structured)
+	})
+}
+
+// --- Test Suite for Framed Serialization ---
+
+func TestKaitaiProcessor_FramedSerialization(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, dummyDataSchemaContent)
+	framingPath := writeTempSchema(t, `
+meta:
+  id: sync_frame
+  endian: be
+seq:
+  - id: sync
+    contents: [0xA5, 0x5A]
+  - id: seq_no
+    type: u2
+  - id: kind
+    type: u1
+    enum: frame_kind
+  - id: len
+    type: u1
+  - id: data_payload
+    size: len
+enums:
+  frame_kind:
+    1: telemetry
+    2: command
+`)
+
+	newProcessor := func(t *testing.T, isParser bool, extra string) service.Processor {
+		t.Helper()
+		conf := kaitaiProcessorConfig()
+		pConf, err := conf.ParseYAML(fmt.Sprintf(`
+schema_path: %s
+is_parser: %t
+framing_schema_path: %s
+framing_data_field_id: data_payload
+%s`, dataPath, isParser, framingPath, extra), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	serialize := func(t *testing.T, processor service.Processor, msg *service.Message) *service.Message {
+		t.Helper()
+		batch, err := processor.Process(ctx, msg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		return batch[0]
+	}
+
+	t.Run("EnvelopeFromMetadata", func(t *testing.T) {
+		processor := newProcessor(t, false, `
+framing_metadata_fields:
+  seq_no: frame_seq
+  kind: frame_kind
+`)
+		msg := service.NewMessage(nil)
+		msg.SetStructured(map[string]any{"value": int64(0x2A)})
+		msg.MetaSetMut("frame_seq", "258")
+		msg.MetaSetMut("frame_kind", "command")
+		out := serialize(t, processor, msg)
+		require.NoError(t, out.GetError())
+		data, err := out.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xA5, 0x5A, 0x01, 0x02, 0x02, 0x01, 0x2A}, data)
+	})
+
+	t.Run("EnvelopeFromHeaderObject", func(t *testing.T) {
+		processor := newProcessor(t, false, "framing_header_key: frame")
+		msg := service.NewMessage(nil)
+		msg.SetStructured(map[string]any{
+			"value": int64(0x2A),
+			"frame": map[string]any{"seq_no": int64(7), "kind": int64(1)},
+		})
+		out := serialize(t, processor, msg)
+		require.NoError(t, out.GetError())
+		data, err := out.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0xA5, 0x5A, 0x00, 0x07, 0x01, 0x01, 0x2A}, data)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		parser := newProcessor(t, true, "framing_header_key: frame")
+		serializer := newProcessor(t, false, "framing_header_key: frame")
+		input := []byte{0xA5, 0x5A, 0x00, 0x09, 0x02, 0x01, 0x33}
+		parsed := serialize(t, parser, service.NewMessage(input))
+		require.NoError(t, parsed.GetError())
+		out := serialize(t, serializer, parsed)
+		require.NoError(t, out.GetError())
+		data, err := out.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, input, data)
+	})
+
+	t.Run("Missing_Envelope_Field", func(t *testing.T) {
+		processor := newProcessor(t, false, "")
+		msg := service.NewMessage(nil)
+		msg.SetStructured(map[string]any{"value": int64(0x2A)})
+		out := serialize(t, processor, msg)
+		require.Error(t, out.GetError())
+		assert.Contains(t, out.GetError().Error(), "failed to serialize frame")
+	})
+
+	t.Run("Invalid_Metadata_Value", func(t *testing.T) {
+		processor := newProcessor(t, false, "framing_metadata_fields:\n  seq_no: frame_seq")
+		msg := service.NewMessage(nil)
+		msg.SetStructured(map[string]any{"value": int64(0x2A), "frame": map[string]any{}})
+		msg.MetaSetMut("frame_seq", "not-a-number")
+		out := serialize(t, processor, msg)
+		require.Error(t, out.GetError())
+		assert.Contains(t, out.GetError().Error(), "frame_seq")
+	})
+}
+
+// --- Test Suite for Layered Decoding ---
+
+func TestKaitaiProcessor_Layers(t *testing.T) {
+	ctx := context.Background()
+	ethernetPath := writeTempSchema(t, `
+meta:
+  id: ethernet
+  endian: be
+seq:
+  - id: ether_type
+    type: u2
+  - id: body
+    size-eos: true
+`)
+	ipPath := writeTempSchema(t, `
+meta:
+  id: ip
+seq:
+  - id: protocol
+    type: u1
+    enum: ip_protocol
+  - id: len
+    type: u1
+  - id: body
+    size: len
+enums:
+  ip_protocol:
+    17: udp
+    6: tcp
+`)
+	udpPath := writeTempSchema(t, `
+meta:
+  id: udp
+  endian: be
+seq:
+  - id: port
+    type: u2
+  - id: body
+    size-eos: true
+`)
+	appPath := writeTempSchema(t, dummyDataSchemaContent)
+	layersYAML := fmt.Sprintf(`
+layers:
+  - schema_path: %s
+    payload_field: body
+    dispatch_field: ether_type
+    dispatch:
+      0x0800: %s
+  - schema_path: %s
+    name: ipv4
+    payload_field: body
+    dispatch_field: protocol
+    dispatch:
+      udp: %s
+  - schema_path: %s
+    payload_field: body
+  - schema_path: %s
+    name: app
+`, ethernetPath, ipPath, ipPath, udpPath, udpPath, appPath)
+
+	newProcessor := func(t *testing.T, yamlConf string) (*KaitaiProcessor, error) {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML("preserve_order: false\n"+yamlConf, nil)
+		require.NoError(t, err)
+		return newKaitaiProcessorFromConfig(pConf, service.MockResources())
+	}
+	process := func(t *testing.T, input []byte) *service.Message {
+		t.Helper()
+		processor, err := newProcessor(t, layersYAML)
+		require.NoError(t, err)
+		batch, err := processor.Process(ctx, service.NewMessage(input))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		return batch[0]
+	}
+
+	t.Run("FullChain", func(t *testing.T) {
+		msg := process(t, []byte{0x08, 0x00, 0x11, 0x03, 0x00, 0x35, 0x2A})
+		require.NoError(t, msg.GetError())
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"ethernet": map[string]any{"ether_type": int64(0x0800)},
+			"ipv4": map[string]any{
+				"protocol": map[string]any{"name": "udp", "valid": true, "value": int64(17)},
+				"len":      int64(3),
+			},
+			"udp": map[string]any{"port": int64(0x35)},
+			"app": map[string]any{"value": int64(0x2A)},
+		}, structured)
+		layers, _ := msg.MetaGet("kaitai_layers")
+		assert.Equal(t, "ethernet,ipv4,udp,app", layers)
+	})
+
+	t.Run("No_Dispatch_Match", func(t *testing.T) {
+		msg := process(t, []byte{0x86, 0xDD, 0x01, 0x02})
+		require.NoError(t, msg.GetError())
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"ethernet": map[string]any{"ether_type": int64(0x86DD), "body": []byte{0x01, 0x02}},
+		}, structured)
+		layers, _ := msg.MetaGet("kaitai_layers")
+		assert.Equal(t, "ethernet", layers)
+	})
+
+	t.Run("Inner_Layer_Error_Keeps_Outer_Layers", func(t *testing.T) {
+		msg := process(t, []byte{0x08, 0x00, 0x11, 0x09, 0x00})
+		require.Error(t, msg.GetError())
+		assert.Contains(t, msg.GetError().Error(), "failed to parse layer 'ipv4'")
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		ethernet := structured.(map[string]any)["ethernet"].(map[string]any)
+		assert.Equal(t, []byte{0x11, 0x09, 0x00}, ethernet["body"])
+	})
+
+	t.Run("Partial_Results", func(t *testing.T) {
+		processor, err := newProcessor(t, layersYAML+"partial_results: true\n")
+		require.NoError(t, err)
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x08, 0x00, 0x11, 0x09, 0x00}))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msg := batch[0]
+		require.Error(t, msg.GetError())
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"protocol": map[string]any{"name": "udp", "valid": true, "value": int64(17)},
+			"len":      int64(9),
+		}, structured.(map[string]any)["ipv4"])
+		errorPath, _ := msg.MetaGetMut("kaitai_error_path")
+		assert.Equal(t, "ipv4.body", errorPath)
+		errorOffset, _ := msg.MetaGetMut("kaitai_error_offset")
+		assert.EqualValues(t, 2, errorOffset)
+		layers, _ := msg.MetaGet("kaitai_layers")
+		assert.Equal(t, "ethernet,ipv4", layers)
+	})
+
+	t.Run("First_Layer_Error", func(t *testing.T) {
+		msg := process(t, []byte{0x08})
+		require.Error(t, msg.GetError())
+		assert.Contains(t, msg.GetError().Error(), "failed to parse layer 'ethernet'")
+	})
+
+	t.Run("Config_Validation", func(t *testing.T) {
+		_, err := newProcessor(t, fmt.Sprintf(`
+layers:
+  - schema_path: %s
+    payload_field: body
+    dispatch_field: ether_type
+    dispatch:
+      0x0800: missing.ksy
+`, ethernetPath))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "dispatch target 'missing.ksy'")
+
+		_, err = newProcessor(t, fmt.Sprintf("schema_path: %s\n%s", appPath, layersYAML))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be combined")
+
+		_, err = newProcessor(t, "is_parser: true")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "schema_path is required")
+	})
+}
+
+// --- Test Suite for Per-Message Schema Selection ---
+
+func TestKaitaiProcessor_InterpolatedSchema(t *testing.T) {
+	ctx := context.Background()
+	schemaDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(schemaDir, "sensor.ksy"), []byte(dummyDataSchemaContent), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(schemaDir, "meter.ksy"), []byte(`
+meta:
+  id: meter
+  endian: be
+seq:
+  - id: reading
+    type: u2
+types:
+  short_reading:
+    seq:
+      - id: reading
+        type: u1
+`), 0644))
+
+	newProcessor := func(t *testing.T, isParser bool, rootType string, extra ...string) *KaitaiProcessor {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf(`
+schema_path: '%s/${! meta("device_type") }.ksy'
+schema_base_dir: '%s'
+preserve_order: false
+root_type: '%s'
+is_parser: %t
+%s`, schemaDir, schemaDir, rootType, isParser, strings.Join(extra, "\n")), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	parse := func(t *testing.T, processor *KaitaiProcessor, deviceType string, data []byte) *service.Message {
+		t.Helper()
+		msg := service.NewMessage(data)
+		msg.MetaSetMut("device_type", deviceType)
+		batch, err := processor.Process(ctx, msg)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		return batch[0]
+	}
+
+	t.Run("SchemaPerMessage", func(t *testing.T) {
+		processor := newProcessor(t, true, "")
+		for i := 0; i < 2; i++ {
+			msg := parse(t, processor, "sensor", []byte{0x2A})
+			require.NoError(t, msg.GetError())
+			structured, err := msg.AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"value": int64(0x2A)}, structured)
+			schemaPath, _ := msg.MetaGet("kaitai_schema_path")
+			assert.Equal(t, filepath.Join(schemaDir, "sensor.ksy"), schemaPath)
+
+			msg = parse(t, processor, "meter", []byte{0x01, 0x02})
+			require.NoError(t, msg.GetError())
+			structured, err = msg.AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"reading": int64(0x0102)}, structured)
+		}
+		assert.Equal(t, 2, processor.schemaCache.Len(), "each resolved schema should be loaded once")
+	})
+
+	t.Run("RootTypeApplied", func(t *testing.T) {
+		processor := newProcessor(t, true, `${! meta("message_type") }`)
+		msg := service.NewMessage([]byte{0x07, 0xFF})
+		msg.MetaSetMut("device_type", "meter")
+		msg.MetaSetMut("message_type", "short_reading")
+		batch, err := processor.Process(ctx, msg)
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+		structured, err := batch[0].AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"reading": int64(0x07)}, structured)
+		rootType, _ := batch[0].MetaGet("kaitai_root_type")
+		assert.Equal(t, "short_reading", rootType)
+	})
+
+	t.Run("SerializerRootType", func(t *testing.T) {
+		processor := newProcessor(t, false, "short_reading")
+		msg := service.NewMessage(nil)
+		msg.SetStructured(map[string]any{"reading": int64(0x07)})
+		msg.MetaSetMut("device_type", "meter")
+		batch, err := processor.Process(ctx, msg)
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+		data, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x07}, data)
+	})
+
+	t.Run("Unknown_Schema", func(t *testing.T) {
+		processor := newProcessor(t, true, "")
+		msg := parse(t, processor, "toaster", []byte{0x2A})
+		require.Error(t, msg.GetError())
+		assert.Contains(t, msg.GetError().Error(), "toaster.ksy")
+	})
+
+	t.Run("Outside_Base_Dir", func(t *testing.T) {
+		outside := writeTempSchema(t, "meta:\n  id: outside\nseq:\n  - id: value\n    type: u1\n")
+		rel, err := filepath.Rel(schemaDir, strings.TrimSuffix(outside, ".ksy"))
+		require.NoError(t, err)
+		processor := newProcessor(t, true, "")
+		msg := parse(t, processor, rel, []byte{0x2A})
+		require.Error(t, msg.GetError())
+		assert.Contains(t, msg.GetError().Error(), "outside schema_base_dir")
+		assert.Zero(t, processor.schemaCache.Len())
+	})
+
+	t.Run("Cache_Size", func(t *testing.T) {
+		processor := newProcessor(t, true, "", "schema_cache_size: 1")
+		for i := 0; i < 2; i++ {
+			msg := parse(t, processor, "sensor", []byte{0x2A})
+			require.NoError(t, msg.GetError())
+			msg = parse(t, processor, "meter", []byte{0x01, 0x02})
+			require.NoError(t, msg.GetError())
+			structured, err := msg.AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"reading": int64(0x0102)}, structured)
+		}
+		assert.Equal(t, 1, processor.schemaCache.Len(), "the least recently used schema should be dropped")
+		_, cached := processor.schemaCache.Load(filepath.Join(schemaDir, "meter.ksy"))
+		assert.True(t, cached)
+	})
+}
+
+// --- Test Suite for Partial Results ---
+
+func TestKaitaiProcessor_PartialResults(t *testing.T) {
+	ctx := context.Background()
+	recordPath := writeTempSchema(t, `
+meta:
+  id: record
+  endian: le
+seq:
+  - id: msg_type
+    type: u1
+  - id: header
+    type: header
+  - id: body
+    size: header.body_len
+types:
+  header:
+    seq:
+      - id: device_id
+        type: u2
+      - id: body_len
+        type: u2
+`)
+	framingPath := writeTempSchema(t, dummyFramingSchemaContent)
+	newProcessor := func(t *testing.T, yamlConf string) *KaitaiProcessor {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML("preserve_order: false\n"+yamlConf, nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	// Body claims 16 bytes but only 2 follow
+	truncated := []byte{0x07, 0x34, 0x12, 0x10, 0x00, 0xAA, 0xBB}
+
+	t.Run("Disabled", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s", recordPath))
+		batch, err := processor.Process(ctx, service.NewMessage(truncated))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.Error(t, batch[0].GetError())
+		raw, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, truncated, raw)
+		_, exists := batch[0].MetaGetMut("kaitai_error_path")
+		assert.False(t, exists)
+	})
+
+	t.Run("Emits_Decoded_Fields", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\npartial_results: true", recordPath))
+		batch, err := processor.Process(ctx, service.NewMessage(truncated))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msg := batch[0]
+		require.Error(t, msg.GetError())
+		assert.Contains(t, msg.GetError().Error(), "failed to parse binary data")
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"msg_type": int64(7),
+			"header":   map[string]any{"device_id": int64(0x1234), "body_len": int64(16)},
+		}, structured)
+		errorPath, _ := msg.MetaGetMut("kaitai_error_path")
+		assert.Equal(t, "body", errorPath)
+		errorOffset, _ := msg.MetaGetMut("kaitai_error_offset")
+		assert.EqualValues(t, 5, errorOffset)
+		rootType, _ := msg.MetaGet("kaitai_root_type")
+		assert.Equal(t, "record", rootType)
+	})
+
+	t.Run("Nested_Failure", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\npartial_results: true", recordPath))
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x07, 0x34, 0x12, 0x10}))
+		require.NoError(t, err)
+		msg := batch[0]
+		require.Error(t, msg.GetError())
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"msg_type": int64(7),
+			"header":   map[string]any{"device_id": int64(0x1234)},
+		}, structured)
+		errorPath, _ := msg.MetaGetMut("kaitai_error_path")
+		assert.Equal(t, "header.body_len", errorPath)
+		errorOffset, _ := msg.MetaGetMut("kaitai_error_offset")
+		assert.EqualValues(t, 3, errorOffset)
+	})
+
+	t.Run("Framed_Payload", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\nframing_schema_path: %s\nframing_data_field_id: data_payload\nframing_header_key: frame\npartial_results: true", recordPath, framingPath))
+		frame := append([]byte{byte(len(truncated))}, truncated...)
+		batch, err := processor.Process(ctx, service.NewMessage(frame))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		msg := batch[0]
+		require.Error(t, msg.GetError())
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		result := structured.(map[string]any)
+		assert.Equal(t, int64(7), result["msg_type"])
+		assert.Equal(t, int64(len(truncated)), result["frame"].(map[string]any)["len"])
+		errorPath, _ := msg.MetaGetMut("kaitai_error_path")
+		assert.Equal(t, "body", errorPath)
+	})
+}
+
+// --- Test Suite for Batch Processing ---
+
+func TestKaitaiProcessor_PreserveOrder(t *testing.T) {
+	ctx := context.Background()
+	readingPath := writeTempSchema(t, `
+meta:
+  id: reading
+  endian: le
+seq:
+  - id: zeta
+    type: u1
+  - id: alpha
+    type: u1
+  - id: mid
+    type: u2
+instances:
+  total:
+    value: zeta + alpha
+  is_set:
+    value: alpha != 0
+`)
+	outerPath := writeTempSchema(t, `
+meta:
+  id: outer
+seq:
+  - id: version
+    type: u1
+  - id: body
+    size-eos: true
+`)
+	framingPath := writeTempSchema(t, dummyFramingSchemaContent)
+	newProcessor := func(t *testing.T, yamlConf string) *KaitaiProcessor {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML(yamlConf, nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	process := func(t *testing.T, processor *KaitaiProcessor, input []byte) string {
+		t.Helper()
+		batch, err := processor.Process(ctx, service.NewMessage(input))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.NoError(t, batch[0].GetError())
+		raw, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		return string(raw)
+	}
+	reading := []byte{0x01, 0x02, 0x04, 0x03}
+
+	t.Run("Declaration_Order_By_Default", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s", readingPath))
+		assert.Equal(t, `{"zeta":1,"alpha":2,"mid":772,"total":3,"is_set":true}`, process(t, processor, reading))
+	})
+
+	t.Run("Frame_Header_Last", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\nframing_schema_path: %s\nframing_data_field_id: data_payload\nframing_header_key: frame", readingPath, framingPath))
+		frame := append([]byte{byte(len(reading))}, reading...)
+		assert.Equal(t, `{"zeta":1,"alpha":2,"mid":772,"total":3,"is_set":true,"frame":{"len":4}}`, process(t, processor, frame))
+	})
+
+	t.Run("Layers_In_Decoding_Order", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("layers:\n  - schema_path: %s\n    payload_field: body\n  - schema_path: %s", outerPath, readingPath))
+		assert.Equal(t, `{"outer":{"version":9},"reading":{"zeta":1,"alpha":2,"mid":772,"total":3,"is_set":true}}`, process(t, processor, append([]byte{0x09}, reading...)))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\npreserve_order: false", readingPath))
+		batch, err := processor.Process(ctx, service.NewMessage(reading))
+		require.NoError(t, err)
+		structured, err := batch[0].AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"zeta": int64(1), "alpha": int64(2), "mid": int64(772), "total": int64(3), "is_set": true}, structured)
+	})
+}
+
+func TestKaitaiProcessor_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	schemaPath := writeTempSchema(t, `
+meta:
+  id: packet
+  endian: le
+seq:
+  - id: len
+    type: u1
+  - id: body
+    size: len
+  - id: crc
+    type: u2
+`)
+	newProcessor := func(t *testing.T, yamlConf string) *KaitaiProcessor {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML(yamlConf, nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	data := []byte{0x03, 'a', 'b', 'c', 0x34, 0x12}
+
+	for name, extra := range map[string]string{
+		"Defaults":       "",
+		"Preserve_Order": "\npreserve_order: true",
+	} {
+		t.Run(name, func(t *testing.T) {
+			parser := newProcessor(t, fmt.Sprintf("schema_path: %s%s", schemaPath, extra))
+			serializer := newProcessor(t, fmt.Sprintf("schema_path: %s\nis_parser: false", schemaPath))
+
+			parsed, err := parser.Process(ctx, service.NewMessage(data))
+			require.NoError(t, err)
+			require.Len(t, parsed, 1)
+			require.NoError(t, parsed[0].GetError())
+
+			serialized, err := serializer.Process(ctx, parsed[0])
+			require.NoError(t, err)
+			require.Len(t, serialized, 1)
+			require.NoError(t, serialized[0].GetError())
+			raw, err := serialized[0].AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, data, raw)
+		})
+	}
+}
+
+func TestKaitaiProcessor_OutputShapes(t *testing.T) {
+	ctx := context.Background()
+	schemaPath := writeTempSchema(t, `
+meta:
+  id: reading
+  endian: be
+seq:
+  - id: kind
+    type: u1
+    enum: kind
+  - id: online
+    type: b1
+  - id: channel
+    type: b7
+  - id: serial
+    type: u8
+  - id: token
+    size: 2
+enums:
+  kind:
+    1: temperature
+    2: humidity
+`)
+	data := []byte{0x02, 0x83, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE, 0xBE, 0xEF}
+	newProcessor := func(t *testing.T, yamlConf string) *KaitaiProcessor {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML("preserve_order: false\n"+yamlConf, nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	shapes := "output_enums: name\noutput_bytes: hex\noutput_big_ints: string\noutput_bit_flags: int"
+
+	t.Run("Defaults", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s", schemaPath))
+		batch, err := processor.Process(ctx, service.NewMessage(data))
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+		structured, err := batch[0].AsStructured()
+		require.NoError(t, err)
+		result := structured.(map[string]any)
+		assert.Equal(t, map[string]any{"value": int64(2), "name": "humidity", "valid": true}, result["kind"])
+		assert.Equal(t, true, result["online"])
+		assert.Equal(t, int64(-2), result["serial"])
+		assert.Equal(t, []byte{0xBE, 0xEF}, result["token"])
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\n%s", schemaPath, shapes))
+		batch, err := processor.Process(ctx, service.NewMessage(data))
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+		raw, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"kind": "humidity", "online": 1, "channel": 3, "serial": "18446744073709551614", "token": "beef"}`, string(raw))
+	})
+
+	t.Run("Serializer_Reads_Shapes", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("schema_path: %s\nis_parser: false\n%s", schemaPath, shapes))
+		input := `{"kind": "humidity", "online": 1, "channel": 3, "serial": "18446744073709551614", "token": "beef"}`
+		batch, err := processor.Process(ctx, service.NewMessage([]byte(input)))
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+		raw, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, data, raw)
+	})
+
+	t.Run("Invalid_Shape", func(t *testing.T) {
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf("schema_path: %s\noutput_bytes: octal", schemaPath), nil)
+		require.NoError(t, err)
+		_, err = newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		assert.ErrorContains(t, err, "output_bytes must be one of raw, hex, base64, array")
+	})
+}
+
+func TestKaitaiProcessor_ProcessBatch(t *testing.T) {
+	ctx := context.Background()
+	dataPath := writeTempSchema(t, dummyDataSchemaContent)
+	framingPath := writeTempSchema(t, dummyFramingSchemaContent)
+	newProcessor := func(t *testing.T, extra string) *KaitaiProcessor {
+		t.Helper()
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf("schema_path: %s\npreserve_order: false\n%s", dataPath, extra), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		return processor
+	}
+	values := func(t *testing.T, batch service.MessageBatch) []any {
+		t.Helper()
+		var result []any
+		for _, msg := range batch {
+			if msg.GetError() != nil {
+				result = append(result, "error")
+				continue
+			}
+			structured, err := msg.AsStructured()
+			require.NoError(t, err)
+			result = append(result, structured.(map[string]any)["value"])
+		}
+		return result
+	}
+
+	t.Run("Keeps_Order_And_Errors", func(t *testing.T) {
+		processor := newProcessor(t, "max_in_flight: 4")
+		var batch service.MessageBatch
+		var want []any
+		for i := range 50 {
+			if i%7 == 3 {
+				batch = append(batch, service.NewMessage(nil)) // Empty data fails to parse
+				want = append(want, "error")
+				continue
+			}
+			batch = append(batch, service.NewMessage([]byte{byte(i)}))
+			want = append(want, int64(i))
+		}
+		batches, err := processor.ProcessBatch(ctx, batch)
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		assert.Equal(t, want, values(t, batches[0]))
+	})
+
+	t.Run("Framed_Messages_Keep_Frame_Order", func(t *testing.T) {
+		processor := newProcessor(t, fmt.Sprintf("framing_schema_path: %s\nframing_data_field_id: data_payload\nmax_in_flight: 2", framingPath))
+		batches, err := processor.ProcessBatch(ctx, service.MessageBatch{
+			service.NewMessage([]byte{0x01, 0x0A, 0x01, 0x0B}),
+			service.NewMessage([]byte{0x01, 0x0C}),
+			service.NewMessage([]byte{0x01, 0x0D, 0x01, 0x0E, 0x01, 0x0F}),
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		assert.Equal(t, []any{int64(0x0A), int64(0x0B), int64(0x0C), int64(0x0D), int64(0x0E), int64(0x0F)}, values(t, batches[0]))
+	})
+
+	t.Run("Flags_Unprocessed_When_Cancelled", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		for _, maxInFlight := range []int{1, 4} {
+			processor := newProcessor(t, fmt.Sprintf("max_in_flight: %d", maxInFlight))
+			batches, err := processor.ProcessBatch(cancelled, service.MessageBatch{
+				service.NewMessage([]byte{0x01}),
+				service.NewMessage([]byte{0x02}),
+			})
+			require.NoError(t, err)
+			require.Len(t, batches, 1)
+			require.Len(t, batches[0], 2)
+			for _, msg := range batches[0] {
+				assert.ErrorIs(t, msg.GetError(), context.Canceled)
+			}
+		}
+	})
+
+	t.Run("Compiles_Schema_Once", func(t *testing.T) {
+		processor := newProcessor(t, "max_in_flight: 4")
+		var batch service.MessageBatch
+		for i := range 20 {
+			batch = append(batch, service.NewMessage([]byte{byte(i)}))
+		}
+		_, err := processor.ProcessBatch(ctx, batch)
+		require.NoError(t, err)
+		assert.Equal(t, 1, processor.interpreters.Len())
+	})
+
+	t.Run("Invalid_Max_In_Flight", func(t *testing.T) {
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf("schema_path: %s\nmax_in_flight: -1", dataPath), nil)
+		require.NoError(t, err)
+		_, err = newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.Error(t, err)
+	})
+}
+
+// --- Test Suite for Schema Sources ---
+
+func TestKaitaiProcessor_SchemaSources(t *testing.T) {
+	ctx := context.Background()
+	const (
+		mainSchema   = "meta:\n  id: reading\n  imports:\n    - common/unit\nseq:\n  - id: unit\n    type: unit\n  - id: value\n    type: u1\n"
+		importSchema = "meta:\n  id: unit\nseq:\n  - id: code\n    type: u1\n"
+	)
+	parse := func(t *testing.T, processor *KaitaiProcessor) map[string]any {
+		t.Helper()
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x03, 0x2A}))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		require.NoError(t, batch[0].GetError())
+		structured, err := batch[0].AsStructured()
+		require.NoError(t, err)
+		return structured.(map[string]any)
+	}
+	want := map[string]any{"unit": map[string]any{"code": int64(3)}, "value": int64(42)}
+
+	t.Run("Inline_Schema", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "common"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "common", "unit.ksy"), []byte(importSchema), 0644))
+		t.Chdir(dir)
+
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf("schema: %q\npreserve_order: false", mainSchema), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		assert.Equal(t, want, parse(t, processor))
+	})
+
+	t.Run("Schema_Resource", func(t *testing.T) {
+		resources := service.MockResources(service.MockResourcesOptAddCache("schemas"))
+		require.NoError(t, resources.AccessCache(ctx, "schemas", func(c service.Cache) {
+			require.NoError(t, c.Set(ctx, "specs/reading.ksy", []byte(mainSchema), nil))
+			require.NoError(t, c.Set(ctx, "specs/common/unit.ksy", []byte(importSchema), nil))
+		}))
+		pConf, err := kaitaiProcessorConfig().ParseYAML("schema_path: specs/reading.ksy\nschema_resource: schemas\npreserve_order: false", nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, resources)
+		require.NoError(t, err)
+		assert.Equal(t, want, parse(t, processor))
+	})
+
+	t.Run("Missing_Resource_Key", func(t *testing.T) {
+		resources := service.MockResources(service.MockResourcesOptAddCache("schemas"))
+		pConf, err := kaitaiProcessorConfig().ParseYAML("schema_path: nowhere.ksy\nschema_resource: schemas", nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, resources)
+		require.NoError(t, err)
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x2A}))
+		require.NoError(t, err)
+		require.Error(t, batch[0].GetError())
+		assert.Contains(t, batch[0].GetError().Error(), "nowhere.ksy")
+	})
+
+	t.Run("Invalid_Config", func(t *testing.T) {
+		dataPath := writeTempSchema(t, dummyDataSchemaContent)
+		for name, tc := range map[string]struct {
+			yaml string
+			err  string
+		}{
+			"Schema_And_Path":       {fmt.Sprintf("schema_path: %s\nschema: %q", dataPath, dummyDataSchemaContent), "schema cannot be combined"},
+			"Unknown_Resource":      {"schema_path: x.ksy\nschema_resource: nope", "schema_resource cache 'nope' not found"},
+			"Resource_Hot_Reload":   {"schema_path: x.ksy\nschema_resource: schemas\nhot_reload: true", "cannot be combined with schema_resource"},
+			"Invalid_Inline_Schema": {"schema: \"meta: [oops\"", "failed to parse schema YAML"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				pConf, err := kaitaiProcessorConfig().ParseYAML(tc.yaml, nil)
+				require.NoError(t, err)
+				_, err = newKaitaiProcessorFromConfig(pConf, service.MockResources(service.MockResourcesOptAddCache("schemas")))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+// --- Test Suite for Bloblang Methods ---
+
+func TestBloblangMethods(t *testing.T) {
+	schemaPath := writeTempSchema(t, `
+meta:
+  id: reading
+  endian: be
+seq:
+  - id: sensor
+    type: u1
+  - id: value
+    type: u2
+    valid:
+      max: 1000
+types:
+  short_reading:
+    seq:
+      - id: value
+        type: u1
+`)
+	query := func(t *testing.T, mapping string, input any) (any, error) {
+		t.Helper()
+		exec, err := bloblang.Parse(fmt.Sprintf(mapping, schemaPath))
+		require.NoError(t, err)
+		return exec.Query(input)
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		result, err := query(t, `root = this.payload.decode("base64").kaitai_parse(schema: %q)`, map[string]any{"payload": "BwEC"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"sensor": int64(7), "value": int64(0x0102)}, result)
+	})
+
+	t.Run("Parse_Root_Type", func(t *testing.T) {
+		result, err := query(t, `root = this.payload.decode("hex").kaitai_parse(schema: %q, root: "short_reading")`, map[string]any{"payload": "2a"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"value": int64(42)}, result)
+	})
+
+	t.Run("Serialize", func(t *testing.T) {
+		result, err := query(t, `root = this.reading.kaitai_serialize(schema: %q).encode("hex")`, map[string]any{
+			"reading": map[string]any{"sensor": 7, "value": 258},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "070102", result)
+	})
+
+	t.Run("Parse_Error", func(t *testing.T) {
+		_, err := query(t, `root.reading = this.payload.decode("hex").kaitai_parse(schema: %q)`, map[string]any{"payload": "07"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "field 'value'")
+	})
+
+	t.Run("Serialize_Error", func(t *testing.T) {
+		_, err := query(t, `root = this.reading.kaitai_serialize(schema: %q)`, map[string]any{
+			"reading": map[string]any{"sensor": 7, "value": 2000},
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, kst.ErrValidationFailed)
+		assert.Contains(t, err.Error(), "'value'")
+	})
+
+	t.Run("Missing_Schema", func(t *testing.T) {
+		_, err := bloblang.Parse(`root = this.payload.kaitai_parse(schema: "nowhere.ksy")`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nowhere.ksy")
+	})
+
+	t.Run("Default_Limits", func(t *testing.T) {
+		sizedPath := writeTempSchema(t, "meta:\n  id: sized\n  endian: le\nseq:\n  - id: len\n    type: u4\n  - id: body\n    size: len\n")
+		exec, err := bloblang.Parse(fmt.Sprintf(`root = this.payload.decode("hex").kaitai_parse(schema: %q)`, sizedPath))
+		require.NoError(t, err)
+		_, err = exec.Query(map[string]any{"payload": "ffffffff0102"})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, kst.ErrLimitExceeded)
+		assert.Contains(t, err.Error(), "max_allocation_size")
+	})
+
+	t.Run("Named_Processor", func(t *testing.T) {
+		ctx := context.Background()
+		resources := service.MockResources(service.MockResourcesOptAddCache("schemas"))
+		require.NoError(t, resources.AccessCache(ctx, "schemas", func(c service.Cache) {
+			require.NoError(t, c.Set(ctx, "specs/reading.ksy", []byte("meta:\n  id: reading\nseq:\n  - id: level\n    type: u1\n"), nil))
+		}))
+		pConf, err := kaitaiProcessorConfig().ParseYAML("schema_path: specs/reading.ksy\nschema_resource: schemas", nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, resources)
+		require.NoError(t, err)
+		// As a processor labelled readings registers itself
+		labelledProcessors.Store("readings", processor)
+		t.Cleanup(func() { labelledProcessors.Delete("readings") })
+
+		exec, err := bloblang.Parse(`root = this.payload.decode("hex").kaitai_parse(schema: "specs/reading.ksy", processor: "readings")`)
+		require.NoError(t, err)
+		result, err := exec.Query(map[string]any{"payload": "09"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"level": int64(9)}, result)
+		_, cached := processor.schemaCache.Load("specs/reading.ksy")
+		assert.True(t, cached, "the processor's cache holds the schema")
+		_, err = exec.Query(map[string]any{"payload": "0a"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, processor.interpreters.Len(), "the schema is compiled once")
+
+		exec, err = bloblang.Parse(`root = this.payload.decode("hex").kaitai_parse(schema: "specs/reading.ksy", processor: "missing")`)
+		require.NoError(t, err)
+		_, err = exec.Query(map[string]any{"payload": "09"})
+		assert.ErrorContains(t, err, "no kaitai processor labelled 'missing'")
+	})
+
+	t.Run("Named_Processor_Serializer_Settings", func(t *testing.T) {
+		blobPath := writeTempSchema(t, "meta:\n  id: blob\nseq:\n  - id: kind\n    type: u1\n    valid:\n      max: 9\n  - id: body\n    size: 2\n")
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf("schema_path: %s\nis_parser: false\nskip_validation: true\noutput_bytes: hex", blobPath), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		labelledProcessors.Store("writers", processor)
+		t.Cleanup(func() { labelledProcessors.Delete("writers") })
+
+		// Out of range and with hex bytes, as the processor accepts
+		exec, err := bloblang.Parse(fmt.Sprintf(`root = this.kaitai_serialize(schema: %q, processor: "writers").encode("hex")`, blobPath))
+		require.NoError(t, err)
+		result, err := exec.Query(map[string]any{"kind": 42, "body": "beef"})
+		require.NoError(t, err)
+		assert.Equal(t, "2abeef", result)
+	})
+}
+
+// --- Test Suite for the kaitai_file Input ---
+
+func TestKaitaiFileInput(t *testing.T) {
+	ctx := context.Background()
+	schemaPath := writeTempSchema(t, `
+meta:
+  id: recording
+types:
+  file_header:
+    seq:
+      - id: magic
+        contents: "RC"
+      - id: version
+        type: u1
+  record:
+    seq:
+      - id: len
+        type: u1
+      - id: body
+        size: len
+`)
+	// Header, then records of 2, 0 and 1 body bytes at offsets 3, 6 and 7
+	filePath := filepath.Join(t.TempDir(), "recording.bin")
+	require.NoError(t, os.WriteFile(filePath, []byte{'R', 'C', 0x01, 0x02, 0xAA, 0xBB, 0x00, 0x01, 0xCC}, 0644))
+
+	newInput := func(t *testing.T, extra string, resources *service.Resources) *kaitaiFileInput {
+		t.Helper()
+		pConf, err := kaitaiFileInputConfig().ParseYAML(fmt.Sprintf(`
+path: %s
+schema_path: %s
+record_type: record
+header_type: file_header
+%s`, filePath, schemaPath, extra), nil)
+		require.NoError(t, err)
+		input, err := newKaitaiFileInputFromConfig(pConf, resources)
+		require.NoError(t, err)
+		require.NoError(t, input.Connect(ctx))
+		t.Cleanup(func() { input.Close(ctx) })
+		return input
+	}
+	type record struct {
+		offset any
+		body   any
+		ack    service.AckFunc
+	}
+	readAll := func(t *testing.T, input *kaitaiFileInput) []record {
+		t.Helper()
+		var records []record
+		for {
+			msg, ack, err := input.Read(ctx)
+			if errors.Is(err, service.ErrEndOfInput) {
+				return records
+			}
+			require.NoError(t, err)
+			structured, err := msg.AsStructured()
+			require.NoError(t, err)
+			offset, _ := msg.MetaGetMut("kaitai_file_offset")
+			records = append(records, record{offset: offset, body: structured.(map[string]any)["body"], ack: ack})
+		}
+	}
+
+	t.Run("Reads_Records_After_Header", func(t *testing.T) {
+		records := readAll(t, newInput(t, "", service.MockResources()))
+		require.Len(t, records, 3)
+		assert.Equal(t, []any{int64(3), int64(6), int64(7)}, []any{records[0].offset, records[1].offset, records[2].offset})
+		assert.Equal(t, []byte{0xAA, 0xBB}, records[0].body)
+		assert.Equal(t, []byte{0xCC}, records[2].body)
+	})
+
+	t.Run("Header_Key", func(t *testing.T) {
+		msg, _, err := newInput(t, "header_key: header", service.MockResources()).Read(ctx)
+		require.NoError(t, err)
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), structured.(map[string]any)["header"].(map[string]any)["version"])
+	})
+
+	t.Run("Header_Key_Copied_Per_Message", func(t *testing.T) {
+		input := newInput(t, "header_key: header", service.MockResources())
+		first, _, err := input.Read(ctx)
+		require.NoError(t, err)
+		structured, err := first.AsStructured()
+		require.NoError(t, err)
+		structured.(map[string]any)["header"].(map[string]any)["version"] = int64(9)
+
+		second, _, err := input.Read(ctx)
+		require.NoError(t, err)
+		structured, err = second.AsStructured()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), structured.(map[string]any)["header"].(map[string]any)["version"])
+	})
+
+	t.Run("Start_Offset", func(t *testing.T) {
+		records := readAll(t, newInput(t, "start_offset: 6", service.MockResources()))
+		require.Len(t, records, 2)
+		assert.Equal(t, int64(6), records[0].offset)
+	})
+
+	t.Run("Resumes_From_Checkpoint", func(t *testing.T) {
+		resources := service.MockResources(service.MockResourcesOptAddCache("checkpoints"))
+		records := readAll(t, newInput(t, "checkpoint_cache: checkpoints", resources))
+		require.Len(t, records, 3)
+
+		// The checkpoint only moves past records acknowledged along with every record before them
+		require.NoError(t, records[1].ack(ctx, nil))
+		require.NoError(t, records[0].ack(ctx, nil))
+		require.NoError(t, records[2].ack(ctx, errors.New("nacked")))
+
+		resumed := readAll(t, newInput(t, "checkpoint_cache: checkpoints", resources))
+		require.Len(t, resumed, 1)
+		assert.Equal(t, int64(7), resumed[0].offset)
+	})
+
+	t.Run("Truncated_Record_Fails", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filePath, []byte{'R', 'C', 0x01, 0x01, 0xAA, 0x02, 0x01}, 0644))
+		input := newInput(t, "", service.MockResources())
+		_, _, err := input.Read(ctx)
+		require.NoError(t, err)
+
+		for range 2 {
+			_, _, err = input.Read(ctx)
+			require.Error(t, err)
+			assert.NotErrorIs(t, err, service.ErrEndOfInput)
+			assert.Contains(t, err.Error(), "truncated")
+			assert.Contains(t, err.Error(), "offset 5")
+		}
+
+		// The record is read again once the rest of it is written
+		file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = file.Write([]byte{0x02})
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		msg, _, err := input.Read(ctx)
+		require.NoError(t, err)
+		offset, _ := msg.MetaGetMut("kaitai_file_offset")
+		assert.Equal(t, int64(5), offset)
+		_, _, err = input.Read(ctx)
+		assert.ErrorIs(t, err, service.ErrEndOfInput)
+	})
+
+	t.Run("Skips_Corrupt_Records", func(t *testing.T) {
+		// The record at offset 5 runs past the end of the file; skipping a
+		// byte finds the record at offset 6, and the truncated one at 8 ends the input
+		require.NoError(t, os.WriteFile(filePath, []byte{'R', 'C', 0x01, 0x01, 0xAA, 0x09, 0x01, 0xCC, 0x03}, 0644))
+		records := readAll(t, newInput(t, "on_error: skip", service.MockResources()))
+		require.Len(t, records, 2)
+		assert.Equal(t, int64(6), records[1].offset)
+		assert.Equal(t, []byte{0xCC}, records[1].body)
+	})
+
+	t.Run("Reads_Past_Read_Buffer", func(t *testing.T) {
+		data := []byte{'R', 'C', 0x01}
+		for i := range 2 * fileReadBufferSize / 100 {
+			data = append(data, 99)
+			data = append(data, bytes.Repeat([]byte{byte(i)}, 99)...)
+		}
+		require.NoError(t, os.WriteFile(filePath, data, 0644))
+		records := readAll(t, newInput(t, "", service.MockResources()))
+		require.Len(t, records, 2*fileReadBufferSize/100)
+		for i, record := range records {
+			assert.Equal(t, int64(3+100*i), record.offset)
+			assert.Equal(t, bytes.Repeat([]byte{byte(i)}, 99), record.body)
+		}
+	})
+
+	t.Run("Gives_Up_After_Max_Retries", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filePath, []byte{'R', 'C', 0x01, 0x01, 0xAA, 0x09, 0x01}, 0644))
+		input := newInput(t, "max_retries: 1", service.MockResources())
+		_, _, err := input.Read(ctx)
+		require.NoError(t, err)
+
+		// The failed read is retried once before reading ends
+		_, _, err = input.Read(ctx)
+		assert.ErrorContains(t, err, "truncated")
+		_, _, err = input.Read(ctx)
+		assert.ErrorIs(t, err, service.ErrEndOfInput)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filePath, []byte{'R', 'C', 0x01, 0x09, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}, 0644))
+		input := newInput(t, "max_allocation_size: 4\nmax_retries: 0", service.MockResources())
+		_, _, err := input.Read(ctx)
+		assert.ErrorIs(t, err, service.ErrEndOfInput)
+
+		input = newInput(t, "max_allocation_size: 4\non_error: fail\nmax_retries: 1", service.MockResources())
+		_, _, err = input.Read(ctx)
+		assert.ErrorIs(t, err, kst.ErrLimitExceeded)
+	})
+
+	t.Run("Buffers_Sized_Reads", func(t *testing.T) {
+		schema, err := schemaSource{}.read(schemaPath)
+		require.NoError(t, err)
+		var data []byte
+		for i := range 4 * fileReadBufferSize / 100 {
+			data = append(data, 99)
+			data = append(data, bytes.Repeat([]byte{byte(i)}, 99)...)
+		}
+		file := &countingReadSeeker{ReadSeeker: bytes.NewReader(data)}
+		records := newRecordReader(newBufferedFile(file), schema, kst.Limits{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		for i := range 4 * fileReadBufferSize / 100 {
+			record, _, err := records.read(ctx, "record")
+			require.NoError(t, err)
+			require.Equal(t, bytes.Repeat([]byte{byte(i)}, 99), record["body"])
+		}
+		assert.True(t, records.eof())
+		// Finding the stream size for every sized field must not drop the buffer
+		assert.LessOrEqual(t, file.reads, len(data)/fileReadBufferSize+2)
+	})
+
+	t.Run("Bad_Header", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filePath, []byte{'X', 'X', 0x01}, 0644))
+		pConf, err := kaitaiFileInputConfig().ParseYAML(fmt.Sprintf("path: %s\nschema_path: %s\nrecord_type: record\nheader_type: file_header", filePath, schemaPath), nil)
+		require.NoError(t, err)
+		input, err := newKaitaiFileInputFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		require.Error(t, input.Connect(ctx))
+	})
+}
+
+// countingReadSeeker counts the reads made of a ReadSeeker
+type countingReadSeeker struct {
+	io.ReadSeeker
+	reads int
+}
+
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+	c.reads++
+	return c.ReadSeeker.Read(p)
+}
+
+// --- Test Suite for the kaitai_frames Scanner ---
+
+func TestKaitaiFramesScanner(t *testing.T) {
+	ctx := context.Background()
+	schemaPath := writeTempSchema(t, `
+meta:
+  id: serial_frame
+seq:
+  - id: sync
+    contents: [0xAA]
+  - id: seq_no
+    type: u1
+  - id: len
+    type: u1
+  - id: payload
+    size: len
+`)
+	scan := func(t *testing.T, extra string, stream []byte) ([]*service.Message, error) {
+		t.Helper()
+		pConf, err := kaitaiFramesScannerConfig().ParseYAML(fmt.Sprintf("schema_path: %s\n%s", schemaPath, extra), nil)
+		require.NoError(t, err)
+		creator, err := newKaitaiFramesScannerCreatorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		// One byte per read, so every frame straddles reads
+		scanner, err := creator.Create(io.NopCloser(iotest.OneByteReader(bytes.NewReader(stream))), func(context.Context, error) error { return nil }, service.NewScannerSourceDetails())
+		require.NoError(t, err)
+		defer scanner.Close(ctx)
+
+		var msgs []*service.Message
+		for {
+			batch, ack, err := scanner.NextBatch(ctx)
+			if errors.Is(err, io.EOF) {
+				return msgs, nil
+			}
+			if err != nil {
+				return msgs, err
+			}
+			require.NoError(t, ack(ctx, nil))
+			msgs = append(msgs, batch...)
+		}
+	}
+	contents := func(t *testing.T, msgs []*service.Message) [][]byte {
+		t.Helper()
+		var result [][]byte
+		for _, msg := range msgs {
+			data, err := msg.AsBytes()
+			require.NoError(t, err)
+			result = append(result, data)
+		}
+		return result
+	}
+	frames := []byte{0xAA, 0x01, 0x02, 0x10, 0x11, 0xAA, 0x02, 0x00}
+
+	t.Run("Cuts_Raw_Frames", func(t *testing.T) {
+		msgs, err := scan(t, "", frames)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{{0xAA, 0x01, 0x02, 0x10, 0x11}, {0xAA, 0x02, 0x00}}, contents(t, msgs))
+		offset, _ := msgs[1].MetaGetMut("kaitai_frame_offset")
+		assert.Equal(t, int64(5), offset)
+	})
+
+	t.Run("Extracts_Payload_And_Metadata", func(t *testing.T) {
+		msgs, err := scan(t, "data_field_id: payload\nmetadata_fields:\n  seq_no: frame_seq", frames)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{{0x10, 0x11}, {}}, contents(t, msgs))
+		seq, _ := msgs[1].MetaGetMut("frame_seq")
+		assert.Equal(t, int64(2), seq)
+	})
+
+	t.Run("Resyncs_After_Garbage", func(t *testing.T) {
+		stream := append([]byte{0x00, 0x01, 0x02}, frames...)
+		msgs, err := scan(t, "resync: sync_marker", stream)
+		require.NoError(t, err)
+		require.Len(t, msgs, 2)
+		offset, _ := msgs[0].MetaGetMut("kaitai_frame_offset")
+		assert.Equal(t, int64(3), offset)
+	})
+
+	t.Run("Drops_Partial_Trailing_Frame", func(t *testing.T) {
+		msgs, err := scan(t, "", append(frames, 0xAA, 0x03, 0x05, 0x01))
+		require.NoError(t, err)
+		assert.Len(t, msgs, 2)
+	})
+
+	t.Run("Frame_Larger_Than_Max_Is_Corrupt", func(t *testing.T) {
+		msgs, err := scan(t, "max_frame_size: 4", frames)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{{0xAA, 0x02, 0x00}}, contents(t, msgs))
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		msgs, err := scan(t, "resync: abort", append([]byte{0x00}, frames...))
+		require.Error(t, err)
+		assert.Empty(t, msgs)
+	})
+
+	t.Run("Large_Frame_Read_In_Growing_Chunks", func(t *testing.T) {
+		largePath := writeTempSchema(t, "meta:\n  id: large_frame\n  endian: be\nseq:\n  - id: len\n    type: u4\n  - id: payload\n    size: len\n")
+		pConf, err := kaitaiFramesScannerConfig().ParseYAML(fmt.Sprintf("schema_path: %s\ndata_field_id: payload", largePath), nil)
+		require.NoError(t, err)
+		creator, err := newKaitaiFramesScannerCreatorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+
+		payload := bytes.Repeat([]byte{0x5A}, 512*1024)
+		stream := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		reads := &countingReader{r: bytes.NewReader(append(stream, payload...))}
+		scanner, err := creator.Create(io.NopCloser(reads), func(context.Context, error) error { return nil }, service.NewScannerSourceDetails())
+		require.NoError(t, err)
+		defer scanner.Close(ctx)
+
+		batch, _, err := scanner.NextBatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{payload}, contents(t, batch))
+		// The partial frame is parsed again each time the buffer doubles,
+		// not after every 4 KB read
+		assert.Less(t, reads.count, 20)
+	})
+
+	t.Run("Frame_Split_Across_Reads_Then_Idle", func(t *testing.T) {
+		// The sender waits for a reply after its frame, so the stream blocks
+		reader := &idleReader{chunks: [][]byte{frames[:4], frames[4:5]}, closed: make(chan struct{})}
+		pConf, err := kaitaiFramesScannerConfig().ParseYAML("schema_path: "+schemaPath, nil)
+		require.NoError(t, err)
+		creator, err := newKaitaiFramesScannerCreatorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		scanner, err := creator.Create(reader, func(context.Context, error) error { return nil }, service.NewScannerSourceDetails())
+		require.NoError(t, err)
+		defer scanner.Close(ctx)
+
+		done := make(chan service.MessageBatch, 1)
+		go func() {
+			batch, _, err := scanner.NextBatch(ctx)
+			assert.NoError(t, err)
+			done <- batch
+		}()
+		select {
+		case batch := <-done:
+			assert.Equal(t, [][]byte{frames[:5]}, contents(t, batch))
+		case <-time.After(5 * time.Second):
+			t.Fatal("complete frame not emitted while the stream is idle")
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		msgs, err := scan(t, "max_allocation_size: 1", frames)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{{0xAA, 0x02, 0x00}}, contents(t, msgs))
+
+		pConf, err := kaitaiFramesScannerConfig().ParseYAML(fmt.Sprintf("schema_path: %s\nmax_depth: -1", schemaPath), nil)
+		require.NoError(t, err)
+		_, err = newKaitaiFramesScannerCreatorFromConfig(pConf, service.MockResources())
+		require.Error(t, err)
+
+		pConf, err = kaitaiFramesScannerConfig().ParseYAML(fmt.Sprintf("schema_path: %s\nmax_frame_size: 64", schemaPath), nil)
+		require.NoError(t, err)
+		creator, err := newKaitaiFramesScannerCreatorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		assert.Equal(t, 64, creator.limits.MaxAllocation, "max_allocation_size defaults to max_frame_size")
+	})
+}
+
+// idleReader returns its chunks one read at a time, then blocks until closed
+type idleReader struct {
+	chunks [][]byte
+	closed chan struct{}
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		<-r.closed
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func (r *idleReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+// countingReader counts the reads of a reader
+type countingReader struct {
+	r     io.Reader
+	count int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.count++
+	return c.r.Read(p)
+}
+
+// --- Test Suite for Schema Hot Reload ---
+
+func TestKaitaiProcessor_HotReload(t *testing.T) {
+	ctx := context.Background()
+	const (
+		v1 = "meta:\n  id: versioned\n  imports:\n    - common\nseq:\n  - id: value\n    type: u1\n"
+		v2 = "meta:\n  id: versioned\n  endian: be\n  imports:\n    - common\nseq:\n  - id: value\n    type: u2\n"
+	)
+
+	setup := func(t *testing.T) (*KaitaiProcessor, string, string) {
+		t.Helper()
+		dir := t.TempDir()
+		schemaPath := filepath.Join(dir, "versioned.ksy")
+		commonPath := filepath.Join(dir, "common.ksy")
+		require.NoError(t, os.WriteFile(schemaPath, []byte(v1), 0644))
+		require.NoError(t, os.WriteFile(commonPath, []byte("meta:\n  id: common\n"), 0644))
+		pConf, err := kaitaiProcessorConfig().ParseYAML(fmt.Sprintf(`
+schema_path: %s
+preserve_order: false
+hot_reload: true
+hot_reload_debounce: 10ms
+`, schemaPath), nil)
+		require.NoError(t, err)
+		processor, err := newKaitaiProcessorFromConfig(pConf, service.MockResources())
+		require.NoError(t, err)
+		t.Cleanup(func() { processor.Close(ctx) })
+		return processor, schemaPath, commonPath
+	}
+	parseValue := func(t *testing.T, processor *KaitaiProcessor) any {
+		t.Helper()
+		batch, err := processor.Process(ctx, service.NewMessage([]byte{0x01, 0x02}))
+		require.NoError(t, err)
+		require.NoError(t, batch[0].GetError())
+		structured, err := batch[0].AsStructured()
+		require.NoError(t, err)
+		return structured.(map[string]any)["value"]
+	}
+	cached := func(processor *KaitaiProcessor, path string) any {
+		schema, _ := processor.schemaCache.Load(path)
+		return schema
+	}
+
+	t.Run("Reloads_Changed_Schema", func(t *testing.T) {
+		processor, schemaPath, _ := setup(t)
+		assert.Equal(t, int64(0x01), parseValue(t, processor))
+
+		require.NoError(t, os.WriteFile(schemaPath, []byte(v2), 0644))
+		assert.Eventually(t, func() bool {
+			return parseValue(t, processor) == int64(0x0102)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Reloads_On_Import_Change", func(t *testing.T) {
+		processor, schemaPath, commonPath := setup(t)
+		parseValue(t, processor)
+		before := cached(processor, schemaPath)
+
+		require.NoError(t, os.WriteFile(commonPath, []byte("meta:\n  id: common\n  endian: le\n"), 0644))
+		assert.Eventually(t, func() bool {
+			return cached(processor, schemaPath) != before
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Keeps_Previous_Version_On_Error", func(t *testing.T) {
+		processor, schemaPath, _ := setup(t)
+		parseValue(t, processor)
+		before := cached(processor, schemaPath)
+
+		for _, broken := range []string{
+			"meta: [not, a, map",
+			"meta:\n  id: versioned\nseq:\n  - id: value\n    type: u1\n    if: (1 +\n",
+		} {
+			require.NoError(t, os.WriteFile(schemaPath, []byte(broken), 0644))
+			processor.reloadSchema(schemaPath)
+			assert.Same(t, before, cached(processor, schemaPath))
+			assert.Equal(t, int64(0x01), parseValue(t, processor))
+		}
+	})
+}
+
 // --- Logging Tests (Basic) ---
 
 type capturingLogger struct {
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// Resynchronization strategies after a frame fails to parse
+const (
+	resyncByte       = "byte"        // Retry at the next byte
+	resyncSyncMarker = "sync_marker" // Skip to the next occurrence of the frame's leading `contents`
+	resyncHeader     = "header"      // Skip to the next offset where the frame header parses
+	resyncAbort      = "abort"       // Stop processing the rest of the message
+)
+
+// frameResync finds where to retry framing after a frame fails to parse
+type frameResync struct {
+	strategy string
+	marker   []byte            // sync_marker: the framing root type's leading contents
+	header   *kst.KaitaiSchema // header: the framing schema cut after its last validated leading field
+}
+
+// newFrameResync prepares strategy for framingSchema, whose root type is rootType
+func newFrameResync(strategy string, framingSchema *kst.KaitaiSchema, rootType string) (*frameResync, error) {
+	r := &frameResync{strategy: strategy}
+	switch strategy {
+	case resyncByte, resyncAbort:
+		return r, nil
+	case resyncSyncMarker:
+		seq := framingRootSeq(framingSchema, rootType)
+		if len(seq) == 0 || seq[0].Contents == nil {
+			return nil, fmt.Errorf("resync strategy %q needs the framing root type '%s' to start with a `contents` field", strategy, rootType)
+		}
+		marker, err := seq[0].ContentsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(marker) == 0 {
+			return nil, fmt.Errorf("resync strategy %q needs non-empty `contents` in field '%s'", strategy, seq[0].ID)
+		}
+		r.marker = marker
+		return r, nil
+	case resyncHeader:
+		seq := framingRootSeq(framingSchema, rootType)
+		end := 0
+		for i, field := range seq {
+			if field.Contents != nil || field.Valid != nil {
+				end = i + 1
+			}
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("resync strategy %q needs a `contents` or `valid` field in the framing root type '%s'", strategy, rootType)
+		}
+		r.header = framingHeaderSchema(framingSchema, rootType, seq[:end])
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unknown resync strategy %q", strategy)
+	}
+}
+
+// framingRootSeq returns the sequence of the framing schema's root type
+func framingRootSeq(schema *kst.KaitaiSchema, rootType string) []kst.SequenceItem {
+	if rootType == "" || rootType == schema.Meta.ID {
+		return schema.Seq
+	}
+	return schema.Types[rootType].Seq
+}
+
+// framingHeaderSchema copies schema with the root type's sequence replaced by
+// header and its instances dropped, so only the header is read
+func framingHeaderSchema(schema *kst.KaitaiSchema, rootType string, header []kst.SequenceItem) *kst.KaitaiSchema {
+	headerSchema := *schema
+	if rootType == "" || rootType == schema.Meta.ID {
+		headerSchema.Seq = header
+		headerSchema.Instances = nil
+		return &headerSchema
+	}
+	headerSchema.Types = make(map[string]kst.Type, len(schema.Types))
+	for name, t := range schema.Types {
+		headerSchema.Types[name] = t
+	}
+	rootDef := schema.Types[rootType]
+	rootDef.Seq = header
+	rootDef.Instances = nil
+	headerSchema.Types[rootType] = rootDef
+	headerSchema.RootType = rootType
+	return &headerSchema
+}
+
+// next returns the offset after failedPos at which to try the next frame, or
+// -1 to stop processing the message
+func (r *frameResync) next(ctx context.Context, data []byte, failedPos int64, logger *slog.Logger, limits kst.Limits) (int64, error) {
+	size := int64(len(data))
+	switch r.strategy {
+	case resyncAbort:
+		return -1, nil
+	case resyncSyncMarker:
+		if i := bytes.Index(data[failedPos+1:], r.marker); i >= 0 {
+			return failedPos + 1 + int64(i), nil
+		}
+		// Keep a trailing marker prefix, which may be a frame continued by the next message
+		for n := min(len(r.marker)-1, len(data)); n > 0; n-- {
+			if size-int64(n) > failedPos && bytes.HasSuffix(data, r.marker[:n]) {
+				return size - int64(n), nil
+			}
+		}
+		return size, nil
+	case resyncHeader:
+		interp, err := kst.NewKaitaiInterpreter(r.header, logger, kst.WithLimits(limits))
+		if err != nil {
+			return 0, fmt.Errorf("creating frame header interpreter: %w", err)
+		}
+		for pos := failedPos + 1; pos < size; pos++ {
+			_, err := interp.Parse(ctx, kaitai.NewStream(bytes.NewReader(data[pos:])))
+			// A header cut short by the end of the message may be a frame continued by the next one
+			if err == nil || isEOFError(err) {
+				return pos, nil
+			}
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+		}
+		return size, nil
+	default:
+		return failedPos + 1, nil
+	}
+}
+
+// isEOFError reports whether err means the data ended before a frame did
+func isEOFError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, isKaitaiCustomEof := err.(kaitai.EndOfStreamError)
+	return isKaitaiCustomEof || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/redpanda-data/benthos/v4/public/service"
+	kcel "github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// scannerReadSize is the least number of bytes a kaitai_frames scanner reads at a time
+const scannerReadSize = 4096
+
+func init() {
+	err := service.RegisterBatchScannerCreator(
+		"kaitai_frames",
+		kaitaiFramesScannerConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return newKaitaiFramesScannerCreatorFromConfig(conf, mgr)
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// kaitaiFramesScannerConfig returns a config spec for a kaitai_frames scanner.
+func kaitaiFramesScannerConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Summary("Cuts a byte stream into frames defined by a Kaitai Struct framing schema, emitting one message per frame.").
+		Description("Frames are cut from the raw stream as it is read, so a frame split across reads of a socket or file is put back together before it is emitted. Each message holds the raw bytes of a frame, or only its payload if 'data_field_id' is set, and carries the frame's byte offset in the stream in the `kaitai_frame_offset` metadata. A partial frame at the end of the stream is dropped with a warning.").
+		Field(service.NewStringField("schema_path").
+			Description("Path to the Kaitai Struct (.ksy) schema of a single frame.").
+			Example("./schemas/serial_frame.ksy")).
+		Field(service.NewStringField("root_type").
+			Description("The type of the schema describing a frame. Defaults to the schema's `meta.id`.").
+			Default("")).
+		Field(service.NewStringField("data_field_id").
+			Description("When set, each message holds only the bytes of this field of the frame, its payload, instead of the whole frame.").
+			Example("payload").
+			Default("")).
+		Field(service.NewStringMapField("metadata_fields").
+			Description("Copy frame fields into the metadata of each message, as for the `kaitai` processor's 'framing_metadata_fields'.").
+			Example(map[string]any{"seq_no": "frame_seq"}).
+			Optional()).
+		Field(service.NewStringEnumField("resync", resyncByte, resyncSyncMarker, resyncHeader, resyncAbort).
+			Description("How to find the next frame after one fails to parse, as for the `kaitai` processor. `abort` stops reading the stream with an error.").
+			Default(resyncByte)).
+		Field(service.NewIntField("max_frame_size").
+			Description("Maximum number of bytes to buffer while waiting for the rest of a frame. A frame still incomplete at this size is treated as corrupt.").
+			Default(1024 * 1024)).
+		Fields(limitFields()...)
+}
+
+// kaitaiFramesScannerCreator creates a kaitaiFramesScanner for each byte stream
+type kaitaiFramesScannerCreator struct {
+	schema         *kst.KaitaiSchema
+	rootType       string
+	dataFieldID    string
+	metadataFields map[string]string
+	resync         *frameResync
+	maxFrameSize   int
+	limits         kst.Limits
+	logger         *service.Logger
+}
+
+// newKaitaiFramesScannerCreatorFromConfig creates a new kaitaiFramesScannerCreator from a parsed config.
+func newKaitaiFramesScannerCreatorFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*kaitaiFramesScannerCreator, error) {
+	schemaPath, err := conf.FieldString("schema_path")
+	if err != nil {
+		return nil, err
+	}
+	rootType, err := conf.FieldString("root_type")
+	if err != nil {
+		return nil, err
+	}
+	dataFieldID, err := conf.FieldString("data_field_id")
+	if err != nil {
+		return nil, err
+	}
+	var metadataFields map[string]string
+	if conf.Contains("metadata_fields") {
+		if metadataFields, err = conf.FieldStringMap("metadata_fields"); err != nil {
+			return nil, err
+		}
+	}
+	resyncStrategy, err := conf.FieldString("resync")
+	if err != nil {
+		return nil, err
+	}
+	maxFrameSize, err := conf.FieldInt("max_frame_size")
+	if err != nil {
+		return nil, err
+	}
+	if maxFrameSize <= 0 {
+		return nil, fmt.Errorf("max_frame_size must be positive")
+	}
+	limits, err := limitsFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	// No single read of a frame can be larger than the frame
+	if limits.MaxAllocation == 0 || limits.MaxAllocation > maxFrameSize {
+		limits.MaxAllocation = maxFrameSize
+	}
+
+	schema, err := schemaSource{logger: mgr.Logger()}.read(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	if rootType == "" {
+		rootType = schema.Meta.ID
+	}
+	resync, err := newFrameResync(resyncStrategy, schema, rootType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kaitaiFramesScannerCreator{
+		schema:         schema,
+		rootType:       rootType,
+		dataFieldID:    dataFieldID,
+		metadataFields: metadataFields,
+		resync:         resync,
+		maxFrameSize:   maxFrameSize,
+		limits:         limits,
+		logger:         mgr.Logger(),
+	}, nil
+}
+
+// Create starts cutting frames from rdr.
+func (c *kaitaiFramesScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	currentSchema := *c.schema // Copy so the schema keeps its own root type
+	currentSchema.RootType = c.rootType
+	logger := slog.New(newBenthosLogHandler(c.logger)).With("component", "frame_interpreter")
+	interpreter, err := kst.NewKaitaiInterpreter(&currentSchema, logger, kst.WithLimits(c.limits))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create frame interpreter: %w", err)
+	}
+	return service.AutoAggregateBatchScannerAcks(&kaitaiFramesScanner{
+		creator:      c,
+		r:            rdr,
+		interpreter:  interpreter,
+		interpLogger: logger,
+		corruptStart: -1,
+	}, aFn), nil
+}
+
+// Close releases nothing, as scanners hold their own resources.
+func (c *kaitaiFramesScannerCreator) Close(ctx context.Context) error {
+	return nil
+}
+
+// kaitaiFramesScanner cuts the frames of one byte stream
+type kaitaiFramesScanner struct {
+	creator      *kaitaiFramesScannerCreator
+	r            io.ReadCloser
+	interpreter  *kst.KaitaiInterpreter // One interpreter parses every frame of the stream
+	interpLogger *slog.Logger
+
+	buf    []byte // Bytes read but not yet cut into frames
+	offset int64  // Stream offset of buf[0]
+	eof    bool   // Whether the stream has been read to the end
+	needed int    // Bytes to try to buffer before parsing a partial frame again
+
+	// Bytes skipped while resynchronizing after a bad frame are reported once per region
+	corruptStart int64
+	corruptErr   error
+}
+
+// NextBatch returns the next frame as a message.
+func (s *kaitaiFramesScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	for {
+		if len(s.buf) == 0 && s.eof {
+			return nil, io.EOF
+		}
+		if len(s.buf) > 0 {
+			frame, size, err := s.parseFrame(ctx)
+			switch {
+			case err == nil:
+				s.reportCorrupt()
+				msg, err := s.frameMessage(frame, s.buf[:size])
+				if err != nil {
+					return nil, err
+				}
+				s.buf = s.buf[size:]
+				s.offset += size
+				s.needed = 0
+				return service.MessageBatch{msg}, nil
+			case !isEOFError(err) || len(s.buf) >= s.creator.maxFrameSize:
+				if err := s.skipCorrupt(ctx, err); err != nil {
+					return nil, err
+				}
+				s.needed = 0
+				continue
+			case s.eof:
+				s.reportCorrupt()
+				s.creator.logger.With("offset", s.offset, "dropped_bytes", len(s.buf)).Warnf("Dropping partial frame at end of stream: %v", err)
+				s.offset += int64(len(s.buf))
+				s.buf = nil
+				return nil, io.EOF
+			}
+			// Read up to double the buffer before parsing the partial frame
+			// again, so a large frame arriving back to back isn't parsed over
+			// again for every 4 KB
+			s.needed = min(2*len(s.buf), s.creator.maxFrameSize)
+		}
+		if err := s.fill(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseFrame parses the frame at the start of the buffer and returns it with its size
+func (s *kaitaiFramesScanner) parseFrame(ctx context.Context) (map[string]any, int64, error) {
+	stream := kaitai.NewStream(bytes.NewReader(s.buf))
+	parsed, err := s.interpreter.Parse(ctx, stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := stream.Pos()
+	if err != nil {
+		return nil, 0, err
+	}
+	if size == 0 {
+		return nil, 0, fmt.Errorf("frame type '%s' is empty, so frames can't advance", s.creator.rootType)
+	}
+	frame, ok := kst.ParsedDataToMap(parsed).(map[string]any)
+	if !ok {
+		return nil, 0, fmt.Errorf("parsed frame is not a map")
+	}
+	return frame, size, nil
+}
+
+// frameMessage makes the message for a frame, holding raw or its payload
+func (s *kaitaiFramesScanner) frameMessage(frame map[string]any, raw []byte) (*service.Message, error) {
+	content := raw
+	if id := s.creator.dataFieldID; id != "" {
+		switch payload := frame[id].(type) {
+		case []byte:
+			content = payload
+		case kcel.KaitaiBytes:
+			content = payload.RawBytes()
+		case nil:
+			return nil, fmt.Errorf("framing data field '%s' not found", id)
+		default:
+			return nil, fmt.Errorf("framing data field '%s' is not bytes (type: %T)", id, payload)
+		}
+	}
+	msg := service.NewMessage(bytes.Clone(content))
+	for key, value := range frameMetadata(frame, s.creator.metadataFields) {
+		msg.MetaSetMut(key, value)
+	}
+	msg.MetaSetMut("kaitai_frame_offset", s.offset)
+	return msg, nil
+}
+
+// skipCorrupt drops the bytes that can't start a frame after a frame failed to parse
+func (s *kaitaiFramesScanner) skipCorrupt(ctx context.Context, frameErr error) error {
+	if s.corruptStart < 0 {
+		s.corruptStart, s.corruptErr = s.offset, frameErr
+	}
+	next, err := s.creator.resync.next(ctx, s.buf, 0, s.interpLogger, s.creator.limits)
+	if err != nil {
+		return fmt.Errorf("failed to resynchronize after frame parse error: %w", err)
+	}
+	if next < 0 {
+		s.reportCorrupt()
+		return fmt.Errorf("failed to parse frame at offset %d: %w", s.offset, frameErr)
+	}
+	if next == 0 {
+		next = 1 // The frame may be longer than max_frame_size
+	}
+	s.buf = s.buf[next:]
+	s.offset += next
+	return nil
+}
+
+// reportCorrupt logs the region skipped since the last frame, if any
+func (s *kaitaiFramesScanner) reportCorrupt() {
+	if s.corruptStart < 0 {
+		return
+	}
+	s.creator.logger.With("start", s.corruptStart, "end", s.offset, "skipped_bytes", s.offset-s.corruptStart).Errorf("Skipped corrupt frame data: %v", s.corruptErr)
+	s.corruptStart = -1
+}
+
+// fill reads more of the stream into the buffer, asking for the bytes needed
+// but returning after any read that gets some, as a socket may have nothing
+// more to send until the frame it holds is answered
+func (s *kaitaiFramesScanner) fill(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		size := max(scannerReadSize, s.needed-len(s.buf))
+		s.buf = slices.Grow(s.buf, size)
+		n, err := s.r.Read(s.buf[len(s.buf) : len(s.buf)+size])
+		s.buf = s.buf[:len(s.buf)+n]
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+			return nil
+		}
+		if err != nil || n > 0 {
+			return err
+		}
+	}
+}
+
+// Close closes the stream.
+func (s *kaitaiFramesScanner) Close(ctx context.Context) error {
+	s.reportCorrupt()
+	return s.r.Close()
+}
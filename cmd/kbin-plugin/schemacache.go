@@ -0,0 +1,170 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+// schemaLRU caches loaded schemas by path. It holds at most size schemas,
+// evicting the least recently used one to make room, so that schema paths
+// resolved per message can't grow it without bound.
+type schemaLRU struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // Of *schemaLRUEntry, most recently used first
+}
+
+// schemaLRUEntry is a cached schema
+type schemaLRUEntry struct {
+	path   string
+	schema *kst.KaitaiSchema
+}
+
+// newSchemaLRU creates a cache holding at most size schemas
+func newSchemaLRU(size int) *schemaLRU {
+	return &schemaLRU{
+		size:    max(size, 1),
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Load returns the schema cached for path, marking it as recently used
+func (c *schemaLRU) Load(path string) (*kst.KaitaiSchema, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*schemaLRUEntry).schema, true
+}
+
+// LoadOrStore returns the schema cached for path if there is one, and
+// otherwise caches schema. The loaded result is true if schema wasn't cached.
+func (c *schemaLRU) LoadOrStore(path string, schema *kst.KaitaiSchema) (*kst.KaitaiSchema, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*schemaLRUEntry).schema, true
+	}
+	c.entries[path] = c.order.PushFront(&schemaLRUEntry{path: path, schema: schema})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*schemaLRUEntry).path)
+	}
+	return schema, false
+}
+
+// Replace replaces the schema cached for path, and reports whether there was one
+func (c *schemaLRU) Replace(path string, schema *kst.KaitaiSchema) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[path]
+	if ok {
+		elem.Value.(*schemaLRUEntry).schema = schema
+	}
+	return ok
+}
+
+// Len returns the number of cached schemas
+func (c *schemaLRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear removes every cached schema
+func (c *schemaLRU) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// compiledCache holds what is compiled from a schema, such as an interpreter,
+// by schema version and role, so it's compiled once rather than per message.
+// A reloaded schema is a new version, and versions no longer in use are
+// evicted as least recently used.
+type compiledCache[T any] struct {
+	mu      sync.Mutex
+	size    int
+	entries map[compiledKey]*list.Element
+	order   *list.List // Of *compiledEntry[T], most recently used first
+}
+
+// compiledKey names what is compiled from a schema for one role, such as
+// parsing frames of a given root type
+type compiledKey struct {
+	schema *kst.KaitaiSchema
+	role   string
+}
+
+// compiledEntry is a cached compilation
+type compiledEntry[T any] struct {
+	key   compiledKey
+	value T
+}
+
+// newCompiledCache creates a cache holding at most size compilations
+func newCompiledCache[T any](size int) *compiledCache[T] {
+	return &compiledCache[T]{
+		size:    max(size, 1),
+		entries: make(map[compiledKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// LoadOrCompile returns what was compiled from schema for role, compiling it
+// first if needed. Concurrent callers may compile the same entry; the first
+// one stored is kept.
+func (c *compiledCache[T]) LoadOrCompile(schema *kst.KaitaiSchema, role string, compile func() (T, error)) (T, error) {
+	key := compiledKey{schema: schema, role: role}
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*compiledEntry[T]).value, nil
+	}
+	c.mu.Unlock()
+
+	value, err := compile()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*compiledEntry[T]).value, nil
+	}
+	c.entries[key] = c.order.PushFront(&compiledEntry[T]{key: key, value: value})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*compiledEntry[T]).key)
+	}
+	return value, nil
+}
+
+// Len returns the number of cached compilations
+func (c *compiledCache[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear removes every cached compilation
+func (c *compiledCache[T]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[compiledKey]*list.Element)
+	c.order.Init()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/redpanda-data/benthos/v4/public/service"
+	kst "github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+	"gopkg.in/yaml.v3"
+)
+
+// schemaWatcher watches schema files and their imports, and calls reload with
+// the path of every cached schema one of whose files changed. Changes are
+// debounced, as editors often write a file in several steps.
+type schemaWatcher struct {
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+	reload   func(schemaPath string)
+	logger   *service.Logger
+
+	mu    sync.Mutex
+	dirs  map[string]bool                // Watched directories; watching them catches files replaced by rename
+	files map[string]map[string]struct{} // Watched file -> schema paths that depend on it
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newSchemaWatcher starts watching for schema changes
+func newSchemaWatcher(debounce time.Duration, reload func(schemaPath string), logger *service.Logger) (*schemaWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &schemaWatcher{
+		watcher:  watcher,
+		debounce: debounce,
+		reload:   reload,
+		logger:   logger,
+		dirs:     make(map[string]bool),
+		files:    make(map[string]map[string]struct{}),
+		done:     make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// watch reloads schemaPath when schemaPath or one of files changes
+func (w *schemaWatcher) watch(schemaPath string, files ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, file := range append([]string{schemaPath}, files...) {
+		file, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		if dir := filepath.Dir(file); !w.dirs[dir] {
+			if err := w.watcher.Add(dir); err != nil {
+				w.logger.With("path", dir).Warnf("Failed to watch schema directory: %v", err)
+				continue
+			}
+			w.dirs[dir] = true
+		}
+		if w.files[file] == nil {
+			w.files[file] = make(map[string]struct{})
+		}
+		w.files[file][schemaPath] = struct{}{}
+	}
+}
+
+// run reloads the schemas affected by file changes until close is called
+func (w *schemaWatcher) run() {
+	defer w.wg.Done()
+	changed := make(map[string]struct{})
+	var debounced <-chan time.Time
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			w.mu.Lock()
+			for schemaPath := range w.files[filepath.Clean(event.Name)] {
+				changed[schemaPath] = struct{}{}
+			}
+			w.mu.Unlock()
+			if len(changed) > 0 {
+				debounced = time.After(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warnf("Schema watcher error: %v", err)
+		case <-debounced:
+			debounced = nil
+			for schemaPath := range changed {
+				delete(changed, schemaPath)
+				w.reload(schemaPath)
+			}
+		}
+	}
+}
+
+// close stops watching. It waits for a reload in progress to finish.
+func (w *schemaWatcher) close() {
+	close(w.done)
+	w.watcher.Close()
+	w.wg.Wait()
+}
+
+// schemaImportFiles returns the files imported by schema, directly or
+// indirectly, resolved relative to the importing file as KSC does. Absolute
+// imports, which KSC looks up in its import paths, and imports that can't be
+// read are left out.
+func schemaImportFiles(schemaPath string, schema *kst.KaitaiSchema) []string {
+	var files []string
+	seen := map[string]bool{filepath.Clean(schemaPath): true}
+	var visit func(path string, imports []string)
+	visit = func(path string, imports []string) {
+		for _, imp := range imports {
+			if strings.HasPrefix(imp, "/") {
+				continue
+			}
+			file := filepath.Join(filepath.Dir(path), filepath.FromSlash(imp)+".ksy")
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			files = append(files, file)
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			var imported kst.KaitaiSchema
+			if err := yaml.Unmarshal(data, &imported); err != nil {
+				continue
+			}
+			visit(file, imported.Meta.Imports)
+		}
+	}
+	visit(schemaPath, schema.Meta.Imports)
+	return files
+}
@@ -12,6 +12,12 @@ import (
 
 // NewEnvironment creates a CEL environment with all Kaitai-specific functions.
 func NewEnvironment() (*cel.Env, error) {
+	return NewEnvironmentWithSchema(nil)
+}
+
+// NewEnvironmentWithSchema creates a CEL environment with all Kaitai-specific
+// functions, resolving the sizes of user types against provider.
+func NewEnvironmentWithSchema(provider SchemaProvider) (*cel.Env, error) {
 	// Create base options
 	opts := []cel.EnvOption{
 		// Add custom type adapter to handle conversion between Go and CEL types
@@ -27,7 +33,7 @@ func NewEnvironment() (*cel.Env, error) {
 		StringFunctions(),
 		TypeConversionFunctions(),
 		ArrayFunctions(),
-		SizeFunctions(),           // Re-added for _sizeof support
+		SizeFunctions(provider),   // Re-added for _sizeof support
 		ByteComparisonFunctions(), // New functions for byte/int array comparisons
 		BitwiseFunctions(),
 		MathFunctions(),
@@ -0,0 +1,41 @@
+package cel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/twinfer/kbin-plugin/pkg/expression"
+)
+
+// FuzzASTTransformer checks that any parsable Kaitai expression transforms to
+// CEL, and that compiling the result fails cleanly rather than panicking
+func FuzzASTTransformer(f *testing.F) {
+	for _, seed := range []string{
+		"a + b * 2",
+		"x > 0 and y < 5 ? 1 : 2",
+		"_root.header.entries[_index].size",
+		"value.as<u4> & 0xff",
+		"[1, 2, 3].max",
+		"'abc'.reverse.length",
+		"_io.pos + _io.size",
+		"sizeof<u4>",
+		"enum_type::member.to_i",
+	} {
+		f.Add(seed)
+	}
+
+	pool, err := NewExpressionPool()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		ast, err := expression.NewExpressionParser(expression.NewExpressionLexer(strings.NewReader(input))).Parse()
+		if err != nil {
+			return
+		}
+		if _, err := NewASTTransformer().Transform(ast); err != nil {
+			return
+		}
+		_, _ = pool.GetExpression(input)
+	})
+}
@@ -18,6 +18,7 @@ type ExpressionPool struct {
 	mu          sync.RWMutex
 	expressions map[string]cel.Program
 	env         *cel.Env
+	programOpts []cel.ProgramOption // Applied to every compiled program (e.g. cel.CostLimit)
 }
 
 // NewExpressionPool creates a new expression pool with a configured CEL environment
@@ -34,8 +35,9 @@ func NewExpressionPool() (*ExpressionPool, error) {
 	}, nil
 }
 
-// NewExpressionPoolWithEnv creates a new expression pool with a custom CEL environment
-func NewExpressionPoolWithEnv(env *cel.Env) (*ExpressionPool, error) {
+// NewExpressionPoolWithEnv creates a new expression pool with a custom CEL environment.
+// Any program options are applied to every expression compiled by the pool.
+func NewExpressionPoolWithEnv(env *cel.Env, opts ...cel.ProgramOption) (*ExpressionPool, error) {
 	if env == nil {
 		return nil, fmt.Errorf("CEL environment cannot be nil")
 	}
@@ -43,6 +45,7 @@ func NewExpressionPoolWithEnv(env *cel.Env) (*ExpressionPool, error) {
 	return &ExpressionPool{
 		env:         env,
 		expressions: make(map[string]cel.Program),
+		programOpts: opts,
 	}, nil
 }
 
@@ -94,7 +97,7 @@ func (e *ExpressionPool) GetExpression(exprStr string) (cel.Program, error) {
 	}
 
 	// Create a Program from the AST
-	program, err := extEnv.Program(ast)
+	program, err := extEnv.Program(ast, e.programOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create program: %w", err)
 	}
@@ -13,22 +13,17 @@ type SchemaProvider interface {
 	CalculateTypeSize(typeName string) int64
 }
 
-// Global schema provider for size calculations
-var globalSchemaProvider SchemaProvider
-
-// SetGlobalSchemaProvider sets the schema provider for size calculations
-func SetGlobalSchemaProvider(provider SchemaProvider) {
-	globalSchemaProvider = provider
-}
-
 // sizeFunctions returns CEL function declarations for size operations.
-func SizeFunctions() cel.EnvOption {
-	return cel.Lib(&sizeLib{})
+// sizeof_type resolves user types against provider, which may be nil.
+func SizeFunctions(provider SchemaProvider) cel.EnvOption {
+	return cel.Lib(&sizeLib{provider: provider})
 }
 
-type sizeLib struct{}
+type sizeLib struct {
+	provider SchemaProvider
+}
 
-func (*sizeLib) CompileOptions() []cel.EnvOption {
+func (l *sizeLib) CompileOptions() []cel.EnvOption {
 	return []cel.EnvOption{
 		// sizeof_value function to get the size of a parsed value
 		cel.Function("sizeof_value",
@@ -92,7 +87,7 @@ func (*sizeLib) CompileOptions() []cel.EnvOption {
 					}
 
 					typeNameStr := string(typeName)
-					size := getTypeSizeFromName(typeNameStr)
+					size := l.typeSize(typeNameStr)
 					return types.Int(size)
 				}),
 			),
@@ -132,8 +127,8 @@ func getFloatTypeSizeFromName(typeName string) int64 {
 	}
 }
 
-// getTypeSizeFromName returns the size in bytes for any type name (built-in or custom)
-func getTypeSizeFromName(typeName string) int64 {
+// typeSize returns the size in bytes for any type name (built-in or custom)
+func (l *sizeLib) typeSize(typeName string) int64 {
 	// First try built-in integer types
 	if size := getIntTypeSizeFromName(typeName); size > 0 {
 		return size
@@ -145,8 +140,8 @@ func getTypeSizeFromName(typeName string) int64 {
 	}
 
 	// For custom types, try to use the schema provider
-	if globalSchemaProvider != nil {
-		if size := globalSchemaProvider.CalculateTypeSize(typeName); size > 0 {
+	if l.provider != nil {
+		if size := l.provider.CalculateTypeSize(typeName); size > 0 {
 			return size
 		}
 	}
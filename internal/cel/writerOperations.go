@@ -11,7 +11,11 @@ import (
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
 	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
 	"golang.org/x/text/transform"
 )
 
@@ -425,20 +429,15 @@ func (*encodingLib) CompileOptions() []cel.EnvOption {
 					switch strings.ToUpper(string(encodingName)) {
 					case "ASCII", "UTF-8", "UTF8":
 						encoded = []byte(string(str))
-					case "UTF-16LE", "UTF16LE":
-						encoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewEncoder()
-						encoded, err = encoder.Bytes([]byte(string(str)))
-						if err != nil {
-							return types.NewErr("failed to encode to UTF-16LE: %v", err)
+					default:
+						enc, ok := textEncoding(string(encodingName))
+						if !ok {
+							return types.NewErr("unsupported encoding: %s", encodingName)
 						}
-					case "UTF-16BE", "UTF16BE":
-						encoder := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewEncoder()
-						encoded, err = encoder.Bytes([]byte(string(str)))
+						encoded, err = enc.NewEncoder().Bytes([]byte(string(str)))
 						if err != nil {
-							return types.NewErr("failed to encode to UTF-16BE: %v", err)
+							return types.NewErr("failed to encode to %s: %v", encodingName, err)
 						}
-					default:
-						return types.NewErr("unsupported encoding: %s", encodingName)
 					}
 
 					return types.Bytes(encoded)
@@ -463,22 +462,16 @@ func (*encodingLib) CompileOptions() []cel.EnvOption {
 					switch strings.ToUpper(string(encodingName)) {
 					case "ASCII", "UTF-8", "UTF8":
 						decoded = string(data)
-					case "UTF-16LE", "UTF16LE":
-						decoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
-						utf8Str, _, err := transform.Bytes(decoder, []byte(data))
-						if err != nil {
-							return types.NewErr("failed to decode UTF-16LE: %v", err)
+					default:
+						enc, ok := textEncoding(string(encodingName))
+						if !ok {
+							return types.NewErr("unsupported encoding: %s", encodingName)
 						}
-						decoded = string(utf8Str)
-					case "UTF-16BE", "UTF16BE":
-						decoder := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
-						utf8Str, _, err := transform.Bytes(decoder, []byte(data))
+						utf8Str, _, err := transform.Bytes(enc.NewDecoder(), []byte(data))
 						if err != nil {
-							return types.NewErr("failed to decode UTF-16BE: %v", err)
+							return types.NewErr("failed to decode %s: %v", encodingName, err)
 						}
 						decoded = string(utf8Str)
-					default:
-						return types.NewErr("unsupported encoding: %s", encodingName)
 					}
 
 					return types.String(decoded)
@@ -532,3 +525,22 @@ func toInt(val ref.Val) (int64, error) {
 		return 0, fmt.Errorf("cannot convert %T to int", val.Value())
 	}
 }
+
+// textEncoding returns the encoding for a non-UTF-8 Kaitai encoding name
+func textEncoding(name string) (encoding.Encoding, bool) {
+	switch strings.ToUpper(name) {
+	case "UTF-16LE", "UTF16LE":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), true
+	case "UTF-16BE", "UTF16BE":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), true
+	case "UTF-32LE", "UTF32LE":
+		return utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM), true
+	case "UTF-32BE", "UTF32BE":
+		return utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM), true
+	case "CP437", "IBM437":
+		return charmap.CodePage437, true
+	case "SHIFT_JIS", "SJIS":
+		return japanese.ShiftJIS, true
+	}
+	return nil, false
+}
@@ -141,7 +141,7 @@ func (p *ExpressionParser) parseExpression(precedence Precedence) Expr {
 		return nil
 	}
 
-	for p.peek.Type != EXPR_EOF && precedence < p.peekPrecedence() {
+	for leftExp != nil && p.peek.Type != EXPR_EOF && precedence < p.peekPrecedence() {
 		infixFn := p.infixParseFn(p.peek.Type)
 		if infixFn == nil {
 			return leftExp // No infix operator, so we're done with this precedence level
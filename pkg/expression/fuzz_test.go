@@ -0,0 +1,57 @@
+package expression
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// ksyExpressionPattern finds expression-valued keys in .ksy files
+var ksyExpressionPattern = regexp.MustCompile(`(?m)^\s*-?\s*(?:value|if|size|pos|repeat-expr|repeat-until|switch-on|expr):\s*(.+)$`)
+
+// addExpressionSeeds seeds the corpus with a few hand-written expressions and
+// every expression found in the official test formats
+func addExpressionSeeds(f *testing.F) {
+	for _, seed := range []string{
+		"a + b * 2",
+		"(x > 0 and y < 5) ? \"yes\" : \"no\"",
+		"_root.header.entries[_index].size.as<u4>",
+		"[0x52, 0x6e, 0x44].length",
+		"sizeof<block>",
+		"-1.5e3 % 7 << 2",
+		"'str'.reverse.substring(1, 3).to_i(16)",
+		"_io.eof or not _parent._io.eof",
+		"enum::value::label",
+	} {
+		f.Add(seed)
+	}
+
+	paths, _ := filepath.Glob("../../test/formats/*.ksy")
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, m := range ksyExpressionPattern.FindAllStringSubmatch(string(data), -1) {
+			f.Add(strings.Trim(strings.TrimSpace(m[1]), `'`))
+		}
+	}
+}
+
+// FuzzExpressionParser checks that lexing and parsing arbitrary text either
+// produces an AST or reports an error, without panicking or hanging
+func FuzzExpressionParser(f *testing.F) {
+	addExpressionSeeds(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		parser := NewExpressionParser(NewExpressionLexer(strings.NewReader(input)))
+		ast, err := parser.Parse()
+		if err == nil && ast == nil {
+			t.Fatalf("no AST and no error for %q", input)
+		}
+		if err == nil {
+			_ = ast.String()
+		}
+	})
+}
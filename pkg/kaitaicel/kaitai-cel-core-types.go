@@ -554,11 +554,8 @@ func NewKaitaiTypeFromValue(value any, typeName string) (KaitaiType, error) {
 			}
 			return NewU8LEFromValue(uint64(val)), nil
 		case int64:
-			val := v
-			if val < 0 {
-				return nil, fmt.Errorf("value %d out of range for u8le (must be non-negative)", val)
-			}
-			return NewU8LEFromValue(uint64(val)), nil
+			// Parsed u8 values above math.MaxInt64 are held as negative int64, so take them as two's complement
+			return NewU8LEFromValue(uint64(v)), nil
 		case uint, uint8, uint16, uint32: // always fits
 			return NewU8LEFromValue(reflect.ValueOf(v).Uint()), nil
 		case float32:
@@ -599,11 +596,8 @@ func NewKaitaiTypeFromValue(value any, typeName string) (KaitaiType, error) {
 			}
 			return NewU8BEFromValue(uint64(val)), nil
 		case int64:
-			val := v
-			if val < 0 {
-				return nil, fmt.Errorf("value %d out of range for u8be (must be non-negative)", val)
-			}
-			return NewU8BEFromValue(uint64(val)), nil
+			// Parsed u8 values above math.MaxInt64 are held as negative int64, so take them as two's complement
+			return NewU8BEFromValue(uint64(v)), nil
 		case uint, uint8, uint16, uint32:
 			return NewU8BEFromValue(reflect.ValueOf(v).Uint()), nil
 		case float32:
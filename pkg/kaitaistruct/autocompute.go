@@ -0,0 +1,366 @@
+package kaitaistruct
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/twinfer/kbin-plugin/pkg/expression"
+)
+
+// inverseFunc maps the value an expression must produce back to the value of its single input field
+type inverseFunc func(result int64) (int64, error)
+
+// autoComputeSource records which field and expression produced an inferred value
+type autoComputeSource struct {
+	value int64
+	field string
+	expr  string
+}
+
+// autoComputeFields back-fills sequence fields that are used as the `size` or
+// `repeat-expr` of later fields. The expression is inverted against the actual
+// length of the data, so `size: len_body - 4` sets len_body to len(body) + 4.
+// Fields that any other expression of the type refers to (e.g. an `if` or a
+// `switch-on`) are left alone. Values already present in the input must match
+// the inferred ones. Fields that are missing from the input and cannot be
+// inferred produce an error.
+func (k *KaitaiSerializer) autoComputeFields(goCtx context.Context, typeName string, sequence []SequenceItem, instances map[string]InstanceDef, typeCtx *SerializeContext) error {
+	seqFields := make(map[string]bool, len(sequence))
+	for _, seq := range sequence {
+		seqFields[seq.ID] = true
+	}
+	otherUses := otherFieldReferences(sequence, instances)
+
+	computed := make(map[string]autoComputeSource)
+	unresolved := make(map[string]error)
+
+	for _, field := range sequence {
+		data, dataOk := typeCtx.Children[field.ID]
+		if !dataOk || data == nil {
+			continue
+		}
+
+		exprs := []struct{ kind, expr string }{}
+		if sizeExpr, ok := field.Size.(string); ok && sizeExpr != "" {
+			exprs = append(exprs, struct{ kind, expr string }{"size", sizeExpr})
+		}
+		if field.RepeatExpr != "" {
+			exprs = append(exprs, struct{ kind, expr string }{"repeat-expr", field.RepeatExpr})
+		}
+
+		for _, e := range exprs {
+			ast, err := parseKaitaiExpression(e.expr)
+			if err != nil {
+				// Leave it to the regular evaluation to report malformed expressions
+				continue
+			}
+
+			target, inverse, invErr := invertExpression(ast)
+			if invErr != nil {
+				for _, id := range referencedIdentifiers(ast) {
+					if _, present := typeCtx.Children[id]; seqFields[id] && !present {
+						unresolved[id] = fmt.Errorf("cannot infer field '%s' from %s expression '%s' of field '%s': %w", id, e.kind, e.expr, field.ID, invErr)
+					}
+				}
+				continue
+			}
+			if !seqFields[target] {
+				// Instances and params are not written, nothing to back-fill
+				continue
+			}
+			if use, ok := otherUses[target]; ok {
+				// The field means more than a length; the caller has to provide it
+				if _, present := typeCtx.Children[target]; !present {
+					unresolved[target] = fmt.Errorf("cannot infer field '%s' from %s expression '%s' of field '%s': it is also used by %s", target, e.kind, e.expr, field.ID, use)
+				}
+				continue
+			}
+
+			var actual int64
+			if e.kind == "repeat-expr" {
+				items, ok := data.([]any)
+				if !ok {
+					return fmt.Errorf("expected array for repeated field '%s', got %T", field.ID, data)
+				}
+				actual = int64(len(items))
+			} else {
+				actual, err = k.measureSizedField(goCtx, field, data, typeCtx)
+				if err != nil {
+					if _, present := typeCtx.Children[target]; !present {
+						unresolved[target] = fmt.Errorf("cannot infer field '%s' from size of field '%s': %w", target, field.ID, err)
+					}
+					continue
+				}
+			}
+
+			value, err := inverse(actual)
+			if err != nil {
+				return fmt.Errorf("cannot infer field '%s' from %s expression '%s' of field '%s': %w", target, e.kind, e.expr, field.ID, err)
+			}
+			if prev, ok := computed[target]; ok && prev.value != value {
+				return fmt.Errorf("conflicting values inferred for field '%s': %d from '%s' of field '%s', %d from '%s' of field '%s'",
+					target, prev.value, prev.expr, prev.field, value, e.expr, field.ID)
+			}
+			computed[target] = autoComputeSource{value: value, field: field.ID, expr: e.expr}
+		}
+	}
+
+	for id, err := range unresolved {
+		if _, ok := computed[id]; !ok {
+			return err
+		}
+	}
+
+	for id, src := range computed {
+		if given, present := typeCtx.Children[id]; present && given != nil {
+			if v, ok := toInt64(given); !ok || v != src.value {
+				return fmt.Errorf("field '%s' is %v but expression '%s' of field '%s' requires %d", id, given, src.expr, src.field, src.value)
+			}
+		}
+		k.logger.DebugContext(goCtx, "Auto-computed field value", "type_name", typeName, "field_id", id, "value", src.value, "from_field", src.field, "expr", src.expr)
+		typeCtx.Children[id] = src.value
+	}
+	return nil
+}
+
+// otherFieldReferences maps each field referenced by an expression other than a
+// `size` or `repeat-expr` that autoComputeFields can invert to a description of
+// that use
+func otherFieldReferences(sequence []SequenceItem, instances map[string]InstanceDef) map[string]string {
+	uses := make(map[string]string)
+	record := func(where, expr string) {
+		if expr == "" {
+			return
+		}
+		ast, err := parseKaitaiExpression(expr)
+		if err != nil {
+			return
+		}
+		for _, id := range referencedIdentifiers(ast) {
+			if _, seen := uses[id]; !seen {
+				uses[id] = where
+			}
+		}
+	}
+	recordLength := func(where, expr string) {
+		if ast, err := parseKaitaiExpression(expr); err == nil {
+			if _, _, err := invertExpression(ast); err == nil {
+				return
+			}
+		}
+		record(where, expr)
+	}
+
+	for _, field := range sequence {
+		record(fmt.Sprintf("the if of field '%s'", field.ID), field.IfExpr)
+		record(fmt.Sprintf("the repeat-until of field '%s'", field.ID), field.RepeatUntil)
+		record(fmt.Sprintf("the value of field '%s'", field.ID), field.Value)
+		if typeSwitch, ok := field.Type.(map[string]any); ok {
+			if switchOn, ok := typeSwitch["switch-on"].(string); ok {
+				record(fmt.Sprintf("the switch-on of field '%s'", field.ID), switchOn)
+			}
+		}
+		if field.Valid != nil {
+			record(fmt.Sprintf("the valid expression of field '%s'", field.ID), field.Valid.Expr)
+		}
+		if size, ok := field.Size.(string); ok {
+			recordLength(fmt.Sprintf("the size of field '%s'", field.ID), size)
+		}
+		recordLength(fmt.Sprintf("the repeat-expr of field '%s'", field.ID), field.RepeatExpr)
+	}
+	for _, id := range sortedKeys(instances) {
+		inst := instances[id]
+		record(fmt.Sprintf("instance '%s'", id), inst.Value)
+		record(fmt.Sprintf("the if of instance '%s'", id), inst.IfExpr)
+		if pos, ok := inst.Pos.(string); ok {
+			record(fmt.Sprintf("the pos of instance '%s'", id), pos)
+		}
+	}
+	return uses
+}
+
+// measureSizedField returns the number of bytes field would occupy without its size constraint.
+// Repeated fields must have items of equal length, since size applies to each item.
+func (k *KaitaiSerializer) measureSizedField(goCtx context.Context, field SequenceItem, data any, typeCtx *SerializeContext) (int64, error) {
+	defer k.enterPath(field.ID)()
+
+	unsized := field
+	unsized.Size = nil
+	unsized.IfExpr = ""
+	unsized.Repeat = ""
+	unsized.RepeatExpr = ""
+	unsized.RepeatUntil = ""
+	if getTypeAsString(unsized.Type) == "" {
+		unsized.Type = "bytes"
+	}
+
+	measure := func(item any) (int64, error) {
+		buf := k.newLayoutBuffer(-1)
+		tmpCtx := &SerializeContext{
+			Value:    typeCtx.Value,
+			Children: typeCtx.Children,
+			Parent:   typeCtx.Parent,
+			Root:     typeCtx.Root,
+			Writer:   kaitai.NewWriter(buf),
+			IO:       kaitai.NewStream(buf),
+		}
+		if err := k.serializeField(goCtx, unsized, item, tmpCtx); err != nil {
+			return 0, err
+		}
+		if err := buf.finish(); err != nil {
+			return 0, err
+		}
+		return int64(buf.Len()), nil
+	}
+
+	if field.Repeat == "" {
+		return measure(data)
+	}
+
+	items, ok := data.([]any)
+	if !ok {
+		return 0, fmt.Errorf("expected array for repeated field '%s', got %T", field.ID, data)
+	}
+	if len(items) == 0 {
+		return 0, fmt.Errorf("no items to measure in repeated field '%s'", field.ID)
+	}
+	first, err := measure(items[0])
+	if err != nil {
+		return 0, err
+	}
+	for i := 1; i < len(items); i++ {
+		n, err := measure(items[i])
+		if err != nil {
+			return 0, err
+		}
+		if n != first {
+			return 0, fmt.Errorf("items of repeated field '%s' have different sizes (%d and %d)", field.ID, first, n)
+		}
+	}
+	return first, nil
+}
+
+// parseKaitaiExpression parses a Kaitai expression string into its AST
+func parseKaitaiExpression(exprStr string) (expression.Expr, error) {
+	lexer := expression.NewExpressionLexer(strings.NewReader(exprStr))
+	parser := expression.NewExpressionParser(lexer)
+	ast, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression '%s': %w", exprStr, err)
+	}
+	return ast, nil
+}
+
+// invertExpression solves an expression of a single field combined with integer
+// constants through +, -, * and / (e.g. `len`, `len - 4`, `(count + 1) * 2`).
+// It returns the field name and a function mapping a result back to the field value.
+func invertExpression(expr expression.Expr) (string, inverseFunc, error) {
+	switch e := expr.(type) {
+	case *expression.Id:
+		return e.Name, func(result int64) (int64, error) { return result, nil }, nil
+
+	case *expression.BinOp:
+		lc, lConst := e.Arg1.(*expression.IntLit)
+		rc, rConst := e.Arg2.(*expression.IntLit)
+		if lConst == rConst {
+			return "", nil, fmt.Errorf("expression '%s' must combine exactly one field with a constant", e)
+		}
+
+		var inner expression.Expr
+		var c int64
+		if rConst {
+			inner, c = e.Arg1, rc.Value
+		} else {
+			inner, c = e.Arg2, lc.Value
+		}
+		target, innerInverse, err := invertExpression(inner)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var step inverseFunc
+		switch e.Op {
+		case expression.BinOpAdd:
+			step = func(result int64) (int64, error) { return result - c, nil }
+		case expression.BinOpSub:
+			if rConst {
+				step = func(result int64) (int64, error) { return result + c, nil }
+			} else {
+				step = func(result int64) (int64, error) { return c - result, nil }
+			}
+		case expression.BinOpMul:
+			if c == 0 {
+				return "", nil, fmt.Errorf("cannot invert multiplication by zero in '%s'", e)
+			}
+			step = func(result int64) (int64, error) {
+				if result%c != 0 {
+					return 0, fmt.Errorf("%d is not a multiple of %d", result, c)
+				}
+				return result / c, nil
+			}
+		case expression.BinOpDiv:
+			if !rConst || c == 0 {
+				return "", nil, fmt.Errorf("cannot invert division in '%s'", e)
+			}
+			step = func(result int64) (int64, error) { return result * c, nil }
+		default:
+			return "", nil, fmt.Errorf("operator '%s' cannot be inverted", e.Op)
+		}
+
+		return target, func(result int64) (int64, error) {
+			v, err := step(result)
+			if err != nil {
+				return 0, err
+			}
+			return innerInverse(v)
+		}, nil
+
+	default:
+		return "", nil, fmt.Errorf("expression '%s' is not a field or simple arithmetic of one field", expr)
+	}
+}
+
+// referencedIdentifiers lists the plain identifiers used anywhere in an expression
+func referencedIdentifiers(expr expression.Expr) []string {
+	var ids []string
+	var walk func(e expression.Expr)
+	walk = func(e expression.Expr) {
+		switch n := e.(type) {
+		case *expression.Id:
+			ids = append(ids, n.Name)
+		case *expression.UnOp:
+			walk(n.Arg)
+		case *expression.BinOp:
+			walk(n.Arg1)
+			walk(n.Arg2)
+		case *expression.TernaryOp:
+			walk(n.Cond)
+			walk(n.IfTrue)
+			walk(n.IfFalse)
+		case *expression.Attr:
+			walk(n.Value)
+		case *expression.Call:
+			walk(n.Value)
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *expression.ArrayIdx:
+			walk(n.Value)
+			walk(n.Idx)
+		case *expression.ArrayLit:
+			for _, el := range n.Elements {
+				walk(el)
+			}
+		case *expression.CastToType:
+			walk(n.Value)
+		case *expression.SizeOf:
+			walk(n.Value)
+		case *expression.AlignOf:
+			walk(n.Value)
+		}
+	}
+	walk(expr)
+	return ids
+}
@@ -0,0 +1,248 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAutoComputeSerializer(t *testing.T, schema *KaitaiSchema) *KaitaiSerializer {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := NewKaitaiSerializer(schema, logger, WithAutoCompute(true))
+	require.NoError(t, err)
+	return s
+}
+
+func TestSerialize_AutoCompute(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("size of bytes field", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_size", Endian: "le"},
+			Seq: []SequenceItem{
+				{ID: "len_body", Type: "u2"},
+				{ID: "body", Type: "bytes", Size: "len_body"},
+			},
+		}
+		data := map[string]any{"body": []byte{0xAA, 0xBB, 0xCC}}
+
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x03, 0x00, 0xAA, 0xBB, 0xCC}, out)
+		_, leaked := data["len_body"]
+		assert.False(t, leaked, "inferred values must not be written into the caller's map")
+	})
+
+	t.Run("arithmetic checks given value", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_arith"},
+			Seq: []SequenceItem{
+				{ID: "len", Type: "u1"},
+				{ID: "name", Type: "str", Size: "len - 4", Encoding: "ASCII"},
+			},
+		}
+
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{"name": "hello"})
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{9}, "hello"...), out)
+
+		out, err = newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{"len": 9, "name": "hello"})
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{9}, "hello"...), out)
+
+		_, err = newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{"len": 99, "name": "hello"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "field 'len' is 99 but expression 'len - 4' of field 'name' requires 9")
+	})
+
+	t.Run("field with other uses is not computed", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_other_use"},
+			Seq: []SequenceItem{
+				{ID: "len", Type: "u1"},
+				{ID: "body", Type: "bytes", Size: "len"},
+				{ID: "extra", Type: "u1", IfExpr: "len > 2"},
+			},
+		}
+
+		_, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{"body": []byte{1, 2, 3}, "extra": 7})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "it is also used by the if of field 'extra'")
+
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{"len": 3, "body": []byte{1, 2, 3}, "extra": 7})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{3, 1, 2, 3, 7}, out)
+	})
+
+	t.Run("repeat-expr count", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_count"},
+			Seq: []SequenceItem{
+				{ID: "num_pairs", Type: "u1"},
+				{ID: "values", Type: "u1", Repeat: "expr", RepeatExpr: "num_pairs * 2"},
+			},
+		}
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{
+			"values": []any{1, 2, 3, 4},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{2, 1, 2, 3, 4}, out)
+
+		_, err = newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{
+			"values": []any{1, 2, 3},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not a multiple of 2")
+	})
+
+	t.Run("sized user type in nested type", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_nested", Endian: "be"},
+			Seq: []SequenceItem{
+				{ID: "rec", Type: "record"},
+			},
+			Types: map[string]Type{
+				"record": {Seq: []SequenceItem{
+					{ID: "body_len", Type: "u1"},
+					{ID: "body", Type: "payload", Size: "body_len"},
+				}},
+				"payload": {Seq: []SequenceItem{
+					{ID: "a", Type: "u2"},
+					{ID: "b", Type: "u4"},
+				}},
+			},
+		}
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{
+			"rec": map[string]any{"body": map[string]any{"a": 1, "b": 2}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{6, 0, 1, 0, 0, 0, 2}, out)
+	})
+
+	t.Run("untyped sized field round-trips", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_untyped"},
+			Seq: []SequenceItem{
+				{ID: "len", Type: "u1"},
+				{ID: "blob", Size: "len"},
+			},
+		}
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{"blob": []byte("xyz")})
+		require.NoError(t, err)
+
+		parsed, err := newTestInterpreter(t, schema).Parse(ctx, kaitai.NewStream(bytes.NewReader(out)))
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, getUnderlyingValue(getParsedValue(t, parsed, "len")))
+		assert.Equal(t, []byte("xyz"), getUnderlyingValue(getParsedValue(t, parsed, "blob")))
+	})
+
+	t.Run("conflicting inferences", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_conflict"},
+			Seq: []SequenceItem{
+				{ID: "len", Type: "u1"},
+				{ID: "a", Type: "bytes", Size: "len"},
+				{ID: "b", Type: "bytes", Size: "len"},
+			},
+		}
+		_, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{
+			"a": []byte{1, 2},
+			"b": []byte{1, 2, 3},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "conflicting values inferred for field 'len'")
+	})
+
+	t.Run("non-invertible expression with missing field", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_noninvertible"},
+			Seq: []SequenceItem{
+				{ID: "w", Type: "u1"},
+				{ID: "h", Type: "u1"},
+				{ID: "pixels", Type: "bytes", Size: "w * h"},
+			},
+		}
+		_, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{
+			"w":      2,
+			"pixels": []byte{1, 2, 3, 4},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot infer field 'h' from size expression 'w * h'")
+
+		// Fields provided by the caller are used as-is
+		out, err := newAutoComputeSerializer(t, schema).Serialize(ctx, map[string]any{
+			"w":      2,
+			"h":      2,
+			"pixels": []byte{1, 2, 3, 4},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{2, 2, 1, 2, 3, 4}, out)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "auto_disabled"},
+			Seq: []SequenceItem{
+				{ID: "count", Type: "u1"},
+				{ID: "items", Type: "u1", Repeat: "expr", RepeatExpr: "count"},
+			},
+		}
+		_, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+			"count": 1,
+			"items": []any{1, 2},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't match expected repeat count")
+	})
+}
+
+func TestInvertExpression(t *testing.T) {
+	tests := []struct {
+		expr   string
+		target string
+		result int64
+		want   int64
+		errMsg string
+	}{
+		{expr: "len", target: "len", result: 10, want: 10},
+		{expr: "len - 4", target: "len", result: 10, want: 14},
+		{expr: "4 + len", target: "len", result: 10, want: 6},
+		{expr: "100 - len", target: "len", result: 10, want: 90},
+		{expr: "(n + 1) * 2", target: "n", result: 10, want: 4},
+		{expr: "n / 8", target: "n", result: 3, want: 24},
+		{expr: "a + b", errMsg: "exactly one field"},
+		{expr: "n % 4", errMsg: "cannot be inverted"},
+		{expr: "hdr.len", errMsg: "not a field or simple arithmetic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			ast, err := parseKaitaiExpression(tt.expr)
+			require.NoError(t, err)
+
+			target, inverse, err := invertExpression(ast)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.target, target)
+			got, err := inverse(tt.result)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReferencedIdentifiers(t *testing.T) {
+	ast, err := parseKaitaiExpression("a * (b + c.d) > e ? f : 1")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "e", "f"}, referencedIdentifiers(ast))
+}
@@ -0,0 +1,152 @@
+package kaitaistruct
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+)
+
+// bitTypePattern matches bit-sized integer types such as b1, b12le or b64be
+var bitTypePattern = regexp.MustCompile(`^b(\d+)(le|be)?$`)
+
+// bitRun holds bits written to a stream that don't fill a byte yet. It mirrors
+// the bit state kaitai.Stream keeps while reading, so bit fields written here
+// are packed the same way ReadBitsIntBe/ReadBitsIntLe unpack them.
+type bitRun struct {
+	bits         uint64 // Pending bits, right-aligned
+	count        int    // Number of pending bits, 0-7 between writes
+	littleEndian bool   // Bit order of the pending bits
+}
+
+// writeBitsInt appends the n low bits of value to the stream. Big-endian runs
+// fill each byte from its most significant bit, little-endian runs from its least.
+func (b *layoutBuffer) writeBitsInt(n int, value uint64, littleEndian bool) error {
+	if n < 1 || n > 64 {
+		return fmt.Errorf("bit field size must be 1-64, got %d", n)
+	}
+	if n < 64 && value>>n != 0 {
+		return fmt.Errorf("value %d does not fit in %d bits", value, n)
+	}
+	if b.bitRun.littleEndian != littleEndian {
+		// Kaitai doesn't allow the bit order to change within a byte, so start a new one
+		b.alignToByte()
+	}
+	b.bitRun.littleEndian = littleEndian
+
+	// Pending bits and value must fit in the 64-bit accumulator
+	if b.bitRun.count+n > 64 {
+		if littleEndian {
+			if err := b.writeBitsInt(32, value&0xFFFFFFFF, true); err != nil {
+				return err
+			}
+			return b.writeBitsInt(n-32, value>>32, true)
+		}
+		if err := b.writeBitsInt(n-32, value>>32, false); err != nil {
+			return err
+		}
+		return b.writeBitsInt(32, value&0xFFFFFFFF, false)
+	}
+
+	total := b.bitRun.count + n
+	if littleEndian {
+		acc := b.bitRun.bits | value<<b.bitRun.count
+		for ; total >= 8; total -= 8 {
+			b.put([]byte{byte(acc)})
+			acc >>= 8
+		}
+		b.bitRun.bits = acc
+	} else {
+		acc := b.bitRun.bits<<n | value
+		for total >= 8 {
+			total -= 8
+			b.put([]byte{byte(acc >> total)})
+		}
+		b.bitRun.bits = acc & (1<<total - 1)
+	}
+	b.bitRun.count = total
+	return nil
+}
+
+// alignToByte writes any pending bits as a final, zero-padded byte
+func (b *layoutBuffer) alignToByte() {
+	if b.bitRun.count == 0 {
+		return
+	}
+	last := byte(b.bitRun.bits)
+	if !b.bitRun.littleEndian {
+		last = byte(b.bitRun.bits << (8 - b.bitRun.count))
+	}
+	b.bitRun = bitRun{}
+	b.put([]byte{last})
+}
+
+// serializeBitField writes a bN, bNle or bNbe field. The bit order comes from
+// the type suffix, then meta/bit-endian, defaulting to big-endian as in the parser.
+func (k *KaitaiSerializer) serializeBitField(goCtx context.Context, typeName string, data any, writer *kaitai.Writer) error {
+	matches := bitTypePattern.FindStringSubmatch(typeName)
+	if matches == nil {
+		return fmt.Errorf("invalid bit field type '%s'", typeName)
+	}
+	numBits, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return fmt.Errorf("invalid number of bits in type '%s': %w", typeName, err)
+	}
+	bitEndian := matches[2]
+	if bitEndian == "" {
+		bitEndian = k.schema.Meta.BitEndian
+	}
+
+	value, err := toBitFieldValue(data)
+	if err != nil {
+		return fmt.Errorf("converting value for type '%s': %w", typeName, err)
+	}
+
+	buf, ok := writer.Writer.(*layoutBuffer)
+	if !ok {
+		return fmt.Errorf("stream does not support bit-level writes")
+	}
+	k.logger.DebugContext(goCtx, "Serializing bit field", "type_name", typeName, "value", value, "bits", numBits, "bit_endian", bitEndian)
+	if err := buf.writeBitsInt(numBits, value, bitEndian == "le"); err != nil {
+		return fmt.Errorf("writing type '%s': %w", typeName, err)
+	}
+	return nil
+}
+
+// toBitFieldValue converts input data for a bit field, accepting booleans
+// (as produced for b1 fields), non-negative integers and parsed bit fields
+func toBitFieldValue(data any) (uint64, error) {
+	switch v := data.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case *kaitaicel.KaitaiBitField:
+		return v.AsUint(), nil
+	case uint64:
+		return v, nil
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return 0, fmt.Errorf("value %v is not a non-negative integer", v)
+		}
+		return uint64(v), nil
+	case string:
+		// Values beyond the exact range of JSON numbers may be given as decimal strings
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not a non-negative integer", v)
+		}
+		return n, nil
+	}
+	n, ok := toInt64(data)
+	if !ok {
+		return 0, fmt.Errorf("expected integer or bool, got %T", data)
+	}
+	// Parsed b64 values above math.MaxInt64 come back as negative int64, so take
+	// negative values as two's complement; narrower fields reject them as too wide
+	return uint64(n), nil
+}
@@ -0,0 +1,93 @@
+package kaitaistruct
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize_BitsRoundTrip(t *testing.T) {
+	// Each bits_* format with the fixture its generated test reads
+	fixtures := map[string]string{
+		"bits_byte_aligned":        "fixed_struct",
+		"bits_enum":                "fixed_struct",
+		"bits_seq_endian_combo":    "process_xor_4",
+		"bits_shift_by_b32_le":     "bits_shift_by_b32_le",
+		"bits_shift_by_b64_le":     "bits_shift_by_b64_le",
+		"bits_signed_res_b32_be":   "bits_shift_by_b32_le",
+		"bits_signed_res_b32_le":   "bits_shift_by_b32_le",
+		"bits_signed_shift_b32_le": "bits_signed_shift_b32_le",
+		"bits_signed_shift_b64_le": "bits_signed_shift_b64_le",
+		"bits_simple":              "fixed_struct",
+		"bits_simple_le":           "fixed_struct",
+		"bits_unaligned_b32_be":    "process_xor_4",
+		"bits_unaligned_b32_le":    "process_xor_4",
+		"bits_unaligned_b64_be":    "process_xor_4",
+		"bits_unaligned_b64_le":    "process_xor_4",
+	}
+
+	for format, fixture := range fixtures {
+		t.Run(format, func(t *testing.T) {
+			schema := loadFormatSchema(t, format)
+			original, err := os.ReadFile("../../test/src/" + fixture + ".bin")
+			require.NoError(t, err)
+
+			parsed := parseBytes(t, schema, original)
+			data := ParsedDataToMap(parsed)
+			out, err := newTestSerializer(t, schema).Serialize(context.Background(), data.(map[string]any))
+			require.NoError(t, err)
+			require.EqualValues(t, parsed.Size, len(out))
+
+			// Bits skipped by alignment are written as zeros, so compare what the parser sees
+			assert.Equal(t, data, ParsedDataToMap(parseBytes(t, schema, out)))
+		})
+	}
+}
+
+func TestLayoutBuffer_WriteBits(t *testing.T) {
+	t.Run("big-endian", func(t *testing.T) {
+		buf := &layoutBuffer{fixedSize: -1, layout: newLayoutState()}
+		require.NoError(t, buf.writeBitsInt(3, 0b101, false))
+		require.NoError(t, buf.writeBitsInt(7, 0b1100110, false))
+		assert.Equal(t, 1, len(buf.Bytes()), "two bits are still pending")
+		buf.alignToByte()
+		assert.Equal(t, []byte{0b10111001, 0b10000000}, buf.Bytes())
+	})
+
+	t.Run("little-endian", func(t *testing.T) {
+		buf := &layoutBuffer{fixedSize: -1, layout: newLayoutState()}
+		require.NoError(t, buf.writeBitsInt(3, 0b101, true))
+		require.NoError(t, buf.writeBitsInt(7, 0b1100110, true))
+		buf.alignToByte()
+		assert.Equal(t, []byte{0b00110101, 0b00000011}, buf.Bytes())
+	})
+
+	t.Run("64 bits after a partial byte", func(t *testing.T) {
+		buf := &layoutBuffer{fixedSize: -1, layout: newLayoutState()}
+		require.NoError(t, buf.writeBitsInt(4, 0xA, false))
+		require.NoError(t, buf.writeBitsInt(64, 0x0123456789ABCDEF, false))
+		buf.alignToByte()
+		assert.Equal(t, []byte{0xA0, 0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0}, buf.Bytes())
+	})
+
+	t.Run("byte write aligns", func(t *testing.T) {
+		buf := &layoutBuffer{fixedSize: -1, layout: newLayoutState()}
+		require.NoError(t, buf.writeBitsInt(1, 1, false))
+		pos, err := buf.Seek(0, 1)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, pos, "the partial byte counts towards the position")
+		_, err = buf.Write([]byte{0xFF})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x80, 0xFF}, buf.Bytes())
+	})
+
+	t.Run("value too wide", func(t *testing.T) {
+		buf := &layoutBuffer{fixedSize: -1, layout: newLayoutState()}
+		err := buf.writeBitsInt(3, 8, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not fit in 3 bits")
+	})
+}
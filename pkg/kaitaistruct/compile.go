@@ -0,0 +1,123 @@
+package kaitaistruct
+
+import (
+	"fmt"
+	"sort"
+
+	internalCel "github.com/twinfer/kbin-plugin/internal/cel"
+)
+
+// CompileExpressions compiles every expression of the schema, such as sizes,
+// conditions and instance values, and returns the first one that doesn't
+// compile. Expressions are otherwise compiled when first evaluated, so this
+// catches mistakes in a schema before any data is parsed with it.
+func (s *KaitaiSchema) CompileExpressions() error {
+	pool, err := internalCel.NewExpressionPool()
+	if err != nil {
+		return fmt.Errorf("creating expression pool: %w", err)
+	}
+	c := &expressionCompiler{pool: pool}
+	if err := c.compileSeq(s.Meta.ID, s.Seq); err != nil {
+		return err
+	}
+	if err := c.compileInstances(s.Meta.ID, s.Instances); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(s.Types) {
+		t := s.Types[name]
+		if err := c.compileType(name, &t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expressionCompiler compiles the expressions of a schema's types
+type expressionCompiler struct {
+	pool *internalCel.ExpressionPool
+}
+
+// compile compiles expr, found in the given place of the schema
+func (c *expressionCompiler) compile(where, expr string) error {
+	if expr == "" {
+		return nil
+	}
+	if _, err := c.pool.GetExpression(expr); err != nil {
+		return fmt.Errorf("%s: %w", where, err)
+	}
+	return nil
+}
+
+// compileType compiles the expressions of t and its nested types
+func (c *expressionCompiler) compileType(name string, t *Type) error {
+	if err := c.compileSeq(name, t.Seq); err != nil {
+		return err
+	}
+	if err := c.compileInstances(name, t.Instances); err != nil {
+		return err
+	}
+	for _, nestedName := range sortedKeys(t.Types) {
+		if nested := t.Types[nestedName]; nested != nil {
+			if err := c.compileType(name+"::"+nestedName, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileSeq compiles the expressions of the fields of typeName
+func (c *expressionCompiler) compileSeq(typeName string, seq []SequenceItem) error {
+	for _, field := range seq {
+		where := fmt.Sprintf("field '%s' of type '%s'", field.ID, typeName)
+		exprs := []string{field.IfExpr, field.RepeatExpr, field.RepeatUntil, field.Value}
+		if size, ok := field.Size.(string); ok {
+			exprs = append(exprs, size)
+		}
+		if typeSwitch, ok := field.Type.(map[string]any); ok {
+			if switchOn, ok := typeSwitch["switch-on"].(string); ok {
+				exprs = append(exprs, switchOn)
+			}
+		}
+		if field.Valid != nil {
+			exprs = append(exprs, field.Valid.Expr)
+		}
+		for _, expr := range exprs {
+			if err := c.compile(where, expr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileInstances compiles the expressions of the instances of typeName
+func (c *expressionCompiler) compileInstances(typeName string, instances map[string]InstanceDef) error {
+	for _, id := range sortedKeys(instances) {
+		inst := instances[id]
+		where := fmt.Sprintf("instance '%s' of type '%s'", id, typeName)
+		exprs := []string{inst.Value, inst.IfExpr, inst.RepeatExpr}
+		if pos, ok := inst.Pos.(string); ok {
+			exprs = append(exprs, pos)
+		}
+		if size, ok := inst.Size.(string); ok {
+			exprs = append(exprs, size)
+		}
+		for _, expr := range exprs {
+			if err := c.compile(where, expr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, so errors are reported deterministically
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package kaitaistruct
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileExpressions(t *testing.T) {
+	compile := func(t *testing.T, ksy string) error {
+		t.Helper()
+		schema, err := NewKaitaiSchemaFromYAML([]byte(ksy))
+		require.NoError(t, err)
+		return schema.CompileExpressions()
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		err := compile(t, `
+meta:
+  id: valid_exprs
+seq:
+  - id: len
+    type: u1
+  - id: body
+    size: len - 1
+    if: len > 0
+  - id: items
+    type: u1
+    repeat: expr
+    repeat-expr: len * 2
+  - id: kind
+    type:
+      switch-on: len
+      cases:
+        1: u1
+        _: u2
+instances:
+  double_len:
+    value: len * 2
+types:
+  inner:
+    seq:
+      - id: x
+        type: u1
+        valid:
+          expr: _ < 10
+`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Broken_Field_Expression", func(t *testing.T) {
+		err := compile(t, `
+meta:
+  id: broken_field
+seq:
+  - id: len
+    type: u1
+  - id: body
+    size: len -
+`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "field 'body' of type 'broken_field'")
+	})
+
+	t.Run("Broken_Nested_Instance", func(t *testing.T) {
+		err := compile(t, `
+meta:
+  id: broken_instance
+seq:
+  - id: a
+    type: outer
+types:
+  outer:
+    seq:
+      - id: b
+        type: u1
+    types:
+      inner:
+        instances:
+          bad:
+            value: (b +
+`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "instance 'bad' of type 'outer::inner'")
+	})
+}
@@ -0,0 +1,113 @@
+package kaitaistruct
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+const (
+	conformanceTestDir = "./testdata/kaitaistruct/formats_test"
+	complianceMatrix   = "../../testdata/kaitaistruct/COMPLIANCE.md"
+)
+
+// conformanceResults runs the generated conformance tests, which live under
+// testdata and so aren't part of ./..., and returns the outcome of each top-level test
+func conformanceResults(t *testing.T) map[string]string {
+	cmd := exec.Command("go", "test", "-json", "-count=1", conformanceTestDir)
+	cmd.Dir = "../.."
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Failing tests are expected; only missing output means the run itself failed
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		t.Fatalf("running conformance tests: %v\n%s", err, stderr.String())
+	}
+
+	results := make(map[string]string)
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev struct{ Action, Test string }
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil || ev.Test == "" || strings.Contains(ev.Test, "/") {
+			continue
+		}
+		switch ev.Action {
+		case "pass", "fail", "skip":
+			results[ev.Test] = ev.Action
+		}
+	}
+	if len(results) == 0 {
+		t.Fatalf("no conformance test results, the generated tests may not compile:\n%s", stdout.String())
+	}
+	return results
+}
+
+// conformanceTestName returns the name suffix the test generator gives a format
+func conformanceTestName(format string) string {
+	var name strings.Builder
+	upper := true
+	for _, r := range format {
+		if r == '_' || r == '-' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
+
+// TestConformance_Matrix runs the tests generated from the KSC test suite and
+// checks every outcome against COMPLIANCE.md, which serves as the list of known
+// failures. Regressions fail, and so do formats that started passing, so the
+// matrix has to be regenerated with the fix.
+func TestConformance_Matrix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the generated conformance tests in a separate go test process")
+	}
+	matrix, err := os.ReadFile(complianceMatrix)
+	if err != nil {
+		t.Fatalf("reading compliance matrix: %v", err)
+	}
+	results := conformanceResults(t)
+
+	for _, line := range strings.Split(string(matrix), "\n") {
+		cells := strings.Split(line, "|")
+		if len(cells) < 5 {
+			continue
+		}
+		format := strings.TrimSpace(cells[1])
+		if format == "" || format == "Format" || strings.HasPrefix(format, "---") {
+			continue
+		}
+		name := conformanceTestName(format)
+		for _, check := range []struct{ test, want string }{
+			{"TestParse_" + name, strings.TrimSpace(cells[2])},
+			{"TestSerialize_" + name, strings.TrimSpace(cells[3])},
+		} {
+			if check.want == "-" {
+				continue
+			}
+			got := results[check.test]
+			if got == "" {
+				got = "fail"
+			}
+			switch {
+			case got == check.want:
+			case check.want == "fail" || check.want == "skip":
+				t.Errorf("%s: %s now, recorded as %s; regenerate COMPLIANCE.md", check.test, got, check.want)
+			default:
+				t.Errorf("%s: %s, recorded as %s", check.test, got, check.want)
+			}
+		}
+	}
+}
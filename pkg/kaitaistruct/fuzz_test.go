@@ -0,0 +1,119 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+)
+
+// fuzzFormats are official formats the fuzz targets parse arbitrary input with.
+// They cover user types, bit fields, terminated strings, repeats, switches,
+// processing, positional instances and substreams.
+var fuzzFormats = []string{
+	"bcd_user_type_be",
+	"bits_simple",
+	"buffered_struct",
+	"enum_0",
+	"expr_io_eof",
+	"if_struct",
+	"instance_std",
+	"position_abs",
+	"repeat_eos_struct",
+	"repeat_n_strz",
+	"repeat_until_complex",
+	"str_pad_term",
+	"switch_integers",
+	"term_strz",
+	"zlib_with_header_78",
+}
+
+// fuzzLimits keep a crafted length or count from exhausting memory or time
+var fuzzLimits = Limits{MaxDepth: 64, MaxRepeatItems: 10000, MaxAllocation: 1 << 20, MaxNodes: 100000}
+
+// loadFuzzSchemas loads fuzzFormats and seeds the corpus with each format's fixture
+func loadFuzzSchemas(f *testing.F) []*KaitaiSchema {
+	schemas := make([]*KaitaiSchema, len(fuzzFormats))
+	for i, format := range fuzzFormats {
+		yamlData, err := os.ReadFile(corpusFormatsDir + "/" + format + ".ksy")
+		if err != nil {
+			f.Fatal(err)
+		}
+		if schemas[i], err = NewKaitaiSchemaFromYAML(yamlData); err != nil {
+			f.Fatalf("%s: %v", format, err)
+		}
+		if fixture, ok := corpusFixture(format); ok {
+			data, err := os.ReadFile(fixture)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(uint8(i), data)
+		}
+	}
+	return schemas
+}
+
+// fuzzParse parses data with limits and a deadline
+func fuzzParse(t *testing.T, schema *KaitaiSchema, data []byte) (*ParsedData, error) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	interp, err := NewKaitaiInterpreter(schema, logger, WithLimits(fuzzLimits))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return interp.Parse(ctx, kaitai.NewStream(bytes.NewReader(data)))
+}
+
+// FuzzParse checks that arbitrary input fails cleanly instead of panicking,
+// hanging or allocating without bound
+func FuzzParse(f *testing.F) {
+	schemas := loadFuzzSchemas(f)
+	f.Fuzz(func(t *testing.T, format uint8, data []byte) {
+		schema := schemas[int(format)%len(schemas)]
+		parsed, err := fuzzParse(t, schema, data)
+		if err == nil {
+			ParsedDataToMap(parsed)
+		}
+	})
+}
+
+// FuzzSerializeAfterParse checks that whatever parses serializes to bytes that
+// parse back to the same data
+func FuzzSerializeAfterParse(f *testing.F) {
+	schemas := loadFuzzSchemas(f)
+	f.Fuzz(func(t *testing.T, format uint8, data []byte) {
+		schema := schemas[int(format)%len(schemas)]
+		parsed, err := fuzzParse(t, schema, data)
+		if err != nil {
+			return
+		}
+		input, ok := ParsedDataToMap(parsed).(map[string]any)
+		if !ok {
+			return
+		}
+
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		serializer, err := NewKaitaiSerializer(schema, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := serializer.Serialize(context.Background(), input)
+		if err != nil {
+			t.Fatalf("%s: serializing parsed data: %v", fuzzFormats[int(format)%len(schemas)], err)
+		}
+		reparsed, err := fuzzParse(t, schema, out)
+		if err != nil {
+			t.Fatalf("%s: parsing serialized data % x: %v", fuzzFormats[int(format)%len(schemas)], out, err)
+		}
+		if got := ParsedDataToMap(reparsed); !reflect.DeepEqual(got, input) {
+			t.Fatalf("%s: round trip changed the data\ninput:    %#v\nreparsed: %#v", fuzzFormats[int(format)%len(schemas)], input, got)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package kaitaistruct
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ImportLoader reads the spec at a slash-separated path ending in `.ksy`,
+// from the same source (directory, fs.FS, cache...) as the importing schema
+type ImportLoader func(path string) ([]byte, error)
+
+// ResolveImports loads the specs listed in `meta.imports`, directly or
+// indirectly, and adds each one as a type named by its `meta.id`, so fields
+// can use it as `type: <id>`. schemaPath is the slash-separated path of s:
+// relative imports are resolved against the directory of the importing spec,
+// and absolute imports (`/common/vlq_base128_le`) are looked up in
+// importPaths, in order, as KSC does.
+//
+// The interpreter applies endianness, bit endianness and encoding
+// schema-wide, so an imported spec declaring different ones is rejected.
+func (s *KaitaiSchema) ResolveImports(schemaPath string, load ImportLoader, importPaths ...string) error {
+	r := &importResolver{
+		root:        s,
+		load:        load,
+		importPaths: importPaths,
+		seen:        map[string]bool{path.Clean(schemaPath): true},
+	}
+	return r.resolve(schemaPath, s.Meta.Imports)
+}
+
+// importResolver merges imported specs into a root schema
+type importResolver struct {
+	root        *KaitaiSchema
+	load        ImportLoader
+	importPaths []string
+	seen        map[string]bool // Specs already merged (or being merged), which may import each other
+}
+
+// resolve merges the specs imported by the spec at importer
+func (r *importResolver) resolve(importer string, imports []string) error {
+	for _, imp := range imports {
+		file, data, err := r.read(importer, imp)
+		if err != nil {
+			return fmt.Errorf("import '%s' of '%s': %w", imp, importer, err)
+		}
+		if data == nil {
+			continue // Already merged
+		}
+		r.seen[file] = true
+		imported, err := NewKaitaiSchemaFromYAML(data)
+		if err != nil {
+			return fmt.Errorf("import '%s' of '%s': parsing '%s': %w", imp, importer, file, err)
+		}
+		if err := r.add(imported); err != nil {
+			return fmt.Errorf("import '%s' of '%s': %w", imp, importer, err)
+		}
+		if err := r.resolve(file, imported.Meta.Imports); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read finds and loads an import. It returns no data for a spec already merged.
+func (r *importResolver) read(importer, imp string) (string, []byte, error) {
+	var candidates []string
+	if strings.HasPrefix(imp, "/") {
+		if len(r.importPaths) == 0 {
+			return "", nil, fmt.Errorf("absolute import needs an import path")
+		}
+		for _, dir := range r.importPaths {
+			candidates = append(candidates, path.Join(dir, imp+".ksy"))
+		}
+	} else {
+		candidates = []string{path.Join(path.Dir(importer), imp+".ksy")}
+	}
+
+	var lastErr error
+	for _, file := range candidates {
+		if r.seen[file] {
+			return file, nil, nil
+		}
+		data, err := r.load(file)
+		if err == nil {
+			return file, data, nil
+		}
+		lastErr = err
+	}
+	return "", nil, lastErr
+}
+
+// add adds imported to the root schema as a type named by its meta.id
+func (r *importResolver) add(imported *KaitaiSchema) error {
+	id := imported.Meta.ID
+	if id == "" {
+		return fmt.Errorf("imported spec has no meta.id")
+	}
+	settings := []struct {
+		name         string
+		root, theirs *string
+	}{
+		{"endian", &r.root.Meta.Endian, &imported.Meta.Endian},
+		{"bit-endian", &r.root.Meta.BitEndian, &imported.Meta.BitEndian},
+		{"encoding", &r.root.Meta.Encoding, &imported.Meta.Encoding},
+	}
+	for _, setting := range settings {
+		switch {
+		case *setting.theirs == "" || *setting.theirs == *setting.root:
+		case *setting.root == "":
+			// Nothing in the root schema relies on a default it doesn't declare
+			*setting.root = *setting.theirs
+		default:
+			return fmt.Errorf("'%s' uses %s %s, but the importing schema uses %s", id, setting.name, *setting.theirs, *setting.root)
+		}
+	}
+
+	if r.root.Types == nil {
+		r.root.Types = make(map[string]Type)
+	}
+	if _, exists := r.root.Types[id]; exists {
+		return nil // A type of the root schema takes precedence
+	}
+	nested := make(map[string]*Type, len(imported.Types))
+	for name, t := range imported.Types {
+		nested[name] = &t
+	}
+	r.root.Types[id] = Type{
+		Seq:       imported.Seq,
+		Types:     nested,
+		Instances: imported.Instances,
+		Enums:     imported.Enums,
+		Params:    imported.Params,
+		Doc:       imported.Doc,
+		DocRef:    imported.DocRef,
+	}
+
+	// Enums are looked up by their last name segment, so `imported::animal` finds them here
+	for name, enum := range imported.Enums {
+		if _, exists := r.root.Enums[name]; !exists {
+			if r.root.Enums == nil {
+				r.root.Enums = make(map[string]EnumDef)
+			}
+			r.root.Enums[name] = enum
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"specs/main.ksy": {Data: []byte(`
+meta:
+  id: main
+  endian: le
+  imports:
+    - common/header
+    - /shared/vlq
+seq:
+  - id: header
+    type: header
+  - id: len
+    type: vlq
+`)},
+		"specs/common/header.ksy": {Data: []byte(`
+meta:
+  id: header
+  imports:
+    - kind
+seq:
+  - id: magic
+    type: u2
+  - id: kind
+    type: u1
+    enum: kind
+enums:
+  kind:
+    1: data
+`)},
+		"specs/common/kind.ksy": {Data: []byte(`
+meta:
+  id: kind
+  imports:
+    - header
+`)},
+		"lib/shared/vlq.ksy": {Data: []byte(`
+meta:
+  id: vlq
+seq:
+  - id: value
+    type: u1
+`)},
+		"specs/big_endian.ksy": {Data: []byte(`
+meta:
+  id: big_endian
+  endian: be
+`)},
+	}
+	load := func(path string) ([]byte, error) { return fs.ReadFile(fsys, path) }
+	loadSchema := func(t *testing.T, path string) *KaitaiSchema {
+		t.Helper()
+		data, err := fs.ReadFile(fsys, path)
+		require.NoError(t, err)
+		schema, err := NewKaitaiSchemaFromYAML(data)
+		require.NoError(t, err)
+		return schema
+	}
+
+	t.Run("Relative_Absolute_And_Circular", func(t *testing.T) {
+		schema := loadSchema(t, "specs/main.ksy")
+		require.NoError(t, schema.ResolveImports("specs/main.ksy", load, "lib"))
+		assert.Contains(t, schema.Types, "header")
+		assert.Contains(t, schema.Types, "kind")
+		assert.Contains(t, schema.Types, "vlq")
+
+		interp, err := NewKaitaiInterpreter(schema, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		require.NoError(t, err)
+		parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{0x34, 0x12, 0x01, 0x07})))
+		require.NoError(t, err)
+		result := ParsedDataToMap(parsed).(map[string]any)
+		header := result["header"].(map[string]any)
+		assert.Equal(t, int64(0x1234), header["magic"])
+		assert.Equal(t, "data", header["kind"].(map[string]any)["name"])
+		assert.Equal(t, map[string]any{"value": int64(7)}, result["len"])
+	})
+
+	t.Run("Missing_Import", func(t *testing.T) {
+		schema, err := NewKaitaiSchemaFromYAML([]byte("meta:\n  id: x\n  imports:\n    - nowhere\n"))
+		require.NoError(t, err)
+		err = schema.ResolveImports("specs/x.ksy", load)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "import 'nowhere' of 'specs/x.ksy'")
+	})
+
+	t.Run("Absolute_Import_Without_Import_Paths", func(t *testing.T) {
+		schema, err := NewKaitaiSchemaFromYAML([]byte("meta:\n  id: x\n  imports:\n    - /shared/vlq\n"))
+		require.NoError(t, err)
+		require.Error(t, schema.ResolveImports("specs/x.ksy", load))
+	})
+
+	t.Run("Conflicting_Endianness", func(t *testing.T) {
+		schema, err := NewKaitaiSchemaFromYAML([]byte("meta:\n  id: x\n  endian: le\n  imports:\n    - big_endian\n"))
+		require.NoError(t, err)
+		err = schema.ResolveImports("specs/x.ksy", load)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "endian be")
+	})
+}
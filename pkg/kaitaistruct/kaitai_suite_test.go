@@ -3,6 +3,7 @@ package kaitaistruct
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
@@ -447,6 +448,41 @@ instances:
 	assert.Equal(t, int64(4), dataMap["sizeof_u4"])
 }
 
+// TestKaitaiSuite_ExprSizeofUserTypePerSchema tests that sizeof<type> resolves
+// user types against the interpreter's own schema when several are in use
+func TestKaitaiSuite_ExprSizeofUserTypePerSchema(t *testing.T) {
+	newInterpreter := func(recordType string) *KaitaiInterpreter {
+		schema, err := NewKaitaiSchemaFromYAML([]byte(fmt.Sprintf(`
+meta:
+  id: sizes
+  endian: le
+instances:
+  sizeof_record:
+    value: sizeof<record>
+types:
+  record:
+    seq:
+      - id: value
+        type: %s
+`, recordType)))
+		require.NoError(t, err)
+		interpreter, err := NewKaitaiInterpreter(schema, nil)
+		require.NoError(t, err)
+		return interpreter
+	}
+	// Created in turn, as workers and layers do
+	wide := newInterpreter("u4")
+	narrow := newInterpreter("u2")
+
+	for interpreter, want := range map[*KaitaiInterpreter]int64{wide: 4, narrow: 2} {
+		result, err := interpreter.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(nil)))
+		require.NoError(t, err)
+		dataMap, ok := ParsedDataToMap(result).(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, want, dataMap["sizeof_record"])
+	}
+}
+
 // TestKaitaiSuite_ExprIoPos tests I/O position expressions with nested types from Kaitai test suite
 func TestKaitaiSuite_ExprIoPos(t *testing.T) {
 	yamlContent := `
@@ -0,0 +1,492 @@
+package kaitaistruct
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+)
+
+// maxLayoutPasses bounds how often serialization is repeated while sizes read
+// through `_io.size` or `_sizeof` are still changing
+const maxLayoutPasses = 4
+
+// layoutState carries stream and type sizes between serialization passes.
+// Every stream and user type instance gets an ordinal in creation order, which
+// is stable between passes as long as the data lays out the same way.
+type layoutState struct {
+	nextID int
+	known  map[int]int64 // Final sizes measured by the previous pass
+	used   map[int]int64 // Sizes handed out to expressions during this pass
+	final  map[int]int64 // Sizes measured at the end of this pass
+}
+
+func newLayoutState() *layoutState {
+	return &layoutState{
+		known: make(map[int]int64),
+		used:  make(map[int]int64),
+		final: make(map[int]int64),
+	}
+}
+
+// startPass prepares for another serialization pass, using the sizes measured so far
+func (l *layoutState) startPass() {
+	if len(l.final) > 0 {
+		l.known = l.final
+	}
+	l.nextID = 0
+	l.used = make(map[int]int64)
+	l.final = make(map[int]int64)
+}
+
+// newID returns the ordinal of the next stream or type
+func (l *layoutState) newID() int {
+	l.nextID++
+	return l.nextID
+}
+
+// size returns the size of id as seen by expressions, preferring the final size
+// from the previous pass over the number of bytes written so far
+func (l *layoutState) size(id int, current int64) int64 {
+	size, ok := l.known[id]
+	if !ok {
+		size = current
+	}
+	l.used[id] = size
+	return size
+}
+
+// record stores the final size of id for this pass
+func (l *layoutState) record(id int, size int64) {
+	l.final[id] = size
+}
+
+// provisional reports whether an expression in this pass saw a guessed size
+func (l *layoutState) provisional() bool {
+	for id := range l.used {
+		if _, ok := l.known[id]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// converged reports whether every size handed out matched the final size
+func (l *layoutState) converged() bool {
+	for id, size := range l.used {
+		if l.final[id] != size {
+			return false
+		}
+	}
+	return true
+}
+
+// layoutBuffer is a seekable byte buffer backing a serialization stream. Writes
+// land at the cursor, overwriting existing bytes and zero-filling any gap, so
+// positional instances and back-patched fields can be written out of order.
+// Through kaitai.NewStream it also serves as `_io`, where the size reported for
+// unsized streams comes from the layout state.
+type layoutBuffer struct {
+	data      []byte
+	pos       int64
+	fixedSize int64 // Declared size of a sized substream, -1 if the size follows the data
+	id        int
+	layout    *layoutState
+	deferred  []func() error // Placements run once the stream's own content is written
+	bitRun    bitRun         // Bits of an unaligned bit field run not yet written
+}
+
+// newLayoutBuffer creates a stream; fixedSize is -1 unless the stream has a declared size
+func (k *KaitaiSerializer) newLayoutBuffer(fixedSize int64) *layoutBuffer {
+	return &layoutBuffer{fixedSize: fixedSize, id: k.layout.newID(), layout: k.layout}
+}
+
+// Write implements io.Writer at the current cursor. Byte-aligned data ends a
+// pending bit run, so its last partial byte is written first.
+func (b *layoutBuffer) Write(p []byte) (int, error) {
+	b.alignToByte()
+	b.put(p)
+	return len(p), nil
+}
+
+// put writes p at the cursor
+func (b *layoutBuffer) put(p []byte) {
+	end := b.pos + int64(len(p))
+	if end > int64(len(b.data)) {
+		b.data = append(b.data, make([]byte, end-int64(len(b.data)))...)
+	}
+	copy(b.data[b.pos:end], p)
+	b.pos = end
+}
+
+// Read implements io.Reader so the buffer can back a kaitai.Stream. A sized
+// substream reads as zero-filled up to its declared size, where it gets padded
+// to, so `_io.eof` holds only at its end.
+func (b *layoutBuffer) Read(p []byte) (int, error) {
+	end := max(int64(len(b.data)), b.fixedSize)
+	if b.pos >= end {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(p)), end-b.pos))
+	clear(p[:n])
+	if b.pos < int64(len(b.data)) {
+		copy(p[:n], b.data[b.pos:])
+	}
+	b.pos += int64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker. Seeking relative to the end uses the stream size
+// expressions should see, which is how kaitai.Stream.Size() measures it.
+func (b *layoutBuffer) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent && offset == 0 && b.bitRun.count > 0 {
+		// Reading the position doesn't end a bit run; like kaitai.Stream it counts the partial byte
+		return b.pos + 1, nil
+	}
+	b.alignToByte()
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = b.pos
+	case io.SeekEnd:
+		base = b.size()
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if base+offset < 0 {
+		return 0, fmt.Errorf("negative position %d", base+offset)
+	}
+	b.pos = base + offset
+	return b.pos, nil
+}
+
+// size returns the stream size as seen by `_io.size`
+func (b *layoutBuffer) size() int64 {
+	if b.fixedSize >= 0 {
+		return b.fixedSize
+	}
+	return b.layout.size(b.id, int64(len(b.data)))
+}
+
+// Len returns the number of bytes written so far, including gaps and a partial bit byte
+func (b *layoutBuffer) Len() int {
+	if b.bitRun.count > 0 && b.pos == int64(len(b.data)) {
+		return len(b.data) + 1
+	}
+	return len(b.data)
+}
+
+// Bytes returns the buffer contents
+func (b *layoutBuffer) Bytes() []byte {
+	return b.data
+}
+
+// finish runs deferred placements, which may queue further ones, and records the final size
+func (b *layoutBuffer) finish() error {
+	b.alignToByte()
+	for i := 0; i < len(b.deferred); i++ {
+		if err := b.deferred[i](); err != nil {
+			return err
+		}
+	}
+	b.deferred = nil
+	b.layout.record(b.id, int64(len(b.data)))
+	return nil
+}
+
+// streamContext returns a copy of sCtx that writes to buf and exposes it as `_io`
+func streamContext(sCtx *SerializeContext, buf *layoutBuffer) *SerializeContext {
+	streamCtx := *sCtx
+	streamCtx.Writer = kaitai.NewWriter(buf)
+	streamCtx.IO = kaitai.NewStream(buf)
+	return &streamCtx
+}
+
+// layoutBufferOf returns the layout buffer behind a context's stream
+func layoutBufferOf(sCtx *SerializeContext) (*layoutBuffer, error) {
+	if sCtx.Writer != nil {
+		if buf, ok := sCtx.Writer.Writer.(*layoutBuffer); ok {
+			return buf, nil
+		}
+	}
+	return nil, fmt.Errorf("stream does not support positioning")
+}
+
+// serializeSizedType serializes a user type with a `size` into its own
+// substream, then pads it to the declared size and writes it to the parent stream
+func (k *KaitaiSerializer) serializeSizedType(goCtx context.Context, field SequenceItem, data any, sCtx *SerializeContext) error {
+	size, err := k.evaluateSizeSpec(goCtx, field.Size, sCtx)
+	if err != nil {
+		return fmt.Errorf("evaluating size for field '%s': %w", field.ID, err)
+	}
+
+	sub := k.newLayoutBuffer(size)
+	unsized := field
+	unsized.Size = nil
+	if err := k.serializeField(goCtx, unsized, data, streamContext(sCtx, sub)); err != nil {
+		return err
+	}
+	if err := sub.finish(); err != nil {
+		return fmt.Errorf("laying out substream of field '%s': %w", field.ID, err)
+	}
+
+	content := sub.Bytes()
+	if int64(len(content)) > size {
+		return fmt.Errorf("data for field '%s' is %d bytes, larger than its size %d", field.ID, len(content), size)
+	}
+	content = append(content, make([]byte, size-int64(len(content)))...)
+	if err := sCtx.Writer.WriteBytes(content); err != nil {
+		return fmt.Errorf("writing substream of field '%s': %w", field.ID, err)
+	}
+	return nil
+}
+
+// evaluateSizeSpec resolves a `size` attribute, which is either a constant or an expression
+func (k *KaitaiSerializer) evaluateSizeSpec(goCtx context.Context, sizeSpec any, sCtx *SerializeContext) (int64, error) {
+	var value any = sizeSpec
+	if expr, ok := sizeSpec.(string); ok {
+		result, err := k.evaluateExpression(goCtx, expr, sCtx)
+		if err != nil {
+			return 0, err
+		}
+		value = result
+	}
+	size, ok := toInt64(value)
+	if !ok {
+		return 0, fmt.Errorf("size is not a number: %v (type %T)", value, value)
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("negative size %d", size)
+	}
+	return size, nil
+}
+
+// positionalPatch describes a sequence field that holds the offset of a
+// positional instance and is written once that offset is known
+type positionalPatch struct {
+	field   string
+	inverse inverseFunc
+}
+
+// positionalInstanceNames returns the names of `pos:` instances in a stable order
+func positionalInstanceNames(instances map[string]InstanceDef) []string {
+	var names []string
+	for name, inst := range instances {
+		if inst.Pos != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// positionalInstanceItems returns the `pos:` instances as the fields written at their positions
+func positionalInstanceItems(instances map[string]InstanceDef) []SequenceItem {
+	var items []SequenceItem
+	for _, name := range positionalInstanceNames(instances) {
+		items = append(items, instances[name].asSequenceItem(name))
+	}
+	return items
+}
+
+// planPositionalPatches finds positional instances whose `pos` is a sequence
+// field (or simple arithmetic of one) that the input leaves out, or that is
+// always computed because auto-compute is enabled. Those fields are written as
+// zero placeholders and back-patched after the instance is placed at the end of
+// the stream. The returned map is keyed by instance name.
+func (k *KaitaiSerializer) planPositionalPatches(goCtx context.Context, typeName string, sequence []SequenceItem, instances map[string]InstanceDef, typeCtx *SerializeContext) map[string]positionalPatch {
+	patches := make(map[string]positionalPatch)
+	for _, name := range positionalInstanceNames(instances) {
+		posExpr, ok := instances[name].Pos.(string)
+		if !ok {
+			continue
+		}
+		if _, hasData := typeCtx.Children[name]; !hasData {
+			continue
+		}
+		ast, err := parseKaitaiExpression(posExpr)
+		if err != nil {
+			continue
+		}
+		target, inverse, err := invertExpression(ast)
+		if err != nil || !slices.ContainsFunc(sequence, func(seq SequenceItem) bool { return seq.ID == target }) {
+			continue
+		}
+		if _, present := typeCtx.Children[target]; present && !k.autoCompute {
+			continue
+		}
+
+		k.logger.DebugContext(goCtx, "Offset field will be back-patched", "type_name", typeName, "field_id", target, "instance_name", name)
+		patches[name] = positionalPatch{field: target, inverse: inverse}
+	}
+
+	if len(patches) > 0 {
+		// Placeholders must not leak into the caller's data
+		children := maps.Clone(typeCtx.Children)
+		for _, patch := range patches {
+			children[patch.field] = int64(0)
+		}
+		typeCtx.Children = children
+		typeCtx.Value = children
+	}
+	return patches
+}
+
+// serializePositionalInstances writes the `pos:` instances of a type that have
+// data. Instances with an explicit position are written there right away; those
+// whose offset field is back-patched are appended once the stream is complete.
+func (k *KaitaiSerializer) serializePositionalInstances(goCtx context.Context, typeName string, sequence []SequenceItem, instances map[string]InstanceDef, patches map[string]positionalPatch, typeCtx *SerializeContext) error {
+	names := positionalInstanceNames(instances)
+	if len(names) == 0 {
+		return nil
+	}
+	buf, err := layoutBufferOf(typeCtx)
+	if err != nil {
+		return fmt.Errorf("writing positional instances of type '%s': %w", typeName, err)
+	}
+
+	for _, name := range names {
+		data, ok := typeCtx.Children[name]
+		if !ok || data == nil {
+			k.logger.DebugContext(goCtx, "No data for positional instance, skipping", "type_name", typeName, "instance_name", name)
+			continue
+		}
+		inst := instances[name]
+		item := inst.asSequenceItem(name)
+
+		if patch, ok := patches[name]; ok {
+			// Nested types resolve against the type stack, which has unwound by the time the stream is finished
+			typeStack, path := slices.Clone(k.typeStack), slices.Clone(k.path)
+			buf.deferred = append(buf.deferred, func() error {
+				savedStack, savedPath := k.typeStack, k.path
+				k.typeStack, k.path = typeStack, path
+				defer func() { k.typeStack, k.path = savedStack, savedPath }()
+
+				pos := int64(buf.Len())
+				if err := k.backPatchField(goCtx, sequence, patch, pos, buf, typeCtx); err != nil {
+					return fmt.Errorf("back-patching offset of instance '%s' in type '%s': %w", name, typeName, err)
+				}
+				return k.writeAt(goCtx, item, data, pos, buf, typeCtx)
+			})
+			continue
+		}
+
+		pos, err := k.evaluatePosition(goCtx, inst.Pos, typeCtx)
+		if err != nil {
+			return fmt.Errorf("evaluating pos of instance '%s' in type '%s': %w", name, typeName, err)
+		}
+		if err := k.writeAt(goCtx, item, data, pos, buf, typeCtx); err != nil {
+			return fmt.Errorf("writing instance '%s' of type '%s' at %d: %w", name, typeName, pos, err)
+		}
+	}
+	return nil
+}
+
+// evaluatePosition resolves a `pos` attribute. A negative position is only
+// tolerated while sizes are still being guessed, and maps to the end of the
+// stream until a later pass supplies the real size.
+func (k *KaitaiSerializer) evaluatePosition(goCtx context.Context, posSpec any, sCtx *SerializeContext) (int64, error) {
+	var value any = posSpec
+	if expr, ok := posSpec.(string); ok {
+		result, err := k.evaluateExpression(goCtx, expr, sCtx)
+		if err != nil {
+			return 0, err
+		}
+		value = result
+	}
+	pos, ok := toInt64(value)
+	if !ok {
+		return 0, fmt.Errorf("position is not a number: %v (type %T)", value, value)
+	}
+	if pos < 0 {
+		if !k.layout.provisional() {
+			return 0, fmt.Errorf("negative position %d", pos)
+		}
+		buf, err := layoutBufferOf(sCtx)
+		if err != nil {
+			return 0, err
+		}
+		pos = int64(buf.Len())
+	}
+	return pos, nil
+}
+
+// writeAt serializes item at pos and restores the cursor afterwards
+func (k *KaitaiSerializer) writeAt(goCtx context.Context, item SequenceItem, data any, pos int64, buf *layoutBuffer, sCtx *SerializeContext) error {
+	k.logger.DebugContext(goCtx, "Writing positional instance", "instance_name", item.ID, "pos", pos)
+	defer k.enterPath(item.ID)()
+	saved := buf.pos
+	buf.pos = pos
+	defer func() { buf.pos = saved }()
+	return k.serializeField(goCtx, item, data, sCtx)
+}
+
+// backPatchField rewrites an offset field in place once the position it refers to is known
+func (k *KaitaiSerializer) backPatchField(goCtx context.Context, sequence []SequenceItem, patch positionalPatch, pos int64, buf *layoutBuffer, typeCtx *SerializeContext) error {
+	value, err := patch.inverse(pos)
+	if err != nil {
+		return err
+	}
+	span, ok := typeCtx.offsets[patch.field]
+	if !ok {
+		return fmt.Errorf("field '%s' was not written", patch.field)
+	}
+	idx := slices.IndexFunc(sequence, func(seq SequenceItem) bool { return seq.ID == patch.field })
+	k.logger.DebugContext(goCtx, "Back-patching offset field", "field_id", patch.field, "value", value, "at", span[0])
+
+	saved := buf.pos
+	buf.pos = span[0]
+	defer func() { buf.pos = saved }()
+	if err := k.serializeField(goCtx, sequence[idx], value, typeCtx); err != nil {
+		return err
+	}
+	if buf.pos != span[1] {
+		return fmt.Errorf("field '%s' changed size from %d to %d bytes", patch.field, span[1]-span[0], buf.pos-span[0])
+	}
+	typeCtx.Children[patch.field] = value
+	return nil
+}
+
+// toInt64 converts numeric values, including Kaitai types, to int64. Floats
+// with a fraction and unsigned values above math.MaxInt64 don't convert.
+func toInt64(value any) (int64, bool) {
+	if kt, ok := value.(kaitaicel.KaitaiType); ok {
+		value = kt.Value()
+	}
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		// -2^63 converts exactly, while 2^63 rounds to an out-of-range int64
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
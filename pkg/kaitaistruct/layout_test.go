@@ -0,0 +1,237 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+)
+
+func loadFormatSchema(t *testing.T, name string) *KaitaiSchema {
+	yamlData, err := os.ReadFile("../../test/formats/" + name + ".ksy")
+	require.NoError(t, err)
+	schema, err := NewKaitaiSchemaFromYAML(yamlData)
+	require.NoError(t, err)
+	return schema
+}
+
+func parseBytes(t *testing.T, schema *KaitaiSchema, data []byte) *ParsedData {
+	parsed, err := newTestInterpreter(t, schema).Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+	require.NoError(t, err)
+	return parsed
+}
+
+func TestSerialize_PositionalInstances(t *testing.T) {
+	ctx := context.Background()
+	headerSchema := &KaitaiSchema{
+		Meta: Meta{ID: "pos_header", Endian: "le"},
+		Seq: []SequenceItem{
+			{ID: "ofs_data", Type: "u4"},
+			{ID: "len_data", Type: "u2"},
+		},
+		Instances: map[string]InstanceDef{
+			"data": {Pos: "ofs_data", Size: "len_data", Type: "bytes"},
+		},
+	}
+
+	t.Run("offset field back-patched", func(t *testing.T) {
+		data := map[string]any{"len_data": 3, "data": []byte{0xAA, 0xBB, 0xCC}}
+		out, err := newTestSerializer(t, headerSchema).Serialize(ctx, data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{6, 0, 0, 0, 3, 0, 0xAA, 0xBB, 0xCC}, out)
+		_, leaked := data["ofs_data"]
+		assert.False(t, leaked, "back-patched values must not be written into the caller's map")
+
+		parsed := parseBytes(t, headerSchema, out)
+		assert.Equal(t, []byte{0xAA, 0xBB, 0xCC}, getUnderlyingValue(getParsedValue(t, parsed, "data")))
+	})
+
+	t.Run("explicit offset leaves a gap", func(t *testing.T) {
+		out, err := newTestSerializer(t, headerSchema).Serialize(ctx, map[string]any{
+			"ofs_data": 8,
+			"len_data": 2,
+			"data":     []byte{1, 2},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{8, 0, 0, 0, 2, 0, 0, 0, 1, 2}, out)
+	})
+
+	t.Run("offset and length with auto-compute", func(t *testing.T) {
+		out, err := newAutoComputeSerializer(t, headerSchema).Serialize(ctx, map[string]any{
+			"ofs_data": 99,
+			"data":     []byte("hello"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{6, 0, 0, 0, 5, 0}, "hello"...), out)
+	})
+
+	t.Run("constant position", func(t *testing.T) {
+		schema := loadFormatSchema(t, "instance_std")
+		out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{"header": "Hello"})
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{0, 0}, "Hello"...), out)
+	})
+
+	t.Run("user type instance in nested type", func(t *testing.T) {
+		schema := loadFormatSchema(t, "position_abs")
+		out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+			"index": map[string]any{"entry": "foo"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{4, 0, 0, 0}, "foo\x00"...), out)
+
+		parsed := parseBytes(t, schema, out)
+		assert.Equal(t, "foo", getUnderlyingValue(getParsedValue(t, parsed, "index", "entry")))
+	})
+
+	t.Run("repeated instance", func(t *testing.T) {
+		schema := loadFormatSchema(t, "instance_std_array")
+		original, err := os.ReadFile("../../test/src/instance_std_array.bin")
+		require.NoError(t, err)
+
+		out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+			"ofs":         16,
+			"entry_size":  4,
+			"qty_entries": 3,
+			"entries":     []any{[]byte{0x11, 0x11, 0x11, 0x11}, []byte{0x22, 0x22, 0x22, 0x22}, []byte{0x33, 0x33, 0x33, 0x33}},
+		})
+		require.NoError(t, err)
+		// The fixture has 0xFF filler where the serializer leaves zeros
+		assert.Equal(t, original[:12], out[:12])
+		assert.Equal(t, original[16:], out[16:])
+	})
+}
+
+func TestSerialize_LayoutPasses(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("position relative to stream size", func(t *testing.T) {
+		schema := loadFormatSchema(t, "position_to_end")
+		out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+			"index": map[string]any{"foo": 0x42, "bar": 0x1234},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x42, 0, 0, 0, 0x34, 0x12, 0, 0}, out)
+
+		parsed := parseBytes(t, schema, out)
+		assert.EqualValues(t, 0x1234, getUnderlyingValue(getParsedValue(t, parsed, "index", "bar")))
+	})
+
+	t.Run("_io in sized substreams round-trips", func(t *testing.T) {
+		schema := loadFormatSchema(t, "expr_io_pos")
+		original, err := os.ReadFile("../../test/src/expr_io_pos.bin")
+		require.NoError(t, err)
+		parsed := parseBytes(t, schema, original)
+
+		out, err := newTestSerializer(t, schema).Serialize(ctx, ParsedDataToMap(parsed).(map[string]any))
+		require.NoError(t, err)
+		assert.Equal(t, original, out)
+	})
+
+	t.Run("_sizeof read before the end of the type", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "sizeof_forward"},
+			Seq: []SequenceItem{
+				{ID: "body", Type: "bytes", Size: 3},
+				{ID: "trailer", Type: "u1", IfExpr: "_sizeof >= 3"},
+			},
+		}
+		out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+			"body":    []byte{1, 2, 3},
+			"trailer": 9,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 2, 3, 9}, out)
+	})
+
+	t.Run("sizes that never settle", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "diverging"},
+			Instances: map[string]InstanceDef{
+				"tail": {Pos: "_io.size", Type: "u1"},
+			},
+		}
+		_, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{"tail": 1})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "layout did not converge")
+	})
+}
+
+func TestSerialize_SizedUserType(t *testing.T) {
+	ctx := context.Background()
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "sized_user", Endian: "be"},
+		Seq: []SequenceItem{
+			{ID: "rec", Type: "record", Size: 4},
+			{ID: "end", Type: "u1"},
+		},
+		Types: map[string]Type{
+			"record": {Seq: []SequenceItem{
+				{ID: "a", Type: "u1"},
+				{ID: "b", Type: "u1", IfExpr: "_io.size == 4"},
+			}},
+		},
+	}
+
+	out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+		"rec": map[string]any{"a": 1, "b": 2},
+		"end": 0xFF,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 0, 0, 0xFF}, out, "substream is padded to its size")
+
+	schema.Seq[0].Size = 1
+	_, err = newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+		"rec": map[string]any{"a": 1, "b": 2},
+		"end": 0xFF,
+	})
+	require.NoError(t, err, "b is skipped because _io.size is the substream size")
+
+	schema.Types["record"].Seq[1].IfExpr = ""
+	_, err = newTestSerializer(t, schema).Serialize(ctx, map[string]any{
+		"rec": map[string]any{"a": 1, "b": 2},
+		"end": 0xFF,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "larger than its size 1")
+}
+
+func TestLayoutBuffer(t *testing.T) {
+	buf := &layoutBuffer{fixedSize: -1, layout: newLayoutState()}
+	w := kaitai.NewWriter(buf)
+	require.NoError(t, w.WriteBytes([]byte{1, 2, 3}))
+
+	_, err := buf.Seek(5, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBytes([]byte{6}))
+	assert.Equal(t, []byte{1, 2, 3, 0, 0, 6}, buf.Bytes(), "gap is zero-filled")
+
+	_, err = buf.Seek(1, 0)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBytes([]byte{9}))
+	assert.Equal(t, []byte{1, 9, 3, 0, 0, 6}, buf.Bytes(), "writes overwrite in place")
+
+	size, err := kaitai.NewStream(buf).Size()
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, size)
+	assert.EqualValues(t, 2, buf.pos, "measuring the size keeps the cursor")
+}
+
+func TestToInt64(t *testing.T) {
+	for _, value := range []any{int8(-3), uint32(7), 12.0, -4.0, uint64(math.MaxInt64), kaitaicel.NewKaitaiU1(9, []byte{9})} {
+		_, ok := toInt64(value)
+		assert.True(t, ok, "%T %v", value, value)
+	}
+	for _, value := range []any{1.5, -0.25, math.NaN(), math.Inf(1), float64(math.MaxInt64), uint64(math.MaxInt64) + 1, "3"} {
+		_, ok := toInt64(value)
+		assert.False(t, ok, "%T %v", value, value)
+	}
+	n, _ := toInt64(float64(math.MinInt64))
+	assert.Equal(t, int64(math.MinInt64), n)
+}
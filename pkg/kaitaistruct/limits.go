@@ -0,0 +1,131 @@
+package kaitaistruct
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/interpreter"
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+)
+
+// ErrLimitExceeded is matched (via errors.Is) by every LimitError
+var ErrLimitExceeded = errors.New("resource limit exceeded")
+
+// ErrNoProgress is matched (via errors.Is) when an item of a `repeat: eos`
+// field consumes no bytes, so repeating it would never reach the end
+var ErrNoProgress = errors.New("repeated item consumed no bytes")
+
+// Names of the individual limits, reported in LimitError.Limit
+const (
+	LimitMaxDepth       = "max_depth"
+	LimitMaxRepeatItems = "max_repeat_items"
+	LimitMaxAllocation  = "max_allocation_size"
+	LimitMaxNodes       = "max_output_nodes"
+	LimitCELCost        = "cel_cost_limit"
+)
+
+// Limits bounds the resources a single parse may consume. It is intended for
+// schemas applied to untrusted input, where a crafted length or count field
+// could otherwise force huge allocations or unbounded loops.
+// A zero value for any field means that limit is not enforced.
+type Limits struct {
+	MaxDepth       int    // Maximum nesting depth of user and switch types
+	MaxRepeatItems int    // Maximum number of items in a single repeated field
+	MaxAllocation  int    // Maximum size in bytes of a single read
+	MaxNodes       int    // Maximum number of fields produced by one parse
+	CELCostLimit   uint64 // Maximum CEL cost of a single expression evaluation
+}
+
+// LimitError reports which limit was exceeded and by how much
+type LimitError struct {
+	Limit  string // One of the Limit* constants
+	Field  string // Field or type being parsed when the limit was hit, if known
+	Max    int64  // Configured maximum
+	Actual int64  // Value that exceeded the maximum
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%s exceeded at '%s': %d > %d", e.Limit, e.Field, e.Actual, e.Max)
+	}
+	return fmt.Sprintf("%s exceeded: %d > %d", e.Limit, e.Actual, e.Max)
+}
+
+// Is allows errors.Is(err, ErrLimitExceeded) to match any LimitError
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// InterpreterOption configures optional behaviour of a KaitaiInterpreter
+type InterpreterOption func(*KaitaiInterpreter)
+
+// WithLimits sets the resource limits enforced while parsing
+func WithLimits(limits Limits) InterpreterOption {
+	return func(k *KaitaiInterpreter) {
+		k.limits = limits
+	}
+}
+
+// checkDepth enforces Limits.MaxDepth once typeName has been pushed onto the type stack
+func (k *KaitaiInterpreter) checkDepth(typeName string) error {
+	if k.limits.MaxDepth > 0 && len(k.typeStack) > k.limits.MaxDepth {
+		return &LimitError{Limit: LimitMaxDepth, Field: typeName, Max: int64(k.limits.MaxDepth), Actual: int64(len(k.typeStack))}
+	}
+	return nil
+}
+
+// checkAllocation enforces Limits.MaxAllocation before reading size bytes
+func (k *KaitaiInterpreter) checkAllocation(fieldID string, size int64) error {
+	if k.limits.MaxAllocation > 0 && size > int64(k.limits.MaxAllocation) {
+		return &LimitError{Limit: LimitMaxAllocation, Field: fieldID, Max: int64(k.limits.MaxAllocation), Actual: size}
+	}
+	return nil
+}
+
+// checkRemainingAllocation enforces Limits.MaxAllocation for a read up to the end of stream
+func (k *KaitaiInterpreter) checkRemainingAllocation(fieldID string, stream *kaitai.Stream) error {
+	if k.limits.MaxAllocation == 0 {
+		return nil
+	}
+	pos, err := stream.Pos()
+	if err != nil {
+		return fmt.Errorf("getting current position for field '%s': %w", fieldID, err)
+	}
+	size, err := stream.Size()
+	if err != nil {
+		return fmt.Errorf("getting stream size for field '%s': %w", fieldID, err)
+	}
+	return k.checkAllocation(fieldID, size-pos)
+}
+
+// checkRepeatItems enforces Limits.MaxRepeatItems for a repeated field holding count items
+func (k *KaitaiInterpreter) checkRepeatItems(fieldID string, count int) error {
+	if k.limits.MaxRepeatItems > 0 && count > k.limits.MaxRepeatItems {
+		return &LimitError{Limit: LimitMaxRepeatItems, Field: fieldID, Max: int64(k.limits.MaxRepeatItems), Actual: int64(count)}
+	}
+	return nil
+}
+
+// countNode records one more output field and enforces Limits.MaxNodes
+func (k *KaitaiInterpreter) countNode(fieldID string) error {
+	k.nodeCount++
+	if k.limits.MaxNodes > 0 && k.nodeCount > k.limits.MaxNodes {
+		return &LimitError{Limit: LimitMaxNodes, Field: fieldID, Max: int64(k.limits.MaxNodes), Actual: int64(k.nodeCount)}
+	}
+	return nil
+}
+
+// celCostError converts a CEL cost limit failure into a LimitError, returning nil for other errors
+func (k *KaitaiInterpreter) celCostError(expr string, details *cel.EvalDetails, err error) error {
+	var cancelled interpreter.EvalCancelledError
+	if k.limits.CELCostLimit == 0 || !errors.As(err, &cancelled) || cancelled.Cause != interpreter.CostLimitExceeded {
+		return nil
+	}
+	actual := k.limits.CELCostLimit + 1
+	if details != nil && details.ActualCost() != nil {
+		actual = *details.ActualCost()
+	}
+	return &LimitError{Limit: LimitCELCost, Field: expr, Max: int64(k.limits.CELCostLimit), Actual: int64(actual)}
+}
@@ -0,0 +1,239 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitedTestInterpreter(t *testing.T, schema *KaitaiSchema, limits Limits) *KaitaiInterpreter {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	interp, err := NewKaitaiInterpreter(schema, logger, WithLimits(limits))
+	require.NoError(t, err)
+	return interp
+}
+
+func requireLimitError(t *testing.T, err error, limit string) *LimitError {
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	var limitErr *LimitError
+	require.True(t, errors.As(err, &limitErr), "expected LimitError, got %v", err)
+	assert.Equal(t, limit, limitErr.Limit)
+	return limitErr
+}
+
+func TestLimits_MaxAllocation(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "alloc_root", Endian: "le"},
+		Seq: []SequenceItem{
+			{ID: "len", Type: "u4"},
+			{ID: "body", Size: "len"},
+		},
+	}
+	// Length field claims 4GB of payload
+	data := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x01, 0x02}
+
+	t.Run("exceeded", func(t *testing.T) {
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxAllocation: 1024})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+		limitErr := requireLimitError(t, err, LimitMaxAllocation)
+		assert.Equal(t, "body", limitErr.Field)
+		assert.EqualValues(t, 1024, limitErr.Max)
+		assert.EqualValues(t, 0xFFFFFFFF, limitErr.Actual)
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxAllocation: 1024})
+		ok := []byte{0x02, 0x00, 0x00, 0x00, 0x01, 0x02}
+		parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(ok)))
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x01, 0x02}, getUnderlyingValue(getParsedValue(t, parsed, "body")))
+	})
+
+	t.Run("size-eos", func(t *testing.T) {
+		eosSchema := &KaitaiSchema{
+			Meta: Meta{ID: "alloc_eos"},
+			Seq:  []SequenceItem{{ID: "rest", SizeEOS: true}},
+		}
+		interp := newLimitedTestInterpreter(t, eosSchema, Limits{MaxAllocation: 2})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{1, 2, 3})))
+		requireLimitError(t, err, LimitMaxAllocation)
+	})
+}
+
+func TestLimits_MaxRepeatItems(t *testing.T) {
+	t.Run("repeat-expr", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "repeat_expr_root"},
+			Seq: []SequenceItem{
+				{ID: "count", Type: "u1"},
+				{ID: "items", Type: "u1", Repeat: "expr", RepeatExpr: "count"},
+			},
+		}
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxRepeatItems: 10})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{200, 1, 2, 3})))
+		limitErr := requireLimitError(t, err, LimitMaxRepeatItems)
+		assert.EqualValues(t, 200, limitErr.Actual)
+	})
+
+	t.Run("repeat eos", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "repeat_eos_root"},
+			Seq: []SequenceItem{
+				{ID: "items", Type: "u1", Repeat: "eos"},
+			},
+		}
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxRepeatItems: 100})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(make([]byte, 200))))
+		requireLimitError(t, err, LimitMaxRepeatItems)
+	})
+
+	t.Run("repeat eos of zero-size items without limits", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "repeat_eos_empty_root"},
+			Seq: []SequenceItem{
+				{ID: "empty", Type: "bytes", Size: 0, Repeat: "eos"},
+			},
+		}
+		interp := newLimitedTestInterpreter(t, schema, Limits{})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{1, 2, 3})))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrNoProgress)
+		assert.Contains(t, err.Error(), "'empty'")
+	})
+
+	t.Run("repeat eos of bit fields", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "repeat_eos_bits_root"},
+			Seq: []SequenceItem{
+				{ID: "flags", Type: "b1", Repeat: "eos"},
+			},
+		}
+		interp := newLimitedTestInterpreter(t, schema, Limits{})
+		parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{0xA5})))
+		require.NoError(t, err)
+		assert.Len(t, ParsedDataToMap(parsed).(map[string]any)["flags"], 8)
+	})
+
+	t.Run("repeat until", func(t *testing.T) {
+		schema := &KaitaiSchema{
+			Meta: Meta{ID: "repeat_until_root"},
+			Seq: []SequenceItem{
+				{ID: "items", Type: "u1", Repeat: "until", RepeatUntil: "_ == 0"},
+			},
+		}
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxRepeatItems: 3})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{1, 2, 3, 4, 0})))
+		requireLimitError(t, err, LimitMaxRepeatItems)
+
+		interp = newLimitedTestInterpreter(t, schema, Limits{MaxRepeatItems: 5})
+		_, err = interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{1, 2, 3, 4, 0})))
+		require.NoError(t, err)
+	})
+}
+
+func TestLimits_MaxDepth(t *testing.T) {
+	yamlData, err := os.ReadFile("../../test/formats/recursive_one.ksy")
+	require.NoError(t, err)
+	schema, err := NewKaitaiSchemaFromYAML(yamlData)
+	require.NoError(t, err)
+
+	// Two recursive levels (one & 3 == 0, 1) followed by the fini terminator (one & 3 == 3)
+	data := []byte{0x00, 0x01, 0x03, 0x34, 0x12}
+
+	t.Run("recursion rejected without a depth limit", func(t *testing.T) {
+		interp := newTestInterpreter(t, schema)
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "circular type dependency detected")
+	})
+
+	t.Run("recursion bounded by depth limit", func(t *testing.T) {
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxDepth: 16})
+		parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, getUnderlyingValue(getParsedValue(t, parsed, "one")))
+		assert.EqualValues(t, 1, getUnderlyingValue(getParsedValue(t, parsed, "next", "one")))
+		assert.EqualValues(t, 3, getUnderlyingValue(getParsedValue(t, parsed, "next", "next", "one")))
+		assert.EqualValues(t, 0x1234, getUnderlyingValue(getParsedValue(t, parsed, "next", "next", "next", "finisher")))
+	})
+
+	t.Run("recursive data serializes", func(t *testing.T) {
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxDepth: 16})
+		parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+		require.NoError(t, err)
+
+		parsedMap, ok := ParsedDataToMap(parsed).(map[string]any)
+		require.True(t, ok)
+		serialized, err := newTestSerializer(t, schema).Serialize(context.Background(), parsedMap)
+		require.NoError(t, err)
+		assert.Equal(t, data, serialized)
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		deep := bytes.Repeat([]byte{0x00}, 100)
+		interp := newLimitedTestInterpreter(t, schema, Limits{MaxDepth: 8})
+		_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(deep)))
+		limitErr := requireLimitError(t, err, LimitMaxDepth)
+		assert.EqualValues(t, 8, limitErr.Max)
+	})
+}
+
+func TestLimits_MaxNodes(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "nodes_root"},
+		Seq: []SequenceItem{
+			{ID: "items", Type: "u1", Repeat: "eos"},
+		},
+	}
+
+	interp := newLimitedTestInterpreter(t, schema, Limits{MaxNodes: 5})
+	_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(make([]byte, 10))))
+	requireLimitError(t, err, LimitMaxNodes)
+
+	// The counter is reset on every Parse call
+	for range 3 {
+		_, err = interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(make([]byte, 3))))
+		require.NoError(t, err)
+	}
+}
+
+func TestLimits_CELCost(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "cel_cost_root"},
+		Seq: []SequenceItem{
+			{ID: "a", Type: "u1"},
+			{ID: "b", Type: "u1"},
+		},
+		Instances: map[string]InstanceDef{
+			"mix": {Value: "(a + b) * (a - b) + (a * b) - (b * 2) + (a * 3)"},
+		},
+	}
+	data := []byte{5, 3}
+
+	interp := newLimitedTestInterpreter(t, schema, Limits{CELCostLimit: 2})
+	_, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+	requireLimitError(t, err, LimitCELCost)
+
+	interp = newLimitedTestInterpreter(t, schema, Limits{CELCostLimit: 10000})
+	parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+	require.NoError(t, err)
+	assert.EqualValues(t, 16+15-6+15, getUnderlyingValue(getParsedValue(t, parsed, "mix")))
+
+	// Only CEL's cost cancellation counts, not other errors mentioning cost
+	assert.NoError(t, interp.celCostError("mix", nil, errors.New("runtime cost limit exceeded")))
+}
+
+func TestLimitError_Message(t *testing.T) {
+	err := &LimitError{Limit: LimitMaxAllocation, Field: "body", Max: 10, Actual: 20}
+	assert.Equal(t, "max_allocation_size exceeded at 'body': 20 > 10", err.Error())
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+}
@@ -0,0 +1,109 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+)
+
+// OrderedMap is an object whose keys keep the order they were first set in.
+// It marshals to JSON in that order, where encoding/json sorts the keys of a map.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewOrderedMap creates an empty OrderedMap
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]any)}
+}
+
+// Set sets the value of key, adding key at the end if it is new
+func (m *OrderedMap) Set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value of key, and whether it is set
+func (m *OrderedMap) Get(key string) (any, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// Delete removes key
+func (m *OrderedMap) Delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// Keys returns the keys in order
+func (m *OrderedMap) Keys() []string {
+	return slices.Clone(m.keys)
+}
+
+// Len returns the number of keys
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// ToMap converts m, and the OrderedMaps nested in it, to plain maps
+func (m *OrderedMap) ToMap() map[string]any {
+	result := make(map[string]any, len(m.keys))
+	for _, key := range m.keys {
+		result[key] = unorderedValue(m.values[key])
+	}
+	return result
+}
+
+// unorderedValue converts the OrderedMaps in value to plain maps
+func unorderedValue(value any) any {
+	switch v := value.(type) {
+	case *OrderedMap:
+		return v.ToMap()
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = unorderedValue(item)
+		}
+		return result
+	}
+	return value
+}
+
+// MarshalJSON implements json.Marshaler, writing the keys in order
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		encodedValue, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// PreserveOrder makes ParsedDataToMap output user types as *OrderedMap, with
+// their fields in declaration order (seq fields, then instances), instead of
+// map[string]any
+func PreserveOrder(enabled bool) OutputOption {
+	return func(f *outputFormat) {
+		f.ordered = enabled
+	}
+}
@@ -0,0 +1,102 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsedDataToMap_PreserveOrder(t *testing.T) {
+	schema, err := NewKaitaiSchemaFromYAML([]byte(`
+meta:
+  id: packet
+  endian: le
+seq:
+  - id: version
+    type: u1
+  - id: header
+    type: header
+  - id: checksum
+    type: u1
+instances:
+  total:
+    value: version + 5
+  is_v2:
+    value: version == 2
+types:
+  header:
+    seq:
+      - id: len_body
+        type: u1
+      - id: flags
+        type: u1
+    instances:
+      has_body:
+        value: len_body > 0
+      body_flag:
+        value: flags & 1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"total", "is_v2"}, schema.InstanceOrder)
+	assert.Equal(t, []string{"has_body", "body_flag"}, schema.Types["header"].InstanceOrder)
+
+	interp, err := NewKaitaiInterpreter(schema, nil)
+	require.NoError(t, err)
+	parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader([]byte{0x02, 0x05, 0x01, 0xAA})))
+	require.NoError(t, err)
+
+	ordered, ok := ParsedDataToMap(parsed, PreserveOrder(true)).(*OrderedMap)
+	require.True(t, ok)
+	assert.Equal(t, []string{"version", "header", "checksum", "total", "is_v2"}, ordered.Keys())
+
+	encoded, err := json.Marshal(ordered)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version":2,"header":{"len_body":5,"flags":1,"has_body":true,"body_flag":1},"checksum":170,"total":7,"is_v2":true}`, string(encoded))
+
+	// The plain form holds the same values
+	assert.Equal(t, ParsedDataToMap(parsed), ordered.ToMap())
+}
+
+func TestParsedData_ChildNames(t *testing.T) {
+	data := &ParsedData{
+		Children: map[string]*ParsedData{
+			"b":   {Value: int64(2)},
+			"a":   {Value: int64(1)},
+			"_io": {Value: map[string]any{}},
+			"z":   {Value: int64(3)},
+		},
+		Order: []string{"z", "skipped", "b"},
+	}
+	assert.Equal(t, []string{"z", "b", "_io", "a"}, data.ChildNames())
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap()
+	m.Set("zeta", 1)
+	m.Set("alpha", []any{NewOrderedMap()})
+	m.Set("mid", "x")
+	m.Set("zeta", 2) // Keeps its position
+
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, m.Keys())
+	value, ok := m.Get("zeta")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+
+	m.Delete("alpha")
+	m.Delete("missing")
+	assert.Equal(t, 2, m.Len())
+
+	encoded, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Equal(t, `{"zeta":2,"mid":"x"}`, string(encoded))
+
+	nested := NewOrderedMap()
+	nested.Set("inner", NewOrderedMap())
+	nested.Set("list", []any{NewOrderedMap()})
+	assert.Equal(t, map[string]any{"inner": map[string]any{}, "list": []any{map[string]any{}}}, nested.ToMap())
+}
@@ -0,0 +1,305 @@
+package kaitaistruct
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+)
+
+// EnumFormat selects how enum values appear in parsed output
+type EnumFormat string
+
+// Enum output formats
+const (
+	EnumObject EnumFormat = "object" // {"name": "cat", "value": 4, "valid": true}
+	EnumName   EnumFormat = "name"   // "cat", or the integer when the value has no name
+	EnumInt    EnumFormat = "int"    // 4
+)
+
+// BytesFormat selects how byte fields appear in parsed output, and how the
+// serializer reads strings given for byte fields
+type BytesFormat string
+
+// Bytes output formats
+const (
+	BytesRaw    BytesFormat = "raw"    // []byte, which encoding/json marshals as base64
+	BytesHex    BytesFormat = "hex"    // "cafe"
+	BytesBase64 BytesFormat = "base64" // "yv4="
+	BytesArray  BytesFormat = "array"  // [202, 254]
+)
+
+// BigIntFormat selects how integers that a float64 can't hold exactly appear
+// in parsed output
+type BigIntFormat string
+
+// Big integer output formats
+const (
+	BigIntNumber BigIntFormat = "number" // 9007199254740993
+	BigIntString BigIntFormat = "string" // "9007199254740993", so JSON consumers don't round it
+)
+
+// BitFlagFormat selects how 1-bit fields appear in parsed output
+type BitFlagFormat string
+
+// Bit flag output formats
+const (
+	BitFlagBool BitFlagFormat = "bool" // true
+	BitFlagInt  BitFlagFormat = "int"  // 1
+)
+
+// maxExactInt is the largest integer a float64, and so a JavaScript number, holds exactly
+const maxExactInt = 1<<53 - 1
+
+// outputFormat holds the shapes selected by OutputOptions; the zero value
+// gives the default shapes
+type outputFormat struct {
+	enums    EnumFormat
+	bytes    BytesFormat
+	bigInts  BigIntFormat
+	bitFlags BitFlagFormat
+	ordered  bool // User types as *OrderedMap
+}
+
+// OutputOption selects the shape of a kind of value in the output of ParsedDataToMap
+type OutputOption func(*outputFormat)
+
+// EnumsAs selects how enum values are output. Defaults to EnumObject.
+func EnumsAs(format EnumFormat) OutputOption {
+	return func(f *outputFormat) {
+		f.enums = format
+	}
+}
+
+// BytesAs selects how byte fields are output. Defaults to BytesRaw.
+func BytesAs(format BytesFormat) OutputOption {
+	return func(f *outputFormat) {
+		f.bytes = format
+	}
+}
+
+// BigIntsAs selects how integers beyond ±(2^53-1) are output. Defaults to BigIntNumber.
+func BigIntsAs(format BigIntFormat) OutputOption {
+	return func(f *outputFormat) {
+		f.bigInts = format
+	}
+}
+
+// BitFlagsAs selects how 1-bit fields are output. Defaults to BitFlagBool.
+func BitFlagsAs(format BitFlagFormat) OutputOption {
+	return func(f *outputFormat) {
+		f.bitFlags = format
+	}
+}
+
+// newOutputFormat applies opts to the default shapes
+func newOutputFormat(opts []OutputOption) outputFormat {
+	var format outputFormat
+	for _, opt := range opts {
+		opt(&format)
+	}
+	return format
+}
+
+// FormatValue converts a single parsed value, a kaitaicel type or a plain Go
+// value, to the shape selected by opts, as ParsedDataToMap does for each field
+func FormatValue(value any, opts ...OutputOption) any {
+	return newOutputFormat(opts).value(value)
+}
+
+// value converts a parsed value to its output shape
+func (f outputFormat) value(value any) any {
+	switch v := value.(type) {
+	case *kaitaicel.KaitaiEnum:
+		return f.enum(v)
+	case *kaitaicel.KaitaiBytes:
+		return f.byteSlice(v.RawBytes())
+	case *kaitaicel.KaitaiInt:
+		if strings.HasPrefix(v.KaitaiTypeName(), "u8") {
+			// u8 values are held as int64, so those above math.MaxInt64 wrap
+			return f.uint(uint64(v.Value().(int64)), v.Value())
+		}
+		return f.int(v.Value().(int64))
+	case *kaitaicel.KaitaiBitField:
+		if v.BitCount() == 1 && f.bitFlags != BitFlagInt {
+			// For 1-bit fields, return as boolean (matching KSC behavior)
+			return v.AsBool()
+		}
+		return f.uint(v.AsUint(), v.AsInt())
+	case []byte:
+		return f.byteSlice(v)
+	case int64:
+		return f.int(v)
+	case uint64:
+		return f.uint(v, v)
+	}
+	return convertKaitaiTypeForSerialization(value, f)
+}
+
+// enum converts an enum value to its output shape
+func (f outputFormat) enum(e *kaitaicel.KaitaiEnum) any {
+	switch f.enums {
+	case EnumName:
+		if e.IsValid() {
+			return e.Name()
+		}
+		return e.IntValue()
+	case EnumInt:
+		return e.IntValue()
+	default:
+		return map[string]any{
+			"value": e.IntValue(),
+			"name":  e.Name(),
+			"valid": e.IsValid(),
+		}
+	}
+}
+
+// byteSlice converts bytes to their output shape
+func (f outputFormat) byteSlice(b []byte) any {
+	switch f.bytes {
+	case BytesHex:
+		return hex.EncodeToString(b)
+	case BytesBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case BytesArray:
+		result := make([]any, len(b))
+		for i, c := range b {
+			result[i] = int64(c)
+		}
+		return result
+	default:
+		return b
+	}
+}
+
+// int converts a signed integer to its output shape
+func (f outputFormat) int(n int64) any {
+	if f.bigInts == BigIntString && (n > maxExactInt || n < -maxExactInt) {
+		return strconv.FormatInt(n, 10)
+	}
+	return n
+}
+
+// uint converts an unsigned integer to its output shape, using number as the
+// value when it is output as a number
+func (f outputFormat) uint(n uint64, number any) any {
+	if f.bigInts == BigIntString && n > maxExactInt {
+		return strconv.FormatUint(n, 10)
+	}
+	return number
+}
+
+// decode converts a value given for a byte field, bytes, a string in format or
+// an array of integers, to bytes
+func (format BytesFormat) decode(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		switch format {
+		case BytesHex:
+			b, err := hex.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("decoding hex bytes: %w", err)
+			}
+			return b, nil
+		case BytesBase64:
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("decoding base64 bytes: %w", err)
+			}
+			return b, nil
+		default:
+			return []byte(v), nil
+		}
+	case *kaitaicel.KaitaiBytes:
+		return v.RawBytes(), nil
+	case []any:
+		b := make([]byte, len(v))
+		for i, item := range v {
+			n, ok := toInt64(item)
+			if !ok || n < 0 || n > 255 {
+				return nil, fmt.Errorf("byte %d: expected an integer from 0 to 255, got %v", i, item)
+			}
+			b[i] = byte(n)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("expected bytes, string or array of integers, got %T", data)
+}
+
+// integerString parses s as a decimal value of the integer type typeName, as
+// output with BigIntsAs(BigIntString)
+func integerString(typeName, s string) (any, bool) {
+	switch {
+	case strings.HasPrefix(typeName, "u"):
+		n, err := strconv.ParseUint(s, 10, 64)
+		return n, err == nil
+	case strings.HasPrefix(typeName, "s"):
+		n, err := strconv.ParseInt(s, 10, 64)
+		return n, err == nil
+	}
+	return nil, false
+}
+
+// numbersFromJSON replaces the json.Number values in a decoded JSON value
+// with int64, uint64 or float64 values, copying only the maps and arrays that
+// hold them. It reports whether anything was replaced.
+func numbersFromJSON(value any) (any, bool) {
+	return replaceNumbers(value, make(map[uintptr]bool))
+}
+
+// replaceNumbers implements numbersFromJSON, leaving maps already being
+// walked, in data that refers back to itself, as they are
+func replaceNumbers(value any, walking map[uintptr]bool) (any, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, true
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n, true
+		}
+		if f, err := v.Float64(); err == nil {
+			return f, true
+		}
+		return v.String(), true
+	case map[string]any:
+		id := reflect.ValueOf(v).Pointer()
+		if walking[id] {
+			return value, false
+		}
+		walking[id] = true
+		defer delete(walking, id)
+		var result map[string]any
+		for key, item := range v {
+			if converted, changed := replaceNumbers(item, walking); changed {
+				if result == nil {
+					result = maps.Clone(v)
+				}
+				result[key] = converted
+			}
+		}
+		return result, result != nil
+	case []any:
+		var result []any
+		for i, item := range v {
+			if converted, changed := replaceNumbers(item, walking); changed {
+				if result == nil {
+					result = slices.Clone(v)
+				}
+				result[i] = converted
+			}
+		}
+		return result, result != nil
+	}
+	return value, false
+}
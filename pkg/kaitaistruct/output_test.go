@@ -0,0 +1,198 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsedDataToMap_OutputFormats(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "shapes", Endian: "be"},
+		Seq: []SequenceItem{
+			{ID: "kind", Type: "u1", Enum: "animal"},
+			{ID: "payload", Size: 2},
+			{ID: "flag", Type: "b1"},
+			{ID: "level", Type: "b7"},
+			{ID: "counter", Type: "u8"},
+			{ID: "delta", Type: "s8"},
+		},
+		Enums: map[string]EnumDef{
+			"animal": {4: "cat", 7: "dog"},
+		},
+	}
+	data := []byte{
+		0x04,
+		0xCA, 0xFE,
+		0x85,
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE,
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	interp, err := NewKaitaiInterpreter(schema, logger)
+	require.NoError(t, err)
+	parsed, err := interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+	require.NoError(t, err)
+
+	t.Run("defaults", func(t *testing.T) {
+		out := ParsedDataToMap(parsed).(map[string]any)
+		assert.Equal(t, map[string]any{"value": int64(4), "name": "cat", "valid": true}, out["kind"])
+		assert.Equal(t, []byte{0xCA, 0xFE}, out["payload"])
+		assert.Equal(t, true, out["flag"])
+		assert.EqualValues(t, 5, out["level"])
+		assert.Equal(t, int64(-2), out["counter"])
+		assert.Equal(t, int64(-1), out["delta"])
+	})
+
+	tests := []struct {
+		name     string
+		opts     []OutputOption
+		expected map[string]any
+	}{
+		{
+			name: "names hex strings ints",
+			opts: []OutputOption{EnumsAs(EnumName), BytesAs(BytesHex), BigIntsAs(BigIntString), BitFlagsAs(BitFlagInt)},
+			expected: map[string]any{
+				"kind":    "cat",
+				"payload": "cafe",
+				"flag":    uint64(1),
+				"level":   uint64(5),
+				"counter": "18446744073709551614",
+				"delta":   int64(-1),
+			},
+		},
+		{
+			name: "ints base64",
+			opts: []OutputOption{EnumsAs(EnumInt), BytesAs(BytesBase64)},
+			expected: map[string]any{
+				"kind":    int64(4),
+				"payload": "yv4=",
+				"flag":    true,
+				"level":   uint64(5),
+				"counter": int64(-2),
+				"delta":   int64(-1),
+			},
+		},
+		{
+			name: "arrays",
+			opts: []OutputOption{BytesAs(BytesArray), BigIntsAs(BigIntString)},
+			expected: map[string]any{
+				"kind":    map[string]any{"value": int64(4), "name": "cat", "valid": true},
+				"payload": []any{int64(0xCA), int64(0xFE)},
+				"flag":    true,
+				"level":   uint64(5),
+				"counter": "18446744073709551614",
+				"delta":   int64(-1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := ParsedDataToMap(parsed, tt.opts...).(map[string]any)
+			for key, want := range tt.expected {
+				assert.EqualValues(t, want, out[key], key)
+			}
+
+			// The serializer reads each shape back, once through JSON
+			encoded, err := json.Marshal(out)
+			require.NoError(t, err)
+			var input map[string]any
+			require.NoError(t, json.Unmarshal(encoded, &input))
+			if _, isNumber := input["counter"].(float64); isNumber {
+				// float64 can't hold the u8 value, which is what BigIntString is for
+				input["counter"] = out["counter"]
+				input["delta"] = out["delta"]
+			}
+
+			serializer, err := NewKaitaiSerializer(schema, logger, WithInputFormat(tt.opts...))
+			require.NoError(t, err)
+			serialized, err := serializer.Serialize(context.Background(), input)
+			require.NoError(t, err)
+			assert.Equal(t, data, serialized)
+		})
+	}
+}
+
+func TestSerializer_EnumName(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "pet", Endian: "le"},
+		Seq: []SequenceItem{
+			{ID: "kind", Type: "u2", Enum: "animal"},
+		},
+		Enums: map[string]EnumDef{
+			"animal": {4: "cat", 7: "dog"},
+		},
+	}
+	serializer, err := NewKaitaiSerializer(schema, nil)
+	require.NoError(t, err)
+
+	serialized, err := serializer.Serialize(context.Background(), map[string]any{"kind": "dog"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x07, 0x00}, serialized)
+
+	_, err = serializer.Serialize(context.Background(), map[string]any{"kind": "cow"})
+	assert.ErrorContains(t, err, "no entry 'cow' in enum 'animal'")
+}
+
+func TestSerializer_JSONNumbers(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "sample", Endian: "be"},
+		Seq: []SequenceItem{
+			{ID: "id", Type: "u2", Valid: &ValidationDef{Max: 1000}},
+			{ID: "serial", Type: "u8"},
+			{ID: "ratio", Type: "f4"},
+			{ID: "raw", Size: 2},
+		},
+	}
+	// Decoded as Benthos decodes messages
+	decoder := json.NewDecoder(bytes.NewReader([]byte(`{"id": 513, "serial": 18446744073709551614, "ratio": 0.5, "raw": [1, 2]}`)))
+	decoder.UseNumber()
+	var input map[string]any
+	require.NoError(t, decoder.Decode(&input))
+
+	serializer, err := NewKaitaiSerializer(schema, nil)
+	require.NoError(t, err)
+	serialized, err := serializer.Serialize(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x02, 0x01,
+		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFE,
+		0x3F, 0x00, 0x00, 0x00,
+		0x01, 0x02,
+	}, serialized)
+	assert.IsType(t, json.Number(""), input["id"], "input is left as it was")
+}
+
+func TestBytesFormat_Decode(t *testing.T) {
+	tests := []struct {
+		format  BytesFormat
+		input   any
+		want    []byte
+		wantErr bool
+	}{
+		{BytesRaw, "AB", []byte("AB"), false},
+		{BytesHex, "cafe", []byte{0xCA, 0xFE}, false},
+		{BytesHex, "xyz", nil, true},
+		{BytesBase64, "yv4=", []byte{0xCA, 0xFE}, false},
+		{BytesRaw, []any{float64(1), int64(2)}, []byte{1, 2}, false},
+		{BytesHex, []any{float64(256)}, nil, true},
+		{BytesRaw, 42, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.format.decode(tt.input)
+		if tt.wantErr {
+			assert.Error(t, err, "%s %v", tt.format, tt.input)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
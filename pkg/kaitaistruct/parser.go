@@ -42,7 +42,14 @@ type KaitaiInterpreter struct {
 	typeStack       []string        // Stack of type names being processed
 	valueStack      []*ParseContext // Stack of parent values for expression evaluation
 	logger          *slog.Logger
-	lastWasBitField bool // Track if last field read was a bit field
+	lastWasBitField bool   // Track if last field read was a bit field
+	limits          Limits // Resource limits for untrusted input
+	nodeCount       int    // Fields produced so far in the current parse
+
+	// Failure reporting for the current parse
+	path       []string      // Path of the field being parsed
+	streamBase int64         // Offset of the current substream in the parsed stream
+	failure    *parseFailure // Innermost failing field, once a field has failed
 }
 
 // ParseContext contains the context for parsing a particular section
@@ -59,13 +66,47 @@ type ParseContext struct {
 type ParsedData struct {
 	Value    any
 	Children map[string]*ParsedData
+	Order    []string // Names of the fields of Type in declaration order: seq, then instances
 	Type     string
 	IsArray  bool
 	Size     int64 // Size in bytes of the parsed data
 }
 
+// ChildNames returns the names of Children in declaration order. Children
+// missing from Order, such as _io, follow in name order.
+func (d *ParsedData) ChildNames() []string {
+	names := make([]string, 0, len(d.Children))
+	for _, name := range d.Order {
+		if _, ok := d.Children[name]; ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == len(d.Children) {
+		return names
+	}
+	var rest []string
+	for name := range d.Children {
+		if !slices.Contains(d.Order, name) {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	return append(names, rest...)
+}
+
 // NewKaitaiInterpreter creates a new interpreter for a given schema with kaitaicel integration
-func NewKaitaiInterpreter(schema *KaitaiSchema, logger *slog.Logger) (*KaitaiInterpreter, error) {
+func NewKaitaiInterpreter(schema *KaitaiSchema, logger *slog.Logger, opts ...InterpreterOption) (*KaitaiInterpreter, error) {
+	interp := &KaitaiInterpreter{
+		schema:          schema,
+		typeStack:       make([]string, 0),
+		valueStack:      make([]*ParseContext, 0),
+		logger:          logger,
+		lastWasBitField: false,
+	}
+	for _, opt := range opts {
+		opt(interp)
+	}
+
 	// Create enhanced CEL environment with Kaitai types
 	enumRegistry := kaitaicel.NewEnumRegistry()
 
@@ -84,38 +125,49 @@ func NewKaitaiInterpreter(schema *KaitaiSchema, logger *slog.Logger) (*KaitaiInt
 		}
 	}
 
-	// Create base internal CEL environment with all Kaitai expression functions
-	baseEnv, err := internalCel.NewEnvironment()
+	// Create base internal CEL environment with all Kaitai expression functions,
+	// sizing user types from this schema
+	baseEnv, err := internalCel.NewEnvironmentWithSchema(schema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base CEL environment: %w", err)
 	}
 
-	// Set the schema provider for size calculations
-	internalCel.SetGlobalSchemaProvider(schema)
-
 	// For now, let's use the base environment without kaitaicel extensions to avoid compatibility issues
 	// The kaitaicel types will still be created and used, but CEL expressions will work with standard types
 	enhancedEnv := baseEnv
 
+	// Bound the cost of each expression evaluation if requested
+	var programOpts []cel.ProgramOption
+	if interp.limits.CELCostLimit > 0 {
+		programOpts = append(programOpts, cel.CostLimit(interp.limits.CELCostLimit))
+	}
+
 	// Create expression pool with enhanced environment
-	pool, err := internalCel.NewExpressionPoolWithEnv(enhancedEnv)
+	pool, err := internalCel.NewExpressionPoolWithEnv(enhancedEnv, programOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create expression pool with enhanced environment: %w", err)
 	}
+	interp.expressionPool = pool
 
-	log := logger
-	if log == nil {
-		log = slog.Default()
+	if interp.logger == nil {
+		interp.logger = slog.Default()
 	}
 
+	return interp, nil
+}
+
+// Fork returns an interpreter for the same schema and limits that shares the
+// compiled CEL environment and expressions, with its own parse state. Forks
+// of one interpreter may parse concurrently, so a schema is compiled once.
+func (k *KaitaiInterpreter) Fork() *KaitaiInterpreter {
 	return &KaitaiInterpreter{
-		schema:          schema,
-		expressionPool:  pool,
-		typeStack:       make([]string, 0),
-		valueStack:      make([]*ParseContext, 0),
-		logger:          log,
-		lastWasBitField: false,
-	}, nil
+		schema:         k.schema,
+		expressionPool: k.expressionPool,
+		typeStack:      make([]string, 0),
+		valueStack:     make([]*ParseContext, 0),
+		logger:         k.logger,
+		limits:         k.limits,
+	}
 }
 
 // AsActivation creates a CEL activation from the parse context with kaitaicel support
@@ -168,6 +220,18 @@ func (k *KaitaiInterpreter) Parse(ctx context.Context, stream *kaitai.Stream) (*
 		return nil, ctx.Err()
 	default:
 	}
+	// Start from empty stacks so an interpreter can parse one stream after another
+	k.nodeCount = 0
+	k.typeStack = k.typeStack[:0]
+	k.valueStack = k.valueStack[:0]
+	k.lastWasBitField = false
+	k.path = k.path[:0]
+	k.streamBase = 0
+	k.failure = nil
+	defer func() {
+		k.valueStack = k.valueStack[:0]
+	}()
+
 	// Create root context
 	rootCtx := &ParseContext{
 		Children: make(map[string]any),
@@ -187,7 +251,7 @@ func (k *KaitaiInterpreter) Parse(ctx context.Context, stream *kaitai.Stream) (*
 	// Parse according to root type
 	result, err := k.parseType(ctx, rootType, stream)
 	if err != nil {
-		return nil, fmt.Errorf("failed parsing root type '%s': %w", rootType, err)
+		return nil, k.parseError(fmt.Errorf("failed parsing root type '%s': %w", rootType, err))
 	}
 
 	// Copy parsed fields to rootCtx.Children for instance evaluation
@@ -264,8 +328,17 @@ func (k *KaitaiInterpreter) Parse(ctx context.Context, stream *kaitai.Stream) (*
 			for name, inst := range instancesToProcess {
 				k.logger.DebugContext(ctx, "Attempting to evaluate root instance", "instance_name", name, "pass", pass+1, "available_instances", fmt.Sprintf("%v", maps.Keys(rootCtx.Children)), "instance_expr", inst.Value)
 
+				leavePath := k.enterPath(name)
 				val, err := k.evaluateInstance(ctx, name, inst, rootCtx) // Pass instance name 'name'
+				leavePath()
+				if errors.Is(err, ErrLimitExceeded) {
+					// Resource limits are not resolved by retrying in a later pass
+					k.failAt(0)
+					k.failure.partial = result
+					return nil, k.parseError(fmt.Errorf("evaluating root instance '%s': %w", name, err))
+				}
 				if err != nil {
+					k.failure = nil // The instance may be evaluated in a later pass
 					k.logger.ErrorContext(ctx, "Root instance evaluation attempt failed (may retry)", "instance_name", name, "pass", pass+1, "error", err)
 				} else {
 					k.logger.DebugContext(ctx, "Root instance evaluated successfully", "instance_name", name, "value", val.Value)
@@ -289,7 +362,9 @@ func (k *KaitaiInterpreter) Parse(ctx context.Context, stream *kaitai.Stream) (*
 			for name := range instancesToProcess {
 				remainingInstanceNames = append(remainingInstanceNames, name)
 			}
-			return nil, fmt.Errorf("failed to evaluate all root instances after %d passes; remaining: %v. Check for circular dependencies or unresolvable expressions.", maxPasses-1, strings.Join(remainingInstanceNames, ", "))
+			k.failAt(0)
+			k.failure.partial = result
+			return nil, k.parseError(fmt.Errorf("failed to evaluate all root instances after %d passes; remaining: %v. Check for circular dependencies or unresolvable expressions.", maxPasses-1, strings.Join(remainingInstanceNames, ", ")))
 		}
 	}
 
@@ -305,8 +380,9 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 		return nil, ctx.Err()
 	default:
 	}
-	// Check for circular dependency
-	if slices.Contains(k.typeStack, typeName) {
+	// Check for circular dependency. With a depth limit configured, recursive
+	// types (e.g. linked structures) are allowed and bounded by that limit instead.
+	if k.limits.MaxDepth == 0 && slices.Contains(k.typeStack, typeName) {
 		k.logger.ErrorContext(ctx, "Circular type dependency detected", "type_name", typeName, "stack", strings.Join(k.typeStack, " -> "))
 		return nil, fmt.Errorf("circular type dependency detected: %s", typeName)
 	}
@@ -348,6 +424,12 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 		return parsedData, nil
 	}
 
+	// Built-in types are leaves; only user and switch types count towards nesting depth
+	if err := k.checkDepth(typeName); err != nil {
+		k.logger.ErrorContext(ctx, "Maximum nesting depth exceeded", "type_name", typeName, "stack", strings.Join(k.typeStack, " -> "))
+		return nil, err
+	}
+
 	// Check if it's a switch type
 	if strings.Contains(typeName, "switch-on:") {
 		// Extract the expression part after "switch-on:"
@@ -400,10 +482,11 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 		if len(k.valueStack) == 0 { // Should not happen if Parse set up rootCtx
 			return nil, fmt.Errorf("internal error: valueStack empty when parsing root type sequence for '%s'", typeName)
 		}
+		result.Order = fieldOrder(k.schema.Seq, k.schema.Instances, k.schema.InstanceOrder)
 		currentRootCtx := k.valueStack[0] // This is the root ParseContext
 		// Parse root level sequence
 		evalCtx := &ParseContext{
-			Children: make(map[string]any),
+			Children: currentRootCtx.Children, // Fields read so far are visible through _root
 			IO:       stream,
 			Parent:   nil, // Root level has no _parent in the Kaitai sense of a containing user type
 			Root:     currentRootCtx,
@@ -416,10 +499,16 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 				stream.AlignToByte()
 			}
 
+			start := fieldStart(stream)
+			leavePath := k.enterPath(seq.ID)
 			field, err := k.parseField(ctx, seq, evalCtx)
 			if err != nil {
+				k.failAt(start)
+				leavePath()
+				k.keepPartial(result, seq.ID)
 				return nil, fmt.Errorf("parsing field '%s' in root type '%s': %w", seq.ID, typeName, err)
 			}
+			leavePath()
 			if field != nil { // Only add if not nil
 				result.Children[seq.ID] = field // Store the ParsedData
 				// Store the underlying value for expressions (convert kaitaicel types)
@@ -440,6 +529,7 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 		return result, nil
 	} else if typePtr, found := k.resolveTypeInHierarchy(typeName); found {
 		typeObj = *typePtr
+		result.Order = fieldOrder(typeObj.Seq, typeObj.Instances, typeObj.InstanceOrder)
 		// Create evaluation context for this specific type
 		typeEvalCtx := &ParseContext{
 			Children: make(map[string]any),
@@ -467,10 +557,16 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 			}
 
 			// parseField will handle all field types, including resolving "switch" and "switch-on:"
+			start := fieldStart(stream)
+			leavePath := k.enterPath(seq.ID)
 			parsedFieldData, parseErr = k.parseField(ctx, seq, typeEvalCtx)
 			if parseErr != nil {
+				k.failAt(start)
+				leavePath()
+				k.keepPartial(result, seq.ID)
 				return nil, fmt.Errorf("parsing field '%s' in type '%s': %w", seq.ID, typeName, parseErr)
 			}
+			leavePath()
 
 			if parsedFieldData != nil { // Only add if not nil (e.g., conditional field was skipped)
 				result.Children[seq.ID] = parsedFieldData // Store the ParsedData
@@ -506,8 +602,16 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 
 				for name, inst := range instancesToProcess {
 					k.logger.DebugContext(ctx, "Attempting to evaluate instance", "type_name", typeName, "instance_name", name, "pass", pass+1, "available_instances", fmt.Sprintf("%v", maps.Keys(typeEvalCtx.Children)))
+					leavePath := k.enterPath(name)
 					val, err := k.evaluateInstance(ctx, name, inst, typeEvalCtx) // Pass instance name
+					leavePath()
+					if errors.Is(err, ErrLimitExceeded) {
+						k.failAt(startPos)
+						k.failure.partial = result
+						return nil, fmt.Errorf("evaluating instance '%s' in type '%s': %w", name, typeName, err)
+					}
 					if err != nil {
+						k.failure = nil // The instance may be evaluated in a later pass
 						// If error is due to missing attribute, it might be a dependency not yet evaluated.
 						// A more sophisticated check could inspect the error for "no such attribute".
 						// For now, we log and hope a subsequent pass resolves it.
@@ -536,6 +640,8 @@ func (k *KaitaiInterpreter) parseType(ctx context.Context, typeName string, stre
 				for name := range instancesToProcess {
 					remainingInstanceNames = append(remainingInstanceNames, name)
 				}
+				k.failAt(startPos)
+				k.failure.partial = result
 				return nil, fmt.Errorf("failed to evaluate all instances for type '%s' after %d passes; remaining: %v. Check for circular dependencies or unresolvable expressions.", typeName, maxPasses-1, strings.Join(remainingInstanceNames, ", "))
 			}
 		}
@@ -567,7 +673,7 @@ func (k *KaitaiInterpreter) parseBuiltinType(ctx context.Context, typeName strin
 
 	// Helper function to read bytes and create kaitai types
 	readAndCreateKaitaiType := func(size int, readerFunc func([]byte, int) (kaitaicel.KaitaiType, error)) (*ParsedData, bool, error) {
-		rawData, err := stream.ReadBytes(size)
+		rawData, err := readBytes(stream, size)
 		if err != nil {
 			k.logger.ErrorContext(ctx, "Failed to read bytes for type", "type", typeName, "size", size, "error", err)
 			return nil, true, fmt.Errorf("reading %d bytes for %s: %w", size, typeName, err)
@@ -799,6 +905,9 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 	// Handle value expressions (computed fields)
 	if field.Value != "" {
 		k.logger.DebugContext(ctx, "Evaluating value expression for field", "field_id", field.ID, "value_expr", field.Value)
+		if err := k.countNode(field.ID); err != nil {
+			return nil, err
+		}
 
 		result, err := k.evaluateExpression(ctx, field.Value, pCtx)
 		if err != nil {
@@ -841,6 +950,10 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 		k.logger.DebugContext(ctx, "Field will be parsed (if condition true)", "field_id", field.ID, "if_expr", field.IfExpr, "result", result)
 	}
 
+	if err := k.countNode(field.ID); err != nil {
+		return nil, err
+	}
+
 	// Handle size attribute if present
 	var size int
 	if field.Size != nil {
@@ -881,6 +994,9 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 			return nil, fmt.Errorf("unsupported size type for field '%s': %T", field.ID, v)
 		}
 		k.logger.DebugContext(ctx, "Determined size for field", "field_id", field.ID, "size", size)
+		if err := k.checkAllocation(field.ID, int64(size)); err != nil {
+			return nil, err
+		}
 	}
 
 	// Handle repeat attribute
@@ -895,8 +1011,8 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 
 	// If no type is specified but size is given, treat as bytes (not string)
 	typeStr := getTypeAsString(field.Type)
-	if typeStr == "" && size > 0 {
-		// Default to bytes type for sized fields without explicit type
+	if typeStr == "" && field.Size != nil {
+		// Default to bytes type for sized fields without explicit type, even when empty
 		field.Type = "bytes"
 		typeStr = "bytes"
 	}
@@ -941,8 +1057,9 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 	var fieldData []byte
 	var subStream *kaitai.Stream
 	var err error
+	subStreamStart := fieldStart(pCtx.IO)
 
-	if size > 0 {
+	if field.Size != nil {
 		// Sized reads need byte alignment
 		if k.lastWasBitField {
 			k.logger.DebugContext(ctx, "Aligning to byte boundary before reading sized data", "field_id", field.ID, "size", size)
@@ -950,7 +1067,7 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 			k.lastWasBitField = false
 		}
 		// Read sized data
-		fieldData, err = pCtx.IO.ReadBytes(size)
+		fieldData, err = readBytes(pCtx.IO, size)
 		if err != nil { // pCtx.IO
 			return nil, fmt.Errorf("reading %d bytes for field '%s': %w", size, field.ID, err)
 		}
@@ -1027,10 +1144,18 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 		if err != nil {
 			return nil, fmt.Errorf("creating switch selector for field '%s': %w", field.ID, err)
 		}
-		resolvedType, err := switchSelector.ResolveType(ctx, pCtx, k)
+		resolvedType, switchValue, found, err := switchSelector.resolve(ctx, pCtx, k)
 		if err != nil {
 			return nil, fmt.Errorf("resolving switch type for field '%s': %w", field.ID, err)
 		}
+		if !found {
+			if field.Size == nil && !field.SizeEOS {
+				return nil, fmt.Errorf("resolving switch type for field '%s': no matching case for switch value '%v'", field.ID, switchValue)
+			}
+			// A sized switch without a matching case is read as raw bytes
+			k.logger.DebugContext(ctx, "No switch case matched, reading field as bytes", "field_id", field.ID, "switch_value", switchValue)
+			return k.parseBytesField(ctx, SequenceItem{ID: field.ID, SizeEOS: size > 0 || field.SizeEOS}, &ParseContext{IO: subStream}, 0)
+		}
 		actualFieldType = resolvedType
 		k.logger.DebugContext(ctx, "Switch object field type resolved", "field_id", field.ID, "resolved_type", actualFieldType)
 	case nil:
@@ -1042,7 +1167,12 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 
 	k.logger.DebugContext(ctx, "Recursively parsing field type", "field_id", field.ID, "field_type_to_parse", actualFieldType)
 	// Parse using the appropriate stream
+	streamBase := k.streamBase
+	if subStream != pCtx.IO {
+		k.streamBase += subStreamStart
+	}
 	result, err := k.parseType(ctx, actualFieldType, subStream)
+	k.streamBase = streamBase
 	if err != nil {
 		return nil, err
 	}
@@ -1082,7 +1212,7 @@ func (k *KaitaiInterpreter) parseField(ctx context.Context, field SequenceItem,
 	// Apply validation if specified
 	if field.Valid != nil {
 		if err := k.validateField(ctx, field, finalResult, pCtx); err != nil {
-			return nil, fmt.Errorf("validation failed for field '%s': %w", field.ID, err)
+			return nil, err
 		}
 	}
 
@@ -1215,6 +1345,9 @@ func (k *KaitaiInterpreter) parseRepeatedField(ctx context.Context, field Sequen
 			k.logger.ErrorContext(ctx, "Repeat expression result is not a number", "field_id", field.ID, "repeat_expr", field.RepeatExpr, "result_type", fmt.Sprintf("%T", expr))
 			return nil, fmt.Errorf("repeat expression for field '%s' ('%s') result is not a number: %v (type %T)", field.ID, field.RepeatExpr, expr, expr)
 		}
+		if err := k.checkRepeatItems(field.ID, count); err != nil {
+			return nil, err
+		}
 	} else if field.Repeat == "eos" {
 		// Repeat until end of stream
 		k.logger.DebugContext(ctx, "Repeating field until EOS", "field_id", field.ID)
@@ -1243,12 +1376,8 @@ func (k *KaitaiInterpreter) parseRepeatedField(ctx context.Context, field Sequen
 			itemField := field
 			itemField.Repeat = ""
 			itemField.RepeatExpr = ""
-			item, err := k.parseField(ctx, itemField, pCtx)
+			item, err := k.parseRepeatedItem(ctx, itemField, pCtx, items)
 			if err != nil {
-				if errors.Is(err, io.EOF) {
-					k.logger.WarnContext(ctx, "EOF reached while parsing repeated item", "field_id", field.ID, "iteration", i+1)
-					break
-				}
 				return nil, fmt.Errorf("parsing repeated item %d for field '%s': %w", i+1, field.ID, err)
 			}
 			items = append(items, item)
@@ -1256,24 +1385,36 @@ func (k *KaitaiInterpreter) parseRepeatedField(ctx context.Context, field Sequen
 	} else if count == -1 {
 		itemNum := 0
 		for {
+			select {
+			case <-ctx.Done():
+				k.logger.InfoContext(ctx, "Parsing EOS-repeated field cancelled", "field_id", field.ID, "items_parsed", len(items))
+				return nil, ctx.Err()
+			default:
+			}
+			if isEOF, err := pCtx.IO.EOF(); err == nil && isEOF {
+				break
+			}
 			itemNum++
+			if err := k.checkRepeatItems(field.ID, itemNum); err != nil {
+				return nil, err
+			}
 			k.logger.DebugContext(ctx, "Parsing EOS-repeated item", "field_id", field.ID, "item_num", itemNum)
+			startPos, err := pCtx.IO.Pos()
+			if err != nil {
+				return nil, fmt.Errorf("getting position of repeated item %d for field '%s': %w", itemNum, field.ID, err)
+			}
 			itemField := field
 			itemField.Repeat = ""
 			itemField.RepeatExpr = ""
-			item, err := k.parseField(ctx, itemField, pCtx)
+			item, err := k.parseRepeatedItem(ctx, itemField, pCtx, items)
 			if err != nil {
-				if errors.Is(err, io.EOF) {
-					break
-				}
 				return nil, fmt.Errorf("error parsing repeated item: %w", err)
 			}
 			items = append(items, item)
-			select {
-			case <-ctx.Done():
-				k.logger.InfoContext(ctx, "Parsing EOS-repeated field cancelled", "field_id", field.ID, "items_parsed", len(items))
-				return nil, ctx.Err()
-			default:
+			// An item that reads nothing would be repeated forever; bit
+			// fields may advance within a byte
+			if endPos, err := pCtx.IO.Pos(); err == nil && endPos == startPos && !k.lastWasBitField {
+				return nil, fmt.Errorf("item %d of field '%s' at offset %d: %w", itemNum, field.ID, startPos, ErrNoProgress)
 			}
 		}
 	} else if count == -2 {
@@ -1281,17 +1422,16 @@ func (k *KaitaiInterpreter) parseRepeatedField(ctx context.Context, field Sequen
 		itemNum := 0
 		for {
 			itemNum++
+			if err := k.checkRepeatItems(field.ID, itemNum); err != nil {
+				return nil, err
+			}
 			k.logger.DebugContext(ctx, "Parsing repeat-until item", "field_id", field.ID, "item_num", itemNum)
 			itemField := field
 			itemField.Repeat = ""
 			itemField.RepeatExpr = ""
 			itemField.RepeatUntil = ""
-			item, err := k.parseField(ctx, itemField, pCtx)
+			item, err := k.parseRepeatedItem(ctx, itemField, pCtx, items)
 			if err != nil {
-				if errors.Is(err, io.EOF) {
-					k.logger.WarnContext(ctx, "EOF reached while parsing repeat-until item", "field_id", field.ID, "items_parsed", len(items))
-					break
-				}
 				return nil, fmt.Errorf("error parsing repeated item %d for field '%s': %w", itemNum, field.ID, err)
 			}
 			items = append(items, item)
@@ -1355,6 +1495,20 @@ func (k *KaitaiInterpreter) parseRepeatedField(ctx context.Context, field Sequen
 	return result, nil
 }
 
+// parseRepeatedItem parses the item of a repeated field that follows items,
+// keeping them as the partial data of the field if the item fails
+func (k *KaitaiInterpreter) parseRepeatedItem(ctx context.Context, itemField SequenceItem, pCtx *ParseContext, items []*ParsedData) (*ParsedData, error) {
+	start := fieldStart(pCtx.IO)
+	defer k.enterPath(indexSegment(len(items)))()
+	item, err := k.parseField(ctx, itemField, pCtx)
+	if err != nil {
+		k.failAt(start)
+		k.keepPartialItems(itemField, items)
+		return nil, err
+	}
+	return item, nil
+}
+
 // parseContentsField handles fields with fixed contents
 func (k *KaitaiInterpreter) parseContentsField(ctx context.Context, field SequenceItem, pCtx *ParseContext) (*ParsedData, error) {
 	k.logger.DebugContext(ctx, "Parsing contents field", "field_id", field.ID)
@@ -1367,30 +1521,9 @@ func (k *KaitaiInterpreter) parseContentsField(ctx context.Context, field Sequen
 	default:
 	}
 	// Determine contents
-	var expected []byte
-
-	switch v := field.Contents.(type) {
-	case []any:
-		// Array of byte values
-		expected = make([]byte, len(v))
-		for i, b := range v {
-			switch val := b.(type) {
-			case float64:
-				expected[i] = byte(val)
-			case int:
-				expected[i] = byte(val)
-			case int64:
-				expected[i] = byte(val)
-			case uint64:
-				expected[i] = byte(val)
-			default:
-				return nil, fmt.Errorf("invalid content byte value for field '%s': %v (type %T)", field.ID, b, b)
-			}
-		}
-	case string:
-		expected = []byte(v)
-	default:
-		return nil, fmt.Errorf("unsupported contents type for field '%s': %T", field.ID, v)
+	expected, err := field.ContentsBytes()
+	if err != nil {
+		return nil, err
 	}
 	k.logger.DebugContext(ctx, "Expected contents for field", "field_id", field.ID, "expected_bytes", fmt.Sprintf("%x", expected))
 
@@ -1425,37 +1558,25 @@ func (k *KaitaiInterpreter) parseStringField(ctx context.Context, field Sequence
 	}
 
 	// Determine encoding
-	encoding := field.Encoding
-	if encoding == "" {
-		encoding = k.schema.Meta.Encoding
-	}
-	if encoding == "" {
-		encoding = "UTF-8" // Default encoding
-	}
+	encoding := k.stringEncoding(field)
 	k.logger.DebugContext(ctx, "Using encoding for string field", "field_id", field.ID, "encoding", encoding)
 
+	terminator, err := fieldTerminator(field, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminator for field '%s': %w", field.ID, err)
+	}
+
 	var strBytes []byte
-	var err error
 
-	if field.Type == "strz" {
-		// Zero-terminated string
-		strBytes, err = pCtx.IO.ReadBytesTerm(0, false, true, true)
-		k.logger.DebugContext(ctx, "Read zero-terminated string", "field_id", field.ID, "bytes_read", len(strBytes), "error", err)
-	} else if size > 0 {
+	if size > 0 {
 		// Fixed-size string (may have terminator/padding)
-		strBytes, err = pCtx.IO.ReadBytes(size)
+		strBytes, err = readBytes(pCtx.IO, size)
 		k.logger.DebugContext(ctx, "Read fixed-size string", "field_id", field.ID, "size", size, "bytes_read", len(strBytes), "error", err)
-	} else if field.Terminator != nil {
-		// String with custom terminator (no fixed size)
-		termValue := extractTerminatorByte(field.Terminator)
-		includeTerminator := false
-		if field.Include != nil {
-			if inc, ok := field.Include.(bool); ok {
-				includeTerminator = inc
-			}
-		}
-		strBytes, err = pCtx.IO.ReadBytesTerm(termValue, includeTerminator, true, true)
-		k.logger.DebugContext(ctx, "Read terminated string", "field_id", field.ID, "terminator", termValue, "include", includeTerminator, "bytes_read", len(strBytes), "error", err)
+	} else if terminator != nil && !field.SizeEOS {
+		// Zero-terminated string or string with custom terminator (no fixed size)
+		include := boolAttr(field.Include, false)
+		strBytes, err = readBytesTerm(pCtx.IO, terminator, include, boolAttr(field.Consume, true), boolAttr(field.EOSError, true))
+		k.logger.DebugContext(ctx, "Read terminated string", "field_id", field.ID, "terminator", terminator, "include", include, "bytes_read", len(strBytes), "error", err)
 	} else if field.SizeEOS {
 		// Read until end of stream
 		k.logger.DebugContext(ctx, "Reading string until EOS", "field_id", field.ID)
@@ -1486,6 +1607,9 @@ func (k *KaitaiInterpreter) parseStringField(ctx context.Context, field Sequence
 			return nil, fmt.Errorf("getting stream size for field '%s': %w", field.ID, err)
 		}
 		remainingSize := endPos - pos
+		if err := k.checkAllocation(field.ID, remainingSize); err != nil {
+			return nil, err
+		}
 		strBytes, err = pCtx.IO.ReadBytes(int(remainingSize))
 		if err != nil {
 			return nil, fmt.Errorf("reading string bytes until EOS for field '%s': %w", field.ID, err)
@@ -1517,7 +1641,7 @@ func (k *KaitaiInterpreter) parseStringField(ctx context.Context, field Sequence
 	// Apply validation if specified
 	if field.Valid != nil {
 		if err := k.validateField(ctx, field, result, pCtx); err != nil {
-			return nil, fmt.Errorf("validation failed for field '%s': %w", field.ID, err)
+			return nil, err
 		}
 	}
 
@@ -1529,40 +1653,13 @@ func (k *KaitaiInterpreter) processStringBytes(data []byte, field SequenceItem)
 	result := data
 	terminatorFound := false
 
-	// Handle terminator
-	if field.Terminator != nil {
-		terminator, err := k.extractByteValue(field.Terminator)
-		if err != nil {
-			return nil, fmt.Errorf("invalid terminator value: %w", err)
-		}
-
-		// Find terminator position
-		terminatorPos := -1
-		for i, b := range result {
-			if b == terminator {
-				terminatorPos = i
-				break
-			}
-		}
-
-		if terminatorPos >= 0 {
-			terminatorFound = true
-			// Include or exclude terminator based on 'include' field
-			include := false
-			if field.Include != nil {
-				if includeVal, ok := field.Include.(bool); ok {
-					include = includeVal
-				}
-			}
-
-			if include {
-				// Include terminator in result
-				result = result[:terminatorPos+1]
-			} else {
-				// Exclude terminator from result
-				result = result[:terminatorPos]
-			}
-		}
+	// Handle terminator, implied by strz
+	terminator, err := fieldTerminator(field, k.stringEncoding(field))
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminator value: %w", err)
+	}
+	if terminator != nil {
+		result, terminatorFound = terminateBytes(result, terminator, boolAttr(field.Include, false))
 	}
 
 	// Handle right padding removal - only if no terminator was found
@@ -1584,6 +1681,11 @@ func (k *KaitaiInterpreter) processStringBytes(data []byte, field SequenceItem)
 
 // extractByteValue converts various representations to a byte value
 func (k *KaitaiInterpreter) extractByteValue(value any) (byte, error) {
+	return byteValue(value)
+}
+
+// byteValue converts various representations to a byte value
+func byteValue(value any) (byte, error) {
 	switch v := value.(type) {
 	case int:
 		if v < 0 || v > 255 {
@@ -1682,21 +1784,25 @@ func (k *KaitaiInterpreter) parseBytesField(ctx context.Context, field SequenceI
 	var err error
 
 	if size > 0 {
-		// Fixed-size bytes
-		bytesData, err = pCtx.IO.ReadBytes(size)
+		// Fixed-size bytes, may have terminator/padding
+		bytesData, err = readBytes(pCtx.IO, size)
+		if err == nil && (field.Terminator != nil || field.PadRight != nil) {
+			bytesData, err = k.processStringBytes(bytesData, field)
+		}
 	} else if field.Terminator != nil {
 		// Bytes with custom terminator
-		termValue := extractTerminatorByte(field.Terminator)
-		includeTerminator := false
-		if field.Include != nil {
-			if inc, ok := field.Include.(bool); ok {
-				includeTerminator = inc
-			}
+		var terminator byte
+		if terminator, err = byteValue(field.Terminator); err != nil {
+			return nil, fmt.Errorf("invalid terminator for field '%s': %w", field.ID, err)
 		}
-		bytesData, err = pCtx.IO.ReadBytesTerm(termValue, includeTerminator, true, true)
-		k.logger.DebugContext(ctx, "Read terminated bytes", "field_id", field.ID, "terminator", termValue, "include", includeTerminator, "bytes_read", len(bytesData), "error", err)
+		include := boolAttr(field.Include, false)
+		bytesData, err = readBytesTerm(pCtx.IO, []byte{terminator}, include, boolAttr(field.Consume, true), boolAttr(field.EOSError, true))
+		k.logger.DebugContext(ctx, "Read terminated bytes", "field_id", field.ID, "terminator", terminator, "include", include, "bytes_read", len(bytesData), "error", err)
 	} else if field.SizeEOS {
 		// Read until end of stream
+		if err := k.checkRemainingAllocation(field.ID, pCtx.IO); err != nil {
+			return nil, err
+		}
 		bytesData, err = pCtx.IO.ReadBytesFull()
 	} else if size == 0 {
 		// Zero-length bytes - create empty byte array
@@ -1728,7 +1834,7 @@ func (k *KaitaiInterpreter) parseBytesField(ctx context.Context, field SequenceI
 	// Apply validation if specified
 	if field.Valid != nil {
 		if err := k.validateField(ctx, field, result, pCtx); err != nil {
-			return nil, fmt.Errorf("validation failed for field '%s': %w", field.ID, err)
+			return nil, err
 		}
 	}
 
@@ -1737,6 +1843,10 @@ func (k *KaitaiInterpreter) parseBytesField(ctx context.Context, field SequenceI
 
 // evaluateInstance calculates an instance field
 func (k *KaitaiInterpreter) evaluateInstance(goCtx context.Context, instanceName string, inst InstanceDef, pCtx *ParseContext) (*ParsedData, error) {
+	if inst.Pos != nil {
+		return k.parsePositionalInstance(goCtx, instanceName, inst, pCtx)
+	}
+
 	k.logger.DebugContext(goCtx, "Evaluating instance expression",
 		"instance_name", instanceName, // Use passed instanceName
 		"instance_expr", inst.Value,
@@ -1888,6 +1998,55 @@ func (k *KaitaiInterpreter) evaluateInstance(goCtx context.Context, instanceName
 	return result, nil
 }
 
+// parsePositionalInstance reads a `pos:` instance at its absolute offset in the
+// current stream and restores the stream position afterwards.
+func (k *KaitaiInterpreter) parsePositionalInstance(goCtx context.Context, instanceName string, inst InstanceDef, pCtx *ParseContext) (*ParsedData, error) {
+	pos, err := k.evaluatePosition(goCtx, inst.Pos, pCtx)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating pos of instance '%s': %w", instanceName, err)
+	}
+	k.logger.DebugContext(goCtx, "Parsing positional instance", "instance_name", instanceName, "pos", pos)
+
+	savedPos, err := pCtx.IO.Pos()
+	if err != nil {
+		return nil, fmt.Errorf("getting current position for instance '%s': %w", instanceName, err)
+	}
+	if _, err := pCtx.IO.Seek(pos, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seeking to %d for instance '%s': %w", pos, instanceName, err)
+	}
+	defer pCtx.IO.Seek(savedPos, io.SeekStart)
+
+	result, err := k.parseField(goCtx, inst.asSequenceItem(instanceName), pCtx)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		// Instance skipped by its if condition
+		return &ParsedData{Children: make(map[string]*ParsedData), Type: inst.Type}, nil
+	}
+	return result, nil
+}
+
+// evaluatePosition resolves a `pos` attribute, which is either a constant or an expression
+func (k *KaitaiInterpreter) evaluatePosition(goCtx context.Context, posSpec any, pCtx *ParseContext) (int64, error) {
+	var value any = posSpec
+	if expr, ok := posSpec.(string); ok {
+		result, err := k.evaluateExpression(goCtx, expr, pCtx)
+		if err != nil {
+			return 0, err
+		}
+		value = result
+	}
+	pos, ok := toInt64(value)
+	if !ok {
+		return 0, fmt.Errorf("position is not a number: %v (type %T)", value, value)
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("negative position %d", pos)
+	}
+	return pos, nil
+}
+
 // evaluateExpression evaluates a Kaitai expression using CEL
 func (k *KaitaiInterpreter) evaluateExpression(ctx context.Context, kaitaiExpr string, pCtx *ParseContext) (any, error) {
 	k.logger.DebugContext(ctx, "Evaluating CEL expression", "kaitai_expr", kaitaiExpr)
@@ -1910,9 +2069,13 @@ func (k *KaitaiInterpreter) evaluateExpression(ctx context.Context, kaitaiExpr s
 	}
 
 	// Evaluate expression
-	result, _, err := program.Eval(activation)
+	result, details, err := program.Eval(activation)
 
 	if err != nil {
+		if limitErr := k.celCostError(kaitaiExpr, details, err); limitErr != nil {
+			return nil, limitErr
+		}
+
 		// Add debug logging for failed expressions to understand type issues
 		if strings.Contains(err.Error(), "no such overload") {
 			k.logger.ErrorContext(ctx, "Expression evaluation failed with type error",
@@ -1960,8 +2123,39 @@ func (k *KaitaiInterpreter) evaluateExpression(ctx context.Context, kaitaiExpr s
 	return result.Value(), nil
 }
 
-// parsedDataToMap converts ParsedData to a map suitable for JSON serialization with kaitaicel support
-func ParsedDataToMap(data *ParsedData) any {
+// readBytes reads n bytes, failing before allocating when fewer remain so a
+// corrupt length field can't force a huge allocation
+func readBytes(stream *kaitai.Stream, n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("negative size %d", n)
+	}
+	pos, err := stream.Pos()
+	if err != nil {
+		return nil, err
+	}
+	size, err := stream.Size()
+	if err != nil {
+		return nil, err
+	}
+	if remaining := size - pos; int64(n) > remaining {
+		if remaining <= 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	return stream.ReadBytes(n)
+}
+
+// ParsedDataToMap converts ParsedData to a map suitable for JSON serialization
+// with kaitaicel support. opts select the shape of enums, bytes, big integers
+// and 1-bit fields; by default enums are objects, bytes are []byte and 1-bit
+// fields are booleans. With PreserveOrder, user types are *OrderedMap.
+func ParsedDataToMap(data *ParsedData, opts ...OutputOption) any {
+	return newOutputFormat(opts).parsedDataToMap(data)
+}
+
+// parsedDataToMap converts ParsedData to its output shape
+func (f outputFormat) parsedDataToMap(data *ParsedData) any {
 	if data == nil {
 		return nil
 	}
@@ -1972,19 +2166,30 @@ func ParsedDataToMap(data *ParsedData) any {
 			result := make([]any, len(arr))
 			for i, v := range arr {
 				if pd, ok := v.(*ParsedData); ok {
-					result[i] = ParsedDataToMap(pd)
+					result[i] = f.parsedDataToMap(pd)
 				} else {
-					result[i] = convertKaitaiTypeForSerialization(v)
+					result[i] = f.value(v)
 				}
 			}
 			return result
 		}
-		return convertKaitaiTypeForSerialization(data.Value)
+		return f.value(data.Value)
 	}
 
 	if len(data.Children) == 0 {
 		// Handle primitive types, including kaitaicel types
-		return convertKaitaiTypeForSerialization(data.Value)
+		return f.value(data.Value)
+	}
+
+	if f.ordered {
+		result := NewOrderedMap()
+		if data.Value != nil {
+			result.Set("_value", f.value(data.Value))
+		}
+		for _, name := range data.ChildNames() {
+			result.Set(name, f.parsedDataToMap(data.Children[name]))
+		}
+		return result
 	}
 
 	// Convert struct type with children
@@ -1992,19 +2197,20 @@ func ParsedDataToMap(data *ParsedData) any {
 
 	// Add value field if it exists and isn't zero/empty
 	if data.Value != nil {
-		result["_value"] = convertKaitaiTypeForSerialization(data.Value)
+		result["_value"] = f.value(data.Value)
 	}
 
 	// Add all children
 	for name, child := range data.Children {
-		result[name] = ParsedDataToMap(child)
+		result[name] = f.parsedDataToMap(child)
 	}
 
 	return result
 }
 
-// convertKaitaiTypeForSerialization converts kaitaicel types to JSON-serializable values
-func convertKaitaiTypeForSerialization(value any) any {
+// convertKaitaiTypeForSerialization converts kaitaicel types to JSON-serializable
+// values, other than those outputFormat.value shapes itself
+func convertKaitaiTypeForSerialization(value any, f outputFormat) any {
 	if value == nil {
 		return nil
 	}
@@ -2061,7 +2267,7 @@ func convertKaitaiTypeForSerialization(value any) any {
 			// Mixed types, return as []any
 			result := make([]any, len(celList))
 			for i, val := range celList {
-				result[i] = convertKaitaiTypeForSerialization(val.Value())
+				result[i] = f.value(val.Value())
 			}
 			return result
 		}
@@ -2070,38 +2276,18 @@ func convertKaitaiTypeForSerialization(value any) any {
 	// Handle kaitaicel types
 	if kaitaiType, ok := value.(kaitaicel.KaitaiType); ok {
 		switch kt := kaitaiType.(type) {
-		case *kaitaicel.KaitaiInt:
-			// Return the underlying integer value
-			return kt.Value()
 		case *kaitaicel.KaitaiFloat:
 			// Return the underlying float value
 			return kt.Value()
 		case *kaitaicel.KaitaiString:
 			// Return the string value, not the raw bytes
 			return kt.Value()
-		case *kaitaicel.KaitaiBytes:
-			// Return the raw bytes for serialization
-			return kt.Value()
 		case *kaitaicel.BcdType:
 			// Return a structured representation of BCD
 			return map[string]any{
 				"asInt": kt.AsInt(),
 				"asStr": kt.AsStr(),
-				"raw":   kt.RawBytes(),
-			}
-		case *kaitaicel.KaitaiBitField:
-			// For 1-bit fields, return as boolean (matching KSC behavior)
-			if kt.BitCount() == 1 {
-				return kt.AsBool()
-			}
-			// Return other bit fields as integer value
-			return kt.AsInt()
-		case *kaitaicel.KaitaiEnum:
-			// Return enum as a structured value
-			return map[string]any{
-				"value": kt.IntValue(),
-				"name":  kt.Name(),
-				"valid": kt.IsValid(),
+				"raw":   f.byteSlice(kt.RawBytes()),
 			}
 		default:
 			// For any other kaitai type, return the underlying value
@@ -2119,7 +2305,7 @@ func convertKaitaiTypeForSerialization(value any) any {
 				for k, v := range mapVal {
 					// Skip internal CEL attributes like _sizeof
 					if k != "_sizeof" {
-						result[k] = convertKaitaiTypeForSerialization(v)
+						result[k] = f.value(v)
 					}
 				}
 				return result
@@ -2260,7 +2446,7 @@ func (k *KaitaiInterpreter) convertToEnum(ctx context.Context, result *ParsedDat
 	}
 
 	// Get enum mapping from schema
-	enumMapping, exists := k.schema.Enums[enumName]
+	enumMapping, exists := k.schema.findEnum(k.typeStack, enumName)
 	if !exists {
 		return nil, fmt.Errorf("enum '%s' not found in schema", enumName)
 	}
@@ -2339,40 +2525,12 @@ func isBitType(typeName string) bool {
 	return false
 }
 
-// extractTerminatorByte extracts a byte value from various input types
-func extractTerminatorByte(val any) byte {
-	switch v := val.(type) {
-	case int:
-		return byte(v)
-	case int64:
-		return byte(v)
-	case float64:
-		return byte(v)
-	case byte:
-		return v
-	case string:
-		// Handle hex strings like "0x7c"
-		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
-			if num, err := strconv.ParseInt(v[2:], 16, 64); err == nil {
-				return byte(num)
-			}
-		}
-		// Try decimal
-		if num, err := strconv.ParseInt(v, 10, 64); err == nil {
-			return byte(num)
-		}
-	}
-	return 0
-}
-
 // validateField validates a parsed field value against its validation rules
 func (k *KaitaiInterpreter) validateField(ctx context.Context, field SequenceItem, result *ParsedData, pCtx *ParseContext) error {
 	if field.Valid == nil {
 		return nil // No validation required
 	}
 
-	validation := field.Valid
-
 	// Extract the actual value to validate
 	var valueToValidate any
 	if result != nil {
@@ -2384,237 +2542,25 @@ func (k *KaitaiInterpreter) validateField(ctx context.Context, field SequenceIte
 		}
 	}
 
-	// Handle enum-specific validation for in-enum check
-	if validation.InEnum && field.Enum != "" {
+	enumValid := func() (bool, error) {
 		// For enums, check the original KaitaiEnum object before value extraction
-		if kaitaiEnum, ok := result.Value.(*kaitaicel.KaitaiEnum); ok {
-			if !kaitaiEnum.IsValid() {
-				return fmt.Errorf("invalid enum value")
-			}
-		} else {
-			// If not a KaitaiEnum, this validation doesn't apply
-			return fmt.Errorf("in-enum validation can only be applied to enum fields")
-		}
-		return nil // Enum validation passed
-	}
-
-	// Handle simple value validation (e.g., valid: 123)
-	if validation.Value != nil {
-		if !k.isEqual(valueToValidate, validation.Value) {
-			return fmt.Errorf("value %v does not equal expected %v", valueToValidate, validation.Value)
+		kaitaiEnum, ok := result.Value.(*kaitaicel.KaitaiEnum)
+		if !ok {
+			return false, fmt.Errorf("in-enum validation can only be applied to enum fields")
 		}
-		return nil
+		return kaitaiEnum.IsValid(), nil
 	}
 
-	// Handle expression-based validation
-	if validation.Expr != "" {
+	evalExpr := func(expr string) (any, error) {
 		// Create a temporary context with the current value as "_"
 		tempCtx := &ParseContext{
-			Children: make(map[string]any),
+			Children: map[string]any{"_": valueToValidate},
 			Parent:   pCtx.Parent,
 			Root:     pCtx.Root,
 			IO:       pCtx.IO,
 		}
-
-		// Add current value as "_" for validation expression
-		tempCtx.Children["_"] = valueToValidate
-
-		// Evaluate the validation expression
-		result, err := k.evaluateExpression(ctx, validation.Expr, tempCtx)
-		if err != nil {
-			return fmt.Errorf("evaluating validation expression '%s': %w", validation.Expr, err)
-		}
-
-		// Check if result is true
-		if !isTrue(result) {
-			return fmt.Errorf("validation expression '%s' failed", validation.Expr)
-		}
-		return nil
-	}
-
-	// Handle min/max validation
-	if validation.Min != nil || validation.Max != nil {
-		if err := k.validateRange(valueToValidate, validation.Min, validation.Max); err != nil {
-			return err
-		}
-		return nil
-	}
-
-	// Handle any-of validation
-	if len(validation.AnyOf) > 0 {
-		for _, allowedValue := range validation.AnyOf {
-			if k.isEqual(valueToValidate, allowedValue) {
-				return nil // Found a match
-			}
-		}
-		return fmt.Errorf("value %v is not in allowed list %v", valueToValidate, validation.AnyOf)
-	}
-
-	return nil // No validation rules matched, consider valid
-}
-
-// isEqual compares two values for equality, handling different numeric types
-func (k *KaitaiInterpreter) isEqual(a, b any) bool {
-	if a == b {
-		return true
-	}
-
-	// Handle numeric comparisons with type conversion
-	aNum, aIsNum := k.toNumber(a)
-	bNum, bIsNum := k.toNumber(b)
-	if aIsNum && bIsNum {
-		return aNum == bNum
-	}
-
-	// Handle byte array comparisons
-	if aByte, aIsByte := a.([]byte); aIsByte {
-		if bByte, bIsByte := b.([]byte); bIsByte {
-			return bytes.Equal(aByte, bByte)
-		}
-		// Compare with array of numbers
-		if bSlice, bIsSlice := b.([]any); bIsSlice {
-			if len(aByte) != len(bSlice) {
-				return false
-			}
-			for i, val := range bSlice {
-				if bNum, ok := k.toNumber(val); ok {
-					if float64(aByte[i]) != bNum {
-						return false
-					}
-				} else {
-					return false
-				}
-			}
-			return true
-		}
-	}
-
-	// Handle string comparisons
-	if aStr, aIsStr := a.(string); aIsStr {
-		if bStr, bIsStr := b.(string); bIsStr {
-			return aStr == bStr
-		}
+		return k.evaluateExpression(ctx, expr, tempCtx)
 	}
 
-	return false
-}
-
-// toNumber converts a value to float64 if possible
-func (k *KaitaiInterpreter) toNumber(value any) (float64, bool) {
-	switch v := value.(type) {
-	case int:
-		return float64(v), true
-	case int8:
-		return float64(v), true
-	case int16:
-		return float64(v), true
-	case int32:
-		return float64(v), true
-	case int64:
-		return float64(v), true
-	case uint:
-		return float64(v), true
-	case uint8:
-		return float64(v), true
-	case uint16:
-		return float64(v), true
-	case uint32:
-		return float64(v), true
-	case uint64:
-		return float64(v), true
-	case float32:
-		return float64(v), true
-	case float64:
-		return v, true
-	default:
-		return 0, false
-	}
-}
-
-// validateRange validates that a value is within the specified min/max range
-func (k *KaitaiInterpreter) validateRange(value, min, max any) error {
-	valueNum, valueIsNum := k.toNumber(value)
-	if !valueIsNum {
-		// For non-numeric types, try string or byte comparison
-		if min != nil && max != nil {
-			if k.compareValues(value, min) < 0 {
-				return fmt.Errorf("value %v is less than minimum %v", value, min)
-			}
-			if k.compareValues(value, max) > 0 {
-				return fmt.Errorf("value %v is greater than maximum %v", value, max)
-			}
-		} else if min != nil {
-			if k.compareValues(value, min) < 0 {
-				return fmt.Errorf("value %v is less than minimum %v", value, min)
-			}
-		} else if max != nil {
-			if k.compareValues(value, max) > 0 {
-				return fmt.Errorf("value %v is greater than maximum %v", value, max)
-			}
-		}
-		return nil
-	}
-
-	// Numeric validation
-	if min != nil {
-		if minNum, minIsNum := k.toNumber(min); minIsNum {
-			if valueNum < minNum {
-				return fmt.Errorf("value %v is less than minimum %v", value, min)
-			}
-		}
-	}
-
-	if max != nil {
-		if maxNum, maxIsNum := k.toNumber(max); maxIsNum {
-			if valueNum > maxNum {
-				return fmt.Errorf("value %v is greater than maximum %v", value, max)
-			}
-		}
-	}
-
-	return nil
-}
-
-// compareValues compares two values, returning -1, 0, or 1
-func (k *KaitaiInterpreter) compareValues(a, b any) int {
-	// Try numeric comparison first
-	if aNum, aIsNum := k.toNumber(a); aIsNum {
-		if bNum, bIsNum := k.toNumber(b); bIsNum {
-			if aNum < bNum {
-				return -1
-			} else if aNum > bNum {
-				return 1
-			}
-			return 0
-		}
-	}
-
-	// Try string comparison
-	if aStr, aIsStr := a.(string); aIsStr {
-		if bStr, bIsStr := b.(string); bIsStr {
-			if aStr < bStr {
-				return -1
-			} else if aStr > bStr {
-				return 1
-			}
-			return 0
-		}
-	}
-
-	// Try byte slice comparison
-	if aBytes, aIsBytes := a.([]byte); aIsBytes {
-		if bBytes, bIsBytes := b.([]byte); bIsBytes {
-			return bytes.Compare(aBytes, bBytes)
-		}
-	}
-
-	// Fallback to string representation
-	aStr := fmt.Sprintf("%v", a)
-	bStr := fmt.Sprintf("%v", b)
-	if aStr < bStr {
-		return -1
-	} else if aStr > bStr {
-		return 1
-	}
-	return 0
+	return checkValid(field, field.ID, valueToValidate, enumValid, evalExpr)
 }
@@ -328,6 +328,37 @@ seq:
 	assert.Equal(t, []byte("world"), dataMap["eos_field"])
 }
 
+// Test sized fields without a type whose size evaluates to 0
+func TestTypeDefaultingEmptySize(t *testing.T) {
+	schemaYAML := `
+meta:
+  id: test_empty_size
+seq:
+  - id: len
+    type: u1
+  - id: body
+    size: len
+  - id: trailer
+    type: u1
+`
+
+	schema, err := NewKaitaiSchemaFromYAML([]byte(schemaYAML))
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	interpreter, err := NewKaitaiInterpreter(schema, logger)
+	require.NoError(t, err)
+
+	stream := kaitai.NewStream(bytes.NewReader([]byte{0x00, 0x2A}))
+	result, err := interpreter.Parse(context.Background(), stream)
+	require.NoError(t, err)
+
+	dataMap, ok := ParsedDataToMap(result).(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, []byte{}, dataMap["body"])
+	assert.Equal(t, int64(0x2A), dataMap["trailer"])
+}
+
 // Test 1-bit field boolean conversion
 func TestOneBitFieldBoolean(t *testing.T) {
 	schemaYAML := `
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"sync"
 	"testing"
 
 	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai" // Import for types.IsError and types.DefaultTypeAdapter
@@ -676,3 +677,66 @@ func ExampleParsedDataToMap() {
 	// Output:
 	// map[string]interface {}{"field_array":[]interface {}{0x1, 0x2}, "field_int":0xa, "field_nested":map[string]interface {}{"sub_field":0x64}, "field_str":"hello"}
 }
+
+func TestParse_InterpreterReuse(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "reused"},
+		Seq: []SequenceItem{
+			{ID: "len", Type: "u1"},
+			{ID: "body", Type: "body"},
+		},
+		Types: map[string]Type{
+			"body": {Seq: []SequenceItem{{ID: "data", Size: "_root.len"}}},
+		},
+	}
+	interp := newTestInterpreter(t, schema)
+	parse := func(data ...byte) (*ParsedData, error) {
+		return interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+	}
+
+	parsed, err := parse(2, 'a', 'b')
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ab"), getUnderlyingValue(getParsedValue(t, parsed, "body", "data")))
+
+	_, err = parse(5, 'x')
+	require.Error(t, err)
+	assert.Empty(t, interp.valueStack, "a failed parse leaves no contexts behind")
+	assert.Empty(t, interp.typeStack)
+
+	// _root refers to the stream being parsed, not to an earlier one
+	parsed, err = parse(1, 'c')
+	require.NoError(t, err)
+	assert.Equal(t, []byte("c"), getUnderlyingValue(getParsedValue(t, parsed, "body", "data")))
+}
+
+func TestParse_ForksParseConcurrently(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "forked"},
+		Seq: []SequenceItem{
+			{ID: "len", Type: "u1"},
+			{ID: "body", Type: "body"},
+		},
+		Types: map[string]Type{
+			"body": {Seq: []SequenceItem{{ID: "data", Size: "_root.len"}}},
+		},
+	}
+	interp := newTestInterpreter(t, schema)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fork := interp.Fork()
+			body := bytes.Repeat([]byte{byte(i)}, i)
+			for range 20 {
+				parsed, err := fork.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(append([]byte{byte(i)}, body...))))
+				if assert.NoError(t, err) {
+					assert.Equal(t, body, getUnderlyingValue(getParsedValue(t, parsed, "body", "data")))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Same(t, interp.expressionPool, interp.Fork().expressionPool, "forks share the compiled expressions")
+}
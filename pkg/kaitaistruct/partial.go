@@ -0,0 +1,120 @@
+package kaitaistruct
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+)
+
+// ParseError reports where a parse failed, along with the data decoded before
+// the failure. Parse returns one for every failed parse; its message is that of
+// the underlying error, which it unwraps to.
+type ParseError struct {
+	Path    string      // Path of the failing field, e.g. "header.entries[2].kind"; empty if no field was being parsed
+	Offset  int64       // Offset of the failing field in the parsed stream
+	Partial *ParsedData // Fields decoded before the failure, nested down to the failing field; nil if there are none
+	Err     error
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseFailure tracks the innermost failing field while a failed parse unwinds
+type parseFailure struct {
+	path    string
+	offset  int64
+	partial *ParsedData
+}
+
+// fieldStart returns the offset of the next field in stream, for failure reporting
+func fieldStart(stream *kaitai.Stream) int64 {
+	pos, err := stream.Pos()
+	if err != nil {
+		return 0
+	}
+	return pos
+}
+
+// failAt records the field at the current path, starting at offset in the
+// current stream, as the point of failure, unless a field nested in it failed first
+func (k *KaitaiInterpreter) failAt(offset int64) {
+	if k.failure == nil {
+		k.failure = &parseFailure{path: k.currentPath(), offset: k.streamBase + offset}
+	}
+}
+
+// keepPartial makes result, whose field id failed, the partial data of the
+// failure, nesting under id whatever that field decoded before failing
+func (k *KaitaiInterpreter) keepPartial(result *ParsedData, id string) {
+	if k.failure.partial != nil {
+		result.Children[id] = k.failure.partial
+	}
+	k.failure.partial = result
+}
+
+// keepPartialItems makes the items of a repeated field decoded before the
+// failing one, followed by what that item decoded, the partial data of the failure
+func (k *KaitaiInterpreter) keepPartialItems(field SequenceItem, items []*ParsedData) {
+	values := make([]any, 0, len(items)+1)
+	for _, item := range items {
+		values = append(values, item)
+	}
+	if k.failure.partial != nil {
+		values = append(values, k.failure.partial)
+	}
+	k.failure.partial = &ParsedData{
+		Value:   values,
+		Type:    getTypeAsString(field.Type),
+		IsArray: true,
+	}
+}
+
+// parseError wraps the error of a failed parse with the recorded failure
+func (k *KaitaiInterpreter) parseError(err error) *ParseError {
+	parseErr := &ParseError{Err: err}
+	if k.failure != nil {
+		parseErr.Path = k.failure.path
+		parseErr.Offset = k.failure.offset
+		parseErr.Partial = k.failure.partial
+	}
+	return parseErr
+}
+
+// enterPath appends a segment (a field ID or an "[i]" index) to the path of the
+// field being parsed, returning a function that removes it again
+func (k *KaitaiInterpreter) enterPath(segment string) func() {
+	k.path = append(k.path, segment)
+	return func() {
+		k.path = k.path[:len(k.path)-1]
+	}
+}
+
+// currentPath renders the path of the field being parsed
+func (k *KaitaiInterpreter) currentPath() string {
+	return renderPath(k.path)
+}
+
+// renderPath joins path segments, e.g. "header.entries[2].kind"
+func renderPath(segments []string) string {
+	var sb strings.Builder
+	for i, segment := range segments {
+		if i > 0 && !strings.HasPrefix(segment, "[") {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(segment)
+	}
+	return sb.String()
+}
+
+// indexSegment is the path segment of item i of a repeated field
+func indexSegment(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
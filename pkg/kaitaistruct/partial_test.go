@@ -0,0 +1,106 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requireParseError(t *testing.T, schema *KaitaiSchema, data []byte) *ParseError {
+	t.Helper()
+	interp, err := NewKaitaiInterpreter(schema, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	_, err = interp.Parse(context.Background(), kaitai.NewStream(bytes.NewReader(data)))
+	require.Error(t, err)
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr), "expected ParseError, got %v", err)
+	return parseErr
+}
+
+func TestParseError_Partial(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "record", Endian: "le"},
+		Seq: []SequenceItem{
+			{ID: "magic", Type: "u2"},
+			{ID: "header", Type: "header"},
+			{ID: "body", Type: "u4"},
+		},
+		Types: map[string]Type{
+			"header": {Seq: []SequenceItem{
+				{ID: "kind", Type: "u1"},
+				{ID: "len_entries", Type: "u1"},
+				{ID: "entries", Type: "entry", Repeat: "expr", RepeatExpr: "len_entries"},
+			}},
+			"entry": {Seq: []SequenceItem{
+				{ID: "tag", Type: "u1"},
+				{ID: "value", Type: "u2"},
+			}},
+		},
+	}
+
+	t.Run("nested field", func(t *testing.T) {
+		// Second entry is cut off after its tag
+		data := []byte{0xCD, 0xAB, 0x07, 0x02, 0x01, 0x10, 0x00, 0x02}
+		parseErr := requireParseError(t, schema, data)
+		assert.Equal(t, "header.entries[1].value", parseErr.Path)
+		assert.EqualValues(t, 8, parseErr.Offset)
+		assert.ErrorIs(t, parseErr, io.EOF)
+
+		partial, ok := ParsedDataToMap(parseErr.Partial).(map[string]any)
+		require.True(t, ok)
+		assert.EqualValues(t, 0xABCD, partial["magic"])
+		assert.NotContains(t, partial, "body")
+		header := partial["header"].(map[string]any)
+		assert.EqualValues(t, 7, header["kind"])
+		entries := header["entries"].([]any)
+		require.Len(t, entries, 2)
+		assert.EqualValues(t, 0x10, entries[0].(map[string]any)["value"])
+		assert.Equal(t, map[string]any{"tag": int64(2)}, entries[1])
+	})
+
+	t.Run("top level field", func(t *testing.T) {
+		data := []byte{0xCD, 0xAB, 0x07, 0x00, 0x01}
+		parseErr := requireParseError(t, schema, data)
+		assert.Equal(t, "body", parseErr.Path)
+		assert.EqualValues(t, 4, parseErr.Offset)
+		partial := ParsedDataToMap(parseErr.Partial).(map[string]any)
+		assert.Contains(t, partial, "header")
+		assert.NotContains(t, partial, "body")
+	})
+
+	t.Run("sized substream offset", func(t *testing.T) {
+		sized := &KaitaiSchema{
+			Meta: Meta{ID: "framed", Endian: "le"},
+			Seq: []SequenceItem{
+				{ID: "len_frame", Type: "u1"},
+				{ID: "frame", Type: "frame", Size: "len_frame"},
+			},
+			Types: map[string]Type{
+				"frame": {Seq: []SequenceItem{
+					{ID: "id", Type: "u1"},
+					{ID: "value", Type: "u4"},
+				}},
+			},
+		}
+		// The frame is 3 bytes, too short for its u4 value
+		parseErr := requireParseError(t, sized, []byte{0x03, 0x09, 0x01, 0x02, 0xFF})
+		assert.Equal(t, "frame.value", parseErr.Path)
+		assert.EqualValues(t, 2, parseErr.Offset)
+		partial := ParsedDataToMap(parseErr.Partial).(map[string]any)
+		assert.EqualValues(t, 9, partial["frame"].(map[string]any)["id"])
+	})
+
+	t.Run("first field", func(t *testing.T) {
+		parseErr := requireParseError(t, schema, []byte{0x01})
+		assert.Equal(t, "magic", parseErr.Path)
+		assert.EqualValues(t, 0, parseErr.Offset)
+		assert.Empty(t, ParsedDataToMap(parseErr.Partial))
+	})
+}
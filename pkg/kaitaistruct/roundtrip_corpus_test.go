@@ -0,0 +1,226 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+)
+
+var roundTripReport = flag.String("roundtrip-report", "", "write the corpus round-trip status report to this file")
+
+const (
+	corpusFormatsDir = "../../test/formats"
+	corpusBinDir     = "../../test/src"
+	corpusKSCTestDir = "../../test/go"
+)
+
+// Round-trip outcomes of a corpus format
+const (
+	roundTripPass       = "pass"
+	roundTripEquivalent = "equivalent"
+	roundTripNoFixture  = "no fixture"
+	roundTripParse      = "parse error"
+	roundTripSerialize  = "serialize error"
+	roundTripMismatch   = "mismatch"
+)
+
+// kscFixturePattern finds the binary a KSC-generated test in test/go reads
+var kscFixturePattern = regexp.MustCompile(`"\.\./\.\./src/([^"]+\.bin)"`)
+
+// corpusFixture returns the binary used by the format's KSC test, falling back to test/src/<format>.bin
+func corpusFixture(format string) (string, bool) {
+	if src, err := os.ReadFile(filepath.Join(corpusKSCTestDir, format+"_test.go")); err == nil {
+		if m := kscFixturePattern.FindSubmatch(src); m != nil {
+			return filepath.Join(corpusBinDir, string(m[1])), true
+		}
+	}
+	path := filepath.Join(corpusBinDir, format+".bin")
+	if _, err := os.Stat(path); err == nil {
+		return path, true
+	}
+	return "", false
+}
+
+// roundTripFormat parses a corpus format's fixture, serializes the result and
+// compares it with the fixture. Trailing bytes the parser never read are ignored.
+func roundTripFormat(t *testing.T, format string) (status string, detail string) {
+	defer func() {
+		if r := recover(); r != nil {
+			status, detail = roundTripParse, fmt.Sprintf("panic: %v", r)
+		}
+	}()
+
+	fixture, ok := corpusFixture(format)
+	if !ok {
+		return roundTripNoFixture, ""
+	}
+	original, err := os.ReadFile(fixture)
+	if err != nil {
+		return roundTripNoFixture, err.Error()
+	}
+	yamlData, err := os.ReadFile(filepath.Join(corpusFormatsDir, format+".ksy"))
+	if err != nil {
+		return roundTripParse, err.Error()
+	}
+	schema, err := NewKaitaiSchemaFromYAML(yamlData)
+	if err != nil {
+		return roundTripParse, err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	parsed, err := newTestInterpreter(t, schema).Parse(ctx, kaitai.NewStream(bytes.NewReader(original)))
+	if err != nil {
+		return roundTripParse, err.Error()
+	}
+
+	data, ok := ParsedDataToMap(parsed).(map[string]any)
+	if !ok && ParsedDataToMap(parsed) == nil {
+		// A root without fields converts to nil
+		data, ok = map[string]any{}, true
+	}
+	if !ok {
+		return roundTripSerialize, fmt.Sprintf("parsed root is %T, not a map", ParsedDataToMap(parsed))
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				status, detail = roundTripSerialize, fmt.Sprintf("panic: %v", r)
+			}
+		}()
+		var out []byte
+		out, err = newTestSerializer(t, schema).Serialize(ctx, data)
+		if err != nil {
+			status, detail = roundTripSerialize, err.Error()
+			return
+		}
+		consumed := original[:min(int(parsed.Size), len(original))]
+		if bytes.Equal(out, original) || bytes.Equal(out, consumed) {
+			status = roundTripPass
+			return
+		}
+		// Bytes the parser skips (padding, alignment, unused gaps) can't be
+		// reproduced, so the output may still read back as the same data. That
+		// is reported, but fails like any other mismatch.
+		status, detail = roundTripMismatch, describeMismatch(out, original)
+		reparsed, err := newTestInterpreter(t, schema).Parse(ctx, kaitai.NewStream(bytes.NewReader(out)))
+		if err == nil && reflect.DeepEqual(ParsedDataToMap(reparsed), ParsedDataToMap(parsed)) {
+			status = roundTripEquivalent
+		}
+	}()
+	return status, detail
+}
+
+// describeMismatch reports where serialized output first differs from the original
+func describeMismatch(out, original []byte) string {
+	n := min(len(out), len(original))
+	for i := range n {
+		if out[i] != original[i] {
+			return fmt.Sprintf("first difference at offset %d: got 0x%02x, want 0x%02x (%d bytes written, %d in fixture)", i, out[i], original[i], len(out), len(original))
+		}
+	}
+	return fmt.Sprintf("%d bytes written, %d in fixture", len(out), len(original))
+}
+
+// Reasons shared by known round-trip failures whose output reads back as the
+// parsed data but holds other bytes where the parser reads nothing
+const (
+	unreadBitsReason    = "bits after the last bit field of a byte are never read and are written as zeros"
+	unreadGapReason     = "bytes the parser skips, past a sized type's fields or before an instance's pos, are written as zeros"
+	unreadPaddingReason = "bytes after a terminator in a sized field are never read and are written as padding"
+)
+
+// knownRoundTripFailures lists corpus formats that parse but don't round-trip, with the reason
+var knownRoundTripFailures = map[string]string{
+	"bits_byte_aligned":       unreadBitsReason,
+	"bits_enum":               unreadBitsReason,
+	"bits_seq_endian_combo":   unreadBitsReason,
+	"buffered_struct":         unreadGapReason,
+	"bytes_pad_term":          unreadPaddingReason,
+	"combine_bool":            unreadBitsReason,
+	"expr_io_ternary":         unreadGapReason,
+	"expr_sizeof_value_sized": unreadGapReason,
+	"instance_std":            unreadGapReason,
+	"instance_std_array":      unreadGapReason,
+	"instance_user_array":     unreadGapReason,
+	"position_abs":            unreadGapReason,
+	"position_to_end":         unreadGapReason,
+	"str_pad_term":            unreadPaddingReason,
+	"str_pad_term_empty":      unreadPaddingReason,
+	"str_pad_term_utf16":      unreadPaddingReason,
+	"valid_fail_inst":         unreadGapReason,
+	"zlib_surrounded":         "compress/flate output differs from zlib's and is larger than the 12-byte field",
+	"zlib_with_header_78":     "compress/flate output differs from zlib's",
+}
+
+// TestRoundTrip_Corpus checks that every corpus format with a fixture serializes
+// back to the bytes it was parsed from. Formats the parser can't read yet are
+// skipped; run with -roundtrip-report=FILE to write the status of every format.
+func TestRoundTrip_Corpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(corpusFormatsDir, "*.ksy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no corpus formats found")
+	}
+
+	type result struct{ format, status, detail string }
+	var results []result
+	counts := make(map[string]int)
+	for _, path := range paths {
+		format := strings.TrimSuffix(filepath.Base(path), ".ksy")
+		t.Run(format, func(t *testing.T) {
+			status, detail := roundTripFormat(t, format)
+			results = append(results, result{format, status, detail})
+			counts[status]++
+
+			reason, known := knownRoundTripFailures[format]
+			switch status {
+			case roundTripPass:
+				if known {
+					t.Errorf("round-trips now, remove it from knownRoundTripFailures")
+				}
+			case roundTripNoFixture, roundTripParse:
+				t.Skipf("%s: %s", status, detail)
+			default:
+				if known {
+					t.Skipf("known failure: %s", reason)
+				}
+				t.Errorf("%s: %s", status, detail)
+			}
+		})
+	}
+
+	t.Logf("round-trip: %d pass, %d equivalent, %d serialize error, %d mismatch, %d parse error, %d no fixture",
+		counts[roundTripPass], counts[roundTripEquivalent], counts[roundTripSerialize], counts[roundTripMismatch],
+		counts[roundTripParse], counts[roundTripNoFixture])
+
+	if *roundTripReport == "" {
+		return
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].format < results[j].format })
+	var report strings.Builder
+	report.WriteString("| Format | Status | Detail |\n|---|---|---|\n")
+	for _, r := range results {
+		detail := strings.ReplaceAll(r.detail, "|", "\\|")
+		if reason, ok := knownRoundTripFailures[r.format]; ok {
+			detail = "known: " + reason
+		}
+		fmt.Fprintf(&report, "| %s | %s | %s |\n", r.format, r.status, strings.ReplaceAll(detail, "\n", " "))
+	}
+	if err := os.WriteFile(*roundTripReport, []byte(report.String()), 0o644); err != nil {
+		t.Fatalf("writing round-trip report: %v", err)
+	}
+}
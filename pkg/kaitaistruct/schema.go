@@ -1,6 +1,10 @@
 package kaitaistruct
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,6 +19,18 @@ type KaitaiSchema struct {
 	DocRef    string                 `yaml:"doc-ref"`
 	Params    []ParameterDef         `yaml:"params"`
 	RootType  string                 `yaml:"-"` // Not in the schema, but set by the processor
+
+	InstanceOrder []string `yaml:"-"` // Instance names in declaration order, which Instances loses
+}
+
+// UnmarshalYAML decodes a schema, recording the declaration order of its instances
+func (s *KaitaiSchema) UnmarshalYAML(value *yaml.Node) error {
+	type plain KaitaiSchema
+	if err := value.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.InstanceOrder = mappingKeys(value, "instances")
+	return nil
 }
 
 // Meta contains metadata about the KSY schema
@@ -48,6 +64,7 @@ type SequenceItem struct {
 	Terminator  any           `yaml:"terminator,omitempty"`
 	Include     any           `yaml:"include,omitempty"`
 	Consume     any           `yaml:"consume,omitempty"`
+	EOSError    any           `yaml:"eos-error,omitempty"`
 	Encoding    string        `yaml:"encoding,omitempty"`
 	PadRight    any           `yaml:"pad-right,omitempty"`
 	Doc         string        `yaml:"doc,omitempty"`
@@ -56,19 +73,105 @@ type SequenceItem struct {
 	Valid       *ValidationDef `yaml:"valid,omitempty"`
 }
 
+// ContentsBytes returns the fixed bytes of a field with `contents`
+func (f SequenceItem) ContentsBytes() ([]byte, error) {
+	switch v := f.Contents.(type) {
+	case []any:
+		// Array of byte values
+		expected := make([]byte, len(v))
+		for i, b := range v {
+			switch val := b.(type) {
+			case float64:
+				expected[i] = byte(val)
+			case int:
+				expected[i] = byte(val)
+			case int64:
+				expected[i] = byte(val)
+			case uint64:
+				expected[i] = byte(val)
+			default:
+				return nil, fmt.Errorf("invalid content byte value for field '%s': %v (type %T)", f.ID, b, b)
+			}
+		}
+		return expected, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported contents type for field '%s': %T", f.ID, v)
+	}
+}
+
 // Type defines a custom type in the KSY schema
 type Type struct {
 	Seq       []SequenceItem         `yaml:"seq"`
 	Types     map[string]*Type       `yaml:"types"`
 	Instances map[string]InstanceDef `yaml:"instances"`
+	Enums     map[string]EnumDef     `yaml:"enums"`
 	Params    []ParameterDef         `yaml:"params"`
 	Doc       string                 `yaml:"doc"`
 	DocRef    string                 `yaml:"doc-ref"`
+
+	InstanceOrder []string `yaml:"-"` // Instance names in declaration order, which Instances loses
+}
+
+// UnmarshalYAML decodes a type, recording the declaration order of its instances
+func (t *Type) UnmarshalYAML(value *yaml.Node) error {
+	type plain Type
+	if err := value.Decode((*plain)(t)); err != nil {
+		return err
+	}
+	t.InstanceOrder = mappingKeys(value, "instances")
+	return nil
+}
+
+// mappingKeys returns the keys of the mapping under key in node, in the order
+// they appear in the document
+func mappingKeys(node *yaml.Node, key string) []string {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key || node.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		entries := node.Content[i+1].Content
+		keys := make([]string, 0, len(entries)/2)
+		for j := 0; j+1 < len(entries); j += 2 {
+			keys = append(keys, entries[j].Value)
+		}
+		return keys
+	}
+	return nil
+}
+
+// fieldOrder lists the fields of a type in declaration order: its seq fields,
+// then its instances. Instances missing from instanceOrder, as in schemas built
+// in code, follow in name order.
+func fieldOrder(seq []SequenceItem, instances map[string]InstanceDef, instanceOrder []string) []string {
+	order := make([]string, 0, len(seq)+len(instances))
+	for _, item := range seq {
+		order = append(order, item.ID)
+	}
+	var unordered []string
+	for name := range instances {
+		if !slices.Contains(instanceOrder, name) {
+			unordered = append(unordered, name)
+		}
+	}
+	for _, name := range instanceOrder {
+		if _, ok := instances[name]; ok {
+			order = append(order, name)
+		}
+	}
+	slices.Sort(unordered)
+	return append(order, unordered...)
 }
 
 // InstanceDef defines an instance (calculated field) in the KSY schema
 type InstanceDef struct {
 	Value      string `yaml:"value"`
+	Pos        any    `yaml:"pos,omitempty"`  // Absolute offset in _io; int or expression
+	Size       any    `yaml:"size,omitempty"` // Size of a positional instance; int or expression
 	Type       string `yaml:"type,omitempty"`
 	Repeat     string `yaml:"repeat,omitempty"`
 	RepeatExpr string `yaml:"repeat-expr,omitempty"`
@@ -78,9 +181,40 @@ type InstanceDef struct {
 	DocRef     string `yaml:"doc-ref,omitempty"`
 }
 
+// asSequenceItem describes a positional instance as the field read or written at its position
+func (inst InstanceDef) asSequenceItem(id string) SequenceItem {
+	return SequenceItem{
+		ID:         id,
+		Type:       inst.Type,
+		Size:       inst.Size,
+		Repeat:     inst.Repeat,
+		RepeatExpr: inst.RepeatExpr,
+		IfExpr:     inst.IfExpr,
+		Encoding:   inst.Encoding,
+	}
+}
+
 // EnumDef defines an enumeration in the KSY schema
 type EnumDef map[any]string
 
+// findEnum looks up an enum declared in one of the types on typeStack, innermost
+// first, then at the top level. A qualified name such as `opcode::code_enum`
+// is looked up by its last segment.
+func (s *KaitaiSchema) findEnum(typeStack []string, name string) (EnumDef, bool) {
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		name = name[i+2:]
+	}
+	for i := len(typeStack) - 1; i >= 0; i-- {
+		if t, ok := s.Types[typeStack[i]]; ok {
+			if enumDef, ok := t.Enums[name]; ok {
+				return enumDef, true
+			}
+		}
+	}
+	enumDef, ok := s.Enums[name]
+	return enumDef, ok
+}
+
 // ParameterDef defines a parameter in the KSY schema
 type ParameterDef struct {
 	ID     string `yaml:"id"`
@@ -163,9 +297,33 @@ func NewKaitaiSchemaFromYAML(data []byte) (*KaitaiSchema, error) {
 	if err := yaml.Unmarshal(data, &schema); err != nil {
 		return nil, err
 	}
+	nameUnnamedFields(schema.Seq)
+	for _, t := range schema.Types {
+		nameUnnamedTypeFields(&t)
+	}
 	return &schema, nil
 }
 
+// nameUnnamedTypeFields names the fields without an id in t and its nested types
+func nameUnnamedTypeFields(t *Type) {
+	nameUnnamedFields(t.Seq)
+	for _, nested := range t.Types {
+		if nested != nil {
+			nameUnnamedTypeFields(nested)
+		}
+	}
+}
+
+// nameUnnamedFields gives fields without an id the `_unnamedN` name KSC uses,
+// N being the field's index in the sequence, so they don't share one entry
+func nameUnnamedFields(seq []SequenceItem) {
+	for i := range seq {
+		if seq[i].ID == "" {
+			seq[i].ID = fmt.Sprintf("_unnamed%d", i)
+		}
+	}
+}
+
 // CalculateTypeSize calculates the size of a custom type in bytes
 func (s *KaitaiSchema) CalculateTypeSize(typeName string) int64 {
 	// Look up the type in our types map
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"maps"
@@ -22,6 +24,44 @@ type KaitaiSerializer struct {
 	expressionPool  *internalCel.ExpressionPool
 	typeStack       []string // Stack of type names being processed for hierarchical resolution
 	logger          *slog.Logger
+	autoCompute     bool // Infer length/count fields from the data they describe
+	layout          *layoutState // Stream and type sizes carried between layout passes
+	skipValidation  bool         // Write values even if they violate `valid` or `contents`
+	path            []string     // Path of the value being serialized, for error reporting
+	bytesInput      BytesFormat  // Encoding of strings given for byte fields
+}
+
+// SerializerOption configures optional behaviour of a KaitaiSerializer
+type SerializerOption func(*KaitaiSerializer)
+
+// WithAutoCompute enables inference of fields that are used as the `size` or
+// `repeat-expr` of other fields (directly or via simple arithmetic such as
+// `len - 4`), so callers do not need to know wire lengths. Fields with any
+// other use are left to the caller, and values the input provides must match
+// the inferred ones.
+func WithAutoCompute(enabled bool) SerializerOption {
+	return func(k *KaitaiSerializer) {
+		k.autoCompute = enabled
+	}
+}
+
+// WithSkipValidation disables checking `valid` and `contents` constraints
+// before values are written. Use it to produce deliberately malformed data,
+// such as test vectors for a parser's error handling.
+func WithSkipValidation(skip bool) SerializerOption {
+	return func(k *KaitaiSerializer) {
+		k.skipValidation = skip
+	}
+}
+
+// WithInputFormat makes the serializer read data in the shapes selected by
+// opts, as output by ParsedDataToMap with the same options. Only the shape of
+// byte fields needs selecting: enum names, integer arrays for byte fields and
+// decimal strings for integers are accepted whatever the options.
+func WithInputFormat(opts ...OutputOption) SerializerOption {
+	return func(k *KaitaiSerializer) {
+		k.bytesInput = newOutputFormat(opts).bytes
+	}
 }
 
 // SerializeContext holds the current state during serialization
@@ -30,12 +70,20 @@ type SerializeContext struct {
 	Parent   *SerializeContext
 	Root     *SerializeContext
 	Writer   *kaitai.Writer
+	IO       *kaitai.Stream // Seekable view of the stream Writer writes to, exposed as _io
 	Children map[string]any
+
+	offsets map[string][2]int64 // Start and end of each sequence field written in IO
+	sizeOf  func() int64        // Size of the current type, exposed as _sizeof
 }
 
 // NewKaitaiSerializer creates a new serializer for a given schema
-func NewKaitaiSerializer(schema *KaitaiSchema, logger *slog.Logger) (*KaitaiSerializer, error) {
-	pool, err := internalCel.NewExpressionPool()
+func NewKaitaiSerializer(schema *KaitaiSchema, logger *slog.Logger, opts ...SerializerOption) (*KaitaiSerializer, error) {
+	env, err := internalCel.NewEnvironmentWithSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	pool, err := internalCel.NewExpressionPoolWithEnv(env)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create expression pool: %w", err)
 	}
@@ -45,11 +93,31 @@ func NewKaitaiSerializer(schema *KaitaiSchema, logger *slog.Logger) (*KaitaiSeri
 		log = slog.Default()
 	}
 
-	return &KaitaiSerializer{
+	serializer := &KaitaiSerializer{
 		schema:          schema,
 		expressionPool:  pool,
 		logger:          log,
-	}, nil
+		layout:          newLayoutState(),
+	}
+	for _, opt := range opts {
+		opt(serializer)
+	}
+	return serializer, nil
+}
+
+// Fork returns a serializer with the same schema and options that shares the
+// compiled CEL environment and expressions, with its own serialization state.
+// Forks of one serializer may serialize concurrently.
+func (k *KaitaiSerializer) Fork() *KaitaiSerializer {
+	return &KaitaiSerializer{
+		schema:         k.schema,
+		expressionPool: k.expressionPool,
+		logger:         k.logger,
+		autoCompute:    k.autoCompute,
+		layout:         newLayoutState(),
+		skipValidation: k.skipValidation,
+		bytesInput:     k.bytesInput,
+	}
 }
 
 // AsActivation creates a CEL activation from the serialization context
@@ -63,6 +131,13 @@ func (ctx *SerializeContext) AsActivation() (cel.Activation, error) {
 
 	// Add special variables
 	vars["_writer"] = ctx.Writer
+	if ctx.IO != nil {
+		vars["_io"] = kaitaicel.ConvertForCELActivation(ctx.IO)
+	}
+	if ctx.sizeOf != nil {
+		// Resolved lazily so only expressions using _sizeof take part in layout passes
+		vars["_sizeof"] = func() any { return ctx.sizeOf() }
+	}
 	if ctx.Root != nil {
 		vars["_root"] = ctx.Root.Value
 	}
@@ -81,29 +156,59 @@ func (k *KaitaiSerializer) Serialize(ctx context.Context, data map[string]any) (
 		return nil, ctx.Err()
 	default:
 	}
+	// Get root type
+	rootType := k.schema.Meta.ID
+	if k.schema.RootType != "" {
+		rootType = k.schema.RootType
+	}
+
+	// JSON decoded with UseNumber, as Benthos does, holds numbers as json.Number
+	if normalized, changed := numbersFromJSON(data); changed {
+		data = normalized.(map[string]any)
+	}
+
+	// Sizes read through _io.size or _sizeof are only final once everything is
+	// written, so serialize again with the measured sizes until they stop changing
+	k.layout = newLayoutState()
+	k.path = nil
+	for pass := 1; ; pass++ {
+		k.layout.startPass()
+		out, err := k.serializePass(ctx, rootType, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed serializing root type '%s': %w", rootType, err)
+		}
+		if k.layout.converged() {
+			k.logger.DebugContext(ctx, "Finished Kaitai serialization", "layout_passes", pass)
+			return out, nil
+		}
+		if pass == maxLayoutPasses {
+			return nil, fmt.Errorf("layout did not converge after %d passes: sizes read through _io.size or _sizeof keep changing", maxLayoutPasses)
+		}
+		k.logger.DebugContext(ctx, "Sizes changed during layout, serializing again", "pass", pass)
+	}
+}
+
+// serializePass performs one complete serialization into a fresh root stream
+func (k *KaitaiSerializer) serializePass(ctx context.Context, rootType string, data map[string]any) ([]byte, error) {
 	// Create a buffer to write to
-	buf := bytes.NewBuffer(nil)
-	writer := kaitai.NewWriter(buf)
+	buf := k.newLayoutBuffer(-1)
 
 	// Create root context
 	rootCtx := &SerializeContext{
 		Value:    data,
 		Children: data,
-		Writer:   writer,
+		Writer:   kaitai.NewWriter(buf),
+		IO:       kaitai.NewStream(buf),
 	}
 	rootCtx.Root = rootCtx
 
-	// Get root type
-	rootType := k.schema.Meta.ID
-	if k.schema.RootType != "" {
-		rootType = k.schema.RootType
-	}
-
 	// Serialize according to root type
 	if err := k.serializeType(ctx, rootType, data, rootCtx); err != nil {
-		return nil, fmt.Errorf("failed serializing root type '%s': %w", rootType, err)
+		return nil, err
+	}
+	if err := buf.finish(); err != nil {
+		return nil, err
 	}
-	k.logger.DebugContext(ctx, "Finished Kaitai serialization")
 	return buf.Bytes(), nil
 }
 
@@ -134,21 +239,12 @@ func (k *KaitaiSerializer) serializeType(goCtx context.Context, typeName string,
 			Value:    dataMap,
 			Children: dataMap,
 			Writer:   sCtx.Writer,
+			IO:       sCtx.IO,
 			Parent:   sCtx,
 			Root:     sCtx.Root,
 		}
-		
-		// Pre-evaluate root-level instances
-		if k.schema.Instances != nil {
-			k.logger.DebugContext(goCtx, "Pre-evaluating root-level instances", "instance_count", len(k.schema.Instances))
-			err := k.evaluateInstancesWithDependencies(goCtx, k.schema.Instances, fieldCtx)
-			if err != nil {
-				k.logger.WarnContext(goCtx, "Some root-level instances could not be evaluated due to dependencies", "error", err)
-			}
-		}
-		
-		// Use helper to serialize sequence
-		return k.serializeSequence(goCtx, typeName, k.schema.Seq, dataMap, fieldCtx)
+
+		return k.serializeTypeBody(goCtx, typeName, k.schema.Seq, k.schema.Instances, fieldCtx)
 	}
 
 	// Check if it's a built-in type (only for primitive type names)
@@ -171,6 +267,10 @@ func (k *KaitaiSerializer) serializeType(goCtx context.Context, typeName string,
 
 	// Serialize type object
 	dataMap, ok := data.(map[string]any)
+	if !ok && data == nil && len(typeObj.Seq) == 0 {
+		// A type without fields parses to nil, as ParsedDataToMap drops empty structs
+		dataMap, ok = map[string]any{}, true
+	}
 	if !ok {
 		return fmt.Errorf("expected map for type %s, got %T", typeName, data)
 	}
@@ -180,6 +280,7 @@ func (k *KaitaiSerializer) serializeType(goCtx context.Context, typeName string,
 		Value:    dataMap,
 		Children: dataMap,
 		Writer:   sCtx.Writer,
+		IO:       sCtx.IO,
 		Parent:   sCtx,
 		Root:     sCtx.Root,
 	}
@@ -191,25 +292,89 @@ func (k *KaitaiSerializer) serializeType(goCtx context.Context, typeName string,
 		k.typeStack = k.typeStack[:len(k.typeStack)-1]
 	}()
 
+	if err := k.serializeTypeBody(goCtx, typeName, typeObj.Seq, typeObj.Instances, fieldCtx); err != nil {
+		k.logger.ErrorContext(goCtx, "Error serializing sequence for type", "type_name", typeName, "error", err)
+
+		return err
+	}
+
+	k.logger.DebugContext(goCtx, "Finished serializing type", "type_name", typeName)
+	return nil
+}
+
+// serializeTypeBody writes the sequence and positional instances of a user type
+// into the stream of fieldCtx and records the type's size for _sizeof.
+func (k *KaitaiSerializer) serializeTypeBody(goCtx context.Context, typeName string, sequence []SequenceItem, instances map[string]InstanceDef, fieldCtx *SerializeContext) error {
+	var sizeID int
+	var start int64
+	if fieldCtx.IO != nil {
+		sizeID = k.layout.newID()
+		start, _ = fieldCtx.IO.Pos()
+		fieldCtx.offsets = make(map[string][2]int64)
+		fieldCtx.sizeOf = func() int64 {
+			pos, _ := fieldCtx.IO.Pos()
+			return k.layout.size(sizeID, pos-start)
+		}
+	}
+
 	// Pre-evaluate instances for this type and add them to the fieldCtx.Children
 	// This makes them available for expressions in seq items (if, size, repeat-expr, etc.)
-	if typeObj.Instances != nil {
-		k.logger.DebugContext(goCtx, "Pre-evaluating instances for type", "type_name", typeName, "instance_count", len(typeObj.Instances))
-		err := k.evaluateInstancesWithDependencies(goCtx, typeObj.Instances, fieldCtx)
+	if instances != nil {
+		k.logger.DebugContext(goCtx, "Pre-evaluating instances for type", "type_name", typeName, "instance_count", len(instances))
+		// Evaluate into a copy so instance values don't leak into the caller's data
+		fieldCtx.Children = maps.Clone(fieldCtx.Children)
+		fieldCtx.Value = fieldCtx.Children
+		err := k.evaluateInstancesWithDependencies(goCtx, instances, fieldCtx)
 		if err != nil {
 			k.logger.WarnContext(goCtx, "Some instances could not be evaluated due to dependencies", "type_name", typeName, "error", err)
 		}
 	}
 
-	// Use helper to serialize sequence
-	if err := k.serializeSequence(goCtx, typeName, typeObj.Seq, dataMap, fieldCtx); err != nil {
-		k.logger.ErrorContext(goCtx, "Error serializing sequence for type", "type_name", typeName, "error", err)
+	inferred, err := k.inferSwitchSelectors(goCtx, typeName, sequence, fieldCtx.Children)
+	if err != nil {
+		return fmt.Errorf("inferring switch selectors for type '%s': %w", typeName, err)
+	}
+	if len(inferred) > 0 {
+		// Add them to a copy so they don't leak into the caller's data
+		children := maps.Clone(fieldCtx.Children)
+		maps.Copy(children, inferred)
+		fieldCtx.Children = children
+		fieldCtx.Value = children
+	}
+
+	if k.autoCompute {
+		// Work on a copy so inferred values don't leak into the caller's data.
+		// Sizes of positional instances are inferred like those of sequence fields.
+		children := maps.Clone(fieldCtx.Children)
+		fields := append(slices.Clone(sequence), positionalInstanceItems(instances)...)
+		if err := k.autoComputeFields(goCtx, typeName, fields, instances, &SerializeContext{
+			Value:    children,
+			Children: children,
+			Parent:   fieldCtx.Parent,
+			Root:     fieldCtx.Root,
+			Writer:   fieldCtx.Writer,
+			IO:       fieldCtx.IO,
+			sizeOf:   fieldCtx.sizeOf,
+		}); err != nil {
+			return fmt.Errorf("auto-computing fields for type '%s': %w", typeName, err)
+		}
+		fieldCtx.Children = children
+		fieldCtx.Value = children
+	}
 
+	patches := k.planPositionalPatches(goCtx, typeName, sequence, instances, fieldCtx)
+
+	// Use helper to serialize sequence
+	if err := k.serializeSequence(goCtx, typeName, sequence, fieldCtx.Children, fieldCtx); err != nil {
 		return err
 	}
 
-	k.logger.DebugContext(goCtx, "Finished serializing type", "type_name", typeName)
-	return nil
+	if fieldCtx.IO != nil {
+		end, _ := fieldCtx.IO.Pos()
+		k.layout.record(sizeID, end-start)
+	}
+
+	return k.serializePositionalInstances(goCtx, typeName, sequence, instances, patches, fieldCtx)
 }
 
 // serializeAdHocSwitchType handles serialization of switch types defined in the type name string.
@@ -272,7 +437,7 @@ func (k *KaitaiSerializer) serializeSequence(goCtx context.Context, typeName str
 			if !dataOk && actualType != "" {
 				k.logger.WarnContext(goCtx, "Data for switch field not found, but type resolved", "field_id", seq.ID, "resolved_type", actualType)
 			}
-			if err := k.serializeField(goCtx, seqCopy, fieldData, typeCtx); err != nil {
+			if err := k.serializeSequenceField(goCtx, seqCopy, fieldData, typeCtx); err != nil {
 				return fmt.Errorf("serializing switch field '%s' (resolved as '%s') in type '%s': %w", seq.ID, actualType, typeName, err)
 			}
 			continue
@@ -287,17 +452,45 @@ func (k *KaitaiSerializer) serializeSequence(goCtx context.Context, typeName str
 				k.logger.DebugContext(goCtx, "Data for conditional field not found, will be handled by 'if' expr", "field_id", seq.ID, "type_name", typeName)
 			}
 		}
-		if err := k.serializeField(goCtx, seq, fieldData, typeCtx); err != nil {
+		if err := k.serializeSequenceField(goCtx, seq, fieldData, typeCtx); err != nil {
 			return fmt.Errorf("error serializing field '%s' in type '%s': %w", seq.ID, typeName, err)
 		}
 	}
 	return nil
 }
 
+// serializeSequenceField serializes a sequence field and records where it was
+// written, so offset fields can be back-patched later
+func (k *KaitaiSerializer) serializeSequenceField(goCtx context.Context, field SequenceItem, data any, typeCtx *SerializeContext) error {
+	defer k.enterPath(field.ID)()
+
+	// Byte-aligned fields start on a fresh byte, as in the parser
+	if !isBitType(getTypeAsString(field.Type)) {
+		if buf, err := layoutBufferOf(typeCtx); err == nil {
+			buf.alignToByte()
+		}
+	}
+
+	if typeCtx.offsets == nil || typeCtx.IO == nil {
+		return k.serializeField(goCtx, field, data, typeCtx)
+	}
+	start, _ := typeCtx.IO.Pos()
+	if err := k.serializeField(goCtx, field, data, typeCtx); err != nil {
+		return err
+	}
+	end, _ := typeCtx.IO.Pos()
+	typeCtx.offsets[field.ID] = [2]int64{start, end}
+	return nil
+}
+
 // serializeBuiltinType handles serialization of built-in types using kaitaicel
 func (k *KaitaiSerializer) serializeBuiltinType(goCtx context.Context, typeName string, data any, writer *kaitai.Writer) (bool, error) {
 	k.logger.DebugContext(goCtx, "Serializing built-in type with kaitaicel", "type_name", typeName)
 
+	if bitTypePattern.MatchString(typeName) {
+		return true, k.serializeBitField(goCtx, typeName, data, writer)
+	}
+
 	// Handle type-specific endianness if not specified (but only for multi-byte types)
 	actualTypeName := typeName
 	if (strings.HasPrefix(typeName, "u") || strings.HasPrefix(typeName, "s") || strings.HasPrefix(typeName, "f")) &&
@@ -312,6 +505,13 @@ func (k *KaitaiSerializer) serializeBuiltinType(goCtx context.Context, typeName
 		}
 	}
 
+	// Integers beyond the exact range of JSON numbers may be given as decimal strings
+	if s, ok := data.(string); ok {
+		if n, ok := integerString(actualTypeName, s); ok {
+			data = n
+		}
+	}
+
 	// Use kaitaicel centralized factory to create the type
 	kaitaiType, err := kaitaicel.NewKaitaiTypeFromValue(data, actualTypeName)
 	if err != nil {
@@ -387,6 +587,32 @@ func (k *KaitaiSerializer) serializeField(goCtx context.Context, field SequenceI
 		return k.serializeRepeatedField(goCtx, field, data, sCtx)
 	}
 
+	// Reject values the parser would reject when reading the data back
+	if field.Valid != nil && !k.skipValidation {
+		if err := k.validateField(goCtx, field, data, sCtx); err != nil {
+			return err
+		}
+	}
+
+	// Switch types are serialized as the case type selected by the switch-on value
+	if switchType, ok := field.Type.(map[string]any); ok {
+		spec, err := NewSwitchTypeSelector(switchType, k.schema)
+		if err != nil {
+			return fmt.Errorf("creating switch selector for field '%s': %w", field.ID, err)
+		}
+		caseType, value, found, err := k.switchCaseType(goCtx, spec, sCtx)
+		if err != nil {
+			return fmt.Errorf("resolving switch type for field '%s': %w", field.ID, err)
+		}
+		if !found {
+			if field.Size == nil && !field.SizeEOS {
+				return fmt.Errorf("resolving switch type for field '%s': no matching case for switch value '%v'", field.ID, value)
+			}
+			caseType = "bytes" // A sized switch without a matching case holds raw bytes, as in the parser
+		}
+		field.Type = caseType
+	}
+
 	// Handle enum fields - extract numeric value from enum object
 	if field.Enum != "" {
 		return k.serializeEnumField(goCtx, field, data, sCtx)
@@ -394,7 +620,7 @@ func (k *KaitaiSerializer) serializeField(goCtx context.Context, field SequenceI
 
 	// Handle contents attribute
 	if field.Contents != nil {
-		return k.serializeContentsField(goCtx, field, sCtx)
+		return k.serializeContentsField(goCtx, field, data, sCtx)
 	}
 
 	// Handle process first (before type-specific handling)
@@ -407,6 +633,11 @@ func (k *KaitaiSerializer) serializeField(goCtx context.Context, field SequenceI
 		return k.serializeStringField(goCtx, field, data, sCtx)
 	}
 
+	// Untyped fields with a size, size-eos or terminator are raw bytes, as in the parser
+	if getTypeAsString(field.Type) == "" && (field.Size != nil || field.SizeEOS || field.Terminator != nil) {
+		field.Type = "bytes"
+	}
+
 	if field.Type == "bytes" {
 		return k.serializeBytesField(goCtx, field, data, sCtx)
 	}
@@ -430,6 +661,11 @@ func (k *KaitaiSerializer) serializeField(goCtx context.Context, field SequenceI
 		return k.serializeType(goCtx, actualType, data, sCtx)
 	}
 
+	// Sized user types get their own substream, padded to the declared size
+	if field.Size != nil {
+		return k.serializeSizedType(goCtx, field, data, sCtx)
+	}
+
 	k.logger.DebugContext(goCtx, "Recursively serializing field's defined type", "field_id", field.ID, "defined_type", field.Type)
 
 	// Default: serialize as a type
@@ -482,6 +718,11 @@ func (k *KaitaiSerializer) serializeRepeatedField(goCtx context.Context, field S
 			return fmt.Errorf("array length %d for field '%s' doesn't match expected repeat count %d from expression '%s'", len(items), field.ID, expectedCount, field.RepeatExpr)
 		}
 	}
+	if field.Repeat == "until" && field.RepeatUntil != "" {
+		if err := k.checkRepeatUntil(goCtx, field, items, sCtx); err != nil {
+			return err
+		}
+	}
 	k.logger.DebugContext(goCtx, "Determined item count for repeated field", "field_id", field.ID, "count", len(items))
 
 	// Serialize each item
@@ -496,8 +737,12 @@ func (k *KaitaiSerializer) serializeRepeatedField(goCtx context.Context, field S
 		itemField := field
 		itemField.Repeat = ""
 		itemField.RepeatExpr = ""
+		itemField.RepeatUntil = ""
 
-		if err := k.serializeField(goCtx, itemField, item, sCtx); err != nil {
+		leavePath := k.enterPath(fmt.Sprintf("[%d]", i))
+		err := k.serializeField(goCtx, itemField, item, sCtx)
+		leavePath()
+		if err != nil {
 			return fmt.Errorf("serializing item %d of repeated field '%s': %w", i, field.ID, err)
 		}
 	}
@@ -506,33 +751,56 @@ func (k *KaitaiSerializer) serializeRepeatedField(goCtx context.Context, field S
 	return nil
 }
 
+// checkRepeatUntil makes sure the items of a repeat-until field read back as the same list:
+// the condition must hold for the last item and for none before it, or the parser would stop elsewhere
+func (k *KaitaiSerializer) checkRepeatUntil(goCtx context.Context, field SequenceItem, items []any, sCtx *SerializeContext) error {
+	if len(items) == 0 {
+		return fmt.Errorf("repeat-until field '%s' needs at least one item", field.ID)
+	}
+	for i, item := range items {
+		// The current item is available as "_", as in the parser
+		tempCtx := &SerializeContext{
+			Value:    item,
+			Children: map[string]any{"_": item},
+			Parent:   sCtx,
+			Root:     sCtx.Root,
+			Writer:   sCtx.Writer,
+			IO:       sCtx.IO,
+		}
+		result, err := k.evaluateExpression(goCtx, field.RepeatUntil, tempCtx)
+		if err != nil {
+			return fmt.Errorf("evaluating repeat-until condition for item %d of field '%s' ('%s'): %w", i, field.ID, field.RepeatUntil, err)
+		}
+		last := i == len(items)-1
+		if isTrue(result) && !last {
+			return fmt.Errorf("item %d of repeat-until field '%s' satisfies '%s' before the last item", i, field.ID, field.RepeatUntil)
+		}
+		if !isTrue(result) && last {
+			return fmt.Errorf("last item of repeat-until field '%s' does not satisfy '%s'", field.ID, field.RepeatUntil)
+		}
+	}
+	return nil
+}
+
 // serializeContentsField handles serialization of fields with fixed content
-func (k *KaitaiSerializer) serializeContentsField(goCtx context.Context, field SequenceItem, sCtx *SerializeContext) error {
+func (k *KaitaiSerializer) serializeContentsField(goCtx context.Context, field SequenceItem, data any, sCtx *SerializeContext) error {
 	k.logger.DebugContext(goCtx, "Serializing contents field", "field_id", field.ID)
 	select {
 	case <-goCtx.Done():
 		return goCtx.Err()
 	default:
 	}
-	var expected []byte
+	expected, err := field.ContentsBytes()
+	if err != nil {
+		return err
+	}
+	k.logger.DebugContext(goCtx, "Expected contents for field", "field_id", field.ID, "expected_bytes", fmt.Sprintf("%x", expected))
 
-	switch v := field.Contents.(type) {
-	case []any:
-		// Array of byte values
-		expected = make([]byte, len(v))
-		for i, b := range v {
-			if val, ok := b.(float64); ok {
-				expected[i] = byte(val)
-			} else {
-				return fmt.Errorf("invalid content byte value for field '%s': %v", field.ID, b)
-			}
+	if !k.skipValidation {
+		if err := k.validateContents(data, expected); err != nil {
+			return err
 		}
-	case string:
-		expected = []byte(v)
-	default:
-		return fmt.Errorf("unsupported contents type for field '%s': %T", field.ID, v)
 	}
-	k.logger.DebugContext(goCtx, "Expected contents for field", "field_id", field.ID, "expected_bytes", fmt.Sprintf("%x", expected))
 
 	// Write the fixed content
 	if err := sCtx.Writer.WriteBytes(expected); err != nil {
@@ -596,8 +864,8 @@ func (k *KaitaiSerializer) serializeStringField(goCtx context.Context, field Seq
 	}
 
 	// Handle size attribute
+	size := -1
 	if field.Size != nil {
-		var size int
 		switch v := field.Size.(type) {
 		case int:
 			size = v
@@ -635,21 +903,12 @@ func (k *KaitaiSerializer) serializeStringField(goCtx context.Context, field Seq
 			return fmt.Errorf("unsupported size type for string field '%s': %T", field.ID, v)
 		}
 		k.logger.DebugContext(goCtx, "Determined size for string field", "field_id", field.ID, "size", size)
-
-		// Validate or adjust length
-		if len(strBytes) > size {
-			strBytes = strBytes[:size] // Truncate
-		} else if len(strBytes) < size {
-			// Pad with zeros
-			padding := make([]byte, size-len(strBytes))
-			strBytes = append(strBytes, padding...)
-		}
 	}
 
-	// Write string bytes
-	if field.Type == "strz" {
-		// Add null terminator
-		strBytes = append(strBytes, 0)
+	// Add terminator and padding, truncating values longer than size
+	strBytes, err := terminateField(field, encoding, strBytes, size, remaining(sCtx.Writer))
+	if err != nil {
+		return err
 	}
 
 	if err := sCtx.Writer.WriteBytes(strBytes); err != nil {
@@ -669,22 +928,14 @@ func (k *KaitaiSerializer) serializeBytesField(goCtx context.Context, field Sequ
 	default:
 	}
 
-	var bytesData []byte
-
-	switch v := data.(type) {
-	case []byte:
-		bytesData = v
-	case string:
-		k.logger.DebugContext(goCtx, "Converting string data to bytes for bytes field", "field_id", field.ID)
-		bytesData = []byte(v)
-	default:
-		return fmt.Errorf("expected bytes or string for field '%s', got %T", field.ID, data)
-
+	bytesData, err := k.bytesInput.decode(data)
+	if err != nil {
+		return fmt.Errorf("field '%s': %w", field.ID, err)
 	}
 
 	// Handle size attribute
+	size := -1
 	if field.Size != nil {
-		var size int
 		switch v := field.Size.(type) {
 		case int:
 			size = v
@@ -714,15 +965,12 @@ func (k *KaitaiSerializer) serializeBytesField(goCtx context.Context, field Sequ
 			return fmt.Errorf("unsupported size type for bytes field '%s': %T", field.ID, v)
 		}
 		k.logger.DebugContext(goCtx, "Determined size for bytes field", "field_id", field.ID, "size", size)
+	}
 
-		// Validate or adjust length
-		if len(bytesData) > size {
-			bytesData = bytesData[:size] // Truncate
-		} else if len(bytesData) < size {
-			// Pad with zeros
-			padding := make([]byte, size-len(bytesData))
-			bytesData = append(bytesData, padding...)
-		}
+	// Add terminator and padding, truncating values longer than size
+	bytesData, err = terminateField(field, "", bytesData, size, remaining(sCtx.Writer))
+	if err != nil {
+		return err
 	}
 
 	// Write bytes
@@ -738,21 +986,32 @@ func (k *KaitaiSerializer) serializeBytesField(goCtx context.Context, field Sequ
 func (k *KaitaiSerializer) serializeProcessedField(goCtx context.Context, field SequenceItem, data any, sCtx *SerializeContext) error {
 	k.logger.DebugContext(goCtx, "Serializing processed field", "field_id", field.ID, "process_spec", field.Process)
 	// First, serialize to a buffer
+	buf := k.newLayoutBuffer(-1)
 	fieldCtx := &SerializeContext{
 		Value:    data,
-		Children: sCtx.Children,                          // Children from the parent context of the field being processed
-		Parent:   sCtx.Parent,                            // Parent context
-		Root:     sCtx.Root,                              // Root context
-		Writer:   kaitai.NewWriter(bytes.NewBuffer(nil)), // Temporary writer
+		Children: sCtx.Children,         // Children from the parent context of the field being processed
+		Parent:   sCtx.Parent,           // Parent context
+		Root:     sCtx.Root,             // Root context
+		Writer:   kaitai.NewWriter(buf), // Temporary writer
+		IO:       kaitai.NewStream(buf),
 	}
 
-	// Serialize the field without processing
+	// Serialize the field without processing. A size applies to the processed
+	// bytes, so the raw form is written without it and padded afterwards.
 	fieldCopy := field
 	fieldCopy.Process = "" // Temporarily remove process to get raw serialized form
+	fieldCopy.Size = nil
+	fieldCopy.SizeEOS = false
+	if getTypeAsString(fieldCopy.Type) == "" {
+		fieldCopy.Type = "bytes"
+	}
 	k.logger.DebugContext(goCtx, "Serializing field to temp buffer before reverse processing", "field_id", field.ID)
 	if err := k.serializeField(goCtx, fieldCopy, data, fieldCtx); err != nil {
 		return fmt.Errorf("serializing field '%s' to temp buffer before processing: %w", field.ID, err)
 	}
+	if err := buf.finish(); err != nil {
+		return fmt.Errorf("laying out field '%s' before processing: %w", field.ID, err)
+	}
 
 	// Get the serialized bytes
 
@@ -769,6 +1028,17 @@ func (k *KaitaiSerializer) serializeProcessedField(goCtx context.Context, field
 	}
 	k.logger.DebugContext(goCtx, "Reverse processed data for field", "field_id", field.ID, "processed_bytes_len", len(processed))
 
+	if field.Size != nil {
+		size, err := k.evaluateSizeSpec(goCtx, field.Size, sCtx)
+		if err != nil {
+			return fmt.Errorf("evaluating size for field '%s': %w", field.ID, err)
+		}
+		if int64(len(processed)) > size {
+			return fmt.Errorf("processed data for field '%s' is %d bytes, larger than its size %d", field.ID, len(processed), size)
+		}
+		processed = append(processed, make([]byte, size-int64(len(processed)))...)
+	}
+
 	// Write the processed data
 	if err := sCtx.Writer.WriteBytes(processed); err != nil {
 		return fmt.Errorf("writing processed field '%s': %w", field.ID, err)
@@ -862,8 +1132,13 @@ func (k *KaitaiSerializer) evaluateInstancesWithDependencies(goCtx context.Conte
 		return nil
 	}
 	
+	// Positional instances are data to be written, not expressions
 	instancesToProcess := make(map[string]InstanceDef)
-	maps.Copy(instancesToProcess, instances)
+	for name, inst := range instances {
+		if inst.Value != "" {
+			instancesToProcess[name] = inst
+		}
+	}
 	
 	maxPasses := len(instancesToProcess) + 2 // Allow a couple of extra passes for dependencies
 	processedInLastPass := -1
@@ -1006,23 +1281,12 @@ func (k *KaitaiSerializer) resolveSwitchTypeForSerialization(goCtx context.Conte
 		if err != nil {
 			return "", fmt.Errorf("failed to create switch type selector: %w", err)
 		}
-		if spec == nil || spec.switchOn == "" {
-			return "", fmt.Errorf("switch specification is nil or switch-on expression is empty")
-		}
-		k.logger.DebugContext(goCtx, "Resolving switch type for serialization from map definition", "switch_on_expr", spec.switchOn)
-
-		switchOnVal, err := k.evaluateExpression(goCtx, spec.switchOn, sCtx)
+		actualType, switchOnVal, found, err := k.switchCaseType(goCtx, spec, sCtx)
 		if err != nil {
-			return "", fmt.Errorf("evaluating switch-on expression '%s': %w", spec.switchOn, err)
+			return "", err
 		}
-		k.logger.DebugContext(goCtx, "Switch-on expression evaluated", "switch_on_expr", spec.switchOn, "value", switchOnVal)
-
-		// If spec.cases is nil (should not happen if NewSwitchTypeSelector parsed a map with cases),
-		// this indicates an issue or a very specific KSY structure not yet fully handled.
-		// However, NewSwitchTypeSelector would likely error if 'cases' is missing from a map.
-		// For safety, we can check, but the primary path here is for map-based cases.
-		if spec.cases == nil {
-			return "", fmt.Errorf("internal error: switch specification parsed as map but cases are nil for switch-on '%s'", spec.switchOn)
+		if found {
+			return actualType, nil
 		}
 
 		var switchKey string
@@ -1036,20 +1300,9 @@ func (k *KaitaiSerializer) resolveSwitchTypeForSerialization(goCtx context.Conte
 		case float32, float64:
 			switchKey = fmt.Sprintf("%g", v)
 		default:
-			k.logger.WarnContext(goCtx, "Switch-on value is of an unexpected type for map case lookup, attempting generic string conversion", "type", fmt.Sprintf("%T", v))
 			switchKey = fmt.Sprintf("%v", v)
 		}
 
-		if actualType, ok := spec.cases[switchKey]; ok {
-			k.logger.DebugContext(goCtx, "Switch case matched", "switch_key", switchKey, "resolved_type", actualType)
-			return actualType, nil
-		}
-
-		if defaultType, ok := spec.cases["_"]; ok {
-			k.logger.DebugContext(goCtx, "Switch case not matched, using default", "switch_key", switchKey, "default_type", defaultType, "switch_on_expr", spec.switchOn, "evaluated_value", switchOnVal)
-			return defaultType, nil
-		}
-
 		k.logger.ErrorContext(goCtx, "No case matched for switch-on value and no default case provided",
 			"switch_on_expr", spec.switchOn, "evaluated_value", switchOnVal,
 			"string_key_used", switchKey, "available_cases", fmt.Sprintf("%v", spec.cases))
@@ -1058,6 +1311,47 @@ func (k *KaitaiSerializer) resolveSwitchTypeForSerialization(goCtx context.Conte
 	}
 }
 
+// switchCaseType evaluates a switch-on expression and returns its value with
+// the matching case type; found is false if no case matches and there is no `_` case
+func (k *KaitaiSerializer) switchCaseType(goCtx context.Context, spec *SwitchTypeSelector, sCtx *SerializeContext) (string, any, bool, error) {
+	if spec.switchOn == "" {
+		return "", nil, false, fmt.Errorf("switch specification is nil or switch-on expression is empty")
+	}
+	k.logger.DebugContext(goCtx, "Resolving switch type for serialization from map definition", "switch_on_expr", spec.switchOn)
+
+	switchOnVal, err := k.evaluateExpression(goCtx, spec.switchOn, sCtx)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("evaluating switch-on expression '%s': %w", spec.switchOn, err)
+	}
+	actualType, found := spec.caseType(switchOnVal, k.lookupEnum)
+	k.logger.DebugContext(goCtx, "Switch-on expression evaluated", "switch_on_expr", spec.switchOn, "value", switchOnVal, "resolved_type", actualType, "found", found)
+	return actualType, switchOnVal, found, nil
+}
+
+// lookupEnum finds an enum declared in the types being serialized or at the top level
+func (k *KaitaiSerializer) lookupEnum(name string) (EnumDef, bool) {
+	return k.schema.findEnum(k.typeStack, name)
+}
+
+// enumValueByName returns the value of the named entry of an enum, or the
+// value of a decimal string
+func (k *KaitaiSerializer) enumValueByName(enumName, name string) (int64, error) {
+	if enumDef, ok := k.lookupEnum(enumName); ok {
+		for key, entry := range enumDef {
+			if entry != name {
+				continue
+			}
+			if value, ok := toInt64(key); ok {
+				return value, nil
+			}
+		}
+	}
+	if value, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return value, nil
+	}
+	return 0, fmt.Errorf("no entry '%s' in enum '%s'", name, enumName)
+}
+
 // resolveTypeInHierarchy resolves a type name by searching through nested type scopes
 func (k *KaitaiSerializer) resolveTypeInHierarchy(typeName string) (*Type, bool) {
 	// Try to resolve in current nested type context first
@@ -1106,6 +1400,14 @@ func (k *KaitaiSerializer) serializeEnumField(goCtx context.Context, field Seque
 		// Float that should be an integer
 		enumValue = v
 		k.logger.DebugContext(goCtx, "Using numeric value for enum", "field_id", field.ID, "enum_value", enumValue)
+	case string:
+		// Enum name, as output with EnumsAs(EnumName), or a decimal value
+		value, err := k.enumValueByName(field.Enum, v)
+		if err != nil {
+			return fmt.Errorf("field '%s': %w", field.ID, err)
+		}
+		enumValue = value
+		k.logger.DebugContext(goCtx, "Resolved enum name to value", "field_id", field.ID, "enum_name", v, "enum_value", enumValue)
 	default:
 		return fmt.Errorf("unsupported enum data format for field '%s': %T", field.ID, data)
 	}
@@ -1118,7 +1420,10 @@ func (k *KaitaiSerializer) serializeEnumField(goCtx context.Context, field Seque
 
 // getWriterBuffer accesses the buffer from a kaitai.Writer
 func getWriterBuffer(writer *kaitai.Writer) ([]byte, error) {
-	if buf, ok := writer.Writer.(*bytes.Buffer); ok {
+	switch buf := writer.Writer.(type) {
+	case *bytes.Buffer:
+		return buf.Bytes(), nil
+	case *layoutBuffer:
 		return buf.Bytes(), nil
 	}
 	return nil, fmt.Errorf("writer doesn't support buffer access")
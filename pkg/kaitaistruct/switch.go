@@ -3,7 +3,13 @@ package kaitaistruct
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/twinfer/kbin-plugin/pkg/expression"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
 )
 
 // TODO: Add context.Context to ResolveType and pass it to evaluateExpression
@@ -87,61 +93,257 @@ func NewSwitchTypeSelector(switchType any, schema *KaitaiSchema) (*SwitchTypeSel
 
 // ResolveType resolves the actual type based on the switch value
 func (s *SwitchTypeSelector) ResolveType(goCtx context.Context, pCtx *ParseContext, interpreter *KaitaiInterpreter) (string, error) {
+	typeName, value, found, err := s.resolve(goCtx, pCtx, interpreter)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no matching case for switch value '%v'", value)
+	}
+	return typeName, nil
+}
+
+// resolve evaluates the switch-on expression and returns its value with the
+// matching case type; found is false if no case matches and there is no `_` case
+func (s *SwitchTypeSelector) resolve(goCtx context.Context, pCtx *ParseContext, interpreter *KaitaiInterpreter) (string, any, bool, error) {
 	// Evaluate switch-on expression
 	result, err := interpreter.evaluateExpression(goCtx, s.switchOn, pCtx)
 	if err != nil {
-		return "", fmt.Errorf("failed to evaluate switch expression: %w", err)
-	}
-
-	// Convert result to string for case matching
-	var switchValue string
-	switch v := result.(type) {
-	case string:
-		switchValue = v
-	case int, int64, uint, uint64, float64:
-		switchValue = fmt.Sprintf("%v", v)
-	case bool:
-		if v {
-			switchValue = "true"
-		} else {
-			switchValue = "false"
-		}
-	default:
-		switchValue = fmt.Sprintf("%v", v)
+		return "", nil, false, fmt.Errorf("failed to evaluate switch expression: %w", err)
 	}
 
+	typeName, found := s.caseType(result, func(name string) (EnumDef, bool) {
+		return interpreter.schema.findEnum(interpreter.typeStack, name)
+	})
+	return typeName, result, found, nil
+}
+
+// caseType returns the type of the case matching value, falling back to the `_` case
+func (s *SwitchTypeSelector) caseType(value any, lookupEnum func(name string) (EnumDef, bool)) (string, bool) {
 	// Look for exact match
-	if typeName, ok := s.cases[switchValue]; ok {
-		return typeName, nil
+	if typeName, ok := s.cases[fmt.Sprintf("%v", value)]; ok {
+		return typeName, true
 	}
 
-	// Look for enum references (format: enum_name::enum_value)
+	// Compare against case keys written as Kaitai literals (0x11, "S", [73], enum::name)
 	for caseKey, typeName := range s.cases {
-		if strings.Contains(caseKey, "::") {
-			parts := strings.Split(caseKey, "::")
-			if len(parts) == 2 {
-				enumName := parts[0]
-				enumValue := parts[1]
-
-				// Check if switch value is an enum value
-				enumIntValue, isEnum := ResolveEnumValue(s.schema, enumName, switchValue)
-				if isEnum {
-					// Check if this case matches the enum value
-					caseEnumIntValue, isCaseEnum := ResolveEnumValue(s.schema, enumName, enumValue)
-					if isCaseEnum && enumIntValue == caseEnumIntValue {
-						return typeName, nil
-					}
-				}
-			}
+		if caseKey != "_" && switchCaseMatches(caseKey, value, lookupEnum) {
+			return typeName, true
 		}
 	}
 
 	// Use default type if available
 	if s.defaultType != "" {
-		return s.defaultType, nil
+		return s.defaultType, true
+	}
+	return "", false
+}
+
+// switchCaseMatches reports whether a switch value selects the case with the given key
+func switchCaseMatches(caseKey string, value any, lookupEnum func(name string) (EnumDef, bool)) bool {
+	literal, ok := parseSwitchCaseKey(caseKey, lookupEnum)
+	if !ok {
+		return false
+	}
+	value = switchValueOf(value)
+
+	// A switch on an enum name string matches the enum case of the same name
+	if name, isName := value.(string); isName && strings.Contains(caseKey, "::") {
+		return name == caseKey[strings.LastIndex(caseKey, "::")+2:]
+	}
+	return isEqual(value, literal)
+}
+
+// parseSwitchCaseKey parses a case key into the value it selects: int64 for
+// integer and enum cases, string, bool or []byte
+func parseSwitchCaseKey(caseKey string, lookupEnum func(name string) (EnumDef, bool)) (any, bool) {
+	key := strings.TrimSpace(caseKey)
+	switch {
+	case key == "true" || key == "false":
+		return key == "true", true
+
+	case len(key) >= 2 && (key[0] == '"' && key[len(key)-1] == '"' || key[0] == '\'' && key[len(key)-1] == '\''):
+		if key[0] == '"' {
+			if str, err := strconv.Unquote(key); err == nil {
+				return str, true
+			}
+		}
+		return key[1 : len(key)-1], true
+
+	case len(key) >= 2 && key[0] == '[' && key[len(key)-1] == ']':
+		var bytesValue []byte
+		for _, part := range strings.Split(key[1:len(key)-1], ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			b, err := strconv.ParseUint(part, 0, 8)
+			if err != nil {
+				return nil, false
+			}
+			bytesValue = append(bytesValue, byte(b))
+		}
+		return bytesValue, true
+
+	case strings.Contains(key, "::"):
+		i := strings.LastIndex(key, "::")
+		enumDef, ok := lookupEnum(key[:i])
+		if !ok {
+			return nil, false
+		}
+		for enumValue, name := range enumDef {
+			if name == key[i+2:] {
+				return toInt64(enumValue)
+			}
+		}
+		return nil, false
+	}
+
+	n, err := strconv.ParseInt(strings.ReplaceAll(key, "_", ""), 0, 64)
+	if err != nil {
+		return nil, false
+	}
+	return n, true
+}
+
+// switchValueOf unwraps a switch-on result for comparison with case values
+func switchValueOf(value any) any {
+	switch v := value.(type) {
+	case *kaitaicel.KaitaiEnum:
+		return v.IntValue()
+	case kaitaicel.KaitaiType:
+		return v.Value()
+	case map[string]any:
+		// Enum object format: {"name": "cat", "value": 4}
+		if enumValue, ok := v["value"]; ok {
+			return enumValue
+		}
+	}
+	return value
+}
+
+// inferSwitchSelectors returns values for switch-on fields missing from the input data.
+// When a switch is on a plain sequence field of the same type, the case is taken
+// from a `_type` hint naming the case type, or else from the only user-type case
+// whose fields cover the keys of the data. The case key is then written as the
+// selector value. Selectors present in the input are left as they are.
+func (k *KaitaiSerializer) inferSwitchSelectors(goCtx context.Context, typeName string, sequence []SequenceItem, children map[string]any) (map[string]any, error) {
+	inferred := make(map[string]any)
+	seqFields := make(map[string]bool, len(sequence))
+	for _, seq := range sequence {
+		seqFields[seq.ID] = true
+	}
+
+	for _, field := range sequence {
+		switchType := field.Type
+		if field.Type == "switch" {
+			switchType = field.Switch
+		}
+		if _, ok := switchType.(map[string]any); !ok {
+			continue
+		}
+		data, dataOk := children[field.ID]
+		if !dataOk || data == nil {
+			continue
+		}
+		spec, err := NewSwitchTypeSelector(switchType, k.schema)
+		if err != nil {
+			return nil, fmt.Errorf("creating switch selector for field '%s': %w", field.ID, err)
+		}
+		ast, err := parseKaitaiExpression(spec.switchOn)
+		if err != nil {
+			continue
+		}
+		id, ok := ast.(*expression.Id)
+		if !ok || !seqFields[id.Name] {
+			// Selectors from other types or computed ones can't be written from here
+			continue
+		}
+		if _, present := children[id.Name]; present {
+			continue
+		}
+
+		items := []any{data}
+		if field.Repeat != "" {
+			if items, ok = data.([]any); !ok {
+				return nil, fmt.Errorf("expected array for repeated field '%s', got %T", field.ID, data)
+			}
+		}
+		caseKey := ""
+		for i, item := range items {
+			key, err := k.inferSwitchCase(spec, item)
+			if err != nil {
+				return nil, fmt.Errorf("cannot infer switch-on field '%s' from field '%s': %w", id.Name, field.ID, err)
+			}
+			if i > 0 && key != caseKey {
+				return nil, fmt.Errorf("cannot infer switch-on field '%s': items of field '%s' select different cases (%s and %s)", id.Name, field.ID, caseKey, key)
+			}
+			caseKey = key
+		}
+		if caseKey == "" {
+			continue
+		}
+		value, ok := parseSwitchCaseKey(caseKey, k.lookupEnum)
+		if !ok {
+			return nil, fmt.Errorf("cannot infer switch-on field '%s': unsupported case key '%s'", id.Name, caseKey)
+		}
+		k.logger.DebugContext(goCtx, "Inferred switch-on field value", "type_name", typeName, "field_id", id.Name, "value", value, "from_field", field.ID)
+		inferred[id.Name] = value
 	}
+	return inferred, nil
+}
+
+// inferSwitchCase returns the key of the case that data was written for
+func (k *KaitaiSerializer) inferSwitchCase(spec *SwitchTypeSelector, data any) (string, error) {
+	dataMap, ok := data.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("value of type %T does not identify a case", data)
+	}
+
+	var matches []string
+	if hint, ok := dataMap["_type"].(string); ok {
+		for caseKey, caseType := range spec.cases {
+			if caseKey != "_" && caseType == hint {
+				matches = append(matches, caseKey)
+			}
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no case has type '%s'", hint)
+		}
+	} else {
+		for caseKey, caseType := range spec.cases {
+			if caseKey == "_" {
+				continue
+			}
+			if t, found := k.resolveTypeInHierarchy(caseType); found && typeHasFields(t, dataMap) {
+				matches = append(matches, caseKey)
+			}
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no case type has all fields of the data")
+		}
+	}
+	if len(matches) > 1 {
+		sort.Strings(matches)
+		return "", fmt.Errorf("data matches several cases (%s), add a `_type` hint", strings.Join(matches, ", "))
+	}
+	return matches[0], nil
+}
 
-	return "", fmt.Errorf("no matching case for switch value '%s'", switchValue)
+// typeHasFields reports whether every field of data, other than `_`-prefixed
+// ones such as `_type`, is a sequence field or instance of t
+func typeHasFields(t *Type, data map[string]any) bool {
+	for key := range data {
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		if _, ok := t.Instances[key]; ok {
+			continue
+		}
+		if !slices.ContainsFunc(t.Seq, func(seq SequenceItem) bool { return seq.ID == key }) {
+			return false
+		}
+	}
+	return true
 }
 
 // ResolveEnumValue resolves an enum name to its integer value
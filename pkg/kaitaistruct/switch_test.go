@@ -0,0 +1,165 @@
+package kaitaistruct
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize_RepeatAndSwitchRoundTrip(t *testing.T) {
+	// Formats with the fixture their generated test reads. repeat_until_calc_array_type,
+	// switch_cast and switch_multi_bool_ops are left out as the parser can't evaluate
+	// their expressions yet.
+	fixtures := map[string]string{
+		"repeat_eos_bit":              "enum_0",
+		"repeat_eos_struct":           "repeat_eos_struct",
+		"repeat_eos_u4":               "repeat_eos_struct",
+		"repeat_until_complex":        "repeat_until_complex",
+		"repeat_until_s4":             "repeat_until_s4",
+		"repeat_until_sized":          "repeat_until_process",
+		"switch_bytearray":            "switch_opcodes",
+		"switch_else_only":            "switch_opcodes",
+		"switch_integers":             "switch_integers",
+		"switch_integers2":            "switch_integers",
+		"switch_manual_enum":          "switch_opcodes",
+		"switch_manual_int":           "switch_opcodes",
+		"switch_manual_int_else":      "switch_opcodes2",
+		"switch_manual_int_size":      "switch_tlv",
+		"switch_manual_int_size_else": "switch_tlv",
+		"switch_manual_int_size_eos":  "switch_tlv",
+		"switch_manual_str":           "switch_opcodes",
+		"switch_manual_str_else":      "switch_opcodes2",
+		"switch_repeat_expr":          "switch_tlv",
+		"switch_repeat_expr_invalid":  "switch_tlv",
+	}
+
+	for format, fixture := range fixtures {
+		t.Run(format, func(t *testing.T) {
+			schema := loadFormatSchema(t, format)
+			original, err := os.ReadFile("../../test/src/" + fixture + ".bin")
+			require.NoError(t, err)
+
+			parsed := parseBytes(t, schema, original)
+			data := ParsedDataToMap(parsed)
+			out, err := newTestSerializer(t, schema).Serialize(context.Background(), data.(map[string]any))
+			require.NoError(t, err)
+			assert.Equal(t, original[:parsed.Size], out)
+		})
+	}
+}
+
+func TestSerialize_RepeatUntil(t *testing.T) {
+	ctx := context.Background()
+	schema := loadFormatSchema(t, "repeat_until_s4")
+
+	tests := []struct {
+		name    string
+		entries []any
+		errMsg  string
+	}{
+		{name: "terminated", entries: []any{1, 2, -1}},
+		{name: "last item doesn't stop", entries: []any{1, 2}, errMsg: "last item of repeat-until field 'entries' does not satisfy"},
+		{name: "earlier item stops", entries: []any{-1, 2, -1}, errMsg: "item 0 of repeat-until field 'entries' satisfies"},
+		{name: "no items", entries: []any{}, errMsg: "needs at least one item"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := newTestSerializer(t, schema).Serialize(ctx, map[string]any{"entries": tt.entries, "afterall": "x"})
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte{1, 0, 0, 0, 2, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 'x', 0}, out)
+		})
+	}
+}
+
+func TestSerialize_SwitchSelectorInference(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("type hint", func(t *testing.T) {
+		schema := loadFormatSchema(t, "switch_manual_int")
+		data := map[string]any{"opcodes": []any{
+			map[string]any{"body": map[string]any{"_type": "strval", "value": "foobar"}},
+			map[string]any{"body": map[string]any{"_type": "intval", "value": 66}},
+		}}
+		out, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("Sfoobar\x00IB"), out)
+		assert.NotContains(t, data["opcodes"].([]any)[0], "code", "input data is left unchanged")
+	})
+
+	t.Run("ambiguous without hint", func(t *testing.T) {
+		schema := loadFormatSchema(t, "switch_manual_int")
+		data := map[string]any{"opcodes": []any{
+			map[string]any{"body": map[string]any{"value": 66}},
+		}}
+		_, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "data matches several cases (73, 83)")
+	})
+
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "messages", Endian: "le"},
+		Seq: []SequenceItem{
+			{ID: "kind", Type: "u1", Enum: "kind"},
+			{ID: "msgs", Repeat: "expr", RepeatExpr: "2", Type: map[string]any{
+				"switch-on": "kind",
+				"cases": map[string]any{
+					"kind::ping":  "ping",
+					"kind::reply": "reply",
+				},
+			}},
+		},
+		Types: map[string]Type{
+			"ping":  {Seq: []SequenceItem{{ID: "seq_no", Type: "u2"}}},
+			"reply": {Seq: []SequenceItem{{ID: "seq_no", Type: "u2"}, {ID: "status", Type: "u1"}}},
+		},
+		Enums: map[string]EnumDef{"kind": {1: "ping", 2: "reply"}},
+	}
+
+	t.Run("unique matching case", func(t *testing.T) {
+		data := map[string]any{"msgs": []any{
+			map[string]any{"seq_no": 1, "status": 0},
+			map[string]any{"seq_no": 2, "status": 1},
+		}}
+		out, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{2, 1, 0, 0, 2, 0, 1}, out)
+	})
+
+	t.Run("items select different cases", func(t *testing.T) {
+		data := map[string]any{"msgs": []any{
+			map[string]any{"seq_no": 1, "status": 0},
+			map[string]any{"_type": "ping", "seq_no": 2},
+		}}
+		_, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "select different cases")
+	})
+
+	t.Run("explicit selector wins", func(t *testing.T) {
+		data := map[string]any{
+			"kind": map[string]any{"name": "ping", "value": 1},
+			"msgs": []any{map[string]any{"seq_no": 1}, map[string]any{"seq_no": 2}},
+		}
+		out, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 1, 0, 2, 0}, out)
+	})
+
+	t.Run("no matching case", func(t *testing.T) {
+		data := map[string]any{"msgs": []any{
+			map[string]any{"length": 1}, map[string]any{"length": 2},
+		}}
+		_, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot infer switch-on field 'kind'")
+	})
+}
@@ -0,0 +1,184 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+)
+
+// strzTerminator returns the terminator implied by strz: one zero byte, or a
+// zero code unit of two or four bytes for UTF-16 and UTF-32
+func strzTerminator(encoding string) []byte {
+	switch enc := strings.ToUpper(encoding); {
+	case strings.HasPrefix(enc, "UTF-16"), strings.HasPrefix(enc, "UTF16"):
+		return []byte{0, 0}
+	case strings.HasPrefix(enc, "UTF-32"), strings.HasPrefix(enc, "UTF32"):
+		return []byte{0, 0, 0, 0}
+	}
+	return []byte{0}
+}
+
+// fieldTerminator returns the terminator of a str, strz or bytes field, or nil if it has none
+func fieldTerminator(field SequenceItem, encoding string) ([]byte, error) {
+	if field.Terminator != nil {
+		b, err := byteValue(field.Terminator)
+		if err != nil {
+			return nil, err
+		}
+		return []byte{b}, nil
+	}
+	if field.Type == "strz" {
+		return strzTerminator(encoding), nil
+	}
+	return nil, nil
+}
+
+// boolAttr reads a boolean KSY attribute such as include or consume
+func boolAttr(value any, defaultValue bool) bool {
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	return defaultValue
+}
+
+// terminateBytes cuts data at the first terminator starting on a code unit
+// boundary, keeping the terminator if include is set
+func terminateBytes(data, term []byte, include bool) ([]byte, bool) {
+	for i := 0; i+len(term) <= len(data); i += len(term) {
+		if bytes.Equal(data[i:i+len(term)], term) {
+			if include {
+				return data[:i+len(term)], true
+			}
+			return data[:i], true
+		}
+	}
+	return data, false
+}
+
+// readBytesTerm reads code units of len(term) bytes up to the terminator. Unlike
+// kaitai.Stream.ReadBytesTerm it supports multi-byte terminators, and returns
+// everything left when the stream ends first and eosError is not set.
+func readBytesTerm(stream *kaitai.Stream, term []byte, include, consume, eosError bool) ([]byte, error) {
+	pos, err := stream.Pos()
+	if err != nil {
+		return nil, err
+	}
+	size, err := stream.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []byte{}
+	for {
+		if size-pos < int64(len(term)) {
+			if eosError {
+				return nil, fmt.Errorf("terminator % x not found before end of stream: %w", term, io.EOF)
+			}
+			rest, err := stream.ReadBytes(int(size - pos))
+			return append(result, rest...), err
+		}
+		unit, err := stream.ReadBytes(len(term))
+		if err != nil {
+			return nil, err
+		}
+		pos += int64(len(term))
+		if !bytes.Equal(unit, term) {
+			result = append(result, unit...)
+			continue
+		}
+		if include {
+			result = append(result, unit...)
+		}
+		if !consume {
+			if _, err := stream.Seek(-int64(len(term)), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// layoutTerminated returns the bytes to write for a value so that the parser
+// reads it back: followed by its terminator, unless the value already ends with
+// it (include) or the terminator is left for the next field (consume: false).
+// Sized values (size >= 0) are padded with pad, or zeros if pad is nil.
+func layoutTerminated(value, term []byte, include, consume bool, pad *byte, size int) []byte {
+	content := value
+	withTerm := term != nil && !include
+	if term != nil && include && bytes.HasSuffix(value, term) {
+		// Values read up to the end of the stream don't end with the terminator
+		content = value[:len(value)-len(term)]
+		withTerm = true
+	}
+
+	result := bytes.Clone(content)
+	if size < 0 {
+		if withTerm && consume {
+			result = append(result, term...)
+		}
+		return result
+	}
+
+	if len(result) > size {
+		return result[:size]
+	}
+	if withTerm && len(result)+len(term) <= size {
+		result = append(result, term...)
+	}
+	fill := byte(0)
+	if pad != nil {
+		fill = *pad
+	}
+	for len(result) < size {
+		result = append(result, fill)
+	}
+	return result
+}
+
+// terminateField lays out a str, strz or bytes value with the field's terminator
+// and padding; size is -1 for fields without a size. remaining is the space left
+// in a sized enclosing stream, or -1: with eos-error: false the parser accepts a
+// value running to its end, so the terminator is dropped when it doesn't fit.
+func terminateField(field SequenceItem, encoding string, value []byte, size int, remaining int64) ([]byte, error) {
+	term, err := fieldTerminator(field, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminator for field '%s': %w", field.ID, err)
+	}
+	var pad *byte
+	if field.PadRight != nil {
+		b, err := byteValue(field.PadRight)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pad-right for field '%s': %w", field.ID, err)
+		}
+		pad = &b
+	}
+	out := layoutTerminated(value, term, boolAttr(field.Include, false), boolAttr(field.Consume, true), pad, size)
+	if size < 0 && remaining >= 0 && int64(len(out)) > remaining && !boolAttr(field.EOSError, true) &&
+		bytes.HasSuffix(out, term) && int64(len(out)-len(term)) <= remaining {
+		out = out[:len(out)-len(term)]
+	}
+	return out, nil
+}
+
+// remaining returns the bytes left before the end of a sized stream, or -1 if
+// the stream's size follows its data
+func remaining(w *kaitai.Writer) int64 {
+	if buf, ok := w.Writer.(*layoutBuffer); ok && buf.fixedSize >= 0 {
+		return max(buf.fixedSize-buf.pos, 0)
+	}
+	return -1
+}
+
+// stringEncoding returns the encoding of a string field, from the field or meta/encoding
+func (k *KaitaiInterpreter) stringEncoding(field SequenceItem) string {
+	if field.Encoding != "" {
+		return field.Encoding
+	}
+	if k.schema != nil && k.schema.Meta.Encoding != "" {
+		return k.schema.Meta.Encoding
+	}
+	return "UTF-8"
+}
@@ -0,0 +1,115 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBytesTerm(t *testing.T) {
+	utf16 := []byte{'a', 0, 0, 1, 0, 0, 'b', 0}
+
+	t.Run("multi-byte terminator on code unit boundary", func(t *testing.T) {
+		stream := kaitai.NewStream(bytes.NewReader(utf16))
+		got, err := readBytesTerm(stream, []byte{0, 0}, false, true, true)
+		require.NoError(t, err)
+		// The 00 00 spanning 'a' and the next unit isn't a terminator
+		assert.Equal(t, []byte{'a', 0, 0, 1}, got)
+		pos, _ := stream.Pos()
+		assert.EqualValues(t, 6, pos)
+	})
+
+	t.Run("include without consume", func(t *testing.T) {
+		stream := kaitai.NewStream(bytes.NewReader(utf16))
+		got, err := readBytesTerm(stream, []byte{0, 0}, true, false, true)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{'a', 0, 0, 1, 0, 0}, got)
+		pos, _ := stream.Pos()
+		assert.EqualValues(t, 4, pos, "the terminator is left in the stream")
+	})
+
+	t.Run("end of stream", func(t *testing.T) {
+		stream := kaitai.NewStream(bytes.NewReader([]byte("abc")))
+		_, err := readBytesTerm(stream, []byte{0}, false, true, true)
+		assert.ErrorIs(t, err, io.EOF)
+
+		stream = kaitai.NewStream(bytes.NewReader([]byte("abc")))
+		got, err := readBytesTerm(stream, []byte{0}, false, true, false)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("abc"), got)
+	})
+}
+
+func TestLayoutTerminated(t *testing.T) {
+	pad := byte(0xFF)
+	tests := []struct {
+		name    string
+		value   []byte
+		include bool
+		consume bool
+		pad     *byte
+		size    int
+		want    []byte
+	}{
+		{name: "unsized", value: []byte("ab"), consume: true, size: -1, want: []byte("ab\x00")},
+		{name: "not consumed", value: []byte("ab"), consume: false, size: -1, want: []byte("ab")},
+		{name: "included", value: []byte("ab\x00"), include: true, consume: true, size: -1, want: []byte("ab\x00")},
+		{name: "included value read to end", value: []byte("ab"), include: true, consume: true, size: -1, want: []byte("ab")},
+		{name: "sized", value: []byte("ab"), consume: true, size: 5, want: []byte("ab\x00\x00\x00")},
+		{name: "sized with pad", value: []byte("ab"), consume: true, pad: &pad, size: 5, want: []byte("ab\x00\xFF\xFF")},
+		{name: "sized without room for terminator", value: []byte("abc"), consume: true, size: 3, want: []byte("abc")},
+		{name: "truncated", value: []byte("abcd"), consume: true, size: 3, want: []byte("abc")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, layoutTerminated(tt.value, []byte{0}, tt.include, tt.consume, tt.pad, tt.size))
+		})
+	}
+}
+
+func TestSerialize_TerminatedRoundTrip(t *testing.T) {
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "terminated", Encoding: "UTF-16LE"},
+		Seq: []SequenceItem{
+			{ID: "s1", Type: "strz"},
+			{ID: "s2", Type: "str", Terminator: 0x7C, Consume: false, Encoding: "ASCII"},
+			{ID: "sep", Size: 1},
+			{ID: "tail", Size: 4, Type: "tail"},
+		},
+		Types: map[string]Type{
+			"tail": {Seq: []SequenceItem{{ID: "value", Type: "strz", EOSError: false}}},
+		},
+	}
+
+	data := map[string]any{"s1": "hi", "s2": "ok", "sep": []byte("|"), "tail": map[string]any{"value": "xy"}}
+	out, err := newTestSerializer(t, schema).Serialize(context.Background(), data)
+	require.NoError(t, err)
+	// The tail fills its sized stream, so its terminator is left out
+	assert.Equal(t, []byte{'h', 0, 'i', 0, 0, 0, 'o', 'k', '|', 'x', 0, 'y', 0}, out)
+	parsed := ParsedDataToMap(parseBytes(t, schema, out)).(map[string]any)
+	assert.Equal(t, "ok", parsed["s2"])
+	assert.Equal(t, "xy", parsed["tail"].(map[string]any)["value"])
+}
+
+func TestSchema_UnnamedFields(t *testing.T) {
+	schema, err := NewKaitaiSchemaFromYAML([]byte(`
+meta:
+  id: unnamed
+seq:
+  - id: a
+    type: u1
+  - type: u1
+types:
+  inner:
+    seq:
+      - type: u2le
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "_unnamed1", schema.Seq[1].ID)
+	assert.Equal(t, "_unnamed0", schema.Types["inner"].Seq[0].ID)
+}
@@ -0,0 +1,381 @@
+package kaitaistruct
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+)
+
+// ErrValidationFailed is matched (via errors.Is) by every ValidationError
+var ErrValidationFailed = errors.New("validation failed")
+
+// Names of the constraints, reported in ValidationError.Rule
+const (
+	RuleEq       = "eq"
+	RuleMin      = "min"
+	RuleMax      = "max"
+	RuleAnyOf    = "any-of"
+	RuleInEnum   = "in-enum"
+	RuleExpr     = "expr"
+	RuleContents = "contents"
+)
+
+// ValidationError reports a field value that violates its `valid` or
+// `contents` constraint, while parsing or serializing
+type ValidationError struct {
+	Path   string // Field path, e.g. "header.entries[2].kind"; the field ID when parsing
+	Rule   string // One of the Rule* constants
+	Value  any    // Offending value
+	Reason string // Human-readable description of the violation
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation failed at '%s': %s", e.Path, e.Reason)
+}
+
+// Is allows errors.Is(err, ErrValidationFailed) to match any ValidationError
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+// checkValid applies the `valid` constraint of field to value. It is shared by
+// the parser and the serializer, which differ only in how enum membership is
+// determined (enumValid) and how `expr` is evaluated with `_` bound to value (evalExpr).
+func checkValid(field SequenceItem, path string, value any, enumValid func() (bool, error), evalExpr func(expr string) (any, error)) error {
+	validation := field.Valid
+	if validation == nil {
+		return nil
+	}
+	fail := func(rule, format string, args ...any) error {
+		return &ValidationError{Path: path, Rule: rule, Value: value, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	// Handle enum-specific validation for in-enum check
+	if validation.InEnum && field.Enum != "" {
+		valid, err := enumValid()
+		if err != nil {
+			return fail(RuleInEnum, "%v", err)
+		}
+		if !valid {
+			return fail(RuleInEnum, "invalid enum value %v for enum '%s'", value, field.Enum)
+		}
+		return nil // Enum validation passed
+	}
+
+	// Handle simple value validation (e.g., valid: 123)
+	if validation.Value != nil {
+		if !isEqual(value, validation.Value) {
+			return fail(RuleEq, "value %v does not equal expected %v", value, validation.Value)
+		}
+		return nil
+	}
+
+	// Handle expression-based validation
+	if validation.Expr != "" {
+		result, err := evalExpr(validation.Expr)
+		if err != nil {
+			return fmt.Errorf("evaluating validation expression '%s': %w", validation.Expr, err)
+		}
+
+		// Check if result is true
+		if !isTrue(result) {
+			return fail(RuleExpr, "validation expression '%s' failed", validation.Expr)
+		}
+		return nil
+	}
+
+	// Handle min/max validation
+	if validation.Min != nil || validation.Max != nil {
+		if err := validateRange(value, validation.Min, validation.Max); err != nil {
+			rule := RuleMin
+			if validation.Max != nil && compareValues(value, validation.Max) > 0 {
+				rule = RuleMax
+			}
+			return fail(rule, "%v", err)
+		}
+		return nil
+	}
+
+	// Handle any-of validation
+	if len(validation.AnyOf) > 0 {
+		for _, allowedValue := range validation.AnyOf {
+			if isEqual(value, allowedValue) {
+				return nil // Found a match
+			}
+		}
+		return fail(RuleAnyOf, "value %v is not in allowed list %v", value, validation.AnyOf)
+	}
+
+	return nil // No validation rules matched, consider valid
+}
+
+// isEqual compares two values for equality, handling different numeric types
+func isEqual(a, b any) bool {
+	// Interface comparison panics for slices and maps, which are handled below
+	if a == nil || b == nil {
+		return a == b
+	}
+	if reflect.TypeOf(a).Comparable() && a == b {
+		return true
+	}
+
+	// Handle numeric comparisons with type conversion
+	aNum, aIsNum := toNumber(a)
+	bNum, bIsNum := toNumber(b)
+	if aIsNum && bIsNum {
+		return aNum == bNum
+	}
+
+	// Handle byte array comparisons
+	if aByte, aIsByte := a.([]byte); aIsByte {
+		if bByte, bIsByte := b.([]byte); bIsByte {
+			return bytes.Equal(aByte, bByte)
+		}
+		// Compare with array of numbers
+		if bSlice, bIsSlice := b.([]any); bIsSlice {
+			if len(aByte) != len(bSlice) {
+				return false
+			}
+			for i, val := range bSlice {
+				if bNum, ok := toNumber(val); ok {
+					if float64(aByte[i]) != bNum {
+						return false
+					}
+				} else {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	// Handle string comparisons
+	if aStr, aIsStr := a.(string); aIsStr {
+		if bStr, bIsStr := b.(string); bIsStr {
+			return aStr == bStr
+		}
+	}
+
+	return false
+}
+
+// toNumber converts a value to float64 if possible
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// validateRange validates that a value is within the specified min/max range
+func validateRange(value, min, max any) error {
+	valueNum, valueIsNum := toNumber(value)
+	if !valueIsNum {
+		// For non-numeric types, try string or byte comparison
+		if min != nil && max != nil {
+			if compareValues(value, min) < 0 {
+				return fmt.Errorf("value %v is less than minimum %v", value, min)
+			}
+			if compareValues(value, max) > 0 {
+				return fmt.Errorf("value %v is greater than maximum %v", value, max)
+			}
+		} else if min != nil {
+			if compareValues(value, min) < 0 {
+				return fmt.Errorf("value %v is less than minimum %v", value, min)
+			}
+		} else if max != nil {
+			if compareValues(value, max) > 0 {
+				return fmt.Errorf("value %v is greater than maximum %v", value, max)
+			}
+		}
+		return nil
+	}
+
+	// Numeric validation
+	if min != nil {
+		if minNum, minIsNum := toNumber(min); minIsNum {
+			if valueNum < minNum {
+				return fmt.Errorf("value %v is less than minimum %v", value, min)
+			}
+		}
+	}
+
+	if max != nil {
+		if maxNum, maxIsNum := toNumber(max); maxIsNum {
+			if valueNum > maxNum {
+				return fmt.Errorf("value %v is greater than maximum %v", value, max)
+			}
+		}
+	}
+
+	return nil
+}
+
+// compareValues compares two values, returning -1, 0, or 1
+func compareValues(a, b any) int {
+	// Try numeric comparison first
+	if aNum, aIsNum := toNumber(a); aIsNum {
+		if bNum, bIsNum := toNumber(b); bIsNum {
+			if aNum < bNum {
+				return -1
+			} else if aNum > bNum {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	// Try string comparison
+	if aStr, aIsStr := a.(string); aIsStr {
+		if bStr, bIsStr := b.(string); bIsStr {
+			if aStr < bStr {
+				return -1
+			} else if aStr > bStr {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	// Try byte comparison, with bounds such as [0x00, 0x10] given as arrays
+	if aBytes, aIsBytes := byteValues(a); aIsBytes {
+		if bBytes, bIsBytes := byteValues(b); bIsBytes {
+			return bytes.Compare(aBytes, bBytes)
+		}
+	}
+
+	// Fallback to string representation
+	aStr := fmt.Sprintf("%v", a)
+	bStr := fmt.Sprintf("%v", b)
+	if aStr < bStr {
+		return -1
+	} else if aStr > bStr {
+		return 1
+	}
+	return 0
+}
+
+// byteValues returns a byte value, or an array of integers from 0 to 255, as bytes
+func byteValues(value any) ([]byte, bool) {
+	switch value.(type) {
+	case []byte, []any, *kaitaicel.KaitaiBytes:
+		b, err := BytesRaw.decode(value)
+		return b, err == nil
+	}
+	return nil, false
+}
+
+// enterPath appends a segment (a field ID or an "[i]" index) to the path of the
+// value being serialized and returns a function that removes it again
+func (k *KaitaiSerializer) enterPath(segment string) func() {
+	k.path = append(k.path, segment)
+	return func() {
+		k.path = k.path[:len(k.path)-1]
+	}
+}
+
+// currentPath renders the path of the value being serialized, e.g. "header.entries[2].kind"
+func (k *KaitaiSerializer) currentPath() string {
+	return renderPath(k.path)
+}
+
+// validateField checks data against the field's `valid` constraint before it is written
+func (k *KaitaiSerializer) validateField(goCtx context.Context, field SequenceItem, data any, sCtx *SerializeContext) error {
+	value := data
+	if enumObj, ok := value.(map[string]any); ok && field.Enum != "" {
+		// Enum object format: {"name": "cat", "value": 4}
+		value = enumObj["value"]
+	}
+	if kaitaiType, ok := value.(kaitaicel.KaitaiType); ok {
+		value = kaitaiType.Value()
+	}
+	if name, ok := value.(string); ok && field.Enum != "" {
+		// Enum name, as output with EnumsAs(EnumName)
+		enumValue, err := k.enumValueByName(field.Enum, name)
+		if err != nil {
+			return err
+		}
+		value = enumValue
+	}
+
+	enumValid := func() (bool, error) {
+		enumDef, ok := k.lookupEnum(field.Enum)
+		if !ok {
+			return false, fmt.Errorf("enum '%s' not found in schema", field.Enum)
+		}
+		for enumValue := range enumDef {
+			if isEqual(value, enumValue) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	evalExpr := func(expr string) (any, error) {
+		tempCtx := &SerializeContext{
+			Children: map[string]any{"_": value},
+			Parent:   sCtx.Parent,
+			Root:     sCtx.Root,
+			IO:       sCtx.IO,
+		}
+		return k.evaluateExpression(goCtx, expr, tempCtx)
+	}
+
+	return checkValid(field, k.currentPath(), value, enumValid, evalExpr)
+}
+
+// validateContents checks that data supplied for a `contents` field matches the fixed contents
+func (k *KaitaiSerializer) validateContents(data any, expected []byte) error {
+	if data == nil {
+		return nil // Nothing supplied, the fixed contents are written
+	}
+	actual := data
+	if str, ok := data.(string); ok {
+		// JSON carries byte arrays as base64, so accept that form as well as the raw string
+		if str == base64.StdEncoding.EncodeToString(expected) {
+			return nil
+		}
+	}
+	if b, err := k.bytesInput.decode(data); err == nil {
+		actual = b
+	}
+	if !isEqual(actual, expected) {
+		return &ValidationError{
+			Path:   k.currentPath(),
+			Rule:   RuleContents,
+			Value:  data,
+			Reason: fmt.Sprintf("value %v does not match contents %x", data, expected),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package kaitaistruct
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
+)
+
+func TestSerialize_Validation(t *testing.T) {
+	ctx := context.Background()
+	schema := &KaitaiSchema{
+		Meta: Meta{ID: "validated", Endian: "le"},
+		Seq: []SequenceItem{
+			{ID: "magic", Contents: []any{0x4B, 0x42}},
+			{ID: "hdr", Type: "header"},
+			{ID: "items", Type: "item", Repeat: "expr", RepeatExpr: "2"},
+		},
+		Types: map[string]Type{
+			"header": {Seq: []SequenceItem{
+				{ID: "version", Type: "u1", Valid: &ValidationDef{Min: 1, Max: 3}},
+				{ID: "kind", Type: "u1", Enum: "kind", Valid: &ValidationDef{InEnum: true}},
+			}},
+			"item": {Seq: []SequenceItem{
+				{ID: "code", Type: "u1", Valid: &ValidationDef{AnyOf: []any{10, 20}}},
+				{ID: "width", Type: "u2", Valid: &ValidationDef{Expr: "_ % 2 == 0"}},
+			}},
+		},
+		Enums: map[string]EnumDef{
+			"kind": {1: "request", 2: "response"},
+		},
+	}
+
+	validData := func() map[string]any {
+		return map[string]any{
+			"hdr": map[string]any{"version": 2, "kind": map[string]any{"name": "response", "value": 2}},
+			"items": []any{
+				map[string]any{"code": 10, "width": 4},
+				map[string]any{"code": 20, "width": 8},
+			},
+		}
+	}
+
+	t.Run("valid data", func(t *testing.T) {
+		out, err := newTestSerializer(t, schema).Serialize(ctx, validData())
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x4B, 0x42, 2, 2, 10, 4, 0, 20, 8, 0}, out)
+	})
+
+	tests := []struct {
+		name   string
+		modify func(data map[string]any)
+		path   string
+		rule   string
+	}{
+		{
+			name:   "below min",
+			modify: func(d map[string]any) { d["hdr"].(map[string]any)["version"] = 0 },
+			path:   "hdr.version",
+			rule:   RuleMin,
+		},
+		{
+			name:   "above max",
+			modify: func(d map[string]any) { d["hdr"].(map[string]any)["version"] = 4 },
+			path:   "hdr.version",
+			rule:   RuleMax,
+		},
+		{
+			name:   "not in enum",
+			modify: func(d map[string]any) { d["hdr"].(map[string]any)["kind"] = 7 },
+			path:   "hdr.kind",
+			rule:   RuleInEnum,
+		},
+		{
+			name:   "not any of",
+			modify: func(d map[string]any) { d["items"].([]any)[1].(map[string]any)["code"] = 30 },
+			path:   "items[1].code",
+			rule:   RuleAnyOf,
+		},
+		{
+			name:   "expression false",
+			modify: func(d map[string]any) { d["items"].([]any)[0].(map[string]any)["width"] = 5 },
+			path:   "items[0].width",
+			rule:   RuleExpr,
+		},
+		{
+			name:   "contents mismatch",
+			modify: func(d map[string]any) { d["magic"] = []byte("XX") },
+			path:   "magic",
+			rule:   RuleContents,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := validData()
+			tt.modify(data)
+
+			_, err := newTestSerializer(t, schema).Serialize(ctx, data)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrValidationFailed)
+
+			var vErr *ValidationError
+			require.True(t, errors.As(err, &vErr))
+			assert.Equal(t, tt.path, vErr.Path)
+			assert.Equal(t, tt.rule, vErr.Rule)
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			skipping, err := NewKaitaiSerializer(schema, logger, WithSkipValidation(true))
+			require.NoError(t, err)
+			_, err = skipping.Serialize(ctx, data)
+			assert.NoError(t, err, "skip validation writes the data as given")
+		})
+	}
+
+	t.Run("matching contents accepted", func(t *testing.T) {
+		data := validData()
+		data["magic"] = "KB"
+		_, err := newTestSerializer(t, schema).Serialize(ctx, data)
+		require.NoError(t, err)
+
+		data["magic"] = "S0I=" // base64 of "KB", as produced by JSON
+		_, err = newTestSerializer(t, schema).Serialize(ctx, data)
+		require.NoError(t, err)
+	})
+}
+
+func TestIsEqual(t *testing.T) {
+	assert.True(t, isEqual(int64(5), 5.0))
+	assert.True(t, isEqual([]byte{1, 2}, []byte{1, 2}))
+	assert.True(t, isEqual([]byte{1, 2}, []any{1, 2}))
+	assert.False(t, isEqual([]byte{1, 2}, []byte{1, 3}))
+	assert.False(t, isEqual(nil, 0))
+	assert.True(t, isEqual("a", "a"))
+}
+
+func TestCompareValues(t *testing.T) {
+	assert.Equal(t, -1, compareValues([]byte{9}, []any{10}))
+	assert.Equal(t, 1, compareValues([]byte{10}, []any{9}))
+	assert.Equal(t, -1, compareValues([]byte{0x02, 0x09}, []any{0x02, 0x10}))
+	assert.Equal(t, 0, compareValues([]byte{0x01, 0xFF}, []any{1, 255}))
+	assert.Equal(t, 1, compareValues(kaitaicel.NewKaitaiBytes([]byte{200}), []any{30}))
+	assert.Equal(t, -1, compareValues([]any{9}, []byte{10}))
+
+	assert.NoError(t, validateRange([]byte{9}, []any{2}, []any{10}))
+	assert.Error(t, validateRange([]byte{11}, []any{2}, []any{10}))
+	assert.Error(t, validateRange([]byte{1}, []any{2}, nil))
+}
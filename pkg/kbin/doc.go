@@ -58,8 +58,9 @@
 //
 //   - WithRootType(string): Specify root type (default: schema ID)
 //   - WithLogger(*slog.Logger): Custom logging
-//   - WithCaching(time.Duration): Enable schema caching
+//   - WithCaching(time.Duration): Enable schema caching, reloading changed files after the timeout
 //   - WithImportPaths(...string): Additional import search paths
+//   - WithFS(fs.FS): Read schemas and their imports from an fs.FS
 //   - WithDebugMode(bool): Enable debug output
 //
 // # Data Type Conversion
@@ -10,8 +10,8 @@ import (
 	"github.com/twinfer/kbin-plugin/pkg/kbin"
 )
 
-// ExampleIntegration demonstrates a complete workflow using the kbin API
-func ExampleIntegration() {
+// Example_integration demonstrates a complete workflow using the kbin API
+func Example_integration() {
 	// Create a temporary directory for our test files
 	tmpDir, err := os.MkdirTemp("", "kbin-example")
 	if err != nil {
@@ -60,7 +60,7 @@ enums:
 		0x4D, 0x53, 0x47, 0x21, // magic: "MSG!"
 		0x01,                   // version: 1
 		0x01,                   // flags: urgent (1)
-		byte(len(message)),     // length: 15 (low byte)
+		byte(len(message)),     // length: 14 (low byte)
 		0x00,                   // length: 0 (high byte, little-endian)
 	}
 	binaryData = append(binaryData, []byte(message)...) // message data
@@ -135,35 +135,34 @@ enums:
 
 	// Output:
 	// === Binary to Structured Data ===
-	// Magic: 0x21474D53
+	// Magic: 0x4D534721
 	// Version: 1
 	// Flags: 1
-	// Length: 15
+	// Length: 14
 	// Message: "Hello, Kaitai!"
 	//
 	// === Structured Data to JSON ===
 	// JSON representation:
 	// {
 	//   "flags": 1,
-	//   "length": 15,
+	//   "length": 14,
+	//   "magic": "TVNHIQ==",
 	//   "message": "Hello, Kaitai!",
 	//   "version": 1
 	// }
-	//
 	// === JSON Modification ===
 	// Modified JSON:
 	// {
 	//   "flags": 2,
 	//   "length": 17,
+	//   "magic": "TVNHIQ==",
 	//   "message": "Modified message!",
 	//   "version": 1
 	// }
-	//
 	// === JSON to Binary ===
-	// New binary data (26 bytes): 4d534721010211004d6f6469666965642006d657373616765214
-	//
+	// New binary data (25 bytes): 4d534721010211004d6f646966696564206d65737361676521
 	// === Verification ===
 	// Verified message: "Modified message!"
 	// Verified flags: 2
 	// Verified length: 17
-}
\ No newline at end of file
+}
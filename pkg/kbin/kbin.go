@@ -30,24 +30,36 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaicel"
 	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
 )
 
 // Parser wraps the Kaitai functionality with caching and configuration
 type Parser struct {
-	schemaCache  map[string]*kaitaistruct.KaitaiSchema
+	schemaCache  map[string]*cachedSchema
 	cacheMutex   sync.RWMutex
 	logger       *slog.Logger
 	options      options
 }
 
+// cachedSchema is a loaded schema along with what tells whether its file changed
+type cachedSchema struct {
+	schema    *kaitaistruct.KaitaiSchema
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time // When the file was last compared with the cached version
+}
+
 // options holds configuration for the parser
 type options struct {
 	rootType       string
@@ -55,7 +67,14 @@ type options struct {
 	enableCaching  bool
 	cacheTimeout   time.Duration
 	importPaths    []string
+	fsys           fs.FS
 	debugMode      bool
+	limits         kaitaistruct.Limits
+	autoCompute    bool
+	skipValidation bool
+	partialResults bool
+	output         []kaitaistruct.OutputOption
+	ownSource      bool // The call's own fsys or import paths, so the schema cache is bypassed
 }
 
 // Option is a function that configures parser options
@@ -75,7 +94,9 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-// WithCaching enables schema caching with the specified timeout
+// WithCaching enables schema caching. After timeout, a cached schema is
+// checked against its file and reloaded if the file changed; a timeout of 0
+// keeps schemas until ClearCache is called.
 func WithCaching(timeout time.Duration) Option {
 	return func(o *options) {
 		o.enableCaching = true
@@ -90,6 +111,15 @@ func WithImportPaths(paths ...string) Option {
 	}
 }
 
+// WithFS makes the parser read schema files, and the files they import, from
+// fsys instead of the OS filesystem. Schema paths are then slash-separated
+// paths in fsys.
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.fsys = fsys
+	}
+}
+
 // WithDebugMode enables debug logging
 func WithDebugMode(enabled bool) Option {
 	return func(o *options) {
@@ -97,6 +127,52 @@ func WithDebugMode(enabled bool) Option {
 	}
 }
 
+// WithLimits sets resource limits for parsing untrusted input (zero fields are unlimited)
+func WithLimits(limits kaitaistruct.Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithAutoCompute makes SerializeFromJSON infer length and count fields
+// (those used as `size` or `repeat-expr` of other fields) from the data
+func WithAutoCompute(enabled bool) Option {
+	return func(o *options) {
+		o.autoCompute = enabled
+	}
+}
+
+// WithSkipValidation makes SerializeFromJSON write data without enforcing
+// `valid` constraints and `contents`, for producing malformed test vectors
+func WithSkipValidation(skip bool) Option {
+	return func(o *options) {
+		o.skipValidation = skip
+	}
+}
+
+// WithPartialResults makes ParseBinary return the fields decoded before a
+// parse failure along with the error. The error matches
+// *kaitaistruct.ParseError, which names the failing field and its offset.
+func WithPartialResults(enabled bool) Option {
+	return func(o *options) {
+		o.partialResults = enabled
+	}
+}
+
+// WithOutput selects the shapes of enums, bytes, big integers and bit flags in
+// parse results, and has SerializeFromJSON read data in the same shapes
+func WithOutput(opts ...kaitaistruct.OutputOption) Option {
+	return func(o *options) {
+		o.output = opts
+	}
+}
+
+// resultOutput returns the shapes of values in parse results. Enums are
+// integers unless WithOutput selects another shape.
+func (o options) resultOutput() []kaitaistruct.OutputOption {
+	return append([]kaitaistruct.OutputOption{kaitaistruct.EnumsAs(kaitaistruct.EnumInt)}, o.output...)
+}
+
 // defaultOptions returns the default configuration
 func defaultOptions() options {
 	return options{
@@ -132,7 +208,7 @@ func NewParser(opts ...Option) *Parser {
 	}
 
 	return &Parser{
-		schemaCache: make(map[string]*kaitaistruct.KaitaiSchema),
+		schemaCache: make(map[string]*cachedSchema),
 		logger:      options.logger,
 		options:     options,
 	}
@@ -150,6 +226,14 @@ func ParseBinaryWithContext(ctx context.Context, data []byte, schemaPath string,
 	return parser.ParseBinary(ctx, data, schemaPath, opts...)
 }
 
+// ParseBinaryWithSchema parses binary data using the given KSY schema
+// content. Relative imports of the schema are resolved against the current
+// directory, or the root of the filesystem given with WithFS.
+func ParseBinaryWithSchema(data []byte, schemaBytes []byte, opts ...Option) (map[string]any, error) {
+	parser := getGlobalParser()
+	return parser.ParseBinaryWithSchema(context.Background(), data, schemaBytes, opts...)
+}
+
 // SerializeToJSON parses binary data and converts it to JSON
 func SerializeToJSON(data []byte, schemaPath string, opts ...Option) ([]byte, error) {
 	parser := getGlobalParser()
@@ -174,28 +258,59 @@ func SerializeFromJSONWithContext(ctx context.Context, jsonData []byte, schemaPa
 	return parser.SerializeFromJSON(ctx, jsonData, schemaPath, opts...)
 }
 
+// callOptions returns the parser's options with those of a single call applied
+func (p *Parser) callOptions(opts []Option) options {
+	merged := p.options
+	merged.importPaths = append([]string(nil), p.options.importPaths...)
+	var own options
+	for _, opt := range opts {
+		opt(&merged)
+		opt(&own)
+	}
+	// Schemas read through the call's own filesystem or import paths aren't
+	// cached, as the cache is keyed by path alone
+	merged.ownSource = own.fsys != nil || len(own.importPaths) > 0
+	return merged
+}
+
 // ParseBinary parses binary data using the specified Kaitai schema
 func (p *Parser) ParseBinary(ctx context.Context, data []byte, schemaPath string, opts ...Option) (map[string]any, error) {
 	// Apply any additional options
-	options := p.options
-	for _, opt := range opts {
-		opt(&options)
-	}
+	options := p.callOptions(opts)
 
 	// Load the schema
-	schema, err := p.loadSchema(schemaPath)
+	schema, err := p.loadSchema(schemaPath, options)
 	if err != nil {
 		return nil, fmt.Errorf("loading schema: %w", err)
 	}
 
-	// Determine root type
-	rootType := options.rootType
-	if rootType == "" {
-		rootType = schema.Meta.ID
+	return p.parse(ctx, data, schema, options)
+}
+
+// ParseBinaryWithSchema parses binary data using the given KSY schema content
+func (p *Parser) ParseBinaryWithSchema(ctx context.Context, data []byte, schemaBytes []byte, opts ...Option) (map[string]any, error) {
+	// Apply any additional options
+	options := p.callOptions(opts)
+
+	// Inline schemas aren't cached, as they have no path to key them by
+	schema, err := p.parseSchema(".", schemaBytes, options)
+	if err != nil {
+		return nil, fmt.Errorf("loading schema: %w", err)
+	}
+
+	return p.parse(ctx, data, schema, options)
+}
+
+// parse parses binary data using a loaded schema
+func (p *Parser) parse(ctx context.Context, data []byte, schema *kaitaistruct.KaitaiSchema, options options) (map[string]any, error) {
+	// Set the root type on a copy, so a cached schema keeps its own
+	currentSchema := *schema
+	if options.rootType != "" {
+		currentSchema.RootType = options.rootType
 	}
 
 	// Create an interpreter for this schema
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, p.logger)
+	interpreter, err := kaitaistruct.NewKaitaiInterpreter(&currentSchema, p.logger, kaitaistruct.WithLimits(options.limits))
 	if err != nil {
 		return nil, fmt.Errorf("creating interpreter: %w", err)
 	}
@@ -206,11 +321,15 @@ func (p *Parser) ParseBinary(ctx context.Context, data []byte, schemaPath string
 	// Parse the data
 	result, err := interpreter.Parse(ctx, stream)
 	if err != nil {
+		var parseErr *kaitaistruct.ParseError
+		if options.partialResults && errors.As(err, &parseErr) {
+			return p.convertParsedDataToMap(parseErr.Partial, options.resultOutput()), fmt.Errorf("parsing data: %w", err)
+		}
 		return nil, fmt.Errorf("parsing data: %w", err)
 	}
 
 	// Convert ParsedData to map
-	resultMap := p.convertParsedDataToMap(result)
+	resultMap := p.convertParsedDataToMap(result, options.resultOutput())
 	return resultMap, nil
 }
 
@@ -234,13 +353,10 @@ func (p *Parser) SerializeToJSON(ctx context.Context, data []byte, schemaPath st
 // SerializeFromJSON converts JSON data back to binary format
 func (p *Parser) SerializeFromJSON(ctx context.Context, jsonData []byte, schemaPath string, opts ...Option) ([]byte, error) {
 	// Apply any additional options
-	options := p.options
-	for _, opt := range opts {
-		opt(&options)
-	}
+	options := p.callOptions(opts)
 
 	// Load the schema
-	schema, err := p.loadSchema(schemaPath)
+	schema, err := p.loadSchema(schemaPath, options)
 	if err != nil {
 		return nil, fmt.Errorf("loading schema: %w", err)
 	}
@@ -258,7 +374,7 @@ func (p *Parser) SerializeFromJSON(ctx context.Context, jsonData []byte, schemaP
 	}
 
 	// Create a serializer
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, p.logger)
+	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, p.logger, kaitaistruct.WithAutoCompute(options.autoCompute), kaitaistruct.WithSkipValidation(options.skipValidation), kaitaistruct.WithInputFormat(options.output...))
 	if err != nil {
 		return nil, fmt.Errorf("creating serializer: %w", err)
 	}
@@ -275,52 +391,115 @@ func (p *Parser) SerializeFromJSON(ctx context.Context, jsonData []byte, schemaP
 	return result, nil
 }
 
-// loadSchema loads a schema from disk with caching support
-func (p *Parser) loadSchema(schemaPath string) (*kaitaistruct.KaitaiSchema, error) {
-	// Check cache first if enabled
-	if p.options.enableCaching {
-		p.cacheMutex.RLock()
-		cached, exists := p.schemaCache[schemaPath]
-		p.cacheMutex.RUnlock()
-		if exists {
-			return cached, nil
-		}
+// loadSchema loads a schema from disk with caching support. A changed schema
+// file replaces the cached version only if it loads and doesn't break
+// expressions that compiled before; otherwise the cached version stays in use.
+func (p *Parser) loadSchema(schemaPath string, options options) (*kaitaistruct.KaitaiSchema, error) {
+	if !options.enableCaching || options.ownSource {
+		return p.readSchemaFile(schemaPath, options)
+	}
+
+	// Check cache first
+	p.cacheMutex.RLock()
+	cached := p.schemaCache[schemaPath]
+	p.cacheMutex.RUnlock()
+	if cached != nil && (options.cacheTimeout <= 0 || time.Since(cached.checkedAt) < options.cacheTimeout) {
+		return cached.schema, nil
+	}
+
+	info, statErr := statFile(options.fsys, schemaPath)
+	if cached != nil && statErr == nil && info.ModTime().Equal(cached.modTime) && info.Size() == cached.size {
+		p.storeSchema(schemaPath, &cachedSchema{schema: cached.schema, modTime: cached.modTime, size: cached.size, checkedAt: time.Now()})
+		return cached.schema, nil
 	}
 
 	// Load the schema from disk
-	data, err := os.ReadFile(schemaPath)
+	schema, err := p.readSchemaFile(schemaPath, options)
+	if err == nil && cached != nil {
+		// Some expressions are beyond the interpreter; only reject what used to compile
+		if compileErr := schema.CompileExpressions(); compileErr != nil && cached.schema.CompileExpressions() == nil {
+			err = fmt.Errorf("compiling schema: %w", compileErr)
+		}
+	}
+	if err != nil {
+		if cached == nil {
+			return nil, err
+		}
+		p.logger.Warn("Schema changed but failed to reload, keeping the previous version", "path", schemaPath, "error", err)
+		p.storeSchema(schemaPath, &cachedSchema{schema: cached.schema, modTime: cached.modTime, size: cached.size, checkedAt: time.Now()})
+		return cached.schema, nil
+	}
+
+	entry := &cachedSchema{schema: schema, checkedAt: time.Now()}
+	if statErr == nil {
+		entry.modTime, entry.size = info.ModTime(), info.Size()
+	}
+	p.storeSchema(schemaPath, entry)
+	return schema, nil
+}
+
+// storeSchema caches a schema entry
+func (p *Parser) storeSchema(schemaPath string, entry *cachedSchema) {
+	p.cacheMutex.Lock()
+	p.schemaCache[schemaPath] = entry
+	p.cacheMutex.Unlock()
+}
+
+// readSchemaFile reads and parses a schema file
+func (p *Parser) readSchemaFile(schemaPath string, options options) (*kaitaistruct.KaitaiSchema, error) {
+	data, err := readFile(options.fsys, schemaPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading schema file: %w", err)
 	}
+	return p.parseSchema(schemaPath, data, options)
+}
 
-	// Parse the schema
+// parseSchema parses the schema found at schemaPath and merges its imports,
+// read from the same filesystem. Absolute imports are looked up in the
+// import paths.
+func (p *Parser) parseSchema(schemaPath string, data []byte, options options) (*kaitaistruct.KaitaiSchema, error) {
 	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(data)
 	if err != nil {
 		return nil, fmt.Errorf("parsing schema: %w", err)
 	}
+	importPaths := make([]string, len(options.importPaths))
+	for i, dir := range options.importPaths {
+		importPaths[i] = filepath.ToSlash(dir)
+	}
+	read := func(name string) ([]byte, error) {
+		return readFile(options.fsys, name)
+	}
+	if err := schema.ResolveImports(filepath.ToSlash(schemaPath), read, importPaths...); err != nil {
+		return nil, fmt.Errorf("resolving imports: %w", err)
+	}
+	return schema, nil
+}
 
-	// Note: Import paths would need to be handled by the interpreter when parsing imports
-	// The schema itself doesn't have an ImportPaths field
-
-	// Cache the schema if enabled
-	if p.options.enableCaching {
-		p.cacheMutex.Lock()
-		p.schemaCache[schemaPath] = schema
-		p.cacheMutex.Unlock()
+// readFile reads a slash-separated path from fsys, or the OS filesystem if nil
+func readFile(fsys fs.FS, name string) ([]byte, error) {
+	if fsys != nil {
+		return fs.ReadFile(fsys, name)
 	}
+	return os.ReadFile(filepath.FromSlash(name))
+}
 
-	return schema, nil
+// statFile describes a slash-separated path of fsys, or the OS filesystem if nil
+func statFile(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if fsys != nil {
+		return fs.Stat(fsys, name)
+	}
+	return os.Stat(filepath.FromSlash(name))
 }
 
 // ClearCache clears the schema cache
 func (p *Parser) ClearCache() {
 	p.cacheMutex.Lock()
 	defer p.cacheMutex.Unlock()
-	p.schemaCache = make(map[string]*kaitaistruct.KaitaiSchema)
+	p.schemaCache = make(map[string]*cachedSchema)
 }
 
 // convertParsedDataToMap converts ParsedData structure to a map
-func (p *Parser) convertParsedDataToMap(pd *kaitaistruct.ParsedData) map[string]any {
+func (p *Parser) convertParsedDataToMap(pd *kaitaistruct.ParsedData, output []kaitaistruct.OutputOption) map[string]any {
 	if pd == nil {
 		return nil
 	}
@@ -329,7 +508,7 @@ func (p *Parser) convertParsedDataToMap(pd *kaitaistruct.ParsedData) map[string]
 	
 	// If the parsed data has a direct value (for primitive types), return it wrapped in a map
 	if pd.Value != nil && pd.Children == nil {
-		result["value"] = p.convertToGoTypes(pd.Value)
+		result["value"] = p.convertToGoTypes(pd.Value, output)
 		return result
 	}
 
@@ -341,10 +520,10 @@ func (p *Parser) convertParsedDataToMap(pd *kaitaistruct.ParsedData) map[string]
 		}
 
 		if child.Value != nil {
-			result[name] = p.convertToGoTypes(child.Value)
+			result[name] = p.convertToGoTypes(child.Value, output)
 		} else if child.Children != nil {
 			// For nested structures, recurse
-			result[name] = p.convertParsedDataToMap(child)
+			result[name] = p.convertParsedDataToMap(child, output)
 		}
 	}
 
@@ -352,7 +531,13 @@ func (p *Parser) convertParsedDataToMap(pd *kaitaistruct.ParsedData) map[string]
 }
 
 // convertToGoTypes recursively converts Kaitai types to standard Go types
-func (p *Parser) convertToGoTypes(v any) any {
+func (p *Parser) convertToGoTypes(v any, output []kaitaistruct.OutputOption) any {
+	// Values with a selectable shape
+	switch v.(type) {
+	case *kaitaicel.KaitaiEnum, *kaitaicel.KaitaiBytes, *kaitaicel.KaitaiInt, *kaitaicel.KaitaiBitField, []byte, int64, uint64:
+		return kaitaistruct.FormatValue(v, output...)
+	}
+
 	// Check if it's a Kaitai type first to avoid infinite recursion
 	if kaitaiType, ok := v.(interface{ Value() any }); ok {
 		// Get the underlying value and stop recursion if it's the same object
@@ -361,7 +546,7 @@ func (p *Parser) convertToGoTypes(v any) any {
 			// This can happen with some types, just return the value as-is
 			return v
 		}
-		return p.convertToGoTypes(underlying)
+		return p.convertToGoTypes(underlying, output)
 	}
 
 	switch val := v.(type) {
@@ -372,13 +557,13 @@ func (p *Parser) convertToGoTypes(v any) any {
 			if k == "_io" || k == "_parent" || k == "_root" {
 				continue
 			}
-			result[k] = p.convertToGoTypes(v)
+			result[k] = p.convertToGoTypes(v, output)
 		}
 		return result
 	case []any:
 		result := make([]any, len(val))
 		for i, item := range val {
-			result[i] = p.convertToGoTypes(item)
+			result[i] = p.convertToGoTypes(item, output)
 		}
 		return result
 	default:
@@ -394,6 +579,6 @@ func ValidateSchema(schemaPath string) error {
 
 // ValidateSchema validates a Kaitai schema file without parsing any data
 func (p *Parser) ValidateSchema(schemaPath string) error {
-	_, err := p.loadSchema(schemaPath)
+	_, err := p.loadSchema(schemaPath, p.options)
 	return err
 }
\ No newline at end of file
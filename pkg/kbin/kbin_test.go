@@ -6,10 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
 )
 
 func TestParseBinary(t *testing.T) {
@@ -218,6 +220,161 @@ seq:
 	parser.ClearCache()
 }
 
+func TestWithLimits(t *testing.T) {
+	schemaContent := `meta:
+  id: counted
+seq:
+  - id: count
+    type: u1
+  - id: items
+    type: u1
+    repeat: expr
+    repeat-expr: count
+`
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "counted.ksy")
+	err := os.WriteFile(schemaPath, []byte(schemaContent), 0644)
+	require.NoError(t, err)
+
+	parser := NewParser(WithLimits(kaitaistruct.Limits{MaxRepeatItems: 4}))
+
+	// Within the limit
+	_, err = parser.ParseBinary(context.Background(), []byte{0x02, 0x01, 0x02}, schemaPath)
+	require.NoError(t, err)
+
+	// Count field claims more items than allowed
+	_, err = parser.ParseBinary(context.Background(), []byte{0xFF, 0x01, 0x02}, schemaPath)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, kaitaistruct.ErrLimitExceeded)
+
+	// Per-call options override the parser defaults
+	_, err = parser.ParseBinary(context.Background(), []byte{0x03, 0x01, 0x02, 0x03}, schemaPath, WithLimits(kaitaistruct.Limits{MaxRepeatItems: 2}))
+	assert.ErrorIs(t, err, kaitaistruct.ErrLimitExceeded)
+}
+
+func TestWithPartialResults(t *testing.T) {
+	schemaContent := `meta:
+  id: record
+  endian: le
+seq:
+  - id: msg_type
+    type: u1
+  - id: body_len
+    type: u2
+  - id: body
+    size: body_len
+`
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "record.ksy")
+	err := os.WriteFile(schemaPath, []byte(schemaContent), 0644)
+	require.NoError(t, err)
+
+	// Body claims 16 bytes but only 2 follow
+	data := []byte{0x07, 0x10, 0x00, 0xAA, 0xBB}
+
+	// By default a failed parse returns nothing
+	result, err := ParseBinary(data, schemaPath)
+	require.Error(t, err)
+	assert.Nil(t, result)
+
+	result, err = ParseBinary(data, schemaPath, WithPartialResults(true))
+	require.Error(t, err)
+	assert.Equal(t, map[string]any{"msg_type": int64(7), "body_len": int64(16)}, result)
+
+	var parseErr *kaitaistruct.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, "body", parseErr.Path)
+	assert.EqualValues(t, 3, parseErr.Offset)
+}
+
+func TestWithOutput(t *testing.T) {
+	schemaContent := `meta:
+  id: tagged
+  endian: le
+seq:
+  - id: kind
+    type: u1
+    enum: kind
+  - id: token
+    size: 2
+enums:
+  kind:
+    1: ping
+    2: pong
+`
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "tagged.ksy")
+	err := os.WriteFile(schemaPath, []byte(schemaContent), 0644)
+	require.NoError(t, err)
+
+	data := []byte{0x02, 0xBE, 0xEF}
+	output := WithOutput(kaitaistruct.EnumsAs(kaitaistruct.EnumName), kaitaistruct.BytesAs(kaitaistruct.BytesHex))
+
+	result, err := ParseBinary(data, schemaPath, output)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"kind": "pong", "token": "beef"}, result)
+
+	jsonData, err := SerializeToJSON(data, schemaPath, output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"kind": "pong", "token": "beef"}`, string(jsonData))
+
+	// The same shapes are read back
+	serialized, err := SerializeFromJSON(jsonData, schemaPath, output)
+	require.NoError(t, err)
+	assert.Equal(t, data, serialized)
+}
+
+func TestSerializeFromJSON_AutoCompute(t *testing.T) {
+	schemaContent := `meta:
+  id: framed
+  endian: be
+seq:
+  - id: body_len
+    type: u2
+  - id: body
+    type: str
+    size: body_len
+    encoding: UTF-8
+`
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "framed.ksy")
+	err := os.WriteFile(schemaPath, []byte(schemaContent), 0644)
+	require.NoError(t, err)
+
+	result, err := SerializeFromJSON([]byte(`{"body": "hi there"}`), schemaPath, WithAutoCompute(true))
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0x00, 0x08}, "hi there"...), result)
+}
+
+func TestSerializeFromJSON_Validation(t *testing.T) {
+	schemaContent := `meta:
+  id: header
+seq:
+  - id: magic
+    contents: "HDR"
+  - id: version
+    type: u1
+    valid:
+      any-of: [1, 2]
+`
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "header.ksy")
+	err := os.WriteFile(schemaPath, []byte(schemaContent), 0644)
+	require.NoError(t, err)
+
+	// contents round-trip as base64 through JSON
+	result, err := SerializeFromJSON([]byte(`{"magic": "SERS", "version": 2}`), schemaPath)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'H', 'D', 'R', 0x02}, result)
+
+	_, err = SerializeFromJSON([]byte(`{"version": 3}`), schemaPath)
+	assert.ErrorIs(t, err, kaitaistruct.ErrValidationFailed)
+
+	result, err = SerializeFromJSON([]byte(`{"version": 3}`), schemaPath, WithSkipValidation(true))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{'H', 'D', 'R', 0x03}, result)
+}
+
 func TestValidateSchema(t *testing.T) {
 	// Valid schema
 	validSchema := `meta:
@@ -247,4 +404,109 @@ seq:
 	// actual validation would happen during parsing
 	err = ValidateSchema(invalidPath)
 	assert.NoError(t, err)
-}
\ No newline at end of file
+}
+func TestCacheTimeoutReloadsChangedSchema(t *testing.T) {
+	ctx := context.Background()
+	schemaPath := filepath.Join(t.TempDir(), "versioned.ksy")
+	writeSchema := func(content string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(schemaPath, []byte(content), 0644))
+		require.NoError(t, os.Chtimes(schemaPath, modTime, modTime))
+	}
+	start := time.Now().Add(-time.Hour)
+	writeSchema("meta:\n  id: versioned\nseq:\n  - id: value\n    type: u1\n", start)
+	data := []byte{0x01, 0x02}
+
+	t.Run("Reloads_After_Timeout", func(t *testing.T) {
+		parser := NewParser(WithCaching(time.Nanosecond))
+		result, err := parser.ParseBinary(ctx, data, schemaPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x01), result["value"])
+
+		writeSchema("meta:\n  id: versioned\n  endian: be\nseq:\n  - id: value\n    type: u2\n", start.Add(time.Minute))
+		result, err = parser.ParseBinary(ctx, data, schemaPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x0102), result["value"])
+
+		// A version with a broken expression doesn't replace the working one
+		writeSchema("meta:\n  id: versioned\nseq:\n  - id: value\n    type: u1\n    if: (1 +\n", start.Add(2*time.Minute))
+		result, err = parser.ParseBinary(ctx, data, schemaPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x0102), result["value"])
+	})
+
+	t.Run("Zero_Timeout_Caches_Until_Cleared", func(t *testing.T) {
+		writeSchema("meta:\n  id: versioned\nseq:\n  - id: value\n    type: u1\n", start.Add(3*time.Minute))
+		parser := NewParser(WithCaching(0))
+		result, err := parser.ParseBinary(ctx, data, schemaPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x01), result["value"])
+
+		writeSchema("meta:\n  id: versioned\n  endian: be\nseq:\n  - id: value\n    type: u2\n", start.Add(4*time.Minute))
+		result, err = parser.ParseBinary(ctx, data, schemaPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x01), result["value"])
+
+		parser.ClearCache()
+		result, err = parser.ParseBinary(ctx, data, schemaPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0x0102), result["value"])
+	})
+}
+
+func TestSchemaSources(t *testing.T) {
+	ctx := context.Background()
+	const mainSchema = "meta:\n  id: reading\n  imports:\n    - common/unit\n    - /shared/flags\nseq:\n  - id: unit\n    type: unit\n  - id: flags\n    type: flags\n"
+	fsys := fstest.MapFS{
+		"specs/reading.ksy":     {Data: []byte(mainSchema)},
+		"specs/common/unit.ksy": {Data: []byte("meta:\n  id: unit\nseq:\n  - id: code\n    type: u1\n")},
+		"lib/shared/flags.ksy":  {Data: []byte("meta:\n  id: flags\nseq:\n  - id: bits\n    type: u1\n")},
+	}
+	data := []byte{0x03, 0x80}
+	want := map[string]any{
+		"unit":  map[string]any{"code": int64(3)},
+		"flags": map[string]any{"bits": int64(0x80)},
+	}
+
+	t.Run("FS", func(t *testing.T) {
+		parser := NewParser(WithFS(fsys), WithImportPaths("lib"))
+		result, err := parser.ParseBinary(ctx, data, "specs/reading.ksy")
+		require.NoError(t, err)
+		assert.Equal(t, want, result)
+	})
+
+	t.Run("Inline_Schema", func(t *testing.T) {
+		parser := NewParser(WithFS(fstest.MapFS{
+			"common/unit.ksy":      fsys["specs/common/unit.ksy"],
+			"lib/shared/flags.ksy": fsys["lib/shared/flags.ksy"],
+		}), WithImportPaths("lib"))
+		result, err := parser.ParseBinaryWithSchema(ctx, data, []byte(mainSchema))
+		require.NoError(t, err)
+		assert.Equal(t, want, result)
+	})
+
+	t.Run("Per_Call_Options", func(t *testing.T) {
+		inlineFS := fstest.MapFS{
+			"common/unit.ksy":      fsys["specs/common/unit.ksy"],
+			"lib/shared/flags.ksy": fsys["lib/shared/flags.ksy"],
+		}
+		result, err := ParseBinaryWithSchema(data, []byte(mainSchema), WithFS(inlineFS), WithImportPaths("lib"))
+		require.NoError(t, err)
+		assert.Equal(t, want, result)
+
+		result, err = NewParser().ParseBinary(ctx, data, "specs/reading.ksy", WithFS(fsys), WithImportPaths("lib"))
+		require.NoError(t, err)
+		assert.Equal(t, want, result)
+	})
+
+	t.Run("Missing_Import", func(t *testing.T) {
+		parser := NewParser(WithFS(fsys))
+		result, err := parser.ParseBinary(ctx, data, "specs/reading.ksy")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "resolving imports")
+		assert.Nil(t, result)
+
+		result, err = ParseBinaryWithSchema(data, []byte(mainSchema))
+		require.Error(t, err)
+		assert.Nil(t, result)
+	})
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
@@ -14,9 +16,13 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"unicode"
+
+	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
 )
 
 var (
@@ -26,15 +32,20 @@ var (
 	kscGoTestDir  = flag.String("ksc_go_test_dir", "test/go", "KSC Go test files directory")
 	kscGenDir     = flag.String("ksc_gen_dir", "testdata/formats_kaitai_go_gen", "KSC generated Go files directory")
 	testOutputDir = flag.String("test_output_dir", "testdata/kaitaistruct/formats_test", "Test output directory")
+	runKSC        = flag.Bool("ksc", true, "Regenerate the KSC Go code for each format (needs ksc on PATH)")
+	matrixFile    = flag.String("matrix", "", "Run the generated tests and write a per-format compliance matrix to this file")
+	diffDir       = flag.String("differential_dir", "testdata/kaitaistruct/differential", "Differential test directory, where the registry of KSC parsers is written")
 )
 
 type TestData struct {
-	FormatName   string
-	StructName   string
-	KsyFileName  string
-	BinFileName  string
-	PackageAlias string
-	Assertions   []Assertion
+	FormatName    string
+	StructName    string
+	KsyFileName   string
+	BinFileName   string
+	PackageAlias  string
+	Assertions    []Assertion
+	ExpectedError string   // KSC error the test expects from parsing, e.g. "io.ErrUnexpectedEOF" or "ValidationNotEqualError"
+	Unsupported   []string // KSC assertions that couldn't be translated
 }
 
 type Assertion struct {
@@ -44,188 +55,410 @@ type Assertion struct {
 	AssertionCode string   // Generated assertion code
 }
 
-const testTemplate = `// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// formatStatus is one row of the compliance matrix
+type formatStatus struct {
+	Format     string
+	StructName string
+	Note       string // Why no test was generated, or untranslated assertions
+	Generated  bool
+	Expects    string // Expected parse error, if any
+	Fixture    string // Binary the KSC test reads
+}
+
+const testTemplate = `// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_{{.StructName}}(t *testing.T) {
+	{{- if .ExpectedError}}
+	_, err := parseFormat(t, "{{.KsyFileName}}", "{{.BinFileName}}")
+	assertParseError(t, err, "{{.ExpectedError}}")
+	{{- else}}
+	customMap, err := parseFormat(t, "{{.KsyFileName}}", "{{.BinFileName}}")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	check{{.StructName}}(t, customMap)
+	{{- end}}
+}
+{{if not .ExpectedError}}
+func TestSerialize_{{.StructName}}(t *testing.T) {
+	customMap, err := parseFormat(t, "{{.KsyFileName}}", "{{.BinFileName}}")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	check{{.StructName}}(t, roundTrip(t, "{{.KsyFileName}}", customMap))
+}
+
+// check{{.StructName}} applies the assertions of the KSC test to parsed data
+func check{{.StructName}}(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	{{- range .Assertions}}
+	{{.AssertionCode}}
+	{{- end}}
+	{{- range .Unsupported}}
+	// Not translated: {{.}}
+	{{- end}}
+}
+{{end}}`
+
+// helpersSource is written next to the generated tests. It parses and
+// serializes with kbin and compares the results with what KSC tests expect.
+const helpersSource = `// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	{{.PackageAlias}} "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/{{.FormatName}}"
 )
 
-func TestParse_{{.StructName}}(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "{{.KsyFileName}}")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-	
+const (
+	formatsDir = "../../../test/formats"
+	binDir     = "../../../test/src"
+)
+
+// kscEnum is an enum label expected by a KSC test, such as "cat" for Enum0_Animal__Cat
+type kscEnum string
+
+// kscErrorRules maps the validation errors of the KSC runtime to kbin validation rules
+var kscErrorRules = map[string][]string{
+	"ValidationNotEqualError":    {kaitaistruct.RuleEq, kaitaistruct.RuleContents},
+	"ValidationLessThanError":    {kaitaistruct.RuleMin},
+	"ValidationGreaterThanError": {kaitaistruct.RuleMax},
+	"ValidationNotAnyOfError":    {kaitaistruct.RuleAnyOf},
+	"ValidationNotInEnumError":   {kaitaistruct.RuleInEnum},
+	"ValidationExprError":        {kaitaistruct.RuleExpr},
+}
+
+func loadSchema(t *testing.T, ksyFile string) *kaitaistruct.KaitaiSchema {
+	t.Helper()
+	yamlData, err := os.ReadFile(filepath.Join(formatsDir, ksyFile))
+	if err != nil {
+		t.Fatalf("reading schema: %v", err)
+	}
 	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
+	if err != nil {
+		t.Fatalf("loading schema: %v", err)
+	}
+	return schema
+}
+
+// parseFormat parses a fixture with kbin, turning panics into errors
+func parseFormat(t *testing.T, ksyFile, binFile string) (result map[string]any, err error) {
+	t.Helper()
+	schema := loadSchema(t, ksyFile)
+	binData, err := os.ReadFile(filepath.Join(binDir, binFile))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	return parseData(schema, binData)
+}
+
+func parseData(schema *kaitaistruct.KaitaiSchema, data []byte) (result map[string]any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "{{.BinFileName}}")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-	
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-	{{range .Assertions}}
-	{{.AssertionCode}}
-	{{- end}}
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	parsed, err := interpreter.Parse(ctx, kaitai.NewStream(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := kaitaistruct.ParsedDataToMap(parsed).(map[string]any); ok {
+		return m, nil
+	}
+	// A root without fields converts to nil
+	return map[string]any{}, nil
 }
 
+// roundTrip serializes parsed data and parses the output again
+func roundTrip(t *testing.T, ksyFile string, data map[string]any) map[string]any {
+	t.Helper()
+	schema := loadSchema(t, ksyFile)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, logger)
+	if err != nil {
+		t.Fatalf("creating serializer: %v", err)
+	}
+	out, err := func() (out []byte, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return serializer.Serialize(context.Background(), data)
+	}()
+	if err != nil {
+		t.Fatalf("serializing: %v", err)
+	}
+	reparsed, err := parseData(schema, out)
+	if err != nil {
+		t.Fatalf("parsing serialized data: %v", err)
+	}
+	return reparsed
+}
 
+// assertParseError checks that parsing failed the way the KSC test expects
+func assertParseError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("expected %s, parsing succeeded", want)
+		return
+	}
+	switch want {
+	case "error":
+	case "io.EOF", "io.ErrUnexpectedEOF":
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected %s, got: %v", want, err)
+		}
+	case "UndecidedEndiannessError":
+		if !strings.Contains(strings.ToLower(err.Error()), "endian") {
+			t.Errorf("expected %s, got: %v", want, err)
+		}
+	default:
+		rules, ok := kscErrorRules[want]
+		if !ok {
+			t.Fatalf("unknown KSC error %s", want)
+		}
+		var vErr *kaitaistruct.ValidationError
+		if !errors.As(err, &vErr) {
+			t.Errorf("expected %s, got: %v", want, err)
+			return
+		}
+		assert.Contains(t, rules, vErr.Rule, "validation rule for %s", want)
+	}
+}
 
-func TestSerialize_{{.StructName}}(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "{{.KsyFileName}}")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-	
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "{{.BinFileName}}")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := {{.PackageAlias}}.New{{.StructName}}()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMap{{.StructName}}(t, original)
-	
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := {{.PackageAlias}}.New{{.StructName}}()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields({{.PackageAlias}}.{{.StructName}}{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMap{{.StructName}} converts KSC struct to map for serializer
-func structToMap{{.StructName}}(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflective{{.StructName}}(t, data)
-}
-
-// structToMapReflective{{.StructName}} uses reflection to convert KSC structs including method calls
-func structToMapReflective{{.StructName}}(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-	
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-	
-	typ := val.Type()
-	
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-		
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
+// lookup follows a path of map keys and list indexes from the root
+func lookup(root any, path ...any) (any, error) {
+	current := root
+	for i, step := range path {
+		switch s := step.(type) {
+		case string:
+			m, ok := current.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is %T, not a map", pathString(path[:i]), current)
+			}
+			v, ok := m[s]
+			if !ok {
+				return nil, fmt.Errorf("%s not found", pathString(path[:i+1]))
+			}
+			current = v
+		case int:
+			list := reflect.ValueOf(current)
+			if list.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s is %T, not a list", pathString(path[:i]), current)
+			}
+			if s >= list.Len() {
+				return nil, fmt.Errorf("%s has %d items", pathString(path[:i]), list.Len())
+			}
+			current = list.Index(s).Interface()
 		}
-		
-		fieldName := strings.ToLower(field.Name)
-		
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
+	}
+	return current, nil
+}
+
+func pathString(path []any) string {
+	var b strings.Builder
+	b.WriteString("root")
+	for _, step := range path {
+		if i, ok := step.(int); ok {
+			fmt.Fprintf(&b, "[%d]", i)
+		} else {
+			fmt.Fprintf(&b, ".%s", step)
 		}
-		
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-				
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-				
-				// Try to call AsStr method  
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-				
-				// Also include raw fields
-				subMap := structToMapReflective{{.StructName}}(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-				
-				result[fieldName] = subResult
+	}
+	return b.String()
+}
+
+// assertValue compares a value at path with what the KSC test expects
+func assertValue(t *testing.T, expected any, root map[string]any, path ...any) {
+	t.Helper()
+	actual, err := lookup(root, path...)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !valuesEqual(expected, actual) {
+		t.Errorf("%s: expected %#v, got %#v", pathString(path), expected, actual)
+	}
+}
+
+// assertInDelta compares a float at path within delta
+func assertInDelta(t *testing.T, expected, delta float64, root map[string]any, path ...any) {
+	t.Helper()
+	actual, err := lookup(root, path...)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	f, ok := toFloat(actual)
+	if !ok || math.Abs(f-expected) > delta {
+		t.Errorf("%s: expected %v ± %v, got %#v", pathString(path), expected, delta, actual)
+	}
+}
+
+// assertLen checks the number of items, bytes or characters at path
+func assertLen(t *testing.T, expected int, root map[string]any, path ...any) {
+	t.Helper()
+	actual, err := lookup(root, path...)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	v := reflect.ValueOf(actual)
+	switch v.Kind() {
+	case reflect.Slice, reflect.String, reflect.Map:
+		if v.Len() != expected {
+			t.Errorf("%s: expected length %d, got %d", pathString(path), expected, v.Len())
+		}
+	default:
+		t.Errorf("%s: expected length %d of %T", pathString(path), expected, actual)
+	}
+}
+
+// assertFormatInt compares the decimal form of an integer at path
+func assertFormatInt(t *testing.T, expected string, root map[string]any, path ...any) {
+	t.Helper()
+	actual, err := lookup(root, path...)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	n, ok := toInt(actual)
+	if !ok || strconv.FormatInt(n, 10) != expected {
+		t.Errorf("%s: expected %s, got %#v", pathString(path), expected, actual)
+	}
+}
+
+// assertRuneCount checks the number of characters of a string at path
+func assertRuneCount(t *testing.T, expected int, root map[string]any, path ...any) {
+	t.Helper()
+	actual, err := lookup(root, path...)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s, ok := actual.(string)
+	if !ok || utf8.RuneCountInString(s) != expected {
+		t.Errorf("%s: expected %d characters, got %#v", pathString(path), expected, actual)
+	}
+}
+
+// assertNil checks that an optional field at path is absent
+func assertNil(t *testing.T, root map[string]any, path ...any) {
+	t.Helper()
+	actual, err := lookup(root, path...)
+	if err == nil && actual != nil {
+		t.Errorf("%s: expected nil, got %#v", pathString(path), actual)
+	}
+}
+
+// valuesEqual compares loosely typed parser output with a KSC expectation.
+// Enums parse to maps holding name and value.
+func valuesEqual(expected, actual any) bool {
+	if m, ok := actual.(map[string]any); ok {
+		if _, isEnum := m["name"]; isEnum {
+			if label, ok := expected.(kscEnum); ok {
+				return m["name"] == string(label)
 			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
+			return valuesEqual(expected, m["value"])
+		}
+	}
+	if label, ok := expected.(kscEnum); ok {
+		return actual == string(label)
+	}
+	if e, ok := toInt(expected); ok {
+		a, ok := toInt(actual)
+		return ok && a == e
+	}
+	if e, ok := toFloat(expected); ok {
+		a, ok := toFloat(actual)
+		return ok && a == e
+	}
+	ev, av := reflect.ValueOf(expected), reflect.ValueOf(actual)
+	if ev.Kind() == reflect.Slice && av.Kind() == reflect.Slice {
+		if ev.Len() != av.Len() {
+			return false
+		}
+		for i := range ev.Len() {
+			if !valuesEqual(ev.Index(i).Interface(), av.Index(i).Interface()) {
+				return false
 			}
-		default:
-			result[fieldName] = fieldVal.Interface()
 		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func toInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		// Values above math.MaxInt64 are held as negative int64 by the parser
+		return int64(n), true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
 	}
-	
-	return result
+	if i, ok := toInt(v); ok {
+		return float64(i), true
+	}
+	return 0, false
 }
 `
 
@@ -239,6 +472,7 @@ func main() {
 
 	// Setup directories
 	absKsyDir := filepath.Join(rootDir, *ksyDir)
+	absBinDir := filepath.Join(rootDir, *binDir)
 	absKscGoTestDir := filepath.Join(rootDir, *kscGoTestDir)
 	absKscGenDir := filepath.Join(rootDir, *kscGenDir)
 	absTestOutputDir := filepath.Join(rootDir, *testOutputDir)
@@ -249,30 +483,55 @@ func main() {
 	os.MkdirAll(absKscGenDir, 0755)
 	os.MkdirAll(absTestOutputDir, 0755)
 
+	// Generated tests are rewritten from scratch so removed formats don't linger
+	oldTests, _ := filepath.Glob(filepath.Join(absTestOutputDir, "*_gen_test.go"))
+	for _, f := range oldTests {
+		os.Remove(f)
+	}
+	if err := writeGoFile(filepath.Join(absTestOutputDir, "helpers_gen_test.go"), []byte(helpersSource)); err != nil {
+		log.Fatalf("Failed to write test helpers: %v", err)
+	}
+
 	// Process each KSY file
 	ksyFiles, _ := filepath.Glob(filepath.Join(absKsyDir, "*.ksy"))
 
 	tmpl := template.Must(template.New("test").Parse(testTemplate))
 
+	var statuses []formatStatus
 	for _, ksyFile := range ksyFiles {
 		formatName := strings.TrimSuffix(filepath.Base(ksyFile), ".ksy")
 		goPackageName := toGoPackageName(formatName)
+		status := formatStatus{Format: formatName, StructName: toPascalCase(goPackageName)}
 
 		log.Printf("Processing: %s", formatName)
 
-		// Generate KSC code
-		if err := generateKSC(ksyFile, absKscGenDir, goPackageName, absKsyDir); err != nil {
-			log.Printf("  Skipping %s: KSC generation failed: %v", formatName, err)
-			continue
+		// KSC code is only needed by the differential tests, so failing to generate it isn't fatal
+		if *runKSC {
+			if err := generateKSC(ksyFile, absKscGenDir, goPackageName, absKsyDir); err != nil {
+				log.Printf("  KSC generation failed for %s: %v", formatName, err)
+			}
 		}
 
 		// Extract test data from KSC test file
 		kscTestFile := filepath.Join(absKscGoTestDir, goPackageName+"_test.go")
-		testData, err := extractTestData(kscTestFile, formatName)
+		testData, err := extractTestData(kscTestFile, formatName, loadNames(ksyFile))
 		if err != nil {
 			log.Printf("  Skipping %s: %v", formatName, err)
+			status.Note = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(absBinDir, testData.BinFileName)); err != nil {
+			log.Printf("  Skipping %s: missing fixture %s", formatName, testData.BinFileName)
+			status.Note = "missing fixture " + testData.BinFileName
+			statuses = append(statuses, status)
 			continue
 		}
+		status.Expects = testData.ExpectedError
+		status.Fixture = testData.BinFileName
+		if len(testData.Unsupported) > 0 {
+			status.Note = fmt.Sprintf("%d assertions not translated", len(testData.Unsupported))
+		}
 
 		// Generate test file
 		outputPath := filepath.Join(absTestOutputDir, goPackageName+"_gen_test.go")
@@ -280,11 +539,28 @@ func main() {
 			log.Printf("  Failed to generate test for %s: %v", formatName, err)
 			// Clean up any partial file that might have been created
 			os.Remove(outputPath)
+			status.Note = err.Error()
+			statuses = append(statuses, status)
 			continue
 		}
+		status.Generated = true
+		statuses = append(statuses, status)
 
 		log.Printf("  ✓ Generated test for %s", formatName)
 	}
+
+	if *diffDir != "" {
+		if err := writeDifferentialRegistry(rootDir, absKscGenDir, filepath.Join(rootDir, *diffDir), statuses); err != nil {
+			log.Fatalf("Failed to write differential registry: %v", err)
+		}
+	}
+
+	if *matrixFile != "" {
+		if err := writeMatrix(rootDir, absTestOutputDir, statuses, *matrixFile); err != nil {
+			log.Fatalf("Failed to write compliance matrix: %v", err)
+		}
+		log.Printf("Wrote compliance matrix to %s", *matrixFile)
+	}
 }
 
 func getProjectRoot() string {
@@ -337,10 +613,60 @@ func generateKSC(ksyFile, outDir, packageName, importPath string) error {
 	return nil
 }
 
-func extractTestData(testFile, formatName string) (*TestData, error) {
+// loadNames maps the names KSC gives fields, instances, params and enum labels
+// back to their ids in the KSY file. Formats the schema loader can't read fall
+// back to toSnakeCase.
+func loadNames(ksyFile string) map[string]string {
+	names := make(map[string]string)
+	yamlData, err := os.ReadFile(ksyFile)
+	if err != nil {
+		return names
+	}
+	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
+	if err != nil {
+		return names
+	}
+	add := func(id string) {
+		if id != "" {
+			names[toPascalCase(id)] = id
+		}
+	}
+	addEnums := func(enums map[string]kaitaistruct.EnumDef) {
+		for _, enum := range enums {
+			for _, label := range enum {
+				add(label)
+			}
+		}
+	}
+	var addType func(seq []kaitaistruct.SequenceItem, instances map[string]kaitaistruct.InstanceDef, params []kaitaistruct.ParameterDef, types map[string]*kaitaistruct.Type, enums map[string]kaitaistruct.EnumDef)
+	addType = func(seq []kaitaistruct.SequenceItem, instances map[string]kaitaistruct.InstanceDef, params []kaitaistruct.ParameterDef, types map[string]*kaitaistruct.Type, enums map[string]kaitaistruct.EnumDef) {
+		for _, field := range seq {
+			add(field.ID)
+		}
+		for id := range instances {
+			add(id)
+		}
+		for _, param := range params {
+			add(param.ID)
+		}
+		addEnums(enums)
+		for _, t := range types {
+			if t != nil {
+				addType(t.Seq, t.Instances, t.Params, t.Types, t.Enums)
+			}
+		}
+	}
+	addType(schema.Seq, schema.Instances, nil, nil, schema.Enums)
+	for _, t := range schema.Types {
+		addType(t.Seq, t.Instances, t.Params, t.Types, t.Enums)
+	}
+	return names
+}
+
+func extractTestData(testFile, formatName string, names map[string]string) (*TestData, error) {
 	content, err := os.ReadFile(testFile)
 	if err != nil {
-		return nil, fmt.Errorf("cannot read test file: %v", err)
+		return nil, fmt.Errorf("no KSC test")
 	}
 
 	// Extract binary filename using regex
@@ -362,175 +688,310 @@ func extractTestData(testFile, formatName string) (*TestData, error) {
 	visitor := &assertionVisitor{
 		fset:           fset,
 		assertions:     []Assertion{},
-		varAssignments: make(map[string]string),
+		varAssignments: make(map[string]ast.Expr),
+		names:          names,
 	}
 	ast.Walk(visitor, node)
 
 	goPackageName := toGoPackageName(formatName)
 
 	return &TestData{
-		FormatName:   formatName,
-		StructName:   toPascalCase(goPackageName),
-		KsyFileName:  formatName + ".ksy",
-		BinFileName:  binFileName,
-		PackageAlias: goPackageName + "_kaitai",
-		Assertions:   visitor.assertions,
+		FormatName:    formatName,
+		StructName:    toPascalCase(goPackageName),
+		KsyFileName:   formatName + ".ksy",
+		BinFileName:   binFileName,
+		PackageAlias:  goPackageName + "_kaitai",
+		Assertions:    visitor.assertions,
+		ExpectedError: visitor.expectedError,
+		Unsupported:   visitor.unsupported,
 	}, nil
 }
 
 type assertionVisitor struct {
 	fset           *token.FileSet
 	assertions     []Assertion
-	varAssignments map[string]string // Maps var names to their expressions
+	varAssignments map[string]ast.Expr // Maps var names to their expressions
+	names          map[string]string   // KSC names to KSY ids
+	rootVar        string              // Variable the KSC test reads into, usually r
+	expectedError  string
+	unsupported    []string
 }
 
+// enumConstPattern matches KSC enum constants such as Enum0_Animal__Cat
+var enumConstPattern = regexp.MustCompile(`^[A-Z]\w*_\w+__([A-Za-z0-9]+)$`)
+
 func (v *assertionVisitor) Visit(node ast.Node) ast.Visitor {
 	// Look for variable assignments like: tmp1, err := r.Ltr.AsInt()
 	if assign, ok := node.(*ast.AssignStmt); ok {
 		if len(assign.Lhs) >= 1 && len(assign.Rhs) == 1 {
 			if ident, ok := assign.Lhs[0].(*ast.Ident); ok {
 				// Store the expression for this variable
-				v.varAssignments[ident.Name] = v.nodeToString(assign.Rhs[0])
+				v.varAssignments[ident.Name] = assign.Rhs[0]
 			}
 		}
 		return v
 	}
 
-	// Look for assert.EqualValues calls
 	call, ok := node.(*ast.CallExpr)
 	if !ok {
 		return v
 	}
-
 	sel, ok := call.Fun.(*ast.SelectorExpr)
 	if !ok {
 		return v
 	}
 
-	if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == "assert" && sel.Sel.Name == "EqualValues" {
-		if len(call.Args) >= 3 {
-			expected := v.nodeToString(call.Args[1])
-			actualNode := call.Args[2]
-			var fieldPath []string
-			var methodName string
-			var successfullyParsedActual bool
-
-			// Check if actual is a variable reference
-			if varIdent, ok := actualNode.(*ast.Ident); ok {
-				// Look up what this variable was assigned to
-				if expr, found := v.varAssignments[varIdent.Name]; found {
-					// Parse the expression to extract field path and method
-					fieldPath, methodName = v.parseExpression(expr)
-					successfullyParsedActual = true
-				}
-			} else if callExpr, ok := actualNode.(*ast.CallExpr); ok { // Handle r.Field.Method() directly
-				actualExprStr := v.nodeToString(callExpr)
-				fieldPath, methodName = v.parseExpression(actualExprStr)
-				successfullyParsedActual = true
-			} else if selExpr, ok := actualNode.(*ast.SelectorExpr); ok { // Handle r.Field directly
-				actualExprStr := v.nodeToString(selExpr)
-				fieldPath, methodName = v.parseExpression(actualExprStr)
-				successfullyParsedActual = true
-			}
+	// The root is the value Read is called on: r.Read(s, &r, &r)
+	if sel.Sel.Name == "Read" && len(call.Args) == 3 {
+		if ident, ok := sel.X.(*ast.Ident); ok && v.rootVar == "" {
+			v.rootVar = ident.Name
+		}
+		return v
+	}
 
-			if successfullyParsedActual && len(fieldPath) > 0 {
-				assertion := v.generateAssertion(fieldPath, methodName, expected)
-				v.assertions = append(v.assertions, assertion)
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok || ident.Name != "assert" {
+		return v
+	}
+
+	switch sel.Sel.Name {
+	case "ErrorIs":
+		if len(call.Args) >= 3 {
+			v.expectedError = v.nodeToString(call.Args[2])
+		}
+	case "ErrorAs":
+		// var wantErr kaitai.ValidationNotEqualError; assert.ErrorAs(t, err, &wantErr)
+		if len(call.Args) >= 3 {
+			if decl := v.varDeclType(call.Args[2]); decl != "" {
+				v.expectedError = strings.TrimPrefix(decl, "kaitai.")
 			}
 		}
+	case "Error":
+		if v.expectedError == "" {
+			v.expectedError = "error"
+		}
+	case "EqualValues", "Equal", "InDelta", "Nil":
+		if assertion, err := v.translate(sel.Sel.Name, call); err != nil {
+			v.unsupported = append(v.unsupported, fmt.Sprintf("%s (%v)", v.nodeToString(call), err))
+		} else {
+			v.assertions = append(v.assertions, assertion)
+		}
 	}
-
 	return v
 }
 
-func (v *assertionVisitor) parseExpression(expr string) ([]string, string) {
-	// Parse expressions like "r.Ltr.AsInt()" or "r.FieldName"
-	expr = strings.TrimPrefix(expr, "r.")
+// varDeclType returns the declared type of &name, from `var name T` statements seen so far
+func (v *assertionVisitor) varDeclType(arg ast.Expr) string {
+	unary, ok := arg.(*ast.UnaryExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := unary.X.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return ""
+	}
+	if spec, ok := ident.Obj.Decl.(*ast.ValueSpec); ok && spec.Type != nil {
+		return v.nodeToString(spec.Type)
+	}
+	return ""
+}
 
-	isMethodCall := strings.HasSuffix(expr, "()")
-	if isMethodCall {
-		expr = strings.TrimSuffix(expr, "()")
+// translate turns a KSC assertion into a check on the parsed map
+func (v *assertionVisitor) translate(name string, call *ast.CallExpr) (Assertion, error) {
+	if name == "Nil" {
+		if len(call.Args) < 2 {
+			return Assertion{}, fmt.Errorf("missing argument")
+		}
+		path, err := v.path(call.Args[1])
+		if err != nil {
+			return Assertion{}, err
+		}
+		return v.generateAssertion(path, "", "", fmt.Sprintf("assertNil(t, customMap%s)", pathArgs(path))), nil
+	}
+	if len(call.Args) < 3 {
+		return Assertion{}, fmt.Errorf("missing argument")
+	}
+	expected, err := v.expected(call.Args[1])
+	if err != nil {
+		return Assertion{}, err
 	}
+	actual := call.Args[2]
 
-	parts := strings.Split(expr, ".")
-	if len(parts) > 0 {
-		if isMethodCall && (parts[len(parts)-1] == "AsInt" || parts[len(parts)-1] == "AsStr") {
-			methodName := parts[len(parts)-1]
-			fieldPath := parts[:len(parts)-1]
-			return fieldPath, methodName
+	if name == "InDelta" {
+		if len(call.Args) < 4 {
+			return Assertion{}, fmt.Errorf("missing delta")
+		}
+		delta, err := v.expected(call.Args[3])
+		if err != nil {
+			return Assertion{}, err
+		}
+		path, err := v.path(actual)
+		if err != nil {
+			return Assertion{}, err
+		}
+		return v.generateAssertion(path, "", expected, fmt.Sprintf("assertInDelta(t, %s, %s, customMap%s)", expected, delta, pathArgs(path))), nil
+	}
+
+	// len(r.Items) and strconv.FormatInt(int64(r.Value), 10)
+	if c, ok := actual.(*ast.CallExpr); ok {
+		switch fn := v.nodeToString(c.Fun); {
+		case fn == "len" && len(c.Args) == 1:
+			path, err := v.path(c.Args[0])
+			if err != nil {
+				return Assertion{}, err
+			}
+			return v.generateAssertion(path, "len", expected, fmt.Sprintf("assertLen(t, %s, customMap%s)", expected, pathArgs(path))), nil
+		case fn == "utf8.RuneCountInString" && len(c.Args) == 1:
+			path, err := v.path(c.Args[0])
+			if err != nil {
+				return Assertion{}, err
+			}
+			return v.generateAssertion(path, "RuneCount", expected, fmt.Sprintf("assertRuneCount(t, %s, customMap%s)", expected, pathArgs(path))), nil
+		case fn == "[]rune" && len(c.Args) == 1:
+			path, err := v.path(c.Args[0])
+			if err != nil {
+				return Assertion{}, err
+			}
+			return v.generateAssertion(path, "", expected, fmt.Sprintf("assertValue(t, string(%s), customMap%s)", expected, pathArgs(path))), nil
+		case fn == "strconv.FormatInt" && len(c.Args) == 2:
+			inner := c.Args[0]
+			if conv, ok := inner.(*ast.CallExpr); ok && len(conv.Args) == 1 {
+				inner = conv.Args[0]
+			}
+			path, err := v.path(inner)
+			if err != nil {
+				return Assertion{}, err
+			}
+			return v.generateAssertion(path, "FormatInt", expected, fmt.Sprintf("assertFormatInt(t, %s, customMap%s)", expected, pathArgs(path))), nil
 		}
-		// If not AsInt/AsStr or not a method call, it's a direct field path
-		return parts, ""
 	}
-	return nil, ""
+
+	path, err := v.path(actual)
+	if err != nil {
+		return Assertion{}, err
+	}
+	return v.generateAssertion(path, "", expected, fmt.Sprintf("assertValue(t, %s, customMap%s)", expected, pathArgs(path))), nil
 }
 
-func (v *assertionVisitor) generateAssertion(fieldPath []string, methodName string, expected string) Assertion {
-	// Generate assertion code for custom parser map access
-	var code strings.Builder
+// path resolves a KSC accessor expression such as r.Docs[0].Main.SomeInt or
+// tmp1 (from tmp1, err := r.Hdr()) to map keys and list indexes
+func (v *assertionVisitor) path(expr ast.Expr) ([]any, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return v.path(e.X)
+	case *ast.Ident:
+		if e.Name == v.rootVar {
+			return nil, nil
+		}
+		if assigned, ok := v.varAssignments[e.Name]; ok {
+			return v.path(assigned)
+		}
+		return nil, fmt.Errorf("unknown variable %s", e.Name)
+	case *ast.SelectorExpr:
+		base, err := v.path(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return append(base, v.fieldName(e.Sel.Name)), nil
+	case *ast.CallExpr:
+		// Instances are methods without arguments
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok || len(e.Args) != 0 {
+			return nil, fmt.Errorf("unsupported call %s", v.nodeToString(e))
+		}
+		base, err := v.path(sel.X)
+		if err != nil {
+			return nil, err
+		}
+		return append(base, v.fieldName(sel.Sel.Name)), nil
+	case *ast.IndexExpr:
+		base, err := v.path(e.X)
+		if err != nil {
+			return nil, err
+		}
+		lit, ok := e.Index.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, fmt.Errorf("unsupported index %s", v.nodeToString(e.Index))
+		}
+		i, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+		return append(base, i), nil
+	}
+	return nil, fmt.Errorf("unsupported expression %s", v.nodeToString(expr))
+}
 
-	// Convert PascalCase field names from KSC tests to snake_case for our parser
-	snakePath := make([]string, len(fieldPath))
-	for i, p := range fieldPath {
-		snakePath[i] = toSnakeCase(p)
+// expected returns Go source for an expected value. Literals are kept, enum
+// constants become kscEnum labels and variables are resolved to what they hold.
+func (v *assertionVisitor) expected(expr ast.Expr) (string, error) {
+	if ident, ok := expr.(*ast.Ident); ok {
+		if m := enumConstPattern.FindStringSubmatch(ident.Name); m != nil {
+			return fmt.Sprintf("kscEnum(%q)", v.fieldName(m[1])), nil
+		}
+		if assigned, ok := v.varAssignments[ident.Name]; ok {
+			return v.expected(assigned)
+		}
 	}
 
-	fullAccessPathString := strings.Join(fieldPath, ".")
-	if methodName != "" {
-		fullAccessPathString += "." + methodName // For display in comments/errors
+	var unsupported error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			unsupported = fmt.Errorf("unsupported expected value %s", v.nodeToString(expr))
+			return false
+		case *ast.Ident:
+			if !expectedIdents[n.Name] {
+				unsupported = fmt.Errorf("unsupported expected value %s", v.nodeToString(expr))
+			}
+		}
+		return true
+	})
+	if unsupported != nil {
+		return "", unsupported
 	}
-	code.WriteString(fmt.Sprintf("\t// Assert %s\n", fullAccessPathString))
+	return v.nodeToString(expr), nil
+}
 
-	currentMapVar := "customMap"
-	varPathPrefix := strings.ToLower(strings.Join(snakePath, "_")) // For unique temp var names
+// expectedIdents are the identifiers allowed in expected values copied from KSC tests
+var expectedIdents = map[string]bool{
+	"true": true, "false": true, "nil": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "byte": true, "rune": true, "string": true,
+}
 
-	// Build nested access for map[string]any
-	for i, key := range snakePath {
-		tempVar := fmt.Sprintf("%s_lvl%d", varPathPrefix, i) // e.g. ltr_lvl0, header_version_lvl0
+// fieldName maps a KSC Go name back to its KSY id
+func (v *assertionVisitor) fieldName(name string) string {
+	if id, ok := v.names[name]; ok {
+		return id
+	}
+	return toSnakeCase(name)
+}
 
-		if i < len(snakePath)-1 {
-			// This is an intermediate key in the path, its value must be another map.
-			code.WriteString(fmt.Sprintf("\tif %s_map, ok := %s[\"%s\"].(map[string]any); ok {\n", tempVar, currentMapVar, key))
-			currentMapVar = tempVar + "_map" // Update current map variable for the next level of nesting
+// pathArgs formats a path as trailing arguments for the assertion helpers
+func pathArgs(path []any) string {
+	var b strings.Builder
+	for _, step := range path {
+		if i, ok := step.(int); ok {
+			fmt.Fprintf(&b, ", %d", i)
 		} else {
-			// This is the last key in the path.
-			finalValueVar := fmt.Sprintf("%s_val", varPathPrefix) // e.g. ltr_val, header_version_val
-
-			if methodName != "" {
-				// Accessing an instance (like AsInt, AsStr) within the map of the last key.
-				// The value of 'key' must be a map, and 'methodName' is a key in that map.
-				code.WriteString(fmt.Sprintf("\tif %s_instancemap, ok := %s[\"%s\"].(map[string]any); ok {\n", tempVar, currentMapVar, key))
-				code.WriteString(fmt.Sprintf("\t\tif %s, ok := %s_instancemap[\"%s\"]; ok {\n", finalValueVar, tempVar, toSnakeCase(methodName)))
-				code.WriteString(fmt.Sprintf("\t\t\tassert.EqualValues(t, %s, %s)\n", expected, finalValueVar))
-				code.WriteString("\t\t} else {\n")
-				code.WriteString(fmt.Sprintf("\t\t\tt.Fatalf(\"Instance '%s' not found in %s (map was %%#v)\", %s_instancemap)\n", methodName, fullAccessPathString, tempVar))
-				code.WriteString("\t\t}\n")
-				code.WriteString("\t} else {\n")
-				code.WriteString(fmt.Sprintf("\t\tt.Fatalf(\"Field '%s' (for instance '%s') not found or not a map in %%#v (got %%T)\", %s, %s[\"%s\"])\n", key, methodName, currentMapVar, currentMapVar, key))
-				code.WriteString("\t}\n")
-			} else {
-				// Direct access to the value of the last key.
-				code.WriteString(fmt.Sprintf("\tif %s, ok := %s[\"%s\"]; ok {\n", finalValueVar, currentMapVar, key))
-				code.WriteString(fmt.Sprintf("\t\tassert.EqualValues(t, %s, %s)\n", expected, finalValueVar))
-				code.WriteString("\t} else {\n")
-				code.WriteString(fmt.Sprintf("\t\tt.Fatalf(\"Field '%s' not found in %s (keys: %%v)\", maps.Keys(%s))\n", key, currentMapVar, currentMapVar))
-				code.WriteString("\t}\n")
-			}
+			fmt.Fprintf(&b, ", %q", step)
 		}
 	}
+	return b.String()
+}
 
-	// Close the nested if blocks correctly
-	for i := len(snakePath) - 1; i > 0; i-- { // if len(snakePath) is 1, this loop doesn't run
-		code.WriteString("\t} else {\n")
-		code.WriteString(fmt.Sprintf("\t\tt.Fatalf(\"Field '%s' not found or not a map while asserting %s\")\n", snakePath[i-1], fullAccessPathString))
-		code.WriteString("\t}\n")
+func (v *assertionVisitor) generateAssertion(path []any, methodName string, expected string, call string) Assertion {
+	fieldPath := make([]string, len(path))
+	for i, step := range path {
+		fieldPath[i] = fmt.Sprint(step)
 	}
-
 	return Assertion{
 		FieldPath:     fieldPath,
 		MethodName:    methodName,
 		Expected:      expected,
-		AssertionCode: code.String(),
+		AssertionCode: call,
 	}
 }
 
@@ -624,31 +1085,144 @@ func generateTest(tmpl *template.Template, data *TestData, outputPath string) er
 	if buf.Len() < 100 {
 		return fmt.Errorf("generated content too small (%d bytes), likely an error", buf.Len())
 	}
+	return writeGoFile(outputPath, buf.Bytes())
+}
 
-	// Add maps import if not already there, for maps.Keys inFatalf
-	if !bytes.Contains(buf.Bytes(), []byte("\"maps\"")) {
-		// This is a bit of a hack; ideally, imports are managed more robustly.
-		// For now, we rely on goimports to add it if maps.Keys is used.
+// writeGoFile gofmts and writes generated source
+func writeGoFile(path string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", filepath.Base(path), err)
 	}
-	// Format with goimports
-	cmd := exec.Command("goimports")
-	cmd.Stdin = &buf
+	return os.WriteFile(path, formatted, 0644)
+}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
+const registryTemplate = `// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package differential_test
 
-	if err := cmd.Run(); err != nil {
-		// Write unformatted if goimports fails
-		if buf.Len() > 0 {
-			return os.WriteFile(outputPath, buf.Bytes(), 0644)
+import (
+{{- range .}}
+	"github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/{{.Format}}"
+{{- end}}
+)
+
+// kscFormats lists the formats whose KSC-generated parser compiles, with the fixture their KSC test reads
+var kscFormats = []kscFormat{
+{{- range .}}
+	{"{{.Format}}", "{{.Fixture}}", func() any { return {{.Format}}.New{{.StructName}}() }},
+{{- end}}
+}
+`
+
+// writeDifferentialRegistry lists the KSC-generated parsers the differential
+// tests can use: those with a fixture whose package compiles
+func writeDifferentialRegistry(rootDir, kscDir string, outDir string, statuses []formatStatus) error {
+	rel, err := filepath.Rel(rootDir, kscDir)
+	if err != nil {
+		return err
+	}
+	// Build errors are reported per package as "# <import path>"
+	cmd := exec.Command("go", "build", "./"+filepath.ToSlash(rel)+"/...")
+	cmd.Dir = rootDir
+	output, _ := cmd.CombinedOutput()
+	broken := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if pkg, ok := strings.CutPrefix(line, "# "); ok {
+			broken[pkg[strings.LastIndex(pkg, "/")+1:]] = true
+		}
+	}
+
+	var formats []formatStatus
+	for _, s := range statuses {
+		if s.Fixture == "" || broken[s.Format] || s.Format != toGoPackageName(s.Format) {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(kscDir, s.Format, s.Format+".go"))
+		if err != nil || !bytes.Contains(src, []byte("func New"+s.StructName+"() ")) {
+			continue
+		}
+		formats = append(formats, s)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Format < formats[j].Format })
+
+	var buf bytes.Buffer
+	if err := template.Must(template.New("registry").Parse(registryTemplate)).Execute(&buf, formats); err != nil {
+		return err
+	}
+	log.Printf("Registered %d KSC parsers for differential tests (%d packages don't compile)", len(formats), len(broken))
+	return writeGoFile(filepath.Join(outDir, "registry_gen_test.go"), buf.Bytes())
+}
+
+// testEvent is a line of `go test -json` output
+type testEvent struct {
+	Action string
+	Test   string
+}
+
+// writeMatrix runs the generated tests and writes a markdown table with the
+// parse and round-trip outcome of every format
+func writeMatrix(rootDir, testDir string, statuses []formatStatus, matrixPath string) error {
+	rel, err := filepath.Rel(rootDir, testDir)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("go", "test", "-json", "-count=1", "./"+filepath.ToSlash(rel))
+	cmd.Dir = rootDir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	// Failing tests are expected; only missing output means the run itself failed
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		return fmt.Errorf("go test: %w", err)
+	}
+
+	results := make(map[string]string)
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev testEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil || ev.Test == "" || strings.Contains(ev.Test, "/") {
+			continue
 		}
-		return fmt.Errorf("goimports failed and no content to write: %v", err)
+		switch ev.Action {
+		case "pass", "fail", "skip":
+			results[ev.Test] = ev.Action
+		}
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no test results, the generated tests may not compile:\n%s", stdout.String())
 	}
 
-	// Check that goimports produced output
-	if out.Len() == 0 {
-		return fmt.Errorf("goimports produced no output")
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Format < statuses[j].Format })
+	counts := make(map[string]int)
+	var rows strings.Builder
+	for _, s := range statuses {
+		parse, serialize := "-", "-"
+		if s.Generated {
+			parse = results["TestParse_"+s.StructName]
+			if s.Expects == "" {
+				serialize = results["TestSerialize_"+s.StructName]
+			}
+		}
+		if parse == "" {
+			parse = "fail"
+		}
+		counts["parse "+parse]++
+		counts["serialize "+serialize]++
+		note := s.Note
+		if s.Expects != "" {
+			note = strings.TrimSpace("expects " + s.Expects + ". " + note)
+		}
+		fmt.Fprintf(&rows, "| %s | %s | %s | %s |\n", s.Format, parse, serialize, strings.ReplaceAll(note, "|", "\\|"))
 	}
 
-	return os.WriteFile(outputPath, out.Bytes(), 0644)
+	var out strings.Builder
+	out.WriteString("# Kaitai Struct conformance\n\n")
+	out.WriteString("Generated by `go run ./scripts/kaitai-testgen-simple.go -ksc=false -matrix <file>`. ")
+	out.WriteString("Parse runs the assertions of the format's KSC test against kbin's parser; serialize runs them again after a serialize and parse round trip.\n\n")
+	fmt.Fprintf(&out, "%d formats: parse %d pass, %d fail, %d not generated; serialize %d pass, %d fail, %d skipped.\n\n",
+		len(statuses), counts["parse pass"], counts["parse fail"], counts["parse -"],
+		counts["serialize pass"], counts["serialize fail"], counts["serialize skip"])
+	out.WriteString("| Format | Parse | Serialize | Notes |\n|---|---|---|---|\n")
+	out.WriteString(rows.String())
+	return os.WriteFile(matrixPath, []byte(out.String()), 0644)
 }
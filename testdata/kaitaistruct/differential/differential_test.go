@@ -0,0 +1,466 @@
+// Package differential_test parses the corpus fixtures with both the
+// KSC-generated Go parsers in testdata/formats_kaitai_go_gen and
+// KaitaiInterpreter, and reports every field where the two trees differ.
+// The registry of formats is generated by scripts/kaitai-testgen-simple.go.
+package differential_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
+	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
+)
+
+var diffReport = flag.String("diff-report", "", "write a per-format summary of the differences to this file")
+
+const (
+	formatsDir    = "../../../test/formats"
+	binDir        = "../../../test/src"
+	parseTimeout  = 10 * time.Second
+	maxDiffsShown = 20
+)
+
+// kscFormat is a format with a compiling KSC-generated parser
+type kscFormat struct {
+	name    string
+	fixture string
+	newRoot func() any // Returns a pointer to the generated root struct
+}
+
+// difference is a field where the KSC and kbin trees disagree
+type difference struct {
+	path string
+	kind string // "value", "type" or "presence"
+	ksc  string
+	kbin string
+}
+
+func (d difference) String() string {
+	return fmt.Sprintf("%s: %s differs, KSC %s, kbin %s", d.path, d.kind, d.ksc, d.kbin)
+}
+
+// formatResult summarises one format for the report
+type formatResult struct {
+	format     string
+	diffs      int
+	mutations  int
+	mutDiffers int
+	note       string
+}
+
+func TestDifferential(t *testing.T) {
+	var results []formatResult
+	for _, f := range kscFormats {
+		t.Run(f.name, func(t *testing.T) {
+			result := formatResult{format: f.name}
+			defer func() { results = append(results, result) }()
+
+			schema, err := loadSchema(f.name)
+			if err != nil {
+				result.note = err.Error()
+				t.Skip(err)
+			}
+			data, err := os.ReadFile(filepath.Join(binDir, f.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diffs, err := compare(f, schema, data)
+			if err != nil {
+				result.note = err.Error()
+				t.Errorf("fixture: %v", err)
+			}
+			result.diffs = len(diffs)
+			reportDiffs(t, "fixture", diffs)
+
+			for _, m := range mutations(data) {
+				result.mutations++
+				diffs, err := compare(f, schema, m.data)
+				if err != nil || len(diffs) > 0 {
+					result.mutDiffers++
+				}
+				if err != nil {
+					t.Errorf("%s: %v", m.name, err)
+				}
+				reportDiffs(t, m.name, diffs)
+			}
+		})
+	}
+	if *diffReport != "" {
+		if err := writeReport(*diffReport, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func loadSchema(format string) (*kaitaistruct.KaitaiSchema, error) {
+	yamlData, err := os.ReadFile(filepath.Join(formatsDir, format+".ksy"))
+	if err != nil {
+		return nil, err
+	}
+	return kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
+}
+
+// compare parses data with both parsers. Both may fail, as expected for
+// malformed input; an error is returned when only one of them does.
+func compare(f kscFormat, schema *kaitaistruct.KaitaiSchema, data []byte) ([]difference, error) {
+	root, kscErr := readKSC(f, data)
+	ours, kbinErr := parseKbin(schema, data)
+	switch {
+	case kscErr != nil && kbinErr != nil:
+		return nil, nil
+	case kscErr != nil:
+		return nil, fmt.Errorf("KSC fails (%v), kbin parses", kscErr)
+	case kbinErr != nil:
+		return nil, fmt.Errorf("kbin fails (%v), KSC parses", kbinErr)
+	}
+	var diffs []difference
+	diffValue("root", reflect.ValueOf(root), ours, &diffs)
+	return diffs, nil
+}
+
+// readKSC runs the generated Read method, turning panics and hangs into errors
+func readKSC(f kscFormat, data []byte) (any, error) {
+	type outcome struct {
+		root any
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", r)}
+			}
+		}()
+		root := f.newRoot()
+		rv := reflect.ValueOf(root)
+		stream := reflect.ValueOf(kaitai.NewStream(bytes.NewReader(data)))
+		out := rv.MethodByName("Read").Call([]reflect.Value{stream, rv, rv})
+		err, _ := out[0].Interface().(error)
+		done <- outcome{root, err}
+	}()
+	select {
+	case o := <-done:
+		return o.root, o.err
+	case <-time.After(parseTimeout):
+		return nil, fmt.Errorf("KSC parser timed out")
+	}
+}
+
+func parseKbin(schema *kaitaistruct.KaitaiSchema, data []byte) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), parseTimeout)
+	defer cancel()
+	parsed, err := interpreter.Parse(ctx, kaitai.NewStream(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+	return kaitaistruct.ParsedDataToMap(parsed), nil
+}
+
+// normalizeName lets KSC names (Byte1, Unnamed0) match KSY ids (byte_1, _unnamed0)
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// kbinInternal are keys kbin adds that KSC exposes differently or not at all
+var kbinInternal = map[string]bool{"_io": true, "_parent": true, "_root": true, "_value": true}
+
+// diffValue walks a KSC value and the matching kbin value, recording differences
+func diffValue(path string, ksc reflect.Value, ours any, diffs *[]difference) {
+	for ksc.Kind() == reflect.Interface || ksc.Kind() == reflect.Pointer {
+		if ksc.IsNil() {
+			if ours != nil {
+				*diffs = append(*diffs, difference{path, "presence", "nil", describe(ours)})
+			}
+			return
+		}
+		if ksc.Kind() == reflect.Pointer && ksc.Elem().Kind() != reflect.Struct {
+			ksc = ksc.Elem()
+			continue
+		}
+		if ksc.Kind() == reflect.Interface {
+			ksc = ksc.Elem()
+			continue
+		}
+		break
+	}
+
+	// Enums parse to maps holding name and value
+	if m, ok := ours.(map[string]any); ok && isEnum(m) && ksc.Kind() != reflect.Pointer {
+		ours = m["value"]
+	}
+
+	switch ksc.Kind() {
+	case reflect.Pointer:
+		diffStruct(path, ksc, ours, diffs)
+	case reflect.Slice:
+		// KSC also reads repeated u1 fields into []uint8, which kbin parses to a list
+		if _, isList := ours.([]any); !isList && ksc.Type().Elem().Kind() == reflect.Uint8 {
+			diffScalar(path, "bytes", ksc.Bytes(), ours, diffs)
+			return
+		}
+		list := reflect.ValueOf(ours)
+		if ours == nil || list.Kind() != reflect.Slice {
+			*diffs = append(*diffs, difference{path, "type", fmt.Sprintf("list of %d", ksc.Len()), describe(ours)})
+			return
+		}
+		if list.Len() != ksc.Len() {
+			*diffs = append(*diffs, difference{path, "value", fmt.Sprintf("%d items", ksc.Len()), fmt.Sprintf("%d items", list.Len())})
+		}
+		for i := range min(ksc.Len(), list.Len()) {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), ksc.Index(i), list.Index(i).Interface(), diffs)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		diffScalar(path, "int", ksc.Int(), ours, diffs)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// Parsed u8 values above math.MaxInt64 are held as negative int64
+		diffScalar(path, "int", int64(ksc.Uint()), ours, diffs)
+	case reflect.Float32, reflect.Float64:
+		diffScalar(path, "float", ksc.Float(), ours, diffs)
+	case reflect.Bool:
+		diffScalar(path, "bool", ksc.Bool(), ours, diffs)
+	case reflect.String:
+		diffScalar(path, "string", ksc.String(), ours, diffs)
+	default:
+		*diffs = append(*diffs, difference{path, "type", ksc.Type().String(), describe(ours)})
+	}
+}
+
+// diffStruct compares a generated struct's fields and instances with a kbin map
+func diffStruct(path string, ksc reflect.Value, ours any, diffs *[]difference) {
+	m, ok := ours.(map[string]any)
+	if !ok {
+		if ours == nil {
+			// Types without fields convert to nil
+			m = map[string]any{}
+		} else {
+			*diffs = append(*diffs, difference{path, "type", ksc.Type().String(), describe(ours)})
+			return
+		}
+	}
+	byName := make(map[string]string, len(m))
+	for k := range m {
+		byName[normalizeName(k)] = k
+	}
+	seen := make(map[string]bool)
+
+	compareMember := func(name string, value reflect.Value) {
+		key, found := byName[normalizeName(name)]
+		childPath := path + "." + name
+		seen[key] = found
+		if !found {
+			// KSC can't tell an absent scalar from its zero value
+			if !isZero(value) {
+				*diffs = append(*diffs, difference{childPath, "presence", describe(value.Interface()), "missing"})
+			}
+			return
+		}
+		diffValue(childPath, value, m[key], diffs)
+	}
+
+	elem := ksc.Elem()
+	for i := range elem.NumField() {
+		field := elem.Type().Field(i)
+		if field.IsExported() {
+			compareMember(field.Name, elem.Field(i))
+		}
+	}
+
+	// Instances are methods without arguments returning a value and an error
+	for i := range ksc.NumMethod() {
+		method := ksc.Type().Method(i)
+		if method.Name == "Read" || method.Type.NumIn() != 1 || method.Type.NumOut() != 2 ||
+			method.Type.Out(1) != reflect.TypeFor[error]() {
+			continue
+		}
+		value, err := callInstance(ksc.Method(i))
+		if err != nil {
+			if key, found := byName[normalizeName(method.Name)]; found {
+				seen[key] = true
+				*diffs = append(*diffs, difference{path + "." + method.Name, "presence", "error: " + err.Error(), describe(m[key])})
+			}
+			continue
+		}
+		compareMember(method.Name, value)
+	}
+
+	var extra []string
+	for k := range m {
+		if !seen[k] && !kbinInternal[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		*diffs = append(*diffs, difference{path + "." + k, "presence", "missing", describe(m[k])})
+	}
+}
+
+// callInstance evaluates a KSC instance, turning panics into errors
+func callInstance(method reflect.Value) (value reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	out := method.Call(nil)
+	if e, _ := out[1].Interface().(error); e != nil {
+		return reflect.Value{}, e
+	}
+	return out[0], nil
+}
+
+func diffScalar(path, kind string, ksc, ours any, diffs *[]difference) {
+	normalized, ok := normalizeScalar(kind, ours)
+	if !ok {
+		*diffs = append(*diffs, difference{path, "type", fmt.Sprintf("%s %v", kind, ksc), describe(ours)})
+		return
+	}
+	if !reflect.DeepEqual(ksc, normalized) {
+		*diffs = append(*diffs, difference{path, "value", fmt.Sprintf("%#v", ksc), fmt.Sprintf("%#v", ours)})
+	}
+}
+
+// normalizeScalar converts a kbin value to the Go type KSC uses for kind
+func normalizeScalar(kind string, v any) (any, bool) {
+	switch kind {
+	case "int":
+		switch n := v.(type) {
+		case int:
+			return int64(n), true
+		case int8:
+			return int64(n), true
+		case int16:
+			return int64(n), true
+		case int32:
+			return int64(n), true
+		case int64:
+			return n, true
+		case uint8:
+			return int64(n), true
+		case uint16:
+			return int64(n), true
+		case uint32:
+			return int64(n), true
+		case uint64:
+			return int64(n), true
+		}
+	case "float":
+		switch n := v.(type) {
+		case float32:
+			return float64(n), true
+		case float64:
+			return n, true
+		}
+	case "bool":
+		b, ok := v.(bool)
+		return b, ok
+	case "string":
+		s, ok := v.(string)
+		return s, ok
+	case "bytes":
+		b, ok := v.([]byte)
+		if ok && b == nil {
+			b = []byte{}
+		}
+		return b, ok
+	}
+	return nil, false
+}
+
+func isEnum(m map[string]any) bool {
+	_, hasName := m["name"]
+	_, hasValue := m["value"]
+	return hasName && hasValue && len(m) <= 3
+}
+
+func isZero(v reflect.Value) bool {
+	if !v.IsValid() || v.IsZero() {
+		return true
+	}
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0
+}
+
+func describe(v any) string {
+	if v == nil {
+		return "nil"
+	}
+	s := fmt.Sprintf("%T %v", v, v)
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
+
+func reportDiffs(t *testing.T, input string, diffs []difference) {
+	t.Helper()
+	for i, d := range diffs {
+		if i == maxDiffsShown {
+			t.Errorf("%s: %d more differences", input, len(diffs)-maxDiffsShown)
+			return
+		}
+		t.Errorf("%s: %s", input, d)
+	}
+}
+
+// mutation is a malformed variant of a fixture
+type mutation struct {
+	name string
+	data []byte
+}
+
+// mutations returns deterministic malformed variants of data: truncations,
+// flipped bits and trailing garbage. Only the lowest bit is flipped, as the
+// KSC runtime allocates a length field's worth of bytes before reading.
+func mutations(data []byte) []mutation {
+	var out []mutation
+	if len(data) == 0 {
+		return out
+	}
+	out = append(out,
+		mutation{"empty", []byte{}},
+		mutation{"truncated to half", bytes.Clone(data[:len(data)/2])},
+		mutation{"last byte dropped", bytes.Clone(data[:len(data)-1])},
+		mutation{"trailing garbage", append(bytes.Clone(data), 0xFF, 0x00, 0xFF, 0x00)},
+	)
+	for i := range 4 {
+		pos := i * len(data) / 4
+		flipped := bytes.Clone(data)
+		flipped[pos] ^= 0x01
+		out = append(out, mutation{fmt.Sprintf("bit 0 of byte %d flipped", pos), flipped})
+	}
+	return out
+}
+
+func writeReport(path string, results []formatResult) error {
+	sort.Slice(results, func(i, j int) bool { return results[i].format < results[j].format })
+	var b strings.Builder
+	b.WriteString("| Format | Fixture differences | Mutations differing | Notes |\n|---|---|---|---|\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "| %s | %d | %d/%d | %s |\n", r.format, r.diffs, r.mutDiffers, r.mutations,
+			strings.ReplaceAll(strings.ReplaceAll(r.note, "|", "\\|"), "\n", " "))
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_BitsEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "bits_enum.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkBitsEnum(t, customMap)
+}
+
+func TestSerialize_BitsEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "bits_enum.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkBitsEnum(t, roundTrip(t, "bits_enum.ksy", customMap))
+}
+
+// checkBitsEnum applies the assertions of the KSC test to parsed data
+func checkBitsEnum(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("platypus"), customMap, "one")
+	assertValue(t, kscEnum("horse"), customMap, "two")
+	assertValue(t, kscEnum("cat"), customMap, "three")
+}
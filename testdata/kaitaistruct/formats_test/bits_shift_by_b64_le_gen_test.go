@@ -1,194 +1,27 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	bits_shift_by_b64_le_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/bits_shift_by_b64_le"
-)
+import "testing"
 
 func TestParse_BitsShiftByB64Le(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "bits_shift_by_b64_le.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "bits_shift_by_b64_le.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert A
-	if a_val, ok := customMap["a"]; ok {
-		assert.EqualValues(t, uint64(18446744073709551615), a_val)
-	} else {
-		t.Fatalf("Field 'a' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert B
-	if b_val, ok := customMap["b"]; ok {
-		assert.EqualValues(t, 0, b_val)
-	} else {
-		t.Fatalf("Field 'b' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "bits_shift_by_b64_le.ksy", "bits_shift_by_b64_le.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
+	checkBitsShiftByB64Le(t, customMap)
 }
 
 func TestSerialize_BitsShiftByB64Le(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "bits_shift_by_b64_le.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "bits_shift_by_b64_le.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := bits_shift_by_b64_le_kaitai.NewBitsShiftByB64Le()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapBitsShiftByB64Le(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := bits_shift_by_b64_le_kaitai.NewBitsShiftByB64Le()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(bits_shift_by_b64_le_kaitai.BitsShiftByB64Le{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "bits_shift_by_b64_le.ksy", "bits_shift_by_b64_le.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapBitsShiftByB64Le converts KSC struct to map for serializer
-func structToMapBitsShiftByB64Le(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveBitsShiftByB64Le(t, data)
+	checkBitsShiftByB64Le(t, roundTrip(t, "bits_shift_by_b64_le.ksy", customMap))
 }
 
-// structToMapReflectiveBitsShiftByB64Le uses reflection to convert KSC structs including method calls
-func structToMapReflectiveBitsShiftByB64Le(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveBitsShiftByB64Le(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkBitsShiftByB64Le applies the assertions of the KSC test to parsed data
+func checkBitsShiftByB64Le(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, uint64(18446744073709551615), customMap, "a")
+	assertValue(t, 0, customMap, "b")
 }
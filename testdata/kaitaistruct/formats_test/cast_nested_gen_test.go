@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_CastNested(t *testing.T) {
+	customMap, err := parseFormat(t, "cast_nested.ksy", "switch_opcodes.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkCastNested(t, customMap)
+}
+
+func TestSerialize_CastNested(t *testing.T) {
+	customMap, err := parseFormat(t, "cast_nested.ksy", "switch_opcodes.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkCastNested(t, roundTrip(t, "cast_nested.ksy", customMap))
+}
+
+// checkCastNested applies the assertions of the KSC test to parsed data
+func checkCastNested(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foobar", customMap, "opcodes_0_str", "value")
+	assertValue(t, "foobar", customMap, "opcodes_0_str_value")
+	assertValue(t, 66, customMap, "opcodes_1_int", "value")
+	assertValue(t, 66, customMap, "opcodes_1_int_value")
+}
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_CastToTop(t *testing.T) {
+	customMap, err := parseFormat(t, "cast_to_top.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkCastToTop(t, customMap)
+}
+
+func TestSerialize_CastToTop(t *testing.T) {
+	customMap, err := parseFormat(t, "cast_to_top.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkCastToTop(t, roundTrip(t, "cast_to_top.ksy", customMap))
+}
+
+// checkCastToTop applies the assertions of the KSC test to parsed data
+func checkCastToTop(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "code")
+	assertValue(t, 65, customMap, "header", "code")
+	assertValue(t, 65, customMap, "header_casted", "code")
+}
@@ -1,250 +1,35 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	combine_bytes_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/combine_bytes"
-)
+import "testing"
 
 func TestParse_CombineBytes(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "combine_bytes.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert BytesTerm
-	if bytes_term_val, ok := customMap["bytes_term"]; ok {
-		assert.EqualValues(t, []uint8{102, 111, 111}, bytes_term_val)
-	} else {
-		t.Fatalf("Field 'bytes_term' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert BytesLimit
-	if bytes_limit_val, ok := customMap["bytes_limit"]; ok {
-		assert.EqualValues(t, []uint8{98, 97, 114, 124}, bytes_limit_val)
-	} else {
-		t.Fatalf("Field 'bytes_limit' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert BytesEos
-	if bytes_eos_val, ok := customMap["bytes_eos"]; ok {
-		assert.EqualValues(t, []uint8{98, 97, 122, 64}, bytes_eos_val)
-	} else {
-		t.Fatalf("Field 'bytes_eos' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert BytesCalc
-	if bytes_calc_val, ok := customMap["bytes_calc"]; ok {
-		assert.EqualValues(t, []uint8{82, 110, 68}, bytes_calc_val)
-	} else {
-		t.Fatalf("Field 'bytes_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "combine_bytes.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert TermOrLimit
-	if term_or_limit_val, ok := customMap["term_or_limit"]; ok {
-		assert.EqualValues(t, []uint8{102, 111, 111}, term_or_limit_val)
-	} else {
-		t.Fatalf("Field 'term_or_limit' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert TermOrEos
-	if term_or_eos_val, ok := customMap["term_or_eos"]; ok {
-		assert.EqualValues(t, []uint8{98, 97, 122, 64}, term_or_eos_val)
-	} else {
-		t.Fatalf("Field 'term_or_eos' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert TermOrCalc
-	if term_or_calc_val, ok := customMap["term_or_calc"]; ok {
-		assert.EqualValues(t, []uint8{102, 111, 111}, term_or_calc_val)
-	} else {
-		t.Fatalf("Field 'term_or_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert LimitOrEos
-	if limit_or_eos_val, ok := customMap["limit_or_eos"]; ok {
-		assert.EqualValues(t, []uint8{98, 97, 114, 124}, limit_or_eos_val)
-	} else {
-		t.Fatalf("Field 'limit_or_eos' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert LimitOrCalc
-	if limit_or_calc_val, ok := customMap["limit_or_calc"]; ok {
-		assert.EqualValues(t, []uint8{82, 110, 68}, limit_or_calc_val)
-	} else {
-		t.Fatalf("Field 'limit_or_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert EosOrCalc
-	if eos_or_calc_val, ok := customMap["eos_or_calc"]; ok {
-		assert.EqualValues(t, []uint8{98, 97, 122, 64}, eos_or_calc_val)
-	} else {
-		t.Fatalf("Field 'eos_or_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
+	checkCombineBytes(t, customMap)
 }
 
 func TestSerialize_CombineBytes(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "combine_bytes.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := combine_bytes_kaitai.NewCombineBytes()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapCombineBytes(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := combine_bytes_kaitai.NewCombineBytes()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(combine_bytes_kaitai.CombineBytes{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "combine_bytes.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkCombineBytes(t, roundTrip(t, "combine_bytes.ksy", customMap))
 }
 
-// structToMapCombineBytes converts KSC struct to map for serializer
-func structToMapCombineBytes(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveCombineBytes(t, data)
-}
-
-// structToMapReflectiveCombineBytes uses reflection to convert KSC structs including method calls
-func structToMapReflectiveCombineBytes(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveCombineBytes(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkCombineBytes applies the assertions of the KSC test to parsed data
+func checkCombineBytes(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, []uint8{102, 111, 111}, customMap, "bytes_term")
+	assertValue(t, []uint8{98, 97, 114, 124}, customMap, "bytes_limit")
+	assertValue(t, []uint8{98, 97, 122, 64}, customMap, "bytes_eos")
+	assertValue(t, []uint8{82, 110, 68}, customMap, "bytes_calc")
+	assertValue(t, []uint8{102, 111, 111}, customMap, "term_or_limit")
+	assertValue(t, []uint8{98, 97, 122, 64}, customMap, "term_or_eos")
+	assertValue(t, []uint8{102, 111, 111}, customMap, "term_or_calc")
+	assertValue(t, []uint8{98, 97, 114, 124}, customMap, "limit_or_eos")
+	assertValue(t, []uint8{82, 110, 68}, customMap, "limit_or_calc")
+	assertValue(t, []uint8{98, 97, 122, 64}, customMap, "eos_or_calc")
 }
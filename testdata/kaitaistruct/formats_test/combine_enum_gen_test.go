@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_CombineEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "combine_enum.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkCombineEnum(t, customMap)
+}
+
+func TestSerialize_CombineEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "combine_enum.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkCombineEnum(t, roundTrip(t, "combine_enum.ksy", customMap))
+}
+
+// checkCombineEnum applies the assertions of the KSC test to parsed data
+func checkCombineEnum(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("pig"), customMap, "enum_u4")
+	assertValue(t, kscEnum("horse"), customMap, "enum_u2")
+	assertValue(t, kscEnum("horse"), customMap, "enum_u4_u2")
+}
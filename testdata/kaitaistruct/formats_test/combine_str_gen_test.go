@@ -1,285 +1,40 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	combine_str_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/combine_str"
-)
+import "testing"
 
 func TestParse_CombineStr(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "combine_str.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert StrTerm
-	if str_term_val, ok := customMap["str_term"]; ok {
-		assert.EqualValues(t, "foo", str_term_val)
-	} else {
-		t.Fatalf("Field 'str_term' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrLimit
-	if str_limit_val, ok := customMap["str_limit"]; ok {
-		assert.EqualValues(t, "bar|", str_limit_val)
-	} else {
-		t.Fatalf("Field 'str_limit' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrEos
-	if str_eos_val, ok := customMap["str_eos"]; ok {
-		assert.EqualValues(t, "baz@", str_eos_val)
-	} else {
-		t.Fatalf("Field 'str_eos' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrCalc
-	if str_calc_val, ok := customMap["str_calc"]; ok {
-		assert.EqualValues(t, "bar", str_calc_val)
-	} else {
-		t.Fatalf("Field 'str_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrCalcBytes
-	if str_calc_bytes_val, ok := customMap["str_calc_bytes"]; ok {
-		assert.EqualValues(t, "baz", str_calc_bytes_val)
-	} else {
-		t.Fatalf("Field 'str_calc_bytes' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert TermOrLimit
-	if term_or_limit_val, ok := customMap["term_or_limit"]; ok {
-		assert.EqualValues(t, "foo", term_or_limit_val)
-	} else {
-		t.Fatalf("Field 'term_or_limit' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert TermOrEos
-	if term_or_eos_val, ok := customMap["term_or_eos"]; ok {
-		assert.EqualValues(t, "baz@", term_or_eos_val)
-	} else {
-		t.Fatalf("Field 'term_or_eos' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert TermOrCalc
-	if term_or_calc_val, ok := customMap["term_or_calc"]; ok {
-		assert.EqualValues(t, "foo", term_or_calc_val)
-	} else {
-		t.Fatalf("Field 'term_or_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert TermOrCalcBytes
-	if term_or_calc_bytes_val, ok := customMap["term_or_calc_bytes"]; ok {
-		assert.EqualValues(t, "baz", term_or_calc_bytes_val)
-	} else {
-		t.Fatalf("Field 'term_or_calc_bytes' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert LimitOrEos
-	if limit_or_eos_val, ok := customMap["limit_or_eos"]; ok {
-		assert.EqualValues(t, "bar|", limit_or_eos_val)
-	} else {
-		t.Fatalf("Field 'limit_or_eos' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert LimitOrCalc
-	if limit_or_calc_val, ok := customMap["limit_or_calc"]; ok {
-		assert.EqualValues(t, "bar", limit_or_calc_val)
-	} else {
-		t.Fatalf("Field 'limit_or_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert LimitOrCalcBytes
-	if limit_or_calc_bytes_val, ok := customMap["limit_or_calc_bytes"]; ok {
-		assert.EqualValues(t, "bar|", limit_or_calc_bytes_val)
-	} else {
-		t.Fatalf("Field 'limit_or_calc_bytes' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert EosOrCalc
-	if eos_or_calc_val, ok := customMap["eos_or_calc"]; ok {
-		assert.EqualValues(t, "bar", eos_or_calc_val)
-	} else {
-		t.Fatalf("Field 'eos_or_calc' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "combine_str.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert EosOrCalcBytes
-	if eos_or_calc_bytes_val, ok := customMap["eos_or_calc_bytes"]; ok {
-		assert.EqualValues(t, "baz@", eos_or_calc_bytes_val)
-	} else {
-		t.Fatalf("Field 'eos_or_calc_bytes' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert CalcOrCalcBytes
-	if calc_or_calc_bytes_val, ok := customMap["calc_or_calc_bytes"]; ok {
-		assert.EqualValues(t, "baz", calc_or_calc_bytes_val)
-	} else {
-		t.Fatalf("Field 'calc_or_calc_bytes' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
+	checkCombineStr(t, customMap)
 }
 
 func TestSerialize_CombineStr(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "combine_str.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := combine_str_kaitai.NewCombineStr()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapCombineStr(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := combine_str_kaitai.NewCombineStr()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(combine_str_kaitai.CombineStr{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "combine_str.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkCombineStr(t, roundTrip(t, "combine_str.ksy", customMap))
 }
 
-// structToMapCombineStr converts KSC struct to map for serializer
-func structToMapCombineStr(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveCombineStr(t, data)
-}
-
-// structToMapReflectiveCombineStr uses reflection to convert KSC structs including method calls
-func structToMapReflectiveCombineStr(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveCombineStr(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkCombineStr applies the assertions of the KSC test to parsed data
+func checkCombineStr(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo", customMap, "str_term")
+	assertValue(t, "bar|", customMap, "str_limit")
+	assertValue(t, "baz@", customMap, "str_eos")
+	assertValue(t, "bar", customMap, "str_calc")
+	assertValue(t, "baz", customMap, "str_calc_bytes")
+	assertValue(t, "foo", customMap, "term_or_limit")
+	assertValue(t, "baz@", customMap, "term_or_eos")
+	assertValue(t, "foo", customMap, "term_or_calc")
+	assertValue(t, "baz", customMap, "term_or_calc_bytes")
+	assertValue(t, "bar|", customMap, "limit_or_eos")
+	assertValue(t, "bar", customMap, "limit_or_calc")
+	assertValue(t, "bar|", customMap, "limit_or_calc_bytes")
+	assertValue(t, "bar", customMap, "eos_or_calc")
+	assertValue(t, "baz@", customMap, "eos_or_calc_bytes")
+	assertValue(t, "baz", customMap, "calc_or_calc_bytes")
 }
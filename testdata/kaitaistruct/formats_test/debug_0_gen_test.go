@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_Debug0(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_0.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDebug0(t, customMap)
+}
+
+func TestSerialize_Debug0(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_0.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDebug0(t, roundTrip(t, "debug_0.ksy", customMap))
+}
+
+// checkDebug0 applies the assertions of the KSC test to parsed data
+func checkDebug0(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "one")
+	assertLen(t, 3, customMap, "array_of_ints")
+	assertValue(t, 65, customMap, "array_of_ints", 0)
+	assertValue(t, 67, customMap, "array_of_ints", 1)
+	assertValue(t, 75, customMap, "array_of_ints", 2)
+}
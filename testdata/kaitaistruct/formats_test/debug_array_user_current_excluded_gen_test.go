@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DebugArrayUserCurrentExcluded(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_array_user_current_excluded.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDebugArrayUserCurrentExcluded(t, customMap)
+}
+
+func TestSerialize_DebugArrayUserCurrentExcluded(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_array_user_current_excluded.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDebugArrayUserCurrentExcluded(t, roundTrip(t, "debug_array_user_current_excluded.ksy", customMap))
+}
+
+// checkDebugArrayUserCurrentExcluded applies the assertions of the KSC test to parsed data
+func checkDebugArrayUserCurrentExcluded(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, []uint8{102, 111, 111}, customMap, "array_of_cats", 0, "meow")
+	assertValue(t, []uint8{124, 98}, customMap, "array_of_cats", 1, "meow")
+	assertValue(t, []uint8{97}, customMap, "array_of_cats", 2, "meow")
+}
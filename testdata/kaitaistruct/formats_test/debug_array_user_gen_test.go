@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DebugArrayUser(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_array_user.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDebugArrayUser(t, customMap)
+}
+
+func TestSerialize_DebugArrayUser(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_array_user.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDebugArrayUser(t, roundTrip(t, "debug_array_user.ksy", customMap))
+}
+
+// checkDebugArrayUser applies the assertions of the KSC test to parsed data
+func checkDebugArrayUser(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "one_cat", "meow")
+	assertLen(t, 3, customMap, "array_of_cats")
+	assertValue(t, 65, customMap, "array_of_cats", 0, "meow")
+	assertValue(t, 67, customMap, "array_of_cats", 1, "meow")
+	assertValue(t, 75, customMap, "array_of_cats", 2, "meow")
+}
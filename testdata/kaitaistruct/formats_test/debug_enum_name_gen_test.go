@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DebugEnumName(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_enum_name.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDebugEnumName(t, customMap)
+}
+
+func TestSerialize_DebugEnumName(t *testing.T) {
+	customMap, err := parseFormat(t, "debug_enum_name.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDebugEnumName(t, roundTrip(t, "debug_enum_name.ksy", customMap))
+}
+
+// checkDebugEnumName applies the assertions of the KSC test to parsed data
+func checkDebugEnumName(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("enum_value_80"), customMap, "one")
+	assertValue(t, kscEnum("enum_value_65"), customMap, "array_of_ints", 0)
+	assertValue(t, kscEnum("enum_value_67"), customMap, "test_type", "field1")
+	assertValue(t, kscEnum("enum_value_11"), customMap, "test_type", "instance_field")
+}
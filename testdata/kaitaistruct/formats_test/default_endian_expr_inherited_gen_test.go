@@ -0,0 +1,40 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DefaultEndianExprInherited(t *testing.T) {
+	customMap, err := parseFormat(t, "default_endian_expr_inherited.ksy", "endian_expr.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDefaultEndianExprInherited(t, customMap)
+}
+
+func TestSerialize_DefaultEndianExprInherited(t *testing.T) {
+	customMap, err := parseFormat(t, "default_endian_expr_inherited.ksy", "endian_expr.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDefaultEndianExprInherited(t, roundTrip(t, "default_endian_expr_inherited.ksy", customMap))
+}
+
+// checkDefaultEndianExprInherited applies the assertions of the KSC test to parsed data
+func checkDefaultEndianExprInherited(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, []uint8{73, 73}, customMap, "docs", 0, "indicator")
+	assertValue(t, 66, customMap, "docs", 0, "main", "insides", "some_int")
+	assertValue(t, 16896, customMap, "docs", 0, "main", "insides", "more", "some_int1")
+	assertValue(t, 66, customMap, "docs", 0, "main", "insides", "more", "some_int2")
+	assertValue(t, 66, customMap, "docs", 0, "main", "insides", "more", "some_inst")
+	assertValue(t, []uint8{77, 77}, customMap, "docs", 1, "indicator")
+	assertValue(t, 66, customMap, "docs", 1, "main", "insides", "some_int")
+	assertValue(t, 66, customMap, "docs", 1, "main", "insides", "more", "some_int1")
+	assertValue(t, 16896, customMap, "docs", 1, "main", "insides", "more", "some_int2")
+	assertValue(t, 1107296256, customMap, "docs", 1, "main", "insides", "more", "some_inst")
+	assertValue(t, []uint8{88, 88}, customMap, "docs", 2, "indicator")
+	assertValue(t, 66, customMap, "docs", 2, "main", "insides", "some_int")
+	assertValue(t, 66, customMap, "docs", 2, "main", "insides", "more", "some_int1")
+	assertValue(t, 16896, customMap, "docs", 2, "main", "insides", "more", "some_int2")
+	assertValue(t, 1107296256, customMap, "docs", 2, "main", "insides", "more", "some_inst")
+}
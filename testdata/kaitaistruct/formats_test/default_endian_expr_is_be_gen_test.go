@@ -0,0 +1,43 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DefaultEndianExprIsBe(t *testing.T) {
+	customMap, err := parseFormat(t, "default_endian_expr_is_be.ksy", "endian_expr.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDefaultEndianExprIsBe(t, customMap)
+}
+
+func TestSerialize_DefaultEndianExprIsBe(t *testing.T) {
+	customMap, err := parseFormat(t, "default_endian_expr_is_be.ksy", "endian_expr.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDefaultEndianExprIsBe(t, roundTrip(t, "default_endian_expr_is_be.ksy", customMap))
+}
+
+// checkDefaultEndianExprIsBe applies the assertions of the KSC test to parsed data
+func checkDefaultEndianExprIsBe(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, []uint8{73, 73}, customMap, "docs", 0, "indicator")
+	assertValue(t, 66, customMap, "docs", 0, "main", "some_int")
+	assertValue(t, 66, customMap, "docs", 0, "main", "some_int_be")
+	assertValue(t, 66, customMap, "docs", 0, "main", "some_int_le")
+	assertValue(t, 66, customMap, "docs", 0, "main", "inst_int")
+	assertValue(t, 66, customMap, "docs", 0, "main", "inst_sub", "foo")
+	assertValue(t, []uint8{77, 77}, customMap, "docs", 1, "indicator")
+	assertValue(t, 66, customMap, "docs", 1, "main", "some_int")
+	assertValue(t, 66, customMap, "docs", 1, "main", "some_int_be")
+	assertValue(t, 66, customMap, "docs", 1, "main", "some_int_le")
+	assertValue(t, 1107296256, customMap, "docs", 1, "main", "inst_int")
+	assertValue(t, 1107296256, customMap, "docs", 1, "main", "inst_sub", "foo")
+	assertValue(t, []uint8{88, 88}, customMap, "docs", 2, "indicator")
+	assertValue(t, 1107296256, customMap, "docs", 2, "main", "some_int")
+	assertValue(t, 66, customMap, "docs", 2, "main", "some_int_be")
+	assertValue(t, 66, customMap, "docs", 2, "main", "some_int_le")
+	assertValue(t, 66, customMap, "docs", 2, "main", "inst_int")
+	assertValue(t, 66, customMap, "docs", 2, "main", "inst_sub", "foo")
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DocstringsDocref(t *testing.T) {
+	customMap, err := parseFormat(t, "docstrings_docref.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDocstringsDocref(t, customMap)
+}
+
+func TestSerialize_DocstringsDocref(t *testing.T) {
+	customMap, err := parseFormat(t, "docstrings_docref.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDocstringsDocref(t, roundTrip(t, "docstrings_docref.ksy", customMap))
+}
+
+// checkDocstringsDocref applies the assertions of the KSC test to parsed data
+func checkDocstringsDocref(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_DocstringsDocrefMulti(t *testing.T) {
+	customMap, err := parseFormat(t, "docstrings_docref_multi.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDocstringsDocrefMulti(t, customMap)
+}
+
+func TestSerialize_DocstringsDocrefMulti(t *testing.T) {
+	customMap, err := parseFormat(t, "docstrings_docref_multi.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDocstringsDocrefMulti(t, roundTrip(t, "docstrings_docref_multi.ksy", customMap))
+}
+
+// checkDocstringsDocrefMulti applies the assertions of the KSC test to parsed data
+func checkDocstringsDocrefMulti(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_Docstrings(t *testing.T) {
+	customMap, err := parseFormat(t, "docstrings.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkDocstrings(t, customMap)
+}
+
+func TestSerialize_Docstrings(t *testing.T) {
+	customMap, err := parseFormat(t, "docstrings.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkDocstrings(t, roundTrip(t, "docstrings.ksy", customMap))
+}
+
+// checkDocstrings applies the assertions of the KSC test to parsed data
+func checkDocstrings(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_Enum0(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_0.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnum0(t, customMap)
+}
+
+func TestSerialize_Enum0(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_0.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnum0(t, roundTrip(t, "enum_0.ksy", customMap))
+}
+
+// checkEnum0 applies the assertions of the KSC test to parsed data
+func checkEnum0(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "pet_1")
+	assertValue(t, kscEnum("chicken"), customMap, "pet_2")
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_Enum1(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_1.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnum1(t, customMap)
+}
+
+func TestSerialize_Enum1(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_1.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnum1(t, roundTrip(t, "enum_1.ksy", customMap))
+}
+
+// checkEnum1 applies the assertions of the KSC test to parsed data
+func checkEnum1(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "main", "submain", "pet_1")
+	assertValue(t, kscEnum("chicken"), customMap, "main", "submain", "pet_2")
+}
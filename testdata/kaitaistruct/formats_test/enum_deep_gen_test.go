@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumDeep(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_deep.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumDeep(t, customMap)
+}
+
+func TestSerialize_EnumDeep(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_deep.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumDeep(t, roundTrip(t, "enum_deep.ksy", customMap))
+}
+
+// checkEnumDeep applies the assertions of the KSC test to parsed data
+func checkEnumDeep(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "pet_1")
+	assertValue(t, kscEnum("hare"), customMap, "pet_2")
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumDeepLiterals(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_deep_literals.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumDeepLiterals(t, customMap)
+}
+
+func TestSerialize_EnumDeepLiterals(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_deep_literals.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumDeepLiterals(t, roundTrip(t, "enum_deep_literals.ksy", customMap))
+}
+
+// checkEnumDeepLiterals applies the assertions of the KSC test to parsed data
+func checkEnumDeepLiterals(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, true, customMap, "is_pet_1_ok")
+	assertValue(t, true, customMap, "is_pet_2_ok")
+}
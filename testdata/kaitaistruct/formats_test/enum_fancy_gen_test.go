@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumFancy(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_fancy.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumFancy(t, customMap)
+}
+
+func TestSerialize_EnumFancy(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_fancy.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumFancy(t, roundTrip(t, "enum_fancy.ksy", customMap))
+}
+
+// checkEnumFancy applies the assertions of the KSC test to parsed data
+func checkEnumFancy(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "pet1")
+	assertValue(t, kscEnum("chicken"), customMap, "pet2")
+}
@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumIf(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_if.ksy", "if_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumIf(t, customMap)
+}
+
+func TestSerialize_EnumIf(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_if.ksy", "if_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumIf(t, roundTrip(t, "enum_if.ksy", customMap))
+}
+
+// checkEnumIf applies the assertions of the KSC test to parsed data
+func checkEnumIf(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("a_string"), customMap, "op1", "opcode")
+	assertValue(t, "foo", customMap, "op1", "arg_str", "str")
+	assertValue(t, kscEnum("a_tuple"), customMap, "op2", "opcode")
+	assertValue(t, 66, customMap, "op2", "arg_tuple", "num1")
+	assertValue(t, 67, customMap, "op2", "arg_tuple", "num2")
+	assertValue(t, kscEnum("a_string"), customMap, "op3", "opcode")
+	assertValue(t, "bar", customMap, "op3", "arg_str", "str")
+}
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumImportLiterals(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_import_literals.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumImportLiterals(t, customMap)
+}
+
+func TestSerialize_EnumImportLiterals(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_import_literals.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumImportLiterals(t, roundTrip(t, "enum_import_literals.ksy", customMap))
+}
+
+// checkEnumImportLiterals applies the assertions of the KSC test to parsed data
+func checkEnumImportLiterals(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 7, customMap, "pet_1_to_i")
+	assertValue(t, true, customMap, "pet_1_eq")
+	assertValue(t, kscEnum("hare"), customMap, "pet_2")
+}
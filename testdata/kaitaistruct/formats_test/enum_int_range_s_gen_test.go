@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumIntRangeS(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_int_range_s.ksy", "enum_int_range_s.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumIntRangeS(t, customMap)
+}
+
+func TestSerialize_EnumIntRangeS(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_int_range_s.ksy", "enum_int_range_s.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumIntRangeS(t, roundTrip(t, "enum_int_range_s.ksy", customMap))
+}
+
+// checkEnumIntRangeS applies the assertions of the KSC test to parsed data
+func checkEnumIntRangeS(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("int_min"), customMap, "f1")
+	assertValue(t, kscEnum("zero"), customMap, "f2")
+	assertValue(t, kscEnum("int_max"), customMap, "f3")
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumIntRangeU(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_int_range_u.ksy", "enum_int_range_u.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumIntRangeU(t, customMap)
+}
+
+func TestSerialize_EnumIntRangeU(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_int_range_u.ksy", "enum_int_range_u.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumIntRangeU(t, roundTrip(t, "enum_int_range_u.ksy", customMap))
+}
+
+// checkEnumIntRangeU applies the assertions of the KSC test to parsed data
+func checkEnumIntRangeU(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("zero"), customMap, "f1")
+	assertValue(t, kscEnum("int_max"), customMap, "f2")
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumInvalid(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_invalid.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumInvalid(t, customMap)
+}
+
+func TestSerialize_EnumInvalid(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_invalid.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumInvalid(t, roundTrip(t, "enum_invalid.ksy", customMap))
+}
+
+// checkEnumInvalid applies the assertions of the KSC test to parsed data
+func checkEnumInvalid(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("dog"), customMap, "pet_1")
+	assertValue(t, 111, customMap, "pet_2")
+}
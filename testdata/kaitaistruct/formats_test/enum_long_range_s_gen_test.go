@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumLongRangeS(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_long_range_s.ksy", "enum_long_range_s.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumLongRangeS(t, customMap)
+}
+
+func TestSerialize_EnumLongRangeS(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_long_range_s.ksy", "enum_long_range_s.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumLongRangeS(t, roundTrip(t, "enum_long_range_s.ksy", customMap))
+}
+
+// checkEnumLongRangeS applies the assertions of the KSC test to parsed data
+func checkEnumLongRangeS(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("long_min"), customMap, "f1")
+	assertValue(t, kscEnum("int_below_min"), customMap, "f2")
+	assertValue(t, kscEnum("int_min"), customMap, "f3")
+	assertValue(t, kscEnum("zero"), customMap, "f4")
+	assertValue(t, kscEnum("int_max"), customMap, "f5")
+	assertValue(t, kscEnum("int_over_max"), customMap, "f6")
+	assertValue(t, kscEnum("long_max"), customMap, "f7")
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumLongRangeU(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_long_range_u.ksy", "enum_long_range_u.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumLongRangeU(t, customMap)
+}
+
+func TestSerialize_EnumLongRangeU(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_long_range_u.ksy", "enum_long_range_u.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumLongRangeU(t, roundTrip(t, "enum_long_range_u.ksy", customMap))
+}
+
+// checkEnumLongRangeU applies the assertions of the KSC test to parsed data
+func checkEnumLongRangeU(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("zero"), customMap, "f1")
+	assertValue(t, kscEnum("int_max"), customMap, "f2")
+	assertValue(t, kscEnum("int_over_max"), customMap, "f3")
+	assertValue(t, kscEnum("long_max"), customMap, "f4")
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumNegative(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_negative.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumNegative(t, customMap)
+}
+
+func TestSerialize_EnumNegative(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_negative.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumNegative(t, roundTrip(t, "enum_negative.ksy", customMap))
+}
+
+// checkEnumNegative applies the assertions of the KSC test to parsed data
+func checkEnumNegative(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("negative_one"), customMap, "f1")
+	assertValue(t, kscEnum("positive_one"), customMap, "f2")
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumOfValueInst(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_of_value_inst.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumOfValueInst(t, customMap)
+}
+
+func TestSerialize_EnumOfValueInst(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_of_value_inst.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumOfValueInst(t, roundTrip(t, "enum_of_value_inst.ksy", customMap))
+}
+
+// checkEnumOfValueInst applies the assertions of the KSC test to parsed data
+func checkEnumOfValueInst(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "pet_1")
+	assertValue(t, kscEnum("chicken"), customMap, "pet_2")
+	assertValue(t, kscEnum("dog"), customMap, "pet_3")
+	assertValue(t, kscEnum("dog"), customMap, "pet_4")
+}
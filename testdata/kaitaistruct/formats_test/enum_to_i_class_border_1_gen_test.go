@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumToIClassBorder1(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_to_i_class_border_1.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumToIClassBorder1(t, customMap)
+}
+
+func TestSerialize_EnumToIClassBorder1(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_to_i_class_border_1.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumToIClassBorder1(t, roundTrip(t, "enum_to_i_class_border_1.ksy", customMap))
+}
+
+// checkEnumToIClassBorder1 applies the assertions of the KSC test to parsed data
+func checkEnumToIClassBorder1(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "pet_1")
+	assertValue(t, kscEnum("chicken"), customMap, "pet_2")
+	assertValue(t, true, customMap, "checker", "is_dog")
+}
@@ -0,0 +1,33 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumToI(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_to_i.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumToI(t, customMap)
+}
+
+func TestSerialize_EnumToI(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_to_i.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumToI(t, roundTrip(t, "enum_to_i.ksy", customMap))
+}
+
+// checkEnumToI applies the assertions of the KSC test to parsed data
+func checkEnumToI(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "pet_1")
+	assertValue(t, kscEnum("chicken"), customMap, "pet_2")
+	assertValue(t, 7, customMap, "pet_1_i")
+	assertValue(t, "7", customMap, "pet_1_i_to_s")
+	assertValue(t, 32775, customMap, "pet_1_mod")
+	assertValue(t, true, customMap, "one_lt_two")
+	assertValue(t, true, customMap, "pet_1_eq_int")
+	assertValue(t, false, customMap, "pet_2_eq_int")
+}
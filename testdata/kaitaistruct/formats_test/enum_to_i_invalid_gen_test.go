@@ -0,0 +1,33 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_EnumToIInvalid(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_to_i_invalid.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkEnumToIInvalid(t, customMap)
+}
+
+func TestSerialize_EnumToIInvalid(t *testing.T) {
+	customMap, err := parseFormat(t, "enum_to_i_invalid.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkEnumToIInvalid(t, roundTrip(t, "enum_to_i_invalid.ksy", customMap))
+}
+
+// checkEnumToIInvalid applies the assertions of the KSC test to parsed data
+func checkEnumToIInvalid(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("dog"), customMap, "pet_1")
+	assertValue(t, 111, customMap, "pet_2")
+	assertValue(t, 111, customMap, "pet_2_i")
+	assertValue(t, "111", customMap, "pet_2_i_to_s")
+	assertValue(t, 32879, customMap, "pet_2_mod")
+	assertValue(t, true, customMap, "one_lt_two")
+	assertValue(t, true, customMap, "pet_2_eq_int_t")
+	assertValue(t, false, customMap, "pet_2_eq_int_f")
+}
@@ -0,0 +1,35 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprBits(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_bits.ksy", "switch_opcodes.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprBits(t, customMap)
+}
+
+func TestSerialize_ExprBits(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_bits.ksy", "switch_opcodes.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprBits(t, roundTrip(t, "expr_bits.ksy", customMap))
+}
+
+// checkExprBits applies the assertions of the KSC test to parsed data
+func checkExprBits(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 2, customMap, "a")
+	assertValue(t, kscEnum("foo"), customMap, "enum_seq")
+	assertValue(t, []uint8{102, 111}, customMap, "byte_size")
+	assertLen(t, 2, customMap, "repeat_expr")
+	assertValue(t, 111, customMap, "repeat_expr", 0)
+	assertValue(t, 98, customMap, "repeat_expr", 1)
+	assertValue(t, 97, customMap, "switch_on_type")
+	assertValue(t, 29184, customMap, "switch_on_endian", "foo")
+	assertValue(t, kscEnum("bar"), customMap, "enum_inst")
+	assertValue(t, 111, customMap, "inst_pos")
+}
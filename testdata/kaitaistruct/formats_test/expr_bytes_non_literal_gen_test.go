@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprBytesNonLiteral(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_bytes_non_literal.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprBytesNonLiteral(t, customMap)
+}
+
+func TestSerialize_ExprBytesNonLiteral(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_bytes_non_literal.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprBytesNonLiteral(t, roundTrip(t, "expr_bytes_non_literal.ksy", customMap))
+}
+
+// checkExprBytesNonLiteral applies the assertions of the KSC test to parsed data
+func checkExprBytesNonLiteral(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 2, customMap, "calc_bytes")
+	assertValue(t, 255, customMap, "calc_bytes", 0)
+	assertValue(t, 1, customMap, "calc_bytes", 1)
+}
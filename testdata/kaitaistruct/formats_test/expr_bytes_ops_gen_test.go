@@ -0,0 +1,41 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprBytesOps(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_bytes_ops.ksy", "nav_parent_switch.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprBytesOps(t, customMap)
+}
+
+func TestSerialize_ExprBytesOps(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_bytes_ops.ksy", "nav_parent_switch.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprBytesOps(t, roundTrip(t, "expr_bytes_ops.ksy", customMap))
+}
+
+// checkExprBytesOps applies the assertions of the KSC test to parsed data
+func checkExprBytesOps(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 3, customMap, "one_size")
+	assertValue(t, 1, customMap, "one_first")
+	assertValue(t, 66, customMap, "one_mid")
+	assertValue(t, 255, customMap, "one_last")
+	assertFormatInt(t, "255", customMap, "one_last")
+	assertValue(t, 1, customMap, "one_min")
+	assertValue(t, 255, customMap, "one_max")
+	assertFormatInt(t, "255", customMap, "one_max")
+	assertValue(t, 3, customMap, "two_size")
+	assertValue(t, 65, customMap, "two_first")
+	assertValue(t, 255, customMap, "two_mid")
+	assertFormatInt(t, "255", customMap, "two_mid")
+	assertValue(t, 75, customMap, "two_last")
+	assertValue(t, 65, customMap, "two_min")
+	assertValue(t, 255, customMap, "two_max")
+	assertFormatInt(t, "255", customMap, "two_max")
+}
@@ -1,271 +1,43 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	expr_calc_array_ops_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/expr_calc_array_ops"
-)
+import "testing"
 
 func TestParse_ExprCalcArrayOps(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_calc_array_ops.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert IntArraySize
-	if int_array_size_val, ok := customMap["int_array_size"]; ok {
-		assert.EqualValues(t, 7, int_array_size_val)
-	} else {
-		t.Fatalf("Field 'int_array_size' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert IntArrayFirst
-	if int_array_first_val, ok := customMap["int_array_first"]; ok {
-		assert.EqualValues(t, 10, int_array_first_val)
-	} else {
-		t.Fatalf("Field 'int_array_first' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert IntArrayMid
-	if int_array_mid_val, ok := customMap["int_array_mid"]; ok {
-		assert.EqualValues(t, 25, int_array_mid_val)
-	} else {
-		t.Fatalf("Field 'int_array_mid' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert IntArrayLast
-	if int_array_last_val, ok := customMap["int_array_last"]; ok {
-		assert.EqualValues(t, 1000, int_array_last_val)
-	} else {
-		t.Fatalf("Field 'int_array_last' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert IntArrayMin
-	if int_array_min_val, ok := customMap["int_array_min"]; ok {
-		assert.EqualValues(t, 10, int_array_min_val)
-	} else {
-		t.Fatalf("Field 'int_array_min' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert IntArrayMax
-	if int_array_max_val, ok := customMap["int_array_max"]; ok {
-		assert.EqualValues(t, 1000, int_array_max_val)
-	} else {
-		t.Fatalf("Field 'int_array_max' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert DoubleArraySize
-	if double_array_size_val, ok := customMap["double_array_size"]; ok {
-		assert.EqualValues(t, 5, double_array_size_val)
-	} else {
-		t.Fatalf("Field 'double_array_size' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrArraySize
-	if str_array_size_val, ok := customMap["str_array_size"]; ok {
-		assert.EqualValues(t, 4, str_array_size_val)
-	} else {
-		t.Fatalf("Field 'str_array_size' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrArrayFirst
-	if str_array_first_val, ok := customMap["str_array_first"]; ok {
-		assert.EqualValues(t, "un", str_array_first_val)
-	} else {
-		t.Fatalf("Field 'str_array_first' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrArrayMid
-	if str_array_mid_val, ok := customMap["str_array_mid"]; ok {
-		assert.EqualValues(t, "deux", str_array_mid_val)
-	} else {
-		t.Fatalf("Field 'str_array_mid' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrArrayLast
-	if str_array_last_val, ok := customMap["str_array_last"]; ok {
-		assert.EqualValues(t, "quatre", str_array_last_val)
-	} else {
-		t.Fatalf("Field 'str_array_last' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrArrayMin
-	if str_array_min_val, ok := customMap["str_array_min"]; ok {
-		assert.EqualValues(t, "deux", str_array_min_val)
-	} else {
-		t.Fatalf("Field 'str_array_min' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "expr_calc_array_ops.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert StrArrayMax
-	if str_array_max_val, ok := customMap["str_array_max"]; ok {
-		assert.EqualValues(t, "un", str_array_max_val)
-	} else {
-		t.Fatalf("Field 'str_array_max' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
+	checkExprCalcArrayOps(t, customMap)
 }
 
 func TestSerialize_ExprCalcArrayOps(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_calc_array_ops.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := expr_calc_array_ops_kaitai.NewExprCalcArrayOps()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapExprCalcArrayOps(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := expr_calc_array_ops_kaitai.NewExprCalcArrayOps()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(expr_calc_array_ops_kaitai.ExprCalcArrayOps{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "expr_calc_array_ops.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapExprCalcArrayOps converts KSC struct to map for serializer
-func structToMapExprCalcArrayOps(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveExprCalcArrayOps(t, data)
+	checkExprCalcArrayOps(t, roundTrip(t, "expr_calc_array_ops.ksy", customMap))
 }
 
-// structToMapReflectiveExprCalcArrayOps uses reflection to convert KSC structs including method calls
-func structToMapReflectiveExprCalcArrayOps(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveExprCalcArrayOps(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkExprCalcArrayOps applies the assertions of the KSC test to parsed data
+func checkExprCalcArrayOps(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 7, customMap, "int_array_size")
+	assertValue(t, 10, customMap, "int_array_first")
+	assertValue(t, 25, customMap, "int_array_mid")
+	assertValue(t, 1000, customMap, "int_array_last")
+	assertValue(t, 10, customMap, "int_array_min")
+	assertValue(t, 1000, customMap, "int_array_max")
+	assertValue(t, 5, customMap, "double_array_size")
+	assertInDelta(t, 10.0, 1e-6, customMap, "double_array_first")
+	assertInDelta(t, 25.0, 1e-6, customMap, "double_array_mid")
+	assertInDelta(t, 3.14159, 1e-6, customMap, "double_array_last")
+	assertInDelta(t, 3.14159, 1e-6, customMap, "double_array_min")
+	assertInDelta(t, 100.0, 1e-6, customMap, "double_array_max")
+	assertValue(t, 4, customMap, "str_array_size")
+	assertValue(t, "un", customMap, "str_array_first")
+	assertValue(t, "deux", customMap, "str_array_mid")
+	assertValue(t, "quatre", customMap, "str_array_last")
+	assertValue(t, "deux", customMap, "str_array_min")
+	assertValue(t, "un", customMap, "str_array_max")
 }
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_enum.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprEnum(t, customMap)
+}
+
+func TestSerialize_ExprEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_enum.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprEnum(t, roundTrip(t, "expr_enum.ksy", customMap))
+}
+
+// checkExprEnum applies the assertions of the KSC test to parsed data
+func checkExprEnum(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("dog"), customMap, "const_dog")
+	assertValue(t, kscEnum("boom"), customMap, "derived_boom")
+	assertValue(t, kscEnum("dog"), customMap, "derived_dog")
+}
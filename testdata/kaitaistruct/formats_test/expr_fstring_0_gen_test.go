@@ -0,0 +1,34 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprFstring0(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_fstring_0.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprFstring0(t, customMap)
+}
+
+func TestSerialize_ExprFstring0(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_fstring_0.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprFstring0(t, roundTrip(t, "expr_fstring_0.ksy", customMap))
+}
+
+// checkExprFstring0 applies the assertions of the KSC test to parsed data
+func checkExprFstring0(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo|b", customMap, "seq_str")
+	assertValue(t, 97, customMap, "seq_int")
+	assertValue(t, "", customMap, "empty")
+	assertValue(t, "abc", customMap, "literal")
+	assertValue(t, "abc\n\tt", customMap, "literal_with_escapes")
+	assertValue(t, "abc=123", customMap, "head_and_int_literal")
+	assertValue(t, "abc=foo", customMap, "head_and_str_literal")
+	assertValue(t, "abc=97", customMap, "head_and_int")
+	assertValue(t, "abc=foo|b", customMap, "head_and_str")
+}
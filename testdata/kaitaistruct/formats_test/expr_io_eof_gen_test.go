@@ -1,213 +1,29 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	expr_io_eof_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/expr_io_eof"
-)
+import "testing"
 
 func TestParse_ExprIoEof(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_io_eof.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert Substream1.One
-	if substream1_one_lvl0_map, ok := customMap["substream1"].(map[string]any); ok {
-		if substream1_one_val, ok := substream1_one_lvl0_map["one"]; ok {
-			assert.EqualValues(t, 1262698832, substream1_one_val)
-		} else {
-			t.Fatalf("Field 'one' not found in substream1_one_lvl0_map (keys: %v)", maps.Keys(substream1_one_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream1' not found or not a map while asserting Substream1.One")
+	customMap, err := parseFormat(t, "expr_io_eof.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Substream2.One
-	if substream2_one_lvl0_map, ok := customMap["substream2"].(map[string]any); ok {
-		if substream2_one_val, ok := substream2_one_lvl0_map["one"]; ok {
-			assert.EqualValues(t, uint32(4294914349), substream2_one_val)
-		} else {
-			t.Fatalf("Field 'one' not found in substream2_one_lvl0_map (keys: %v)", maps.Keys(substream2_one_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream2' not found or not a map while asserting Substream2.One")
-	}
-
-	// Assert Substream2.Two
-	if substream2_two_lvl0_map, ok := customMap["substream2"].(map[string]any); ok {
-		if substream2_two_val, ok := substream2_two_lvl0_map["two"]; ok {
-			assert.EqualValues(t, 1262698832, substream2_two_val)
-		} else {
-			t.Fatalf("Field 'two' not found in substream2_two_lvl0_map (keys: %v)", maps.Keys(substream2_two_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream2' not found or not a map while asserting Substream2.Two")
-	}
-
+	checkExprIoEof(t, customMap)
 }
 
 func TestSerialize_ExprIoEof(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_io_eof.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := expr_io_eof_kaitai.NewExprIoEof()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapExprIoEof(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := expr_io_eof_kaitai.NewExprIoEof()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(expr_io_eof_kaitai.ExprIoEof{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "expr_io_eof.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkExprIoEof(t, roundTrip(t, "expr_io_eof.ksy", customMap))
 }
 
-// structToMapExprIoEof converts KSC struct to map for serializer
-func structToMapExprIoEof(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveExprIoEof(t, data)
-}
-
-// structToMapReflectiveExprIoEof uses reflection to convert KSC structs including method calls
-func structToMapReflectiveExprIoEof(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveExprIoEof(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkExprIoEof applies the assertions of the KSC test to parsed data
+func checkExprIoEof(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 1262698832, customMap, "substream1", "one")
+	assertNil(t, customMap, "substream1", "two")
+	assertValue(t, uint32(4294914349), customMap, "substream2", "one")
+	assertValue(t, 1262698832, customMap, "substream2", "two")
 }
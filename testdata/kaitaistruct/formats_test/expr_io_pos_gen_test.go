@@ -1,246 +1,31 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	expr_io_pos_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/expr_io_pos"
-)
+import "testing"
 
 func TestParse_ExprIoPos(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_io_pos.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "expr_io_pos.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert Substream1.MyStr
-	if substream1_my_str_lvl0_map, ok := customMap["substream1"].(map[string]any); ok {
-		if substream1_my_str_val, ok := substream1_my_str_lvl0_map["my_str"]; ok {
-			assert.EqualValues(t, "CURIOSITY", substream1_my_str_val)
-		} else {
-			t.Fatalf("Field 'my_str' not found in substream1_my_str_lvl0_map (keys: %v)", maps.Keys(substream1_my_str_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream1' not found or not a map while asserting Substream1.MyStr")
-	}
-
-	// Assert Substream1.Body
-	if substream1_body_lvl0_map, ok := customMap["substream1"].(map[string]any); ok {
-		if substream1_body_val, ok := substream1_body_lvl0_map["body"]; ok {
-			assert.EqualValues(t, []uint8{17, 34, 51, 68}, substream1_body_val)
-		} else {
-			t.Fatalf("Field 'body' not found in substream1_body_lvl0_map (keys: %v)", maps.Keys(substream1_body_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream1' not found or not a map while asserting Substream1.Body")
-	}
-
-	// Assert Substream1.Number
-	if substream1_number_lvl0_map, ok := customMap["substream1"].(map[string]any); ok {
-		if substream1_number_val, ok := substream1_number_lvl0_map["number"]; ok {
-			assert.EqualValues(t, 66, substream1_number_val)
-		} else {
-			t.Fatalf("Field 'number' not found in substream1_number_lvl0_map (keys: %v)", maps.Keys(substream1_number_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream1' not found or not a map while asserting Substream1.Number")
+	customMap, err := parseFormat(t, "expr_io_pos.ksy", "expr_io_pos.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Substream2.MyStr
-	if substream2_my_str_lvl0_map, ok := customMap["substream2"].(map[string]any); ok {
-		if substream2_my_str_val, ok := substream2_my_str_lvl0_map["my_str"]; ok {
-			assert.EqualValues(t, "KILLED", substream2_my_str_val)
-		} else {
-			t.Fatalf("Field 'my_str' not found in substream2_my_str_lvl0_map (keys: %v)", maps.Keys(substream2_my_str_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream2' not found or not a map while asserting Substream2.MyStr")
-	}
-
-	// Assert Substream2.Body
-	if substream2_body_lvl0_map, ok := customMap["substream2"].(map[string]any); ok {
-		if substream2_body_val, ok := substream2_body_lvl0_map["body"]; ok {
-			assert.EqualValues(t, []uint8{97, 32, 99, 97, 116}, substream2_body_val)
-		} else {
-			t.Fatalf("Field 'body' not found in substream2_body_lvl0_map (keys: %v)", maps.Keys(substream2_body_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream2' not found or not a map while asserting Substream2.Body")
-	}
-
-	// Assert Substream2.Number
-	if substream2_number_lvl0_map, ok := customMap["substream2"].(map[string]any); ok {
-		if substream2_number_val, ok := substream2_number_lvl0_map["number"]; ok {
-			assert.EqualValues(t, 103, substream2_number_val)
-		} else {
-			t.Fatalf("Field 'number' not found in substream2_number_lvl0_map (keys: %v)", maps.Keys(substream2_number_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'substream2' not found or not a map while asserting Substream2.Number")
-	}
-
+	checkExprIoPos(t, customMap)
 }
 
 func TestSerialize_ExprIoPos(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_io_pos.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "expr_io_pos.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := expr_io_pos_kaitai.NewExprIoPos()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapExprIoPos(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := expr_io_pos_kaitai.NewExprIoPos()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(expr_io_pos_kaitai.ExprIoPos{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "expr_io_pos.ksy", "expr_io_pos.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkExprIoPos(t, roundTrip(t, "expr_io_pos.ksy", customMap))
 }
 
-// structToMapExprIoPos converts KSC struct to map for serializer
-func structToMapExprIoPos(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveExprIoPos(t, data)
-}
-
-// structToMapReflectiveExprIoPos uses reflection to convert KSC structs including method calls
-func structToMapReflectiveExprIoPos(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveExprIoPos(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkExprIoPos applies the assertions of the KSC test to parsed data
+func checkExprIoPos(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "CURIOSITY", customMap, "substream1", "my_str")
+	assertValue(t, []uint8{17, 34, 51, 68}, customMap, "substream1", "body")
+	assertValue(t, 66, customMap, "substream1", "number")
+	assertValue(t, "KILLED", customMap, "substream2", "my_str")
+	assertValue(t, []uint8{97, 32, 99, 97, 116}, customMap, "substream2", "body")
+	assertValue(t, 103, customMap, "substream2", "number")
 }
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprIoTernary(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_io_ternary.ksy", "if_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprIoTernary(t, customMap)
+}
+
+func TestSerialize_ExprIoTernary(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_io_ternary.ksy", "if_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprIoTernary(t, roundTrip(t, "expr_io_ternary.ksy", customMap))
+}
+
+// checkExprIoTernary applies the assertions of the KSC test to parsed data
+func checkExprIoTernary(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 8, customMap, "one_or_two_io_size1")
+	assertValue(t, 8, customMap, "one_or_two_io_size2")
+	assertValue(t, 11, customMap, "one_or_two_io_size_add_3")
+}
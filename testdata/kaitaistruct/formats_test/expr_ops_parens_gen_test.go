@@ -0,0 +1,34 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprOpsParens(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_ops_parens.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprOpsParens(t, customMap)
+}
+
+func TestSerialize_ExprOpsParens(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_ops_parens.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprOpsParens(t, roundTrip(t, "expr_ops_parens.ksy", customMap))
+}
+
+// checkExprOpsParens applies the assertions of the KSC test to parsed data
+func checkExprOpsParens(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "29", customMap, "i_sum_to_str")
+	assertValue(t, 9, customMap, "f_sum_to_int")
+	assertValue(t, 10, customMap, "str_concat_len")
+	assertValue(t, "9876543210", customMap, "str_concat_rev")
+	assertValue(t, "23456", customMap, "str_concat_substr_2_to_7")
+	assertValue(t, 123456789, customMap, "str_concat_to_i")
+	assertValue(t, 0, customMap, "bool_eq")
+	assertValue(t, 0, customMap, "bool_and")
+	assertValue(t, 1, customMap, "bool_or")
+}
@@ -1,215 +1,30 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	expr_sizeof_value_0_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/expr_sizeof_value_0"
-)
+import "testing"
 
 func TestParse_ExprSizeofValue0(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_sizeof_value_0.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert SelfSizeof
-	if self_sizeof_val, ok := customMap["self_sizeof"]; ok {
-		assert.EqualValues(t, (((1 + 4) + 2) + 2), self_sizeof_val)
-	} else {
-		t.Fatalf("Field 'self_sizeof' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert SizeofBlock
-	if sizeof_block_val, ok := customMap["sizeof_block"]; ok {
-		assert.EqualValues(t, ((1 + 4) + 2), sizeof_block_val)
-	} else {
-		t.Fatalf("Field 'sizeof_block' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert SizeofBlockA
-	if sizeof_block_a_val, ok := customMap["sizeof_block_a"]; ok {
-		assert.EqualValues(t, 1, sizeof_block_a_val)
-	} else {
-		t.Fatalf("Field 'sizeof_block_a' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "expr_sizeof_value_0.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert SizeofBlockB
-	if sizeof_block_b_val, ok := customMap["sizeof_block_b"]; ok {
-		assert.EqualValues(t, 4, sizeof_block_b_val)
-	} else {
-		t.Fatalf("Field 'sizeof_block_b' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert SizeofBlockC
-	if sizeof_block_c_val, ok := customMap["sizeof_block_c"]; ok {
-		assert.EqualValues(t, 2, sizeof_block_c_val)
-	} else {
-		t.Fatalf("Field 'sizeof_block_c' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
+	checkExprSizeofValue0(t, customMap)
 }
 
 func TestSerialize_ExprSizeofValue0(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "expr_sizeof_value_0.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := expr_sizeof_value_0_kaitai.NewExprSizeofValue0()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapExprSizeofValue0(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := expr_sizeof_value_0_kaitai.NewExprSizeofValue0()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(expr_sizeof_value_0_kaitai.ExprSizeofValue0{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "expr_sizeof_value_0.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapExprSizeofValue0 converts KSC struct to map for serializer
-func structToMapExprSizeofValue0(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveExprSizeofValue0(t, data)
+	checkExprSizeofValue0(t, roundTrip(t, "expr_sizeof_value_0.ksy", customMap))
 }
 
-// structToMapReflectiveExprSizeofValue0 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveExprSizeofValue0(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveExprSizeofValue0(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkExprSizeofValue0 applies the assertions of the KSC test to parsed data
+func checkExprSizeofValue0(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, (((1 + 4) + 2) + 2), customMap, "self_sizeof")
+	assertValue(t, ((1 + 4) + 2), customMap, "sizeof_block")
+	assertValue(t, 1, customMap, "sizeof_block_a")
+	assertValue(t, 4, customMap, "sizeof_block_b")
+	assertValue(t, 2, customMap, "sizeof_block_c")
 }
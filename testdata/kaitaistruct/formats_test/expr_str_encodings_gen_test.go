@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ExprStrEncodings(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_str_encodings.ksy", "str_encodings.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkExprStrEncodings(t, customMap)
+}
+
+func TestSerialize_ExprStrEncodings(t *testing.T) {
+	customMap, err := parseFormat(t, "expr_str_encodings.ksy", "str_encodings.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkExprStrEncodings(t, roundTrip(t, "expr_str_encodings.ksy", customMap))
+}
+
+// checkExprStrEncodings applies the assertions of the KSC test to parsed data
+func checkExprStrEncodings(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, true, customMap, "str1_eq")
+	assertValue(t, true, customMap, "str2_eq")
+	assertValue(t, true, customMap, "str3_eq")
+	assertValue(t, true, customMap, "str3_eq_str2")
+	assertValue(t, true, customMap, "str4_eq")
+	assertValue(t, true, customMap, "str4_gt_str_calc")
+	assertValue(t, true, customMap, "str4_gt_str_from_bytes")
+}
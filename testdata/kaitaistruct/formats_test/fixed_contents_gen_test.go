@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_FixedContents(t *testing.T) {
+	customMap, err := parseFormat(t, "fixed_contents.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkFixedContents(t, customMap)
+}
+
+func TestSerialize_FixedContents(t *testing.T) {
+	customMap, err := parseFormat(t, "fixed_contents.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkFixedContents(t, roundTrip(t, "fixed_contents.ksy", customMap))
+}
+
+// checkFixedContents applies the assertions of the KSC test to parsed data
+func checkFixedContents(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
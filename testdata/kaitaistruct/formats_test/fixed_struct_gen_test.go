@@ -1,400 +1,45 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	fixed_struct_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/fixed_struct"
-)
+import "testing"
 
 func TestParse_FixedStruct(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "fixed_struct.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert tmp1.Uint8
-	if tmp1_uint8_lvl0_map, ok := customMap["tmp1"].(map[string]any); ok {
-		if tmp1_uint8_val, ok := tmp1_uint8_lvl0_map["uint8"]; ok {
-			assert.EqualValues(t, 255, tmp1_uint8_val)
-		} else {
-			t.Fatalf("Field 'uint8' not found in tmp1_uint8_lvl0_map (keys: %v)", maps.Keys(tmp1_uint8_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp1' not found or not a map while asserting tmp1.Uint8")
-	}
-
-	// Assert tmp2.Uint16
-	if tmp2_uint16_lvl0_map, ok := customMap["tmp2"].(map[string]any); ok {
-		if tmp2_uint16_val, ok := tmp2_uint16_lvl0_map["uint16"]; ok {
-			assert.EqualValues(t, 65535, tmp2_uint16_val)
-		} else {
-			t.Fatalf("Field 'uint16' not found in tmp2_uint16_lvl0_map (keys: %v)", maps.Keys(tmp2_uint16_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp2' not found or not a map while asserting tmp2.Uint16")
-	}
-
-	// Assert tmp3.Uint32
-	if tmp3_uint32_lvl0_map, ok := customMap["tmp3"].(map[string]any); ok {
-		if tmp3_uint32_val, ok := tmp3_uint32_lvl0_map["uint32"]; ok {
-			assert.EqualValues(t, uint32(4294967295), tmp3_uint32_val)
-		} else {
-			t.Fatalf("Field 'uint32' not found in tmp3_uint32_lvl0_map (keys: %v)", maps.Keys(tmp3_uint32_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp3' not found or not a map while asserting tmp3.Uint32")
-	}
-
-	// Assert tmp4.Uint64
-	if tmp4_uint64_lvl0_map, ok := customMap["tmp4"].(map[string]any); ok {
-		if tmp4_uint64_val, ok := tmp4_uint64_lvl0_map["uint64"]; ok {
-			assert.EqualValues(t, uint64(18446744073709551615), tmp4_uint64_val)
-		} else {
-			t.Fatalf("Field 'uint64' not found in tmp4_uint64_lvl0_map (keys: %v)", maps.Keys(tmp4_uint64_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp4' not found or not a map while asserting tmp4.Uint64")
-	}
-
-	// Assert tmp5.Sint8
-	if tmp5_sint8_lvl0_map, ok := customMap["tmp5"].(map[string]any); ok {
-		if tmp5_sint8_val, ok := tmp5_sint8_lvl0_map["sint8"]; ok {
-			assert.EqualValues(t, -1, tmp5_sint8_val)
-		} else {
-			t.Fatalf("Field 'sint8' not found in tmp5_sint8_lvl0_map (keys: %v)", maps.Keys(tmp5_sint8_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp5' not found or not a map while asserting tmp5.Sint8")
-	}
-
-	// Assert tmp6.Sint16
-	if tmp6_sint16_lvl0_map, ok := customMap["tmp6"].(map[string]any); ok {
-		if tmp6_sint16_val, ok := tmp6_sint16_lvl0_map["sint16"]; ok {
-			assert.EqualValues(t, -1, tmp6_sint16_val)
-		} else {
-			t.Fatalf("Field 'sint16' not found in tmp6_sint16_lvl0_map (keys: %v)", maps.Keys(tmp6_sint16_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp6' not found or not a map while asserting tmp6.Sint16")
-	}
-
-	// Assert tmp7.Sint32
-	if tmp7_sint32_lvl0_map, ok := customMap["tmp7"].(map[string]any); ok {
-		if tmp7_sint32_val, ok := tmp7_sint32_lvl0_map["sint32"]; ok {
-			assert.EqualValues(t, -1, tmp7_sint32_val)
-		} else {
-			t.Fatalf("Field 'sint32' not found in tmp7_sint32_lvl0_map (keys: %v)", maps.Keys(tmp7_sint32_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp7' not found or not a map while asserting tmp7.Sint32")
-	}
-
-	// Assert tmp8.Sint64
-	if tmp8_sint64_lvl0_map, ok := customMap["tmp8"].(map[string]any); ok {
-		if tmp8_sint64_val, ok := tmp8_sint64_lvl0_map["sint64"]; ok {
-			assert.EqualValues(t, -1, tmp8_sint64_val)
-		} else {
-			t.Fatalf("Field 'sint64' not found in tmp8_sint64_lvl0_map (keys: %v)", maps.Keys(tmp8_sint64_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp8' not found or not a map while asserting tmp8.Sint64")
-	}
-
-	// Assert tmp9.Uint16le
-	if tmp9_uint16le_lvl0_map, ok := customMap["tmp9"].(map[string]any); ok {
-		if tmp9_uint16le_val, ok := tmp9_uint16le_lvl0_map["uint16le"]; ok {
-			assert.EqualValues(t, 66, tmp9_uint16le_val)
-		} else {
-			t.Fatalf("Field 'uint16le' not found in tmp9_uint16le_lvl0_map (keys: %v)", maps.Keys(tmp9_uint16le_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp9' not found or not a map while asserting tmp9.Uint16le")
-	}
-
-	// Assert tmp10.Uint32le
-	if tmp10_uint32le_lvl0_map, ok := customMap["tmp10"].(map[string]any); ok {
-		if tmp10_uint32le_val, ok := tmp10_uint32le_lvl0_map["uint32le"]; ok {
-			assert.EqualValues(t, 66, tmp10_uint32le_val)
-		} else {
-			t.Fatalf("Field 'uint32le' not found in tmp10_uint32le_lvl0_map (keys: %v)", maps.Keys(tmp10_uint32le_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp10' not found or not a map while asserting tmp10.Uint32le")
-	}
-
-	// Assert tmp11.Uint64le
-	if tmp11_uint64le_lvl0_map, ok := customMap["tmp11"].(map[string]any); ok {
-		if tmp11_uint64le_val, ok := tmp11_uint64le_lvl0_map["uint64le"]; ok {
-			assert.EqualValues(t, 66, tmp11_uint64le_val)
-		} else {
-			t.Fatalf("Field 'uint64le' not found in tmp11_uint64le_lvl0_map (keys: %v)", maps.Keys(tmp11_uint64le_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp11' not found or not a map while asserting tmp11.Uint64le")
+	customMap, err := parseFormat(t, "fixed_struct.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert tmp12.Sint16le
-	if tmp12_sint16le_lvl0_map, ok := customMap["tmp12"].(map[string]any); ok {
-		if tmp12_sint16le_val, ok := tmp12_sint16le_lvl0_map["sint16le"]; ok {
-			assert.EqualValues(t, -66, tmp12_sint16le_val)
-		} else {
-			t.Fatalf("Field 'sint16le' not found in tmp12_sint16le_lvl0_map (keys: %v)", maps.Keys(tmp12_sint16le_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp12' not found or not a map while asserting tmp12.Sint16le")
-	}
-
-	// Assert tmp13.Sint32le
-	if tmp13_sint32le_lvl0_map, ok := customMap["tmp13"].(map[string]any); ok {
-		if tmp13_sint32le_val, ok := tmp13_sint32le_lvl0_map["sint32le"]; ok {
-			assert.EqualValues(t, -66, tmp13_sint32le_val)
-		} else {
-			t.Fatalf("Field 'sint32le' not found in tmp13_sint32le_lvl0_map (keys: %v)", maps.Keys(tmp13_sint32le_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp13' not found or not a map while asserting tmp13.Sint32le")
-	}
-
-	// Assert tmp14.Sint64le
-	if tmp14_sint64le_lvl0_map, ok := customMap["tmp14"].(map[string]any); ok {
-		if tmp14_sint64le_val, ok := tmp14_sint64le_lvl0_map["sint64le"]; ok {
-			assert.EqualValues(t, -66, tmp14_sint64le_val)
-		} else {
-			t.Fatalf("Field 'sint64le' not found in tmp14_sint64le_lvl0_map (keys: %v)", maps.Keys(tmp14_sint64le_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp14' not found or not a map while asserting tmp14.Sint64le")
-	}
-
-	// Assert tmp15.Uint16be
-	if tmp15_uint16be_lvl0_map, ok := customMap["tmp15"].(map[string]any); ok {
-		if tmp15_uint16be_val, ok := tmp15_uint16be_lvl0_map["uint16be"]; ok {
-			assert.EqualValues(t, 66, tmp15_uint16be_val)
-		} else {
-			t.Fatalf("Field 'uint16be' not found in tmp15_uint16be_lvl0_map (keys: %v)", maps.Keys(tmp15_uint16be_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp15' not found or not a map while asserting tmp15.Uint16be")
-	}
-
-	// Assert tmp16.Uint32be
-	if tmp16_uint32be_lvl0_map, ok := customMap["tmp16"].(map[string]any); ok {
-		if tmp16_uint32be_val, ok := tmp16_uint32be_lvl0_map["uint32be"]; ok {
-			assert.EqualValues(t, 66, tmp16_uint32be_val)
-		} else {
-			t.Fatalf("Field 'uint32be' not found in tmp16_uint32be_lvl0_map (keys: %v)", maps.Keys(tmp16_uint32be_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp16' not found or not a map while asserting tmp16.Uint32be")
-	}
-
-	// Assert tmp17.Uint64be
-	if tmp17_uint64be_lvl0_map, ok := customMap["tmp17"].(map[string]any); ok {
-		if tmp17_uint64be_val, ok := tmp17_uint64be_lvl0_map["uint64be"]; ok {
-			assert.EqualValues(t, 66, tmp17_uint64be_val)
-		} else {
-			t.Fatalf("Field 'uint64be' not found in tmp17_uint64be_lvl0_map (keys: %v)", maps.Keys(tmp17_uint64be_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp17' not found or not a map while asserting tmp17.Uint64be")
-	}
-
-	// Assert tmp18.Sint16be
-	if tmp18_sint16be_lvl0_map, ok := customMap["tmp18"].(map[string]any); ok {
-		if tmp18_sint16be_val, ok := tmp18_sint16be_lvl0_map["sint16be"]; ok {
-			assert.EqualValues(t, -66, tmp18_sint16be_val)
-		} else {
-			t.Fatalf("Field 'sint16be' not found in tmp18_sint16be_lvl0_map (keys: %v)", maps.Keys(tmp18_sint16be_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp18' not found or not a map while asserting tmp18.Sint16be")
-	}
-
-	// Assert tmp19.Sint32be
-	if tmp19_sint32be_lvl0_map, ok := customMap["tmp19"].(map[string]any); ok {
-		if tmp19_sint32be_val, ok := tmp19_sint32be_lvl0_map["sint32be"]; ok {
-			assert.EqualValues(t, -66, tmp19_sint32be_val)
-		} else {
-			t.Fatalf("Field 'sint32be' not found in tmp19_sint32be_lvl0_map (keys: %v)", maps.Keys(tmp19_sint32be_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp19' not found or not a map while asserting tmp19.Sint32be")
-	}
-
-	// Assert tmp20.Sint64be
-	if tmp20_sint64be_lvl0_map, ok := customMap["tmp20"].(map[string]any); ok {
-		if tmp20_sint64be_val, ok := tmp20_sint64be_lvl0_map["sint64be"]; ok {
-			assert.EqualValues(t, -66, tmp20_sint64be_val)
-		} else {
-			t.Fatalf("Field 'sint64be' not found in tmp20_sint64be_lvl0_map (keys: %v)", maps.Keys(tmp20_sint64be_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'tmp20' not found or not a map while asserting tmp20.Sint64be")
-	}
-
+	checkFixedStruct(t, customMap)
 }
 
 func TestSerialize_FixedStruct(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "fixed_struct.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := fixed_struct_kaitai.NewFixedStruct()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapFixedStruct(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := fixed_struct_kaitai.NewFixedStruct()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(fixed_struct_kaitai.FixedStruct{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "fixed_struct.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapFixedStruct converts KSC struct to map for serializer
-func structToMapFixedStruct(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveFixedStruct(t, data)
+	checkFixedStruct(t, roundTrip(t, "fixed_struct.ksy", customMap))
 }
 
-// structToMapReflectiveFixedStruct uses reflection to convert KSC structs including method calls
-func structToMapReflectiveFixedStruct(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveFixedStruct(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkFixedStruct applies the assertions of the KSC test to parsed data
+func checkFixedStruct(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 255, customMap, "hdr", "uint8")
+	assertValue(t, 65535, customMap, "hdr", "uint16")
+	assertValue(t, uint32(4294967295), customMap, "hdr", "uint32")
+	assertValue(t, uint64(18446744073709551615), customMap, "hdr", "uint64")
+	assertValue(t, -1, customMap, "hdr", "sint8")
+	assertValue(t, -1, customMap, "hdr", "sint16")
+	assertValue(t, -1, customMap, "hdr", "sint32")
+	assertValue(t, -1, customMap, "hdr", "sint64")
+	assertValue(t, 66, customMap, "hdr", "uint16le")
+	assertValue(t, 66, customMap, "hdr", "uint32le")
+	assertValue(t, 66, customMap, "hdr", "uint64le")
+	assertValue(t, -66, customMap, "hdr", "sint16le")
+	assertValue(t, -66, customMap, "hdr", "sint32le")
+	assertValue(t, -66, customMap, "hdr", "sint64le")
+	assertValue(t, 66, customMap, "hdr", "uint16be")
+	assertValue(t, 66, customMap, "hdr", "uint32be")
+	assertValue(t, 66, customMap, "hdr", "uint64be")
+	assertValue(t, -66, customMap, "hdr", "sint16be")
+	assertValue(t, -66, customMap, "hdr", "sint32be")
+	assertValue(t, -66, customMap, "hdr", "sint64be")
 }
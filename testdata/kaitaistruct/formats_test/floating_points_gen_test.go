@@ -0,0 +1,33 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_FloatingPoints(t *testing.T) {
+	customMap, err := parseFormat(t, "floating_points.ksy", "floating_points.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkFloatingPoints(t, customMap)
+}
+
+func TestSerialize_FloatingPoints(t *testing.T) {
+	customMap, err := parseFormat(t, "floating_points.ksy", "floating_points.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkFloatingPoints(t, roundTrip(t, "floating_points.ksy", customMap))
+}
+
+// checkFloatingPoints applies the assertions of the KSC test to parsed data
+func checkFloatingPoints(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, float32(0.5), customMap, "single_value")
+	assertValue(t, float32(0.5), customMap, "single_value_be")
+	assertValue(t, 0.25, customMap, "double_value")
+	assertValue(t, 0.25, customMap, "double_value_be")
+	assertInDelta(t, 1.2345, 1e-6, customMap, "approximate_value")
+	assertInDelta(t, 1.5, 1e-6, customMap, "single_value_plus_int")
+	assertInDelta(t, 1.0, 1e-6, customMap, "single_value_plus_float")
+	assertInDelta(t, 0.3, 1e-6, customMap, "double_value_plus_float")
+}
@@ -0,0 +1,26 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_IfInstances(t *testing.T) {
+	customMap, err := parseFormat(t, "if_instances.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkIfInstances(t, customMap)
+}
+
+func TestSerialize_IfInstances(t *testing.T) {
+	customMap, err := parseFormat(t, "if_instances.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkIfInstances(t, roundTrip(t, "if_instances.ksy", customMap))
+}
+
+// checkIfInstances applies the assertions of the KSC test to parsed data
+func checkIfInstances(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertNil(t, customMap, "never_happens")
+}
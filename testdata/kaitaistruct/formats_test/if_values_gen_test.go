@@ -0,0 +1,31 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_IfValues(t *testing.T) {
+	customMap, err := parseFormat(t, "if_values.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkIfValues(t, customMap)
+}
+
+func TestSerialize_IfValues(t *testing.T) {
+	customMap, err := parseFormat(t, "if_values.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkIfValues(t, roundTrip(t, "if_values.ksy", customMap))
+}
+
+// checkIfValues applies the assertions of the KSC test to parsed data
+func checkIfValues(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "codes", 0, "opcode")
+	assertValue(t, 40, customMap, "codes", 0, "half_opcode")
+	assertValue(t, 65, customMap, "codes", 1, "opcode")
+	assertNil(t, customMap, "codes", 1, "half_opcode")
+	assertValue(t, 67, customMap, "codes", 2, "opcode")
+	assertNil(t, customMap, "codes", 2, "half_opcode")
+}
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ImportsAbsAbs(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_abs_abs.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkImportsAbsAbs(t, customMap)
+}
+
+func TestSerialize_ImportsAbsAbs(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_abs_abs.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkImportsAbsAbs(t, roundTrip(t, "imports_abs_abs.ksy", customMap))
+}
+
+// checkImportsAbsAbs applies the assertions of the KSC test to parsed data
+func checkImportsAbsAbs(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "one")
+	assertValue(t, 65, customMap, "two", "one")
+	assertValue(t, 67, customMap, "two", "two", "one")
+}
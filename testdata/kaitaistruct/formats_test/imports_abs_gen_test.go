@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ImportsAbs(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_abs.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkImportsAbs(t, customMap)
+}
+
+func TestSerialize_ImportsAbs(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_abs.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkImportsAbs(t, roundTrip(t, "imports_abs.ksy", customMap))
+}
+
+// checkImportsAbs applies the assertions of the KSC test to parsed data
+func checkImportsAbs(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "len", "value")
+	assertLen(t, 80, customMap, "body")
+}
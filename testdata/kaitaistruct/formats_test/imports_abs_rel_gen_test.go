@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ImportsAbsRel(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_abs_rel.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkImportsAbsRel(t, customMap)
+}
+
+func TestSerialize_ImportsAbsRel(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_abs_rel.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkImportsAbsRel(t, roundTrip(t, "imports_abs_rel.ksy", customMap))
+}
+
+// checkImportsAbsRel applies the assertions of the KSC test to parsed data
+func checkImportsAbsRel(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "one")
+	assertValue(t, 65, customMap, "two", "one")
+	assertValue(t, 67, customMap, "two", "two", "one")
+}
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ImportsCastToImported2(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_cast_to_imported2.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkImportsCastToImported2(t, customMap)
+}
+
+func TestSerialize_ImportsCastToImported2(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_cast_to_imported2.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkImportsCastToImported2(t, roundTrip(t, "imports_cast_to_imported2.ksy", customMap))
+}
+
+// checkImportsCastToImported2 applies the assertions of the KSC test to parsed data
+func checkImportsCastToImported2(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 236, customMap, "hw", "one")
+	assertValue(t, 236, customMap, "two", "hw", "one")
+}
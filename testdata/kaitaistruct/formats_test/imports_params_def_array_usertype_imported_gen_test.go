@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ImportsParamsDefArrayUsertypeImported(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_params_def_array_usertype_imported.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkImportsParamsDefArrayUsertypeImported(t, customMap)
+}
+
+func TestSerialize_ImportsParamsDefArrayUsertypeImported(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_params_def_array_usertype_imported.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkImportsParamsDefArrayUsertypeImported(t, roundTrip(t, "imports_params_def_array_usertype_imported.ksy", customMap))
+}
+
+// checkImportsParamsDefArrayUsertypeImported applies the assertions of the KSC test to parsed data
+func checkImportsParamsDefArrayUsertypeImported(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 2, customMap, "hws")
+	assertValue(t, 236, customMap, "hws", 0, "one")
+	assertValue(t, 187, customMap, "hws", 1, "one")
+	assertValue(t, 236, customMap, "two", "hw0one")
+	assertValue(t, 187, customMap, "two", "hw1one")
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ImportsParamsDefEnumImported(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_params_def_enum_imported.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkImportsParamsDefEnumImported(t, customMap)
+}
+
+func TestSerialize_ImportsParamsDefEnumImported(t *testing.T) {
+	customMap, err := parseFormat(t, "imports_params_def_enum_imported.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkImportsParamsDefEnumImported(t, roundTrip(t, "imports_params_def_enum_imported.ksy", customMap))
+}
+
+// checkImportsParamsDefEnumImported applies the assertions of the KSC test to parsed data
+func checkImportsParamsDefEnumImported(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "one", "pet1")
+	assertValue(t, kscEnum("hare"), customMap, "one", "pet2")
+	assertValue(t, kscEnum("cat"), customMap, "two", "pet1param")
+	assertValue(t, kscEnum("hare"), customMap, "two", "pet2param")
+}
@@ -1,213 +1,28 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	imports_rel_1_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/imports_rel_1"
-)
+import "testing"
 
 func TestParse_ImportsRel1(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "imports_rel_1.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert One
-	if one_val, ok := customMap["one"]; ok {
-		assert.EqualValues(t, 80, one_val)
-	} else {
-		t.Fatalf("Field 'one' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "imports_rel_1.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Two.One
-	if two_one_lvl0_map, ok := customMap["two"].(map[string]any); ok {
-		if two_one_val, ok := two_one_lvl0_map["one"]; ok {
-			assert.EqualValues(t, 65, two_one_val)
-		} else {
-			t.Fatalf("Field 'one' not found in two_one_lvl0_map (keys: %v)", maps.Keys(two_one_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'two' not found or not a map while asserting Two.One")
-	}
-
-	// Assert Two.Two.One
-	if two_two_one_lvl0_map, ok := customMap["two"].(map[string]any); ok {
-		if two_two_one_lvl1_map, ok := two_two_one_lvl0_map["two"].(map[string]any); ok {
-			if two_two_one_val, ok := two_two_one_lvl1_map["one"]; ok {
-				assert.EqualValues(t, 67, two_two_one_val)
-			} else {
-				t.Fatalf("Field 'one' not found in two_two_one_lvl1_map (keys: %v)", maps.Keys(two_two_one_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'two' not found or not a map while asserting Two.Two.One")
-		}
-	} else {
-		t.Fatalf("Field 'two' not found or not a map while asserting Two.Two.One")
-	}
-
+	checkImportsRel1(t, customMap)
 }
 
 func TestSerialize_ImportsRel1(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "imports_rel_1.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := imports_rel_1_kaitai.NewImportsRel1()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapImportsRel1(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := imports_rel_1_kaitai.NewImportsRel1()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(imports_rel_1_kaitai.ImportsRel1{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "imports_rel_1.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkImportsRel1(t, roundTrip(t, "imports_rel_1.ksy", customMap))
 }
 
-// structToMapImportsRel1 converts KSC struct to map for serializer
-func structToMapImportsRel1(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveImportsRel1(t, data)
-}
-
-// structToMapReflectiveImportsRel1 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveImportsRel1(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveImportsRel1(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkImportsRel1 applies the assertions of the KSC test to parsed data
+func checkImportsRel1(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "one")
+	assertValue(t, 65, customMap, "two", "one")
+	assertValue(t, 67, customMap, "two", "two", "one")
 }
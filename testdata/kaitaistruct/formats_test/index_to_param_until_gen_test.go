@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_IndexToParamUntil(t *testing.T) {
+	customMap, err := parseFormat(t, "index_to_param_until.ksy", "index_sizes.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkIndexToParamUntil(t, customMap)
+}
+
+func TestSerialize_IndexToParamUntil(t *testing.T) {
+	customMap, err := parseFormat(t, "index_to_param_until.ksy", "index_sizes.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkIndexToParamUntil(t, roundTrip(t, "index_to_param_until.ksy", customMap))
+}
+
+// checkIndexToParamUntil applies the assertions of the KSC test to parsed data
+func checkIndexToParamUntil(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 3, customMap, "qty")
+	assertValue(t, 1, customMap, "sizes", 0)
+	assertValue(t, 8, customMap, "sizes", 1)
+	assertValue(t, 4, customMap, "sizes", 2)
+	assertValue(t, "A", customMap, "blocks", 0, "buf")
+	assertValue(t, "BBBBBBBB", customMap, "blocks", 1, "buf")
+	assertValue(t, "CCCC", customMap, "blocks", 2, "buf")
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_InstanceIoUser(t *testing.T) {
+	customMap, err := parseFormat(t, "instance_io_user.ksy", "instance_io.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkInstanceIoUser(t, customMap)
+}
+
+func TestSerialize_InstanceIoUser(t *testing.T) {
+	customMap, err := parseFormat(t, "instance_io_user.ksy", "instance_io.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkInstanceIoUser(t, roundTrip(t, "instance_io_user.ksy", customMap))
+}
+
+// checkInstanceIoUser applies the assertions of the KSC test to parsed data
+func checkInstanceIoUser(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 3, customMap, "qty_entries")
+	assertValue(t, "the", customMap, "entries", 0, "name")
+	assertValue(t, "rainy", customMap, "entries", 1, "name")
+	assertValue(t, "day it is", customMap, "entries", 2, "name")
+}
@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_InstanceStdArray(t *testing.T) {
+	customMap, err := parseFormat(t, "instance_std_array.ksy", "instance_std_array.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkInstanceStdArray(t, customMap)
+}
+
+func TestSerialize_InstanceStdArray(t *testing.T) {
+	customMap, err := parseFormat(t, "instance_std_array.ksy", "instance_std_array.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkInstanceStdArray(t, roundTrip(t, "instance_std_array.ksy", customMap))
+}
+
+// checkInstanceStdArray applies the assertions of the KSC test to parsed data
+func checkInstanceStdArray(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 16, customMap, "ofs")
+	assertValue(t, 3, customMap, "qty_entries")
+	assertValue(t, 4, customMap, "entry_size")
+	assertLen(t, 3, customMap, "entries")
+	assertValue(t, []uint8{17, 17, 17, 17}, customMap, "entries", 0)
+	assertValue(t, []uint8{34, 34, 34, 34}, customMap, "entries", 1)
+	assertValue(t, []uint8{51, 51, 51, 51}, customMap, "entries", 2)
+}
@@ -0,0 +1,35 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_InstanceUserArray(t *testing.T) {
+	customMap, err := parseFormat(t, "instance_user_array.ksy", "instance_std_array.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkInstanceUserArray(t, customMap)
+}
+
+func TestSerialize_InstanceUserArray(t *testing.T) {
+	customMap, err := parseFormat(t, "instance_user_array.ksy", "instance_std_array.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkInstanceUserArray(t, roundTrip(t, "instance_user_array.ksy", customMap))
+}
+
+// checkInstanceUserArray applies the assertions of the KSC test to parsed data
+func checkInstanceUserArray(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 16, customMap, "ofs")
+	assertValue(t, 3, customMap, "qty_entries")
+	assertValue(t, 4, customMap, "entry_size")
+	assertLen(t, 3, customMap, "user_entries")
+	assertValue(t, 4369, customMap, "user_entries", 0, "word1")
+	assertValue(t, 4369, customMap, "user_entries", 0, "word2")
+	assertValue(t, 8738, customMap, "user_entries", 1, "word1")
+	assertValue(t, 8738, customMap, "user_entries", 1, "word2")
+	assertValue(t, 13107, customMap, "user_entries", 2, "word1")
+	assertValue(t, 13107, customMap, "user_entries", 2, "word2")
+}
@@ -0,0 +1,33 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_IntegersDoubleOverflow(t *testing.T) {
+	customMap, err := parseFormat(t, "integers_double_overflow.ksy", "integers_double_overflow.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkIntegersDoubleOverflow(t, customMap)
+}
+
+func TestSerialize_IntegersDoubleOverflow(t *testing.T) {
+	customMap, err := parseFormat(t, "integers_double_overflow.ksy", "integers_double_overflow.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkIntegersDoubleOverflow(t, roundTrip(t, "integers_double_overflow.ksy", customMap))
+}
+
+// checkIntegersDoubleOverflow applies the assertions of the KSC test to parsed data
+func checkIntegersDoubleOverflow(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, -int64(9007199254740991), customMap, "signed_safe_min_be")
+	assertValue(t, -int64(9007199254740991), customMap, "signed_safe_min_le")
+	assertValue(t, int64(9007199254740991), customMap, "signed_safe_max_be")
+	assertValue(t, int64(9007199254740991), customMap, "signed_safe_max_le")
+	assertFormatInt(t, "-9007199254740993", customMap, "signed_unsafe_neg_be")
+	assertFormatInt(t, "-9007199254740993", customMap, "signed_unsafe_neg_le")
+	assertFormatInt(t, "9007199254740993", customMap, "signed_unsafe_pos_be")
+	assertFormatInt(t, "9007199254740993", customMap, "signed_unsafe_pos_be")
+}
@@ -1,320 +1,45 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	integers_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/integers"
-)
+import "testing"
 
 func TestParse_Integers(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "integers.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert Uint8
-	if uint8_val, ok := customMap["uint8"]; ok {
-		assert.EqualValues(t, 255, uint8_val)
-	} else {
-		t.Fatalf("Field 'uint8' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint16
-	if uint16_val, ok := customMap["uint16"]; ok {
-		assert.EqualValues(t, 65535, uint16_val)
-	} else {
-		t.Fatalf("Field 'uint16' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint32
-	if uint32_val, ok := customMap["uint32"]; ok {
-		assert.EqualValues(t, uint32(4294967295), uint32_val)
-	} else {
-		t.Fatalf("Field 'uint32' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint64
-	if uint64_val, ok := customMap["uint64"]; ok {
-		assert.EqualValues(t, uint64(18446744073709551615), uint64_val)
-	} else {
-		t.Fatalf("Field 'uint64' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint8
-	if sint8_val, ok := customMap["sint8"]; ok {
-		assert.EqualValues(t, -1, sint8_val)
-	} else {
-		t.Fatalf("Field 'sint8' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint16
-	if sint16_val, ok := customMap["sint16"]; ok {
-		assert.EqualValues(t, -1, sint16_val)
-	} else {
-		t.Fatalf("Field 'sint16' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint32
-	if sint32_val, ok := customMap["sint32"]; ok {
-		assert.EqualValues(t, -1, sint32_val)
-	} else {
-		t.Fatalf("Field 'sint32' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint64
-	if sint64_val, ok := customMap["sint64"]; ok {
-		assert.EqualValues(t, -1, sint64_val)
-	} else {
-		t.Fatalf("Field 'sint64' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint16le
-	if uint16le_val, ok := customMap["uint16le"]; ok {
-		assert.EqualValues(t, 66, uint16le_val)
-	} else {
-		t.Fatalf("Field 'uint16le' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint32le
-	if uint32le_val, ok := customMap["uint32le"]; ok {
-		assert.EqualValues(t, 66, uint32le_val)
-	} else {
-		t.Fatalf("Field 'uint32le' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint64le
-	if uint64le_val, ok := customMap["uint64le"]; ok {
-		assert.EqualValues(t, 66, uint64le_val)
-	} else {
-		t.Fatalf("Field 'uint64le' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "integers.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Sint16le
-	if sint16le_val, ok := customMap["sint16le"]; ok {
-		assert.EqualValues(t, -66, sint16le_val)
-	} else {
-		t.Fatalf("Field 'sint16le' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint32le
-	if sint32le_val, ok := customMap["sint32le"]; ok {
-		assert.EqualValues(t, -66, sint32le_val)
-	} else {
-		t.Fatalf("Field 'sint32le' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint64le
-	if sint64le_val, ok := customMap["sint64le"]; ok {
-		assert.EqualValues(t, -66, sint64le_val)
-	} else {
-		t.Fatalf("Field 'sint64le' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint16be
-	if uint16be_val, ok := customMap["uint16be"]; ok {
-		assert.EqualValues(t, 66, uint16be_val)
-	} else {
-		t.Fatalf("Field 'uint16be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint32be
-	if uint32be_val, ok := customMap["uint32be"]; ok {
-		assert.EqualValues(t, 66, uint32be_val)
-	} else {
-		t.Fatalf("Field 'uint32be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Uint64be
-	if uint64be_val, ok := customMap["uint64be"]; ok {
-		assert.EqualValues(t, 66, uint64be_val)
-	} else {
-		t.Fatalf("Field 'uint64be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint16be
-	if sint16be_val, ok := customMap["sint16be"]; ok {
-		assert.EqualValues(t, -66, sint16be_val)
-	} else {
-		t.Fatalf("Field 'sint16be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint32be
-	if sint32be_val, ok := customMap["sint32be"]; ok {
-		assert.EqualValues(t, -66, sint32be_val)
-	} else {
-		t.Fatalf("Field 'sint32be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Sint64be
-	if sint64be_val, ok := customMap["sint64be"]; ok {
-		assert.EqualValues(t, -66, sint64be_val)
-	} else {
-		t.Fatalf("Field 'sint64be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
+	checkIntegers(t, customMap)
 }
 
 func TestSerialize_Integers(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "integers.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := integers_kaitai.NewIntegers()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapIntegers(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := integers_kaitai.NewIntegers()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(integers_kaitai.Integers{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "integers.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapIntegers converts KSC struct to map for serializer
-func structToMapIntegers(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveIntegers(t, data)
+	checkIntegers(t, roundTrip(t, "integers.ksy", customMap))
 }
 
-// structToMapReflectiveIntegers uses reflection to convert KSC structs including method calls
-func structToMapReflectiveIntegers(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveIntegers(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkIntegers applies the assertions of the KSC test to parsed data
+func checkIntegers(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 255, customMap, "uint8")
+	assertValue(t, 65535, customMap, "uint16")
+	assertValue(t, uint32(4294967295), customMap, "uint32")
+	assertValue(t, uint64(18446744073709551615), customMap, "uint64")
+	assertValue(t, -1, customMap, "sint8")
+	assertValue(t, -1, customMap, "sint16")
+	assertValue(t, -1, customMap, "sint32")
+	assertValue(t, -1, customMap, "sint64")
+	assertValue(t, 66, customMap, "uint16le")
+	assertValue(t, 66, customMap, "uint32le")
+	assertValue(t, 66, customMap, "uint64le")
+	assertValue(t, -66, customMap, "sint16le")
+	assertValue(t, -66, customMap, "sint32le")
+	assertValue(t, -66, customMap, "sint64le")
+	assertValue(t, 66, customMap, "uint16be")
+	assertValue(t, 66, customMap, "uint32be")
+	assertValue(t, 66, customMap, "uint64be")
+	assertValue(t, -66, customMap, "sint16be")
+	assertValue(t, -66, customMap, "sint32be")
+	assertValue(t, -66, customMap, "sint64be")
 }
@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_IoLocalVar(t *testing.T) {
+	customMap, err := parseFormat(t, "io_local_var.ksy", "full256.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkIoLocalVar(t, customMap)
+}
+
+func TestSerialize_IoLocalVar(t *testing.T) {
+	customMap, err := parseFormat(t, "io_local_var.ksy", "full256.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkIoLocalVar(t, roundTrip(t, "io_local_var.ksy", customMap))
+}
+
+// checkIoLocalVar applies the assertions of the KSC test to parsed data
+func checkIoLocalVar(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, []uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, customMap, "skip")
+	assertValue(t, 20, customMap, "followup")
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_MetaTags(t *testing.T) {
+	customMap, err := parseFormat(t, "meta_tags.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkMetaTags(t, customMap)
+}
+
+func TestSerialize_MetaTags(t *testing.T) {
+	customMap, err := parseFormat(t, "meta_tags.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkMetaTags(t, roundTrip(t, "meta_tags.ksy", customMap))
+}
+
+// checkMetaTags applies the assertions of the KSC test to parsed data
+func checkMetaTags(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
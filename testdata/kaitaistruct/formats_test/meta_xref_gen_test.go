@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_MetaXref(t *testing.T) {
+	customMap, err := parseFormat(t, "meta_xref.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkMetaXref(t, customMap)
+}
+
+func TestSerialize_MetaXref(t *testing.T) {
+	customMap, err := parseFormat(t, "meta_xref.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkMetaXref(t, roundTrip(t, "meta_xref.ksy", customMap))
+}
+
+// checkMetaXref applies the assertions of the KSC test to parsed data
+func checkMetaXref(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
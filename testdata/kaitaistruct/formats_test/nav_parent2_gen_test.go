@@ -0,0 +1,35 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_NavParent2(t *testing.T) {
+	customMap, err := parseFormat(t, "nav_parent2.ksy", "nav_parent2.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkNavParent2(t, customMap)
+}
+
+func TestSerialize_NavParent2(t *testing.T) {
+	customMap, err := parseFormat(t, "nav_parent2.ksy", "nav_parent2.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkNavParent2(t, roundTrip(t, "nav_parent2.ksy", customMap))
+}
+
+// checkNavParent2 applies the assertions of the KSC test to parsed data
+func checkNavParent2(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 8, customMap, "ofs_tags")
+	assertValue(t, 2, customMap, "num_tags")
+	assertValue(t, "RAHC", customMap, "tags", 0, "name")
+	assertValue(t, 32, customMap, "tags", 0, "ofs")
+	assertValue(t, 3, customMap, "tags", 0, "num_items")
+	assertValue(t, "foo", customMap, "tags", 0, "tag_content", "content")
+	assertValue(t, "RAHC", customMap, "tags", 1, "name")
+	assertValue(t, 35, customMap, "tags", 1, "ofs")
+	assertValue(t, 6, customMap, "tags", 1, "num_items")
+	assertValue(t, "barbaz", customMap, "tags", 1, "tag_content", "content")
+}
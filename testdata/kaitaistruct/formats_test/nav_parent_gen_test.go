@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_NavParent(t *testing.T) {
+	customMap, err := parseFormat(t, "nav_parent.ksy", "nav.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkNavParent(t, customMap)
+}
+
+func TestSerialize_NavParent(t *testing.T) {
+	customMap, err := parseFormat(t, "nav_parent.ksy", "nav.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkNavParent(t, roundTrip(t, "nav_parent.ksy", customMap))
+}
+
+// checkNavParent applies the assertions of the KSC test to parsed data
+func checkNavParent(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 2, customMap, "header", "qty_entries")
+	assertValue(t, 8, customMap, "header", "filename_len")
+	assertLen(t, 2, customMap, "index", "entries")
+	assertValue(t, "FIRST___", customMap, "index", "entries", 0, "filename")
+	assertValue(t, "SECOND__", customMap, "index", "entries", 1, "filename")
+}
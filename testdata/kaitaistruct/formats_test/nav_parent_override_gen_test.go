@@ -1,213 +1,28 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	nav_parent_override_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/nav_parent_override"
-)
+import "testing"
 
 func TestParse_NavParentOverride(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nav_parent_override.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "nav_parent_codes.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert ChildSize
-	if child_size_val, ok := customMap["child_size"]; ok {
-		assert.EqualValues(t, 3, child_size_val)
-	} else {
-		t.Fatalf("Field 'child_size' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "nav_parent_override.ksy", "nav_parent_codes.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Child1.Data
-	if child1_data_lvl0_map, ok := customMap["child1"].(map[string]any); ok {
-		if child1_data_val, ok := child1_data_lvl0_map["data"]; ok {
-			assert.EqualValues(t, []uint8{73, 49, 50}, child1_data_val)
-		} else {
-			t.Fatalf("Field 'data' not found in child1_data_lvl0_map (keys: %v)", maps.Keys(child1_data_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'child1' not found or not a map while asserting Child1.Data")
-	}
-
-	// Assert Mediator2.Child2.Data
-	if mediator2_child2_data_lvl0_map, ok := customMap["mediator2"].(map[string]any); ok {
-		if mediator2_child2_data_lvl1_map, ok := mediator2_child2_data_lvl0_map["child2"].(map[string]any); ok {
-			if mediator2_child2_data_val, ok := mediator2_child2_data_lvl1_map["data"]; ok {
-				assert.EqualValues(t, []uint8{51, 66, 98}, mediator2_child2_data_val)
-			} else {
-				t.Fatalf("Field 'data' not found in mediator2_child2_data_lvl1_map (keys: %v)", maps.Keys(mediator2_child2_data_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'child2' not found or not a map while asserting Mediator2.Child2.Data")
-		}
-	} else {
-		t.Fatalf("Field 'mediator2' not found or not a map while asserting Mediator2.Child2.Data")
-	}
-
+	checkNavParentOverride(t, customMap)
 }
 
 func TestSerialize_NavParentOverride(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nav_parent_override.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "nav_parent_codes.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := nav_parent_override_kaitai.NewNavParentOverride()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapNavParentOverride(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := nav_parent_override_kaitai.NewNavParentOverride()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(nav_parent_override_kaitai.NavParentOverride{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "nav_parent_override.ksy", "nav_parent_codes.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkNavParentOverride(t, roundTrip(t, "nav_parent_override.ksy", customMap))
 }
 
-// structToMapNavParentOverride converts KSC struct to map for serializer
-func structToMapNavParentOverride(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveNavParentOverride(t, data)
-}
-
-// structToMapReflectiveNavParentOverride uses reflection to convert KSC structs including method calls
-func structToMapReflectiveNavParentOverride(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveNavParentOverride(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkNavParentOverride applies the assertions of the KSC test to parsed data
+func checkNavParentOverride(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 3, customMap, "child_size")
+	assertValue(t, []uint8{73, 49, 50}, customMap, "child_1", "data")
+	assertValue(t, []uint8{51, 66, 98}, customMap, "mediator_2", "child_2", "data")
 }
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_NavParentRecursive(t *testing.T) {
+	customMap, err := parseFormat(t, "nav_parent_recursive.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkNavParentRecursive(t, customMap)
+}
+
+func TestSerialize_NavParentRecursive(t *testing.T) {
+	customMap, err := parseFormat(t, "nav_parent_recursive.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkNavParentRecursive(t, roundTrip(t, "nav_parent_recursive.ksy", customMap))
+}
+
+// checkNavParentRecursive applies the assertions of the KSC test to parsed data
+func checkNavParentRecursive(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 255, customMap, "value")
+	assertValue(t, 1, customMap, "next", "value")
+	assertValue(t, 255, customMap, "next", "parent_value")
+	assertNil(t, customMap, "next", "next")
+}
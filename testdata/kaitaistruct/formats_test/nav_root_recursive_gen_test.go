@@ -1,209 +1,29 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	nav_root_recursive_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/nav_root_recursive"
-)
+import "testing"
 
 func TestParse_NavRootRecursive(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nav_root_recursive.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "enum_negative.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert Value
-	if value_val, ok := customMap["value"]; ok {
-		assert.EqualValues(t, 255, value_val)
-	} else {
-		t.Fatalf("Field 'value' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "nav_root_recursive.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Next.Value
-	if next_value_lvl0_map, ok := customMap["next"].(map[string]any); ok {
-		if next_value_val, ok := next_value_lvl0_map["value"]; ok {
-			assert.EqualValues(t, 1, next_value_val)
-		} else {
-			t.Fatalf("Field 'value' not found in next_value_lvl0_map (keys: %v)", maps.Keys(next_value_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'next' not found or not a map while asserting Next.Value")
-	}
-
-	// Assert Next.RootValue
-	if next_root_value_lvl0_map, ok := customMap["next"].(map[string]any); ok {
-		if next_root_value_val, ok := next_root_value_lvl0_map["root_value"]; ok {
-			assert.EqualValues(t, 255, next_root_value_val)
-		} else {
-			t.Fatalf("Field 'root_value' not found in next_root_value_lvl0_map (keys: %v)", maps.Keys(next_root_value_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'next' not found or not a map while asserting Next.RootValue")
-	}
-
+	checkNavRootRecursive(t, customMap)
 }
 
 func TestSerialize_NavRootRecursive(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nav_root_recursive.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "enum_negative.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := nav_root_recursive_kaitai.NewNavRootRecursive()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapNavRootRecursive(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := nav_root_recursive_kaitai.NewNavRootRecursive()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(nav_root_recursive_kaitai.NavRootRecursive{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "nav_root_recursive.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkNavRootRecursive(t, roundTrip(t, "nav_root_recursive.ksy", customMap))
 }
 
-// structToMapNavRootRecursive converts KSC struct to map for serializer
-func structToMapNavRootRecursive(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveNavRootRecursive(t, data)
-}
-
-// structToMapReflectiveNavRootRecursive uses reflection to convert KSC structs including method calls
-func structToMapReflectiveNavRootRecursive(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveNavRootRecursive(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkNavRootRecursive applies the assertions of the KSC test to parsed data
+func checkNavRootRecursive(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 255, customMap, "value")
+	assertValue(t, 1, customMap, "next", "value")
+	assertValue(t, 255, customMap, "next", "root_value")
+	assertNil(t, customMap, "next", "next")
 }
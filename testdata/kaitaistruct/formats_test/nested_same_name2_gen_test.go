@@ -1,239 +1,30 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	nested_same_name2_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/nested_same_name2"
-)
+import "testing"
 
 func TestParse_NestedSameName2(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_same_name2.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "nested_same_name2.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert Version
-	if version_val, ok := customMap["version"]; ok {
-		assert.EqualValues(t, 66, version_val)
-	} else {
-		t.Fatalf("Field 'version' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert MainData.MainSize
-	if main_data_main_size_lvl0_map, ok := customMap["main_data"].(map[string]any); ok {
-		if main_data_main_size_val, ok := main_data_main_size_lvl0_map["main_size"]; ok {
-			assert.EqualValues(t, 2, main_data_main_size_val)
-		} else {
-			t.Fatalf("Field 'main_size' not found in main_data_main_size_lvl0_map (keys: %v)", maps.Keys(main_data_main_size_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'main_data' not found or not a map while asserting MainData.MainSize")
-	}
-
-	// Assert MainData.Foo.Data1
-	if main_data_foo_data1_lvl0_map, ok := customMap["main_data"].(map[string]any); ok {
-		if main_data_foo_data1_lvl1_map, ok := main_data_foo_data1_lvl0_map["foo"].(map[string]any); ok {
-			if main_data_foo_data1_val, ok := main_data_foo_data1_lvl1_map["data1"]; ok {
-				assert.EqualValues(t, []uint8{17, 17, 17, 17}, main_data_foo_data1_val)
-			} else {
-				t.Fatalf("Field 'data1' not found in main_data_foo_data1_lvl1_map (keys: %v)", maps.Keys(main_data_foo_data1_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'foo' not found or not a map while asserting MainData.Foo.Data1")
-		}
-	} else {
-		t.Fatalf("Field 'main_data' not found or not a map while asserting MainData.Foo.Data1")
+	customMap, err := parseFormat(t, "nested_same_name2.ksy", "nested_same_name2.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Dummy.DummySize
-	if dummy_dummy_size_lvl0_map, ok := customMap["dummy"].(map[string]any); ok {
-		if dummy_dummy_size_val, ok := dummy_dummy_size_lvl0_map["dummy_size"]; ok {
-			assert.EqualValues(t, 3, dummy_dummy_size_val)
-		} else {
-			t.Fatalf("Field 'dummy_size' not found in dummy_dummy_size_lvl0_map (keys: %v)", maps.Keys(dummy_dummy_size_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'dummy' not found or not a map while asserting Dummy.DummySize")
-	}
-
-	// Assert Dummy.Foo.Data2
-	if dummy_foo_data2_lvl0_map, ok := customMap["dummy"].(map[string]any); ok {
-		if dummy_foo_data2_lvl1_map, ok := dummy_foo_data2_lvl0_map["foo"].(map[string]any); ok {
-			if dummy_foo_data2_val, ok := dummy_foo_data2_lvl1_map["data2"]; ok {
-				assert.EqualValues(t, []uint8{34, 34, 34, 34, 34, 34}, dummy_foo_data2_val)
-			} else {
-				t.Fatalf("Field 'data2' not found in dummy_foo_data2_lvl1_map (keys: %v)", maps.Keys(dummy_foo_data2_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'foo' not found or not a map while asserting Dummy.Foo.Data2")
-		}
-	} else {
-		t.Fatalf("Field 'dummy' not found or not a map while asserting Dummy.Foo.Data2")
-	}
-
+	checkNestedSameName2(t, customMap)
 }
 
 func TestSerialize_NestedSameName2(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_same_name2.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "nested_same_name2.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := nested_same_name2_kaitai.NewNestedSameName2()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapNestedSameName2(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := nested_same_name2_kaitai.NewNestedSameName2()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(nested_same_name2_kaitai.NestedSameName2{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "nested_same_name2.ksy", "nested_same_name2.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapNestedSameName2 converts KSC struct to map for serializer
-func structToMapNestedSameName2(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveNestedSameName2(t, data)
+	checkNestedSameName2(t, roundTrip(t, "nested_same_name2.ksy", customMap))
 }
 
-// structToMapReflectiveNestedSameName2 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveNestedSameName2(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveNestedSameName2(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkNestedSameName2 applies the assertions of the KSC test to parsed data
+func checkNestedSameName2(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 66, customMap, "version")
+	assertValue(t, 2, customMap, "main_data", "main_size")
+	assertValue(t, []uint8{17, 17, 17, 17}, customMap, "main_data", "foo", "data1")
+	assertValue(t, 3, customMap, "dummy", "dummy_size")
+	assertValue(t, []uint8{34, 34, 34, 34, 34, 34}, customMap, "dummy", "foo", "data2")
 }
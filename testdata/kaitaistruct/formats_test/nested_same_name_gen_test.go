@@ -1,206 +1,27 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	nested_same_name_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/nested_same_name"
-)
+import "testing"
 
 func TestParse_NestedSameName(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_same_name.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "repeat_n_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert MainData.MainSize
-	if main_data_main_size_lvl0_map, ok := customMap["main_data"].(map[string]any); ok {
-		if main_data_main_size_val, ok := main_data_main_size_lvl0_map["main_size"]; ok {
-			assert.EqualValues(t, 2, main_data_main_size_val)
-		} else {
-			t.Fatalf("Field 'main_size' not found in main_data_main_size_lvl0_map (keys: %v)", maps.Keys(main_data_main_size_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'main_data' not found or not a map while asserting MainData.MainSize")
-	}
-
-	// Assert MainData.Foo.Data
-	if main_data_foo_data_lvl0_map, ok := customMap["main_data"].(map[string]any); ok {
-		if main_data_foo_data_lvl1_map, ok := main_data_foo_data_lvl0_map["foo"].(map[string]any); ok {
-			if main_data_foo_data_val, ok := main_data_foo_data_lvl1_map["data"]; ok {
-				assert.EqualValues(t, []uint8{16, 0, 0, 0}, main_data_foo_data_val)
-			} else {
-				t.Fatalf("Field 'data' not found in main_data_foo_data_lvl1_map (keys: %v)", maps.Keys(main_data_foo_data_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'foo' not found or not a map while asserting MainData.Foo.Data")
-		}
-	} else {
-		t.Fatalf("Field 'main_data' not found or not a map while asserting MainData.Foo.Data")
+	customMap, err := parseFormat(t, "nested_same_name.ksy", "repeat_n_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
+	checkNestedSameName(t, customMap)
 }
 
 func TestSerialize_NestedSameName(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_same_name.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "repeat_n_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := nested_same_name_kaitai.NewNestedSameName()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapNestedSameName(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := nested_same_name_kaitai.NewNestedSameName()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(nested_same_name_kaitai.NestedSameName{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "nested_same_name.ksy", "repeat_n_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapNestedSameName converts KSC struct to map for serializer
-func structToMapNestedSameName(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveNestedSameName(t, data)
+	checkNestedSameName(t, roundTrip(t, "nested_same_name.ksy", customMap))
 }
 
-// structToMapReflectiveNestedSameName uses reflection to convert KSC structs including method calls
-func structToMapReflectiveNestedSameName(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveNestedSameName(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkNestedSameName applies the assertions of the KSC test to parsed data
+func checkNestedSameName(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 2, customMap, "main_data", "main_size")
+	assertValue(t, []uint8{16, 0, 0, 0}, customMap, "main_data", "foo", "data")
 }
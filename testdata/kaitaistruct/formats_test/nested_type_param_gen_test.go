@@ -1,202 +1,27 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	nested_type_param_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/nested_type_param"
-)
+import "testing"
 
 func TestParse_NestedTypeParam(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_type_param.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert MainSeq.MyLen
-	if main_seq_my_len_lvl0_map, ok := customMap["main_seq"].(map[string]any); ok {
-		if main_seq_my_len_val, ok := main_seq_my_len_lvl0_map["my_len"]; ok {
-			assert.EqualValues(t, 5, main_seq_my_len_val)
-		} else {
-			t.Fatalf("Field 'my_len' not found in main_seq_my_len_lvl0_map (keys: %v)", maps.Keys(main_seq_my_len_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'main_seq' not found or not a map while asserting MainSeq.MyLen")
-	}
-
-	// Assert MainSeq.Body
-	if main_seq_body_lvl0_map, ok := customMap["main_seq"].(map[string]any); ok {
-		if main_seq_body_val, ok := main_seq_body_lvl0_map["body"]; ok {
-			assert.EqualValues(t, "foo|b", main_seq_body_val)
-		} else {
-			t.Fatalf("Field 'body' not found in main_seq_body_lvl0_map (keys: %v)", maps.Keys(main_seq_body_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'main_seq' not found or not a map while asserting MainSeq.Body")
+	customMap, err := parseFormat(t, "nested_type_param.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
+	checkNestedTypeParam(t, customMap)
 }
 
 func TestSerialize_NestedTypeParam(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_type_param.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := nested_type_param_kaitai.NewNestedTypeParam()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapNestedTypeParam(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := nested_type_param_kaitai.NewNestedTypeParam()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(nested_type_param_kaitai.NestedTypeParam{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "nested_type_param.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapNestedTypeParam converts KSC struct to map for serializer
-func structToMapNestedTypeParam(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveNestedTypeParam(t, data)
+	checkNestedTypeParam(t, roundTrip(t, "nested_type_param.ksy", customMap))
 }
 
-// structToMapReflectiveNestedTypeParam uses reflection to convert KSC structs including method calls
-func structToMapReflectiveNestedTypeParam(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveNestedTypeParam(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkNestedTypeParam applies the assertions of the KSC test to parsed data
+func checkNestedTypeParam(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 5, customMap, "main_seq", "my_len")
+	assertValue(t, "foo|b", customMap, "main_seq", "body")
 }
@@ -1,243 +1,30 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	nested_types3_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/nested_types3"
-)
+import "testing"
 
 func TestParse_NestedTypes3(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_types3.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert ACc.ValueCc
-	if acc_value_cc_lvl0_map, ok := customMap["acc"].(map[string]any); ok {
-		if acc_value_cc_val, ok := acc_value_cc_lvl0_map["value_cc"]; ok {
-			assert.EqualValues(t, 80, acc_value_cc_val)
-		} else {
-			t.Fatalf("Field 'value_cc' not found in acc_value_cc_lvl0_map (keys: %v)", maps.Keys(acc_value_cc_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'acc' not found or not a map while asserting ACc.ValueCc")
-	}
-
-	// Assert ACD.ValueD
-	if acd_value_d_lvl0_map, ok := customMap["acd"].(map[string]any); ok {
-		if acd_value_d_val, ok := acd_value_d_lvl0_map["value_d"]; ok {
-			assert.EqualValues(t, 65, acd_value_d_val)
-		} else {
-			t.Fatalf("Field 'value_d' not found in acd_value_d_lvl0_map (keys: %v)", maps.Keys(acd_value_d_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'acd' not found or not a map while asserting ACD.ValueD")
-	}
-
-	// Assert B.ValueB
-	if b_value_b_lvl0_map, ok := customMap["b"].(map[string]any); ok {
-		if b_value_b_val, ok := b_value_b_lvl0_map["value_b"]; ok {
-			assert.EqualValues(t, 67, b_value_b_val)
-		} else {
-			t.Fatalf("Field 'value_b' not found in b_value_b_lvl0_map (keys: %v)", maps.Keys(b_value_b_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'b' not found or not a map while asserting B.ValueB")
+	customMap, err := parseFormat(t, "nested_types3.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert B.ACc.ValueCc
-	if b_acc_value_cc_lvl0_map, ok := customMap["b"].(map[string]any); ok {
-		if b_acc_value_cc_lvl1_map, ok := b_acc_value_cc_lvl0_map["acc"].(map[string]any); ok {
-			if b_acc_value_cc_val, ok := b_acc_value_cc_lvl1_map["value_cc"]; ok {
-				assert.EqualValues(t, 75, b_acc_value_cc_val)
-			} else {
-				t.Fatalf("Field 'value_cc' not found in b_acc_value_cc_lvl1_map (keys: %v)", maps.Keys(b_acc_value_cc_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'acc' not found or not a map while asserting B.ACc.ValueCc")
-		}
-	} else {
-		t.Fatalf("Field 'b' not found or not a map while asserting B.ACc.ValueCc")
-	}
-
-	// Assert B.ACD.ValueD
-	if b_acd_value_d_lvl0_map, ok := customMap["b"].(map[string]any); ok {
-		if b_acd_value_d_lvl1_map, ok := b_acd_value_d_lvl0_map["acd"].(map[string]any); ok {
-			if b_acd_value_d_val, ok := b_acd_value_d_lvl1_map["value_d"]; ok {
-				assert.EqualValues(t, 45, b_acd_value_d_val)
-			} else {
-				t.Fatalf("Field 'value_d' not found in b_acd_value_d_lvl1_map (keys: %v)", maps.Keys(b_acd_value_d_lvl1_map))
-			}
-		} else {
-			t.Fatalf("Field 'acd' not found or not a map while asserting B.ACD.ValueD")
-		}
-	} else {
-		t.Fatalf("Field 'b' not found or not a map while asserting B.ACD.ValueD")
-	}
-
+	checkNestedTypes3(t, customMap)
 }
 
 func TestSerialize_NestedTypes3(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "nested_types3.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := nested_types3_kaitai.NewNestedTypes3()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapNestedTypes3(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := nested_types3_kaitai.NewNestedTypes3()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(nested_types3_kaitai.NestedTypes3{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
+	customMap, err := parseFormat(t, "nested_types3.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
-	}
-}
-
-// structToMapNestedTypes3 converts KSC struct to map for serializer
-func structToMapNestedTypes3(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveNestedTypes3(t, data)
+	checkNestedTypes3(t, roundTrip(t, "nested_types3.ksy", customMap))
 }
 
-// structToMapReflectiveNestedTypes3 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveNestedTypes3(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveNestedTypes3(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkNestedTypes3 applies the assertions of the KSC test to parsed data
+func checkNestedTypes3(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "a_cc", "value_cc")
+	assertValue(t, 65, customMap, "a_c_d", "value_d")
+	assertValue(t, 67, customMap, "b", "value_b")
+	assertValue(t, 75, customMap, "b", "a_cc", "value_cc")
+	assertValue(t, 45, customMap, "b", "a_c_d", "value_d")
 }
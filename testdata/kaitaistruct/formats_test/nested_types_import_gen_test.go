@@ -0,0 +1,36 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_NestedTypesImport(t *testing.T) {
+	customMap, err := parseFormat(t, "nested_types_import.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkNestedTypesImport(t, customMap)
+}
+
+func TestSerialize_NestedTypesImport(t *testing.T) {
+	customMap, err := parseFormat(t, "nested_types_import.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkNestedTypesImport(t, roundTrip(t, "nested_types_import.ksy", customMap))
+}
+
+// checkNestedTypesImport applies the assertions of the KSC test to parsed data
+func checkNestedTypesImport(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 80, customMap, "a_cc", "value_cc")
+	assertValue(t, 65, customMap, "a_c_d", "value_d")
+	assertValue(t, 67, customMap, "b", "value_b")
+	assertValue(t, 75, customMap, "b", "a_cc", "value_cc")
+	assertValue(t, 45, customMap, "b", "a_c_d", "value_d")
+	assertNil(t, customMap, "a_cc", "_parent")
+	assertNil(t, customMap, "a_cc", "_root")
+	assertNil(t, customMap, "a_c_d", "_parent")
+	assertNil(t, customMap, "a_c_d", "_root")
+	assertNil(t, customMap, "b", "_parent")
+	assertNil(t, customMap, "b", "_root")
+}
@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_OpaqueExternalType02Parent(t *testing.T) {
+	customMap, err := parseFormat(t, "opaque_external_type_02_parent.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkOpaqueExternalType02Parent(t, customMap)
+}
+
+func TestSerialize_OpaqueExternalType02Parent(t *testing.T) {
+	customMap, err := parseFormat(t, "opaque_external_type_02_parent.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkOpaqueExternalType02Parent(t, roundTrip(t, "opaque_external_type_02_parent.ksy", customMap))
+}
+
+// checkOpaqueExternalType02Parent applies the assertions of the KSC test to parsed data
+func checkOpaqueExternalType02Parent(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo", customMap, "parent", "child", "s1")
+	assertValue(t, "bar", customMap, "parent", "child", "s2")
+	assertValue(t, "|baz@", customMap, "parent", "child", "s3", "s3")
+}
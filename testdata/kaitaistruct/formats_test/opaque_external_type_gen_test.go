@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_OpaqueExternalType(t *testing.T) {
+	customMap, err := parseFormat(t, "opaque_external_type.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkOpaqueExternalType(t, customMap)
+}
+
+func TestSerialize_OpaqueExternalType(t *testing.T) {
+	customMap, err := parseFormat(t, "opaque_external_type.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkOpaqueExternalType(t, roundTrip(t, "opaque_external_type.ksy", customMap))
+}
+
+// checkOpaqueExternalType applies the assertions of the KSC test to parsed data
+func checkOpaqueExternalType(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo", customMap, "one", "s1")
+	assertValue(t, "bar", customMap, "one", "s2")
+	assertValue(t, "|baz@", customMap, "one", "s3")
+}
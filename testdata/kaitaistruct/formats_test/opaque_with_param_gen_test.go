@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_OpaqueWithParam(t *testing.T) {
+	customMap, err := parseFormat(t, "opaque_with_param.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkOpaqueWithParam(t, customMap)
+}
+
+func TestSerialize_OpaqueWithParam(t *testing.T) {
+	customMap, err := parseFormat(t, "opaque_with_param.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkOpaqueWithParam(t, roundTrip(t, "opaque_with_param.ksy", customMap))
+}
+
+// checkOpaqueWithParam applies the assertions of the KSC test to parsed data
+func checkOpaqueWithParam(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo|b", customMap, "one", "buf")
+	assertValue(t, 97, customMap, "one", "trailer")
+}
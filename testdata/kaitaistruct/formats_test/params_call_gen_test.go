@@ -1,213 +1,28 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	params_call_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/params_call"
-)
+import "testing"
 
 func TestParse_ParamsCall(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "params_call.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert Buf1.Body
-	if buf1_body_lvl0_map, ok := customMap["buf1"].(map[string]any); ok {
-		if buf1_body_val, ok := buf1_body_lvl0_map["body"]; ok {
-			assert.EqualValues(t, "foo|b", buf1_body_val)
-		} else {
-			t.Fatalf("Field 'body' not found in buf1_body_lvl0_map (keys: %v)", maps.Keys(buf1_body_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'buf1' not found or not a map while asserting Buf1.Body")
+	customMap, err := parseFormat(t, "params_call.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert Buf2.Body
-	if buf2_body_lvl0_map, ok := customMap["buf2"].(map[string]any); ok {
-		if buf2_body_val, ok := buf2_body_lvl0_map["body"]; ok {
-			assert.EqualValues(t, "ar|ba", buf2_body_val)
-		} else {
-			t.Fatalf("Field 'body' not found in buf2_body_lvl0_map (keys: %v)", maps.Keys(buf2_body_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'buf2' not found or not a map while asserting Buf2.Body")
-	}
-
-	// Assert Buf2.Trailer
-	if buf2_trailer_lvl0_map, ok := customMap["buf2"].(map[string]any); ok {
-		if buf2_trailer_val, ok := buf2_trailer_lvl0_map["trailer"]; ok {
-			assert.EqualValues(t, 122, buf2_trailer_val)
-		} else {
-			t.Fatalf("Field 'trailer' not found in buf2_trailer_lvl0_map (keys: %v)", maps.Keys(buf2_trailer_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'buf2' not found or not a map while asserting Buf2.Trailer")
-	}
-
+	checkParamsCall(t, customMap)
 }
 
 func TestSerialize_ParamsCall(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "params_call.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "term_strz.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := params_call_kaitai.NewParamsCall()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapParamsCall(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := params_call_kaitai.NewParamsCall()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(params_call_kaitai.ParamsCall{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "params_call.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkParamsCall(t, roundTrip(t, "params_call.ksy", customMap))
 }
 
-// structToMapParamsCall converts KSC struct to map for serializer
-func structToMapParamsCall(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveParamsCall(t, data)
-}
-
-// structToMapReflectiveParamsCall uses reflection to convert KSC structs including method calls
-func structToMapReflectiveParamsCall(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveParamsCall(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkParamsCall applies the assertions of the KSC test to parsed data
+func checkParamsCall(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo|b", customMap, "buf1", "body")
+	assertValue(t, "ar|ba", customMap, "buf2", "body")
+	assertValue(t, 122, customMap, "buf2", "trailer")
 }
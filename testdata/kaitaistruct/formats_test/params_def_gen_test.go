@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ParamsDef(t *testing.T) {
+	customMap, err := parseFormat(t, "params_def.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkParamsDef(t, customMap)
+}
+
+func TestSerialize_ParamsDef(t *testing.T) {
+	customMap, err := parseFormat(t, "params_def.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkParamsDef(t, roundTrip(t, "params_def.ksy", customMap))
+}
+
+// checkParamsDef applies the assertions of the KSC test to parsed data
+func checkParamsDef(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo|b", customMap, "buf")
+	assertValue(t, 97, customMap, "trailer")
+}
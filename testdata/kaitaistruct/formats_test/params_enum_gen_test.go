@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ParamsEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "params_enum.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkParamsEnum(t, customMap)
+}
+
+func TestSerialize_ParamsEnum(t *testing.T) {
+	customMap, err := parseFormat(t, "params_enum.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkParamsEnum(t, roundTrip(t, "params_enum.ksy", customMap))
+}
+
+// checkParamsEnum applies the assertions of the KSC test to parsed data
+func checkParamsEnum(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, kscEnum("cat"), customMap, "one")
+	assertValue(t, true, customMap, "invoke_with_param", "is_cat")
+}
@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ParamsPassArrayInt(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_array_int.ksy", "position_to_end.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkParamsPassArrayInt(t, customMap)
+}
+
+func TestSerialize_ParamsPassArrayInt(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_array_int.ksy", "position_to_end.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkParamsPassArrayInt(t, roundTrip(t, "params_pass_array_int.ksy", customMap))
+}
+
+// checkParamsPassArrayInt applies the assertions of the KSC test to parsed data
+func checkParamsPassArrayInt(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 3, customMap, "pass_ints", "nums")
+	assertValue(t, 513, customMap, "pass_ints", "nums", 0)
+	assertValue(t, 1027, customMap, "pass_ints", "nums", 1)
+	assertValue(t, 1541, customMap, "pass_ints", "nums", 2)
+	assertLen(t, 2, customMap, "pass_ints_calc", "nums")
+	assertValue(t, 27643, customMap, "pass_ints_calc", "nums", 0)
+	assertValue(t, 7, customMap, "pass_ints_calc", "nums", 1)
+}
@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ParamsPassArrayStr(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_array_str.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkParamsPassArrayStr(t, customMap)
+}
+
+func TestSerialize_ParamsPassArrayStr(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_array_str.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkParamsPassArrayStr(t, roundTrip(t, "params_pass_array_str.ksy", customMap))
+}
+
+// checkParamsPassArrayStr applies the assertions of the KSC test to parsed data
+func checkParamsPassArrayStr(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 3, customMap, "pass_str_array", "strs")
+	assertValue(t, "fo", customMap, "pass_str_array", "strs", 0)
+	assertValue(t, "o|", customMap, "pass_str_array", "strs", 1)
+	assertValue(t, "ba", customMap, "pass_str_array", "strs", 2)
+	assertLen(t, 2, customMap, "pass_str_array_calc", "strs")
+	assertValue(t, "aB", customMap, "pass_str_array_calc", "strs", 0)
+	assertValue(t, "Cd", customMap, "pass_str_array_calc", "strs", 1)
+}
@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ParamsPassArrayUsertype(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_array_usertype.ksy", "position_to_end.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkParamsPassArrayUsertype(t, customMap)
+}
+
+func TestSerialize_ParamsPassArrayUsertype(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_array_usertype.ksy", "position_to_end.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkParamsPassArrayUsertype(t, roundTrip(t, "params_pass_array_usertype.ksy", customMap))
+}
+
+// checkParamsPassArrayUsertype applies the assertions of the KSC test to parsed data
+func checkParamsPassArrayUsertype(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 2, customMap, "pass_blocks", "bar")
+	assertValue(t, 1, customMap, "pass_blocks", "bar", 0, "foo")
+	assertValue(t, 2, customMap, "pass_blocks", "bar", 1, "foo")
+	assertValue(t, []uint8{3}, customMap, "pass_blocks", "one")
+	assertValue(t, []uint8{4, 5}, customMap, "pass_blocks", "two")
+}
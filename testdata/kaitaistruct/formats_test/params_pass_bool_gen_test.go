@@ -0,0 +1,39 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ParamsPassBool(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_bool.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkParamsPassBool(t, customMap)
+}
+
+func TestSerialize_ParamsPassBool(t *testing.T) {
+	customMap, err := parseFormat(t, "params_pass_bool.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkParamsPassBool(t, roundTrip(t, "params_pass_bool.ksy", customMap))
+}
+
+// checkParamsPassBool applies the assertions of the KSC test to parsed data
+func checkParamsPassBool(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, false, customMap, "s_false")
+	assertValue(t, true, customMap, "s_true")
+	assertValue(t, true, customMap, "seq_b1", "arg")
+	assertLen(t, 1, customMap, "seq_b1", "foo")
+	assertValue(t, false, customMap, "seq_bool", "arg")
+	assertLen(t, 2, customMap, "seq_bool", "foo")
+	assertValue(t, false, customMap, "literal_b1", "arg")
+	assertLen(t, 2, customMap, "literal_b1", "foo")
+	assertValue(t, true, customMap, "literal_bool", "arg")
+	assertLen(t, 1, customMap, "literal_bool", "foo")
+	assertValue(t, true, customMap, "inst_b1", "arg")
+	assertLen(t, 1, customMap, "inst_b1", "foo")
+	assertValue(t, false, customMap, "inst_bool", "arg")
+	assertLen(t, 2, customMap, "inst_bool", "foo")
+}
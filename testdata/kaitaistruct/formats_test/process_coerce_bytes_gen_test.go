@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ProcessCoerceBytes(t *testing.T) {
+	customMap, err := parseFormat(t, "process_coerce_bytes.ksy", "process_coerce_bytes.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkProcessCoerceBytes(t, customMap)
+}
+
+func TestSerialize_ProcessCoerceBytes(t *testing.T) {
+	customMap, err := parseFormat(t, "process_coerce_bytes.ksy", "process_coerce_bytes.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkProcessCoerceBytes(t, roundTrip(t, "process_coerce_bytes.ksy", customMap))
+}
+
+// checkProcessCoerceBytes applies the assertions of the KSC test to parsed data
+func checkProcessCoerceBytes(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 0, customMap, "records", 0, "flag")
+	assertValue(t, []uint8{65, 65, 65, 65}, customMap, "records", 0, "buf")
+	assertValue(t, 1, customMap, "records", 1, "flag")
+	assertValue(t, []uint8{66, 66, 66, 66}, customMap, "records", 1, "buf")
+}
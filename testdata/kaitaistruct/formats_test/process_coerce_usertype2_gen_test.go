@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ProcessCoerceUsertype2(t *testing.T) {
+	customMap, err := parseFormat(t, "process_coerce_usertype2.ksy", "process_coerce_bytes.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkProcessCoerceUsertype2(t, customMap)
+}
+
+func TestSerialize_ProcessCoerceUsertype2(t *testing.T) {
+	customMap, err := parseFormat(t, "process_coerce_usertype2.ksy", "process_coerce_bytes.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkProcessCoerceUsertype2(t, roundTrip(t, "process_coerce_usertype2.ksy", customMap))
+}
+
+// checkProcessCoerceUsertype2 applies the assertions of the KSC test to parsed data
+func checkProcessCoerceUsertype2(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 0, customMap, "records", 0, "flag")
+	assertValue(t, 1094795585, customMap, "records", 0, "buf", "value")
+	assertValue(t, 1, customMap, "records", 1, "flag")
+	assertValue(t, 1111638594, customMap, "records", 1, "buf", "value")
+}
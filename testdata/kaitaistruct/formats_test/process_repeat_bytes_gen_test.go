@@ -0,0 +1,27 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ProcessRepeatBytes(t *testing.T) {
+	customMap, err := parseFormat(t, "process_repeat_bytes.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkProcessRepeatBytes(t, customMap)
+}
+
+func TestSerialize_ProcessRepeatBytes(t *testing.T) {
+	customMap, err := parseFormat(t, "process_repeat_bytes.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkProcessRepeatBytes(t, roundTrip(t, "process_repeat_bytes.ksy", customMap))
+}
+
+// checkProcessRepeatBytes applies the assertions of the KSC test to parsed data
+func checkProcessRepeatBytes(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, []uint8{114, 37, 61, 138, 20}, customMap, "bufs", 0)
+	assertValue(t, []uint8{74, 82, 170, 16, 68}, customMap, "bufs", 1)
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ProcessRepeatUsertype(t *testing.T) {
+	customMap, err := parseFormat(t, "process_repeat_usertype.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkProcessRepeatUsertype(t, customMap)
+}
+
+func TestSerialize_ProcessRepeatUsertype(t *testing.T) {
+	customMap, err := parseFormat(t, "process_repeat_usertype.ksy", "process_xor_4.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkProcessRepeatUsertype(t, roundTrip(t, "process_repeat_usertype.ksy", customMap))
+}
+
+// checkProcessRepeatUsertype applies the assertions of the KSC test to parsed data
+func checkProcessRepeatUsertype(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, -1975704206, customMap, "blocks", 0, "a")
+	assertValue(t, 20, customMap, "blocks", 0, "b")
+	assertValue(t, 279597642, customMap, "blocks", 1, "a")
+	assertValue(t, 68, customMap, "blocks", 1, "b")
+}
@@ -0,0 +1,26 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_RepeatEosBit(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_eos_bit.ksy", "enum_0.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkRepeatEosBit(t, customMap)
+}
+
+func TestSerialize_RepeatEosBit(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_eos_bit.ksy", "enum_0.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkRepeatEosBit(t, roundTrip(t, "repeat_eos_bit.ksy", customMap))
+}
+
+// checkRepeatEosBit applies the assertions of the KSC test to parsed data
+func checkRepeatEosBit(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 16, customMap, "nibbles")
+}
@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_RepeatEosStruct(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_eos_struct.ksy", "repeat_eos_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkRepeatEosStruct(t, customMap)
+}
+
+func TestSerialize_RepeatEosStruct(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_eos_struct.ksy", "repeat_eos_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkRepeatEosStruct(t, roundTrip(t, "repeat_eos_struct.ksy", customMap))
+}
+
+// checkRepeatEosStruct applies the assertions of the KSC test to parsed data
+func checkRepeatEosStruct(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 2, customMap, "chunks")
+	assertValue(t, 0, customMap, "chunks", 0, "offset")
+	assertValue(t, 66, customMap, "chunks", 0, "len")
+	assertValue(t, 66, customMap, "chunks", 1, "offset")
+	assertValue(t, 2069, customMap, "chunks", 1, "len")
+}
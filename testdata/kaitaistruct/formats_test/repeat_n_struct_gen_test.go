@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_RepeatNStruct(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_n_struct.ksy", "repeat_n_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkRepeatNStruct(t, customMap)
+}
+
+func TestSerialize_RepeatNStruct(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_n_struct.ksy", "repeat_n_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkRepeatNStruct(t, roundTrip(t, "repeat_n_struct.ksy", customMap))
+}
+
+// checkRepeatNStruct applies the assertions of the KSC test to parsed data
+func checkRepeatNStruct(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 2, customMap, "chunks")
+	assertValue(t, 16, customMap, "chunks", 0, "offset")
+	assertValue(t, 8312, customMap, "chunks", 0, "len")
+	assertValue(t, 8328, customMap, "chunks", 1, "offset")
+	assertValue(t, 15, customMap, "chunks", 1, "len")
+}
@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_RepeatUntilCalcArrayType(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_until_calc_array_type.ksy", "repeat_until_process.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkRepeatUntilCalcArrayType(t, customMap)
+}
+
+func TestSerialize_RepeatUntilCalcArrayType(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_until_calc_array_type.ksy", "repeat_until_process.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkRepeatUntilCalcArrayType(t, roundTrip(t, "repeat_until_calc_array_type.ksy", customMap))
+}
+
+// checkRepeatUntilCalcArrayType applies the assertions of the KSC test to parsed data
+func checkRepeatUntilCalcArrayType(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 3, customMap, "records")
+	assertValue(t, 232, customMap, "records", 0, "marker")
+	assertValue(t, uint32(2863311546), customMap, "records", 0, "body")
+	assertValue(t, 250, customMap, "records", 1, "marker")
+	assertValue(t, uint32(2863315102), customMap, "records", 1, "body")
+	assertValue(t, 170, customMap, "records", 2, "marker")
+	assertValue(t, 1431655765, customMap, "records", 2, "body")
+}
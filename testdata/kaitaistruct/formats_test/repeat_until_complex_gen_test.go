@@ -0,0 +1,40 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_RepeatUntilComplex(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_until_complex.ksy", "repeat_until_complex.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkRepeatUntilComplex(t, customMap)
+}
+
+func TestSerialize_RepeatUntilComplex(t *testing.T) {
+	customMap, err := parseFormat(t, "repeat_until_complex.ksy", "repeat_until_complex.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkRepeatUntilComplex(t, roundTrip(t, "repeat_until_complex.ksy", customMap))
+}
+
+// checkRepeatUntilComplex applies the assertions of the KSC test to parsed data
+func checkRepeatUntilComplex(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 3, customMap, "first")
+	assertValue(t, 4, customMap, "first", 0, "count")
+	assertValue(t, []int{(0 + 1), 2, 3, 4}, customMap, "first", 0, "values")
+	assertValue(t, 2, customMap, "first", 1, "count")
+	assertValue(t, []int{(0 + 1), 2}, customMap, "first", 1, "values")
+	assertValue(t, 0, customMap, "first", 2, "count")
+	assertLen(t, 4, customMap, "second")
+	assertValue(t, 6, customMap, "second", 0, "count")
+	assertValue(t, []int{(0 + 1), 2, 3, 4, 5, 6}, customMap, "second", 0, "values")
+	assertValue(t, 3, customMap, "second", 1, "count")
+	assertValue(t, []int{(0 + 1), 2, 3}, customMap, "second", 1, "values")
+	assertValue(t, 4, customMap, "second", 2, "count")
+	assertValue(t, []int{(0 + 1), 2, 3, 4}, customMap, "second", 2, "values")
+	assertValue(t, 0, customMap, "second", 3, "count")
+	assertValue(t, []int{(0 + 102), 111, 111, 98, 97, 114, 0}, customMap, "third")
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_StrEncodingsDefault(t *testing.T) {
+	customMap, err := parseFormat(t, "str_encodings_default.ksy", "str_encodings.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkStrEncodingsDefault(t, customMap)
+}
+
+func TestSerialize_StrEncodingsDefault(t *testing.T) {
+	customMap, err := parseFormat(t, "str_encodings_default.ksy", "str_encodings.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkStrEncodingsDefault(t, roundTrip(t, "str_encodings_default.ksy", customMap))
+}
+
+// checkStrEncodingsDefault applies the assertions of the KSC test to parsed data
+func checkStrEncodingsDefault(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "Some ASCII", customMap, "str1")
+	assertValue(t, "\u3053\u3093\u306b\u3061\u306f", customMap, "rest", "str2")
+	assertValue(t, "\u3053\u3093\u306b\u3061\u306f", customMap, "rest", "str3")
+	assertValue(t, "\u2591\u2592\u2593", customMap, "rest", "str4")
+}
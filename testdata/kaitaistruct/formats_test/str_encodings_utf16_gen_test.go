@@ -1,238 +1,31 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	str_encodings_utf16_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/str_encodings_utf16"
-)
+import "testing"
 
 func TestParse_StrEncodingsUtf16(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "str_encodings_utf16.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "str_encodings_utf16.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert LenBe
-	if len_be_val, ok := customMap["len_be"]; ok {
-		assert.EqualValues(t, 12, len_be_val)
-	} else {
-		t.Fatalf("Field 'len_be' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert BeBomRemoved.Bom
-	if be_bom_removed_bom_lvl0_map, ok := customMap["be_bom_removed"].(map[string]any); ok {
-		if be_bom_removed_bom_val, ok := be_bom_removed_bom_lvl0_map["bom"]; ok {
-			assert.EqualValues(t, 65279, be_bom_removed_bom_val)
-		} else {
-			t.Fatalf("Field 'bom' not found in be_bom_removed_bom_lvl0_map (keys: %v)", maps.Keys(be_bom_removed_bom_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'be_bom_removed' not found or not a map while asserting BeBomRemoved.Bom")
-	}
-
-	// Assert BeBomRemoved.Str
-	if be_bom_removed_str_lvl0_map, ok := customMap["be_bom_removed"].(map[string]any); ok {
-		if be_bom_removed_str_val, ok := be_bom_removed_str_lvl0_map["str"]; ok {
-			assert.EqualValues(t, "\u3053\u3093\u306b\u3061\u306f", be_bom_removed_str_val)
-		} else {
-			t.Fatalf("Field 'str' not found in be_bom_removed_str_lvl0_map (keys: %v)", maps.Keys(be_bom_removed_str_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'be_bom_removed' not found or not a map while asserting BeBomRemoved.Str")
+	customMap, err := parseFormat(t, "str_encodings_utf16.ksy", "str_encodings_utf16.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert LenLe
-	if len_le_val, ok := customMap["len_le"]; ok {
-		assert.EqualValues(t, 12, len_le_val)
-	} else {
-		t.Fatalf("Field 'len_le' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert LeBomRemoved.Bom
-	if le_bom_removed_bom_lvl0_map, ok := customMap["le_bom_removed"].(map[string]any); ok {
-		if le_bom_removed_bom_val, ok := le_bom_removed_bom_lvl0_map["bom"]; ok {
-			assert.EqualValues(t, 65279, le_bom_removed_bom_val)
-		} else {
-			t.Fatalf("Field 'bom' not found in le_bom_removed_bom_lvl0_map (keys: %v)", maps.Keys(le_bom_removed_bom_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'le_bom_removed' not found or not a map while asserting LeBomRemoved.Bom")
-	}
-
-	// Assert LeBomRemoved.Str
-	if le_bom_removed_str_lvl0_map, ok := customMap["le_bom_removed"].(map[string]any); ok {
-		if le_bom_removed_str_val, ok := le_bom_removed_str_lvl0_map["str"]; ok {
-			assert.EqualValues(t, "\u3053\u3093\u306b\u3061\u306f", le_bom_removed_str_val)
-		} else {
-			t.Fatalf("Field 'str' not found in le_bom_removed_str_lvl0_map (keys: %v)", maps.Keys(le_bom_removed_str_lvl0_map))
-		}
-	} else {
-		t.Fatalf("Field 'le_bom_removed' not found or not a map while asserting LeBomRemoved.Str")
-	}
-
+	checkStrEncodingsUtf16(t, customMap)
 }
 
 func TestSerialize_StrEncodingsUtf16(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "str_encodings_utf16.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "str_encodings_utf16.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := str_encodings_utf16_kaitai.NewStrEncodingsUtf16()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapStrEncodingsUtf16(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := str_encodings_utf16_kaitai.NewStrEncodingsUtf16()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(str_encodings_utf16_kaitai.StrEncodingsUtf16{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "str_encodings_utf16.ksy", "str_encodings_utf16.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkStrEncodingsUtf16(t, roundTrip(t, "str_encodings_utf16.ksy", customMap))
 }
 
-// structToMapStrEncodingsUtf16 converts KSC struct to map for serializer
-func structToMapStrEncodingsUtf16(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveStrEncodingsUtf16(t, data)
-}
-
-// structToMapReflectiveStrEncodingsUtf16 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveStrEncodingsUtf16(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveStrEncodingsUtf16(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkStrEncodingsUtf16 applies the assertions of the KSC test to parsed data
+func checkStrEncodingsUtf16(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 12, customMap, "len_be")
+	assertValue(t, 65279, customMap, "be_bom_removed", "bom")
+	assertValue(t, "\u3053\u3093\u306b\u3061\u306f", customMap, "be_bom_removed", "str")
+	assertValue(t, 12, customMap, "len_le")
+	assertValue(t, 65279, customMap, "le_bom_removed", "bom")
+	assertValue(t, "\u3053\u3093\u306b\u3061\u306f", customMap, "le_bom_removed", "str")
 }
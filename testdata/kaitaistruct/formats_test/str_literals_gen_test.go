@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_StrLiterals(t *testing.T) {
+	customMap, err := parseFormat(t, "str_literals.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkStrLiterals(t, customMap)
+}
+
+func TestSerialize_StrLiterals(t *testing.T) {
+	customMap, err := parseFormat(t, "str_literals.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkStrLiterals(t, roundTrip(t, "str_literals.ksy", customMap))
+}
+
+// checkStrLiterals applies the assertions of the KSC test to parsed data
+func checkStrLiterals(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, string([]rune{0, 1, 2, 7, 8, 10, 13, 9, 11, 12, 27, 61, 7, 10, 36, 9787}), customMap, "complex_str")
+	assertValue(t, string([]rune{34, 34, 34}), customMap, "double_quotes")
+	assertValue(t, string([]rune{92, 92, 92}), customMap, "backslashes")
+	assertValue(t, string([]rune{0, 50, 50}), customMap, "octal_eatup")
+	assertValue(t, string([]rune{2, 50}), customMap, "octal_eatup2")
+}
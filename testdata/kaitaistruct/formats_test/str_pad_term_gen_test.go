@@ -1,208 +1,29 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	str_pad_term_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/str_pad_term"
-)
+import "testing"
 
 func TestParse_StrPadTerm(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "str_pad_term.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "str_pad_term.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert StrPad
-	if str_pad_val, ok := customMap["str_pad"]; ok {
-		assert.EqualValues(t, "str1", str_pad_val)
-	} else {
-		t.Fatalf("Field 'str_pad' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrTerm
-	if str_term_val, ok := customMap["str_term"]; ok {
-		assert.EqualValues(t, "str2foo", str_term_val)
-	} else {
-		t.Fatalf("Field 'str_term' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrTermAndPad
-	if str_term_and_pad_val, ok := customMap["str_term_and_pad"]; ok {
-		assert.EqualValues(t, "str+++3bar+++", str_term_and_pad_val)
-	} else {
-		t.Fatalf("Field 'str_term_and_pad' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrTermInclude
-	if str_term_include_val, ok := customMap["str_term_include"]; ok {
-		assert.EqualValues(t, "str4baz@", str_term_include_val)
-	} else {
-		t.Fatalf("Field 'str_term_include' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "str_pad_term.ksy", "str_pad_term.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
+	checkStrPadTerm(t, customMap)
 }
 
 func TestSerialize_StrPadTerm(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "str_pad_term.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "str_pad_term.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := str_pad_term_kaitai.NewStrPadTerm()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapStrPadTerm(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := str_pad_term_kaitai.NewStrPadTerm()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(str_pad_term_kaitai.StrPadTerm{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "str_pad_term.ksy", "str_pad_term.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkStrPadTerm(t, roundTrip(t, "str_pad_term.ksy", customMap))
 }
 
-// structToMapStrPadTerm converts KSC struct to map for serializer
-func structToMapStrPadTerm(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveStrPadTerm(t, data)
-}
-
-// structToMapReflectiveStrPadTerm uses reflection to convert KSC structs including method calls
-func structToMapReflectiveStrPadTerm(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveStrPadTerm(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkStrPadTerm applies the assertions of the KSC test to parsed data
+func checkStrPadTerm(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "str1", customMap, "str_pad")
+	assertValue(t, "str2foo", customMap, "str_term")
+	assertValue(t, "str+++3bar+++", customMap, "str_term_and_pad")
+	assertValue(t, "str4baz@", customMap, "str_term_include")
 }
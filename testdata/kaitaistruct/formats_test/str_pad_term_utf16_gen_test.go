@@ -1,201 +1,28 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	str_pad_term_utf16_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/str_pad_term_utf16"
-)
+import "testing"
 
 func TestParse_StrPadTermUtf16(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "str_pad_term_utf16.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "str_pad_term_utf16.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert StrTerm
-	if str_term_val, ok := customMap["str_term"]; ok {
-		assert.EqualValues(t, "a\u0200b", str_term_val)
-	} else {
-		t.Fatalf("Field 'str_term' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "str_pad_term_utf16.ksy", "str_pad_term_utf16.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
-	// Assert StrTermInclude
-	if str_term_include_val, ok := customMap["str_term_include"]; ok {
-		assert.EqualValues(t, "c\u0200d\000", str_term_include_val)
-	} else {
-		t.Fatalf("Field 'str_term_include' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert StrTermAndPad
-	if str_term_and_pad_val, ok := customMap["str_term_and_pad"]; ok {
-		assert.EqualValues(t, "e\u0200f", str_term_and_pad_val)
-	} else {
-		t.Fatalf("Field 'str_term_and_pad' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
+	checkStrPadTermUtf16(t, customMap)
 }
 
 func TestSerialize_StrPadTermUtf16(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "str_pad_term_utf16.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "str_pad_term_utf16.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := str_pad_term_utf16_kaitai.NewStrPadTermUtf16()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapStrPadTermUtf16(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := str_pad_term_utf16_kaitai.NewStrPadTermUtf16()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(str_pad_term_utf16_kaitai.StrPadTermUtf16{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "str_pad_term_utf16.ksy", "str_pad_term_utf16.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkStrPadTermUtf16(t, roundTrip(t, "str_pad_term_utf16.ksy", customMap))
 }
 
-// structToMapStrPadTermUtf16 converts KSC struct to map for serializer
-func structToMapStrPadTermUtf16(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveStrPadTermUtf16(t, data)
-}
-
-// structToMapReflectiveStrPadTermUtf16 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveStrPadTermUtf16(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveStrPadTermUtf16(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkStrPadTermUtf16 applies the assertions of the KSC test to parsed data
+func checkStrPadTermUtf16(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "a\u0200b", customMap, "str_term")
+	assertValue(t, "c\u0200d\000", customMap, "str_term_include")
+	assertValue(t, "e\u0200f", customMap, "str_term_and_pad")
 }
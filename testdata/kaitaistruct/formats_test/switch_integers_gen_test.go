@@ -0,0 +1,34 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_SwitchIntegers(t *testing.T) {
+	customMap, err := parseFormat(t, "switch_integers.ksy", "switch_integers.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkSwitchIntegers(t, customMap)
+}
+
+func TestSerialize_SwitchIntegers(t *testing.T) {
+	customMap, err := parseFormat(t, "switch_integers.ksy", "switch_integers.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkSwitchIntegers(t, roundTrip(t, "switch_integers.ksy", customMap))
+}
+
+// checkSwitchIntegers applies the assertions of the KSC test to parsed data
+func checkSwitchIntegers(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 4, customMap, "opcodes")
+	assertValue(t, 1, customMap, "opcodes", 0, "code")
+	assertValue(t, 7, customMap, "opcodes", 0, "body")
+	assertValue(t, 2, customMap, "opcodes", 1, "code")
+	assertValue(t, 16448, customMap, "opcodes", 1, "body")
+	assertValue(t, 4, customMap, "opcodes", 2, "code")
+	assertValue(t, 4919, customMap, "opcodes", 2, "body")
+	assertValue(t, 8, customMap, "opcodes", 3, "code")
+	assertValue(t, 4919, customMap, "opcodes", 3, "body")
+}
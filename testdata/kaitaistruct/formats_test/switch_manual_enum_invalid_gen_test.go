@@ -0,0 +1,30 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_SwitchManualEnumInvalid(t *testing.T) {
+	customMap, err := parseFormat(t, "switch_manual_enum_invalid.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkSwitchManualEnumInvalid(t, customMap)
+}
+
+func TestSerialize_SwitchManualEnumInvalid(t *testing.T) {
+	customMap, err := parseFormat(t, "switch_manual_enum_invalid.ksy", "enum_negative.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkSwitchManualEnumInvalid(t, roundTrip(t, "switch_manual_enum_invalid.ksy", customMap))
+}
+
+// checkSwitchManualEnumInvalid applies the assertions of the KSC test to parsed data
+func checkSwitchManualEnumInvalid(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertLen(t, 2, customMap, "opcodes")
+	assertValue(t, 255, customMap, "opcodes", 0, "code")
+	assertNil(t, customMap, "opcodes", 0, "body")
+	assertValue(t, 1, customMap, "opcodes", 1, "code")
+	assertNil(t, customMap, "opcodes", 1, "body")
+}
@@ -1,208 +1,29 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	term_strz_utf16_v1_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/term_strz_utf16_v1"
-)
+import "testing"
 
 func TestParse_TermStrzUtf16V1(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "term_strz_utf16_v1.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "term_strz_utf16.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert S1
-	if s1_val, ok := customMap["s1"]; ok {
-		assert.EqualValues(t, "a\u0200b", s1_val)
-	} else {
-		t.Fatalf("Field 's1' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert S2
-	if s2_val, ok := customMap["s2"]; ok {
-		assert.EqualValues(t, "c\u0200d", s2_val)
-	} else {
-		t.Fatalf("Field 's2' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert Term
-	if term_val, ok := customMap["term"]; ok {
-		assert.EqualValues(t, 0, term_val)
-	} else {
-		t.Fatalf("Field 'term' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert S3
-	if s3_val, ok := customMap["s3"]; ok {
-		assert.EqualValues(t, "e\u0200f\000", s3_val)
-	} else {
-		t.Fatalf("Field 's3' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "term_strz_utf16_v1.ksy", "term_strz_utf16.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
+	checkTermStrzUtf16V1(t, customMap)
 }
 
 func TestSerialize_TermStrzUtf16V1(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "term_strz_utf16_v1.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "term_strz_utf16.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := term_strz_utf16_v1_kaitai.NewTermStrzUtf16V1()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapTermStrzUtf16V1(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := term_strz_utf16_v1_kaitai.NewTermStrzUtf16V1()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(term_strz_utf16_v1_kaitai.TermStrzUtf16V1{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "term_strz_utf16_v1.ksy", "term_strz_utf16.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkTermStrzUtf16V1(t, roundTrip(t, "term_strz_utf16_v1.ksy", customMap))
 }
 
-// structToMapTermStrzUtf16V1 converts KSC struct to map for serializer
-func structToMapTermStrzUtf16V1(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveTermStrzUtf16V1(t, data)
-}
-
-// structToMapReflectiveTermStrzUtf16V1 uses reflection to convert KSC structs including method calls
-func structToMapReflectiveTermStrzUtf16V1(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveTermStrzUtf16V1(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkTermStrzUtf16V1 applies the assertions of the KSC test to parsed data
+func checkTermStrzUtf16V1(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "a\u0200b", customMap, "s1")
+	assertValue(t, "c\u0200d", customMap, "s2")
+	assertValue(t, 0, customMap, "term")
+	assertValue(t, "e\u0200f\000", customMap, "s3")
 }
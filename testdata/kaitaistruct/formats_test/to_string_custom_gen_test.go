@@ -0,0 +1,26 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ToStringCustom(t *testing.T) {
+	customMap, err := parseFormat(t, "to_string_custom.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkToStringCustom(t, customMap)
+}
+
+func TestSerialize_ToStringCustom(t *testing.T) {
+	customMap, err := parseFormat(t, "to_string_custom.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkToStringCustom(t, roundTrip(t, "to_string_custom.ksy", customMap))
+}
+
+// checkToStringCustom applies the assertions of the KSC test to parsed data
+func checkToStringCustom(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	// Not translated: assert.EqualValues(t, "s1 = foo, s2 = bar", repr) (unsupported call fmt.Sprint(r))
+}
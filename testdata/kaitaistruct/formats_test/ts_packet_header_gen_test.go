@@ -0,0 +1,32 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_TsPacketHeader(t *testing.T) {
+	customMap, err := parseFormat(t, "ts_packet_header.ksy", "ts_packet.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkTsPacketHeader(t, customMap)
+}
+
+func TestSerialize_TsPacketHeader(t *testing.T) {
+	customMap, err := parseFormat(t, "ts_packet_header.ksy", "ts_packet.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkTsPacketHeader(t, roundTrip(t, "ts_packet_header.ksy", customMap))
+}
+
+// checkTsPacketHeader applies the assertions of the KSC test to parsed data
+func checkTsPacketHeader(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 71, customMap, "sync_byte")
+	assertValue(t, false, customMap, "transport_error_indicator")
+	assertValue(t, false, customMap, "payload_unit_start_indicator")
+	assertValue(t, true, customMap, "transport_priority")
+	assertValue(t, 33, customMap, "pid")
+	assertValue(t, 0, customMap, "transport_scrambling_control")
+	assertValue(t, kscEnum("payload_only"), customMap, "adaptation_field_control")
+}
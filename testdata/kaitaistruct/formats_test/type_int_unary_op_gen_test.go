@@ -1,208 +1,29 @@
-// Code generated by kaitai-test-gen-simple.go; DO NOT EDIT.
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
 package formats_test
 
-import (
-	"bytes"
-	"context"
-	"io"
-	"log/slog"
-	"maps"
-	"os"
-	"path/filepath"
-	"reflect"
-	"strings"
-	"testing"
-
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
-	"github.com/kaitai-io/kaitai_struct_go_runtime/kaitai"
-	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
-	"github.com/twinfer/kbin-plugin/pkg/kaitaistruct"
-	type_int_unary_op_kaitai "github.com/twinfer/kbin-plugin/testdata/formats_kaitai_go_gen/type_int_unary_op"
-)
+import "testing"
 
 func TestParse_TypeIntUnaryOp(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "type_int_unary_op.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	interpreter, err := kaitaistruct.NewKaitaiInterpreter(schema, logger)
-	require.NoError(t, err)
-
-	// Read binary file
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	binData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse with custom parser
-	stream := kaitai.NewStream(bytes.NewReader(binData))
-	parsed, err := interpreter.Parse(context.Background(), stream)
-	require.NoError(t, err)
-
-	// Convert to map for assertions
-	customMap := kaitaistruct.ParsedDataToMap(parsed).(map[string]any)
-
-	// Assertions from KSC test
-
-	// Assert ValueS2
-	if value_s2_val, ok := customMap["value_s2"]; ok {
-		assert.EqualValues(t, 16720, value_s2_val)
-	} else {
-		t.Fatalf("Field 'value_s2' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert ValueS8
-	if value_s8_val, ok := customMap["value_s8"]; ok {
-		assert.EqualValues(t, int64(4706543082108963651), value_s8_val)
-	} else {
-		t.Fatalf("Field 'value_s8' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert UnaryS2
-	if unary_s2_val, ok := customMap["unary_s2"]; ok {
-		assert.EqualValues(t, -16720, unary_s2_val)
-	} else {
-		t.Fatalf("Field 'unary_s2' not found in customMap (keys: %v)", maps.Keys(customMap))
-	}
-
-	// Assert UnaryS8
-	if unary_s8_val, ok := customMap["unary_s8"]; ok {
-		assert.EqualValues(t, int64(-4706543082108963651), unary_s8_val)
-	} else {
-		t.Fatalf("Field 'unary_s8' not found in customMap (keys: %v)", maps.Keys(customMap))
+	customMap, err := parseFormat(t, "type_int_unary_op.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
 	}
-
+	checkTypeIntUnaryOp(t, customMap)
 }
 
 func TestSerialize_TypeIntUnaryOp(t *testing.T) {
-	// Load schema
-	ksyPath := filepath.Join("../../../test/formats", "type_int_unary_op.ksy")
-	yamlData, err := os.ReadFile(ksyPath)
-	require.NoError(t, err)
-
-	schema, err := kaitaistruct.NewKaitaiSchemaFromYAML(yamlData)
-	require.NoError(t, err)
-
-	serializer, err := kaitaistruct.NewKaitaiSerializer(schema, nil)
-	require.NoError(t, err)
-
-	// Read original binary
-	binPath := filepath.Join("../../../test/src", "fixed_struct.bin")
-	originalData, err := os.ReadFile(binPath)
-	require.NoError(t, err)
-
-	// Parse original
-	stream := kaitai.NewStream(bytes.NewReader(originalData))
-	original := type_int_unary_op_kaitai.NewTypeIntUnaryOp()
-	err = original.Read(stream, original, original)
-	require.NoError(t, err)
-
-	// Serialize and verify round-trip
-	inputMap := structToMapTypeIntUnaryOp(t, original)
-
-	serialized, err := serializer.Serialize(context.Background(), inputMap)
-	require.NoError(t, err)
-
-	// Parse serialized data
-	stream2 := kaitai.NewStream(bytes.NewReader(serialized))
-	reparsed := type_int_unary_op_kaitai.NewTypeIntUnaryOp()
-	err = reparsed.Read(stream2, reparsed, reparsed)
-	require.NoError(t, err)
-
-	// Compare structures with Phase 2/3 enhanced comparison
-	cmpOpts := []cmp.Option{
-		cmpopts.IgnoreUnexported(
-			kaitai.Stream{},
-		),
-		cmpopts.IgnoreFields(type_int_unary_op_kaitai.TypeIntUnaryOp{}, "_io", "_parent", "_root"),
-		cmpopts.EquateEmpty(),
-	}
-	if diff := cmp.Diff(original, reparsed, cmpOpts...); diff != "" {
-		t.Errorf("Serialization mismatch (-original +reparsed):\n%s", diff)
+	customMap, err := parseFormat(t, "type_int_unary_op.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
 	}
+	checkTypeIntUnaryOp(t, roundTrip(t, "type_int_unary_op.ksy", customMap))
 }
 
-// structToMapTypeIntUnaryOp converts KSC struct to map for serializer
-func structToMapTypeIntUnaryOp(t *testing.T, data any) map[string]any {
-	// Use reflection to handle KSC structs with method-based values
-	return structToMapReflectiveTypeIntUnaryOp(t, data)
-}
-
-// structToMapReflectiveTypeIntUnaryOp uses reflection to convert KSC structs including method calls
-func structToMapReflectiveTypeIntUnaryOp(t *testing.T, data any) map[string]any {
-	result := make(map[string]any)
-
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
-	}
-
-	typ := val.Type()
-
-	// Process fields
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-
-		// Skip internal KSC fields
-		if strings.HasPrefix(field.Name, "_") {
-			continue
-		}
-
-		fieldName := strings.ToLower(field.Name)
-
-		if !fieldVal.IsValid() || fieldVal.IsZero() {
-			continue
-		}
-
-		// Handle different field types
-		switch fieldVal.Kind() {
-		case reflect.Ptr:
-			if !fieldVal.IsNil() {
-				// For custom types, check for AsInt/AsStr methods
-				subResult := make(map[string]any)
-
-				// Try to call AsInt method
-				if method := fieldVal.MethodByName("AsInt"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asint"] = results[0].Interface()
-					}
-				}
-
-				// Try to call AsStr method
-				if method := fieldVal.MethodByName("AsStr"); method.IsValid() {
-					if results := method.Call(nil); len(results) == 2 && results[1].IsNil() {
-						subResult["asstr"] = results[0].Interface()
-					}
-				}
-
-				// Also include raw fields
-				subMap := structToMapReflectiveTypeIntUnaryOp(t, fieldVal.Interface())
-				for k, v := range subMap {
-					if _, exists := subResult[k]; !exists {
-						subResult[k] = v
-					}
-				}
-
-				result[fieldName] = subResult
-			}
-		case reflect.Slice:
-			if fieldVal.Len() > 0 {
-				slice := make([]any, fieldVal.Len())
-				for j := 0; j < fieldVal.Len(); j++ {
-					slice[j] = fieldVal.Index(j).Interface()
-				}
-				result[fieldName] = slice
-			}
-		default:
-			result[fieldName] = fieldVal.Interface()
-		}
-	}
-
-	return result
+// checkTypeIntUnaryOp applies the assertions of the KSC test to parsed data
+func checkTypeIntUnaryOp(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, 16720, customMap, "value_s2")
+	assertValue(t, int64(4706543082108963651), customMap, "value_s8")
+	assertValue(t, -16720, customMap, "unary_s2")
+	assertValue(t, int64(-4706543082108963651), customMap, "unary_s8")
 }
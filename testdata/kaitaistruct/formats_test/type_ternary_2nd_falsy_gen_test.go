@@ -0,0 +1,38 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_TypeTernary2ndFalsy(t *testing.T) {
+	customMap, err := parseFormat(t, "type_ternary_2nd_falsy.ksy", "switch_integers.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkTypeTernary2ndFalsy(t, customMap)
+}
+
+func TestSerialize_TypeTernary2ndFalsy(t *testing.T) {
+	customMap, err := parseFormat(t, "type_ternary_2nd_falsy.ksy", "switch_integers.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkTypeTernary2ndFalsy(t, roundTrip(t, "type_ternary_2nd_falsy.ksy", customMap))
+}
+
+// checkTypeTernary2ndFalsy applies the assertions of the KSC test to parsed data
+func checkTypeTernary2ndFalsy(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, false, customMap, "v_false")
+	assertValue(t, 0, customMap, "v_int_zero")
+	assertValue(t, -0, customMap, "v_int_neg_zero")
+	assertInDelta(t, 0.0, 1e-6, customMap, "v_float_zero")
+	assertInDelta(t, -0.0, 1e-6, customMap, "v_float_neg_zero")
+	assertValue(t, "0", customMap, "v_str_w_zero")
+	assertRuneCount(t, 1, customMap, "v_str_w_zero")
+	assertValue(t, 7, customMap, "ut", "m")
+	assertNil(t, customMap, "v_null_ut")
+	assertValue(t, "", customMap, "v_str_empty")
+	assertRuneCount(t, 0, customMap, "v_str_empty")
+	assertLen(t, 2, customMap, "int_array")
+	assertLen(t, 0, customMap, "v_int_array_empty")
+}
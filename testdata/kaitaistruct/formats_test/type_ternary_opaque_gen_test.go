@@ -0,0 +1,28 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_TypeTernaryOpaque(t *testing.T) {
+	customMap, err := parseFormat(t, "type_ternary_opaque.ksy", "term_strz.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkTypeTernaryOpaque(t, customMap)
+}
+
+func TestSerialize_TypeTernaryOpaque(t *testing.T) {
+	customMap, err := parseFormat(t, "type_ternary_opaque.ksy", "term_strz.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkTypeTernaryOpaque(t, roundTrip(t, "type_ternary_opaque.ksy", customMap))
+}
+
+// checkTypeTernaryOpaque applies the assertions of the KSC test to parsed data
+func checkTypeTernaryOpaque(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, "foo", customMap, "dif", "s1")
+	assertValue(t, "bar", customMap, "dif", "s2")
+	assertValue(t, "|baz@", customMap, "dif", "s3")
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ValidEqStrEncodings(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_eq_str_encodings.ksy", "str_encodings.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkValidEqStrEncodings(t, customMap)
+}
+
+func TestSerialize_ValidEqStrEncodings(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_eq_str_encodings.ksy", "str_encodings.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkValidEqStrEncodings(t, roundTrip(t, "valid_eq_str_encodings.ksy", customMap))
+}
+
+// checkValidEqStrEncodings applies the assertions of the KSC test to parsed data
+func checkValidEqStrEncodings(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ValidLong(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_long.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkValidLong(t, customMap)
+}
+
+func TestSerialize_ValidLong(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_long.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkValidLong(t, roundTrip(t, "valid_long.ksy", customMap))
+}
+
+// checkValidLong applies the assertions of the KSC test to parsed data
+func checkValidLong(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ValidNotParsedIf(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_not_parsed_if.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkValidNotParsedIf(t, customMap)
+}
+
+func TestSerialize_ValidNotParsedIf(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_not_parsed_if.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkValidNotParsedIf(t, roundTrip(t, "valid_not_parsed_if.ksy", customMap))
+}
+
+// checkValidNotParsedIf applies the assertions of the KSC test to parsed data
+func checkValidNotParsedIf(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ValidOptionalId(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_optional_id.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkValidOptionalId(t, customMap)
+}
+
+func TestSerialize_ValidOptionalId(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_optional_id.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkValidOptionalId(t, roundTrip(t, "valid_optional_id.ksy", customMap))
+}
+
+// checkValidOptionalId applies the assertions of the KSC test to parsed data
+func checkValidOptionalId(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
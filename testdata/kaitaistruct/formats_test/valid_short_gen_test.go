@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ValidShort(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_short.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkValidShort(t, customMap)
+}
+
+func TestSerialize_ValidShort(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_short.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkValidShort(t, roundTrip(t, "valid_short.ksy", customMap))
+}
+
+// checkValidShort applies the assertions of the KSC test to parsed data
+func checkValidShort(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,25 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_ValidSwitch(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_switch.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkValidSwitch(t, customMap)
+}
+
+func TestSerialize_ValidSwitch(t *testing.T) {
+	customMap, err := parseFormat(t, "valid_switch.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkValidSwitch(t, roundTrip(t, "valid_switch.ksy", customMap))
+}
+
+// checkValidSwitch applies the assertions of the KSC test to parsed data
+func checkValidSwitch(t *testing.T, customMap map[string]any) {
+	t.Helper()
+}
@@ -0,0 +1,29 @@
+// Code generated by kaitai-testgen-simple.go; DO NOT EDIT.
+package formats_test
+
+import "testing"
+
+func TestParse_YamlInts(t *testing.T) {
+	customMap, err := parseFormat(t, "yaml_ints.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	checkYamlInts(t, customMap)
+}
+
+func TestSerialize_YamlInts(t *testing.T) {
+	customMap, err := parseFormat(t, "yaml_ints.ksy", "fixed_struct.bin")
+	if err != nil {
+		t.Skipf("parsing: %v", err)
+	}
+	checkYamlInts(t, roundTrip(t, "yaml_ints.ksy", customMap))
+}
+
+// checkYamlInts applies the assertions of the KSC test to parsed data
+func checkYamlInts(t *testing.T, customMap map[string]any) {
+	t.Helper()
+	assertValue(t, uint32(4294967295), customMap, "test_u4_dec")
+	assertValue(t, uint32(4294967295), customMap, "test_u4_hex")
+	assertValue(t, uint64(18446744073709551615), customMap, "test_u8_dec")
+	assertValue(t, uint64(18446744073709551615), customMap, "test_u8_hex")
+}